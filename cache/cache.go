@@ -19,6 +19,15 @@ type Cache interface {
 	// Exists checks if a key exists in the cache
 	Exists(ctx context.Context, key string) (bool, error)
 
+	// AddUnique records a member in an approximate distinct counter, bucketed by time
+	AddUnique(ctx context.Context, counter string, member string, bucket time.Duration) error
+
+	// CountUnique estimates the distinct members added to a counter within the window ending now
+	CountUnique(ctx context.Context, counter string, window time.Duration) (int64, error)
+
+	// CountUniqueAt estimates the distinct members added within the window ending at the given time
+	CountUniqueAt(ctx context.Context, counter string, at time.Time, window time.Duration) (int64, error)
+
 	// Close closes the cache connection
 	Close() error
 }
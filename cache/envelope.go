@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// envelopePrefix starts every value written with RedisConfig.WriteTimestamps, so plain JSON
+// written by other clients, older versions or with the option off can still be read
+const envelopePrefix = `{"_written_at":`
+
+// envelope wraps a stored value with the time it was written, so a Warmer can tell whether
+// an entry is fresher than what it would load
+type envelope struct {
+	WrittenAt int64           `json:"_written_at"` // Unix milliseconds
+	Value     json.RawMessage `json:"_value"`
+}
+
+// encodeValue marshals a value, wrapping it in an envelope stamped with the current time
+// when WriteTimestamps is enabled
+func (r *RedisCache) encodeValue(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil || !r.config.WriteTimestamps {
+		return data, err
+	}
+	return json.Marshal(envelope{WrittenAt: r.now().UnixMilli(), Value: data})
+}
+
+// decodeValue returns a stored value's JSON and the time it was written. Values without an
+// envelope are returned unchanged with a zero time.
+func decodeValue(data []byte) (json.RawMessage, time.Time) {
+	if !bytes.HasPrefix(data, []byte(envelopePrefix)) {
+		return data, time.Time{}
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Value == nil {
+		return data, time.Time{}
+	}
+	return env.Value, time.UnixMilli(env.WrittenAt)
+}
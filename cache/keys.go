@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyHasher transforms a cache key into a fixed-size, opaque form
+type KeyHasher func(key string) string
+
+// SHA256KeyHasher hashes keys to lowercase hex-encoded SHA-256
+func SHA256KeyHasher(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashingEnabled reports whether key hashing is configured
+func (r *RedisCache) hashingEnabled() bool {
+	return r.config.HashKeysLongerThan > 0 || len(r.config.SensitivePrefixes) > 0
+}
+
+// key applies the configured key transformation. Keys with a sensitive prefix keep the
+// prefix readable and hash the remainder; other keys over the length threshold are hashed whole.
+func (r *RedisCache) key(key string) string {
+	if !r.hashingEnabled() {
+		return key
+	}
+
+	hasher := r.config.KeyHasher
+	if hasher == nil {
+		hasher = SHA256KeyHasher
+	}
+
+	for _, prefix := range r.config.SensitivePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return prefix + hasher(key[len(prefix):])
+		}
+	}
+
+	if r.config.HashKeysLongerThan > 0 && len(key) > r.config.HashKeysLongerThan {
+		return hasher(key)
+	}
+
+	return key
+}
+
+// keyMapKey returns the key under which the original form of a hashed key is recorded
+func keyMapKey(hashed string) string {
+	return "keymap:" + hashed
+}
+
+// recordOriginalKey stores the original key for a hashed key when StoreOriginalKeys is enabled
+func (r *RedisCache) recordOriginalKey(ctx context.Context, pipe redis.Pipeliner, key, hashed string, expiration time.Duration) {
+	if !r.config.StoreOriginalKeys || key == hashed {
+		return
+	}
+	pipe.Set(ctx, keyMapKey(hashed), key, expiration)
+}
+
+// DebugResolveKey returns the original key for a hashed key. It only works for keys
+// written while StoreOriginalKeys was enabled and returns ErrKeyNotFound otherwise.
+func (r *RedisCache) DebugResolveKey(ctx context.Context, hashed string) (string, error) {
+	key, err := r.client.Get(ctx, keyMapKey(hashed)).Result()
+	if err == redis.Nil {
+		return "", ErrKeyNotFound
+	}
+	return key, err
+}
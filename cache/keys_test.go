@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHashingCache returns a cache using the given miniredis server and hashing settings
+func newHashingCache(t *testing.T, mr *miniredis.Miniredis, config RedisConfig) *RedisCache {
+	t.Helper()
+
+	config.Address = mr.Addr()
+	c, err := NewRedisCache(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestSHA256KeyHasher_Stable(t *testing.T) {
+	a := SHA256KeyHasher("https://example.com/a?b=c")
+	b := SHA256KeyHasher("https://example.com/a?b=c")
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 64)
+	assert.NotEqual(t, a, SHA256KeyHasher("https://example.com/a?b=d"))
+}
+
+func TestKeyHashing_Threshold(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newHashingCache(t, mr, RedisConfig{HashKeysLongerThan: 16})
+	ctx := context.Background()
+
+	short := "user:1"
+	long := "page:https://example.com/some/very/long/path"
+
+	require.NoError(t, c.Set(ctx, short, "s", time.Minute))
+	require.NoError(t, c.Set(ctx, long, "l", time.Minute))
+
+	// Short keys are stored as-is, long keys hashed
+	assert.True(t, mr.Exists(short))
+	assert.False(t, mr.Exists(long))
+	assert.True(t, mr.Exists(SHA256KeyHasher(long)))
+
+	var got string
+	require.NoError(t, c.Get(ctx, long, &got))
+	assert.Equal(t, "l", got)
+
+	exists, err := c.Exists(ctx, long)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, c.Delete(ctx, long))
+	assert.False(t, mr.Exists(SHA256KeyHasher(long)))
+}
+
+func TestKeyHashing_SensitivePrefixes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newHashingCache(t, mr, RedisConfig{
+		SensitivePrefixes: []string{"email:"},
+		StoreOriginalKeys: true,
+	})
+	ctx := context.Background()
+
+	key := "email:bob@example.com"
+	require.NoError(t, c.Set(ctx, key, "profile", time.Minute))
+
+	hashed := "email:" + SHA256KeyHasher("bob@example.com")
+	assert.True(t, mr.Exists(hashed))
+	for _, k := range mr.Keys() {
+		if !strings.HasPrefix(k, "keymap:") {
+			assert.NotContains(t, k, "bob@example.com")
+		}
+	}
+
+	original, err := c.DebugResolveKey(ctx, hashed)
+	require.NoError(t, err)
+	assert.Equal(t, key, original)
+	assert.Equal(t, time.Minute, mr.TTL(keyMapKey(hashed)))
+
+	_, err = c.DebugResolveKey(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeyHashing_CustomHasher(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newHashingCache(t, mr, RedisConfig{
+		HashKeysLongerThan: 4,
+		KeyHasher:          func(key string) string { return "custom-" + strings.ToUpper(key) },
+	})
+
+	require.NoError(t, c.Set(context.Background(), "abcdef", 1, time.Minute))
+	assert.True(t, mr.Exists("custom-ABCDEF"))
+}
+
+func TestKeyHashing_AppliesToLocksLimitersAndTags(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newHashingCache(t, mr, RedisConfig{SensitivePrefixes: []string{"email:"}})
+	ctx := context.Background()
+
+	key := "email:alice@example.com"
+	hashed := "email:" + SHA256KeyHasher("alice@example.com")
+
+	lock := c.NewDistributedLock(key, time.Minute)
+	require.NoError(t, lock.Acquire(ctx))
+	assert.True(t, mr.Exists("lock:"+hashed))
+	require.NoError(t, lock.Release(ctx))
+
+	limiter := c.NewRateLimiter(time.Minute, 10)
+	_, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, mr.Exists("ratelimit:"+hashed))
+
+	require.NoError(t, c.SetWithTags(ctx, "item", 1, time.Minute, []string{key}))
+	members, err := mr.SMembers("tag:" + hashed)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"item"}, members)
+
+	deleted, err := c.InvalidateTag(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestKeyHashing_DisabledKeepsExistingKeysReachable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	key := "page:https://example.com/some/very/long/path"
+	require.NoError(t, mr.Set(key, `"legacy"`))
+
+	c := newHashingCache(t, mr, RedisConfig{})
+	var got string
+	require.NoError(t, c.Get(ctx, key, &got))
+	assert.Equal(t, "legacy", got)
+}
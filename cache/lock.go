@@ -3,6 +3,8 @@ package cache
 import (
 	"context"
 	"errors"
+	"math/rand/v2"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,26 +19,60 @@ var (
 	ErrLockReleaseUnauthorized = errors.New("lock is not owned by this instance")
 )
 
+// Retry delays for AcquireWithTimeout, doubling from min to max
+const (
+	lockRetryMinDelay = 10 * time.Millisecond
+	lockRetryMaxDelay = 500 * time.Millisecond
+)
+
 // DistributedLock represents a Redis-based distributed lock
 type DistributedLock struct {
 	redis  *redis.Client
 	key    string
+	name   string // Caller's key, for tracing
 	token  string
 	expiry time.Duration
+	config *RedisConfig // Tracing and operation limits
+
+	// Reentrant locks count nested acquisitions by their owner
+	reentrant bool
+	mu        sync.Mutex
+	holds     int
 }
 
 // NewDistributedLock creates a new distributed lock
 func (r *RedisCache) NewDistributedLock(key string, expiry time.Duration) *DistributedLock {
 	return &DistributedLock{
 		redis:  r.client,
-		key:    "lock:" + key,
+		key:    "lock:" + r.key(key),
+		name:   key,
 		token:  uuid.New().String(), // Unique token to identify lock owner
 		expiry: expiry,
+		config: &r.config,
 	}
 }
 
+// NewOwnedLock creates a reentrant lock held on behalf of owner, such as a workflow ID.
+// Acquire succeeds while the lock is held by the same owner, refreshing its expiry, and
+// Release only deletes the key once every Acquire on this lock has been released. Owners
+// must be unique among the lock's users; nested acquisitions are counted per
+// DistributedLock, so reenter through the same value.
+func (r *RedisCache) NewOwnedLock(key, owner string, expiry time.Duration) *DistributedLock {
+	dl := r.NewDistributedLock(key, expiry)
+	dl.token = owner
+	dl.reentrant = true
+	return dl
+}
+
 // Acquire attempts to acquire the lock
-func (dl *DistributedLock) Acquire(ctx context.Context) error {
+func (dl *DistributedLock) Acquire(ctx context.Context) (err error) {
+	ctx, end := startOp(ctx, dl.config, OpLockAcquire, dl.name)
+	defer func() { end(err) }()
+
+	if dl.reentrant {
+		return dl.reenter(ctx)
+	}
+
 	// Use SET NX to set the lock key only if it doesn't exist
 	ok, err := dl.redis.SetNX(ctx, dl.key, dl.token, dl.expiry).Result()
 	if err != nil {
@@ -50,8 +86,87 @@ func (dl *DistributedLock) Acquire(ctx context.Context) error {
 	return nil
 }
 
+// reenter acquires the lock if it is free or already held by this owner
+func (dl *DistributedLock) reenter(ctx context.Context) error {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	// Refresh the expiry if this owner holds the lock (1), or take it if free (2)
+	const script = `
+		local owner = redis.call("GET", KEYS[1])
+		if owner == ARGV[1] then
+			redis.call("PEXPIRE", KEYS[1], ARGV[2])
+			return 1
+		elseif not owner then
+			redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+			return 2
+		end
+		return 0
+	`
+
+	res, err := dl.redis.Eval(ctx, script, []string{dl.key}, dl.token, dl.expiry.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case 0:
+		return ErrLockAcquisitionFailed
+	case 2:
+		// A fresh key starts a new hold count, even if earlier holds expired unreleased
+		dl.holds = 1
+	default:
+		dl.holds++
+	}
+	return nil
+}
+
+// AcquireWithTimeout retries Acquire until it succeeds or wait elapses, in which case it
+// returns ErrLockAcquisitionFailed. Retries back off exponentially with jitter, so waiters
+// spread out instead of hitting Redis together when the lock is released. If ctx is
+// canceled first, its error is returned.
+func (dl *DistributedLock) AcquireWithTimeout(ctx context.Context, wait time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	delay := lockRetryMinDelay
+	for {
+		err := dl.Acquire(waitCtx)
+		if err == nil || (!errors.Is(err, ErrLockAcquisitionFailed) && waitCtx.Err() == nil) {
+			return err
+		}
+
+		// Sleep between half and the full delay
+		timer := time.NewTimer(delay/2 + rand.N(delay/2+1))
+		select {
+		case <-timer.C:
+		case <-waitCtx.Done():
+			timer.Stop()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return ErrLockAcquisitionFailed
+		}
+		delay = min(delay*2, lockRetryMaxDelay)
+	}
+}
+
 // Release releases the lock if it's owned by this instance
-func (dl *DistributedLock) Release(ctx context.Context) error {
+func (dl *DistributedLock) Release(ctx context.Context) (err error) {
+	ctx, end := startOp(ctx, dl.config, OpLockRelease, dl.name)
+	defer func() { end(err) }()
+
+	if dl.reentrant {
+		dl.mu.Lock()
+		defer dl.mu.Unlock()
+
+		// Nested acquisitions only give up their hold
+		if dl.holds > 1 {
+			dl.holds--
+			return nil
+		}
+	}
+
 	// Use Lua script to ensure we only delete our own lock
 	const script = `
 		if redis.call("GET", KEYS[1]) == ARGV[1] then
@@ -66,6 +181,11 @@ func (dl *DistributedLock) Release(ctx context.Context) error {
 		return err
 	}
 
+	if dl.reentrant {
+		// The key is gone or belongs to someone else either way
+		dl.holds = 0
+	}
+
 	if res.(int64) == 0 {
 		return ErrLockReleaseUnauthorized
 	}
@@ -74,7 +194,10 @@ func (dl *DistributedLock) Release(ctx context.Context) error {
 }
 
 // Extend extends the lock's expiry time if it's owned by this instance
-func (dl *DistributedLock) Extend(ctx context.Context, extension time.Duration) error {
+func (dl *DistributedLock) Extend(ctx context.Context, extension time.Duration) (err error) {
+	ctx, end := startOp(ctx, dl.config, OpLockExtend, dl.name)
+	defer func() { end(err) }()
+
 	// Use Lua script to ensure we only extend our own lock
 	const script = `
 		if redis.call("GET", KEYS[1]) == ARGV[1] then
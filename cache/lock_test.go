@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistributedLock_AcquireWithTimeout_WaitsForRelease(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	holder := c.NewDistributedLock("report", time.Minute)
+	require.NoError(t, holder.Acquire(ctx))
+
+	waiter := c.NewDistributedLock("report", time.Minute)
+	assert.ErrorIs(t, waiter.Acquire(ctx), ErrLockAcquisitionFailed)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		holder.Release(ctx)
+	}()
+
+	start := time.Now()
+	require.NoError(t, waiter.AcquireWithTimeout(ctx, 2*time.Second))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// The waiter now owns the lock
+	assert.ErrorIs(t, holder.Release(ctx), ErrLockReleaseUnauthorized)
+	assert.NoError(t, waiter.Release(ctx))
+}
+
+func TestDistributedLock_AcquireWithTimeout_GivesUp(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	holder := c.NewDistributedLock("report", time.Minute)
+	require.NoError(t, holder.Acquire(ctx))
+
+	start := time.Now()
+	err := c.NewDistributedLock("report", time.Minute).AcquireWithTimeout(ctx, 150*time.Millisecond)
+	assert.ErrorIs(t, err, ErrLockAcquisitionFailed)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestDistributedLock_AcquireWithTimeout_ContextCanceled(t *testing.T) {
+	c, _ := newTestCache(t)
+
+	holder := c.NewDistributedLock("report", time.Minute)
+	require.NoError(t, holder.Acquire(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := c.NewDistributedLock("report", time.Minute).AcquireWithTimeout(ctx, 5*time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDistributedLock_Reentrant(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	lock := c.NewOwnedLock("workflow", "order-42", time.Minute)
+	require.NoError(t, lock.Acquire(ctx))
+	mr.FastForward(30 * time.Second)
+	require.NoError(t, lock.Acquire(ctx), "the owner reenters")
+	assert.Equal(t, time.Minute, mr.TTL("lock:workflow"), "reentering refreshes the expiry")
+
+	other := c.NewOwnedLock("workflow", "order-43", time.Minute)
+	assert.ErrorIs(t, other.Acquire(ctx), ErrLockAcquisitionFailed)
+	assert.ErrorIs(t, c.NewDistributedLock("workflow", time.Minute).Acquire(ctx), ErrLockAcquisitionFailed)
+
+	// The first release only gives up the nested hold
+	require.NoError(t, lock.Release(ctx))
+	assert.True(t, mr.Exists("lock:workflow"))
+	assert.ErrorIs(t, other.Acquire(ctx), ErrLockAcquisitionFailed)
+
+	require.NoError(t, lock.Release(ctx))
+	assert.False(t, mr.Exists("lock:workflow"))
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockReleaseUnauthorized)
+
+	require.NoError(t, other.Acquire(ctx))
+}
+
+func TestDistributedLock_ReentrantAfterExpiry(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	lock := c.NewOwnedLock("workflow", "order-42", time.Second)
+	require.NoError(t, lock.Acquire(ctx))
+	require.NoError(t, lock.Acquire(ctx))
+
+	// Once the key expires and another owner takes it, the old holds are not honored
+	mr.FastForward(2 * time.Second)
+	other := c.NewOwnedLock("workflow", "order-43", time.Minute)
+	require.NoError(t, other.Acquire(ctx))
+
+	require.NoError(t, lock.Release(ctx))
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockReleaseUnauthorized)
+	assert.True(t, mr.Exists("lock:workflow"))
+}
+
+func TestDistributedLock_ReentrantRetakeAfterExpiry(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	lock := c.NewOwnedLock("workflow", "order-42", time.Second)
+	require.NoError(t, lock.Acquire(ctx))
+	require.NoError(t, lock.Acquire(ctx))
+
+	// Retaking an expired key starts over with a single hold
+	mr.FastForward(2 * time.Second)
+	require.False(t, mr.Exists("lock:workflow"))
+	require.NoError(t, lock.Acquire(ctx))
+
+	require.NoError(t, lock.Release(ctx))
+	assert.False(t, mr.Exists("lock:workflow"), "one release frees a lock taken once")
+}
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger defines the interface used by the cache package for diagnostics.
+// Arguments after the message are alternating key/value pairs.
+type Logger interface {
+	Warn(message string, args ...interface{})
+}
+
+// stdLogger is the default Logger, writing key=value lines through the standard log package
+type stdLogger struct{}
+
+func (stdLogger) Warn(message string, args ...interface{}) {
+	var b strings.Builder
+	b.WriteString("WARN ")
+	b.WriteString(message)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+	log.Print(b.String())
+}
@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMigrateConflict is returned by Migrate when a destination key exists and the policy is ConflictError
+var ErrMigrateConflict = errors.New("destination key already exists")
+
+// ConflictPolicy decides what Migrate does with keys that already exist in the destination
+type ConflictPolicy int
+
+const (
+	// ConflictSkip leaves existing destination keys untouched
+	ConflictSkip ConflictPolicy = iota
+	// ConflictOverwrite replaces existing destination keys
+	ConflictOverwrite
+	// ConflictError stops the migration with ErrMigrateConflict
+	ConflictError
+)
+
+// MigrateOptions configures Migrate. Patterns and transforms see keys as stored in Redis,
+// after any key hashing.
+type MigrateOptions struct {
+	Pattern       string                  // SCAN match pattern for source keys (default "*")
+	Transform     func(key string) string // Maps a source key to its destination key; returning "" skips the key
+	BatchSize     int64                   // SCAN count hint (default 100)
+	KeysPerSecond int                     // Maximum keys processed per second (0 disables throttling)
+	OnConflict    ConflictPolicy          // What to do when the destination key exists (default ConflictSkip)
+	DeleteSource  bool                    // Delete source keys once copied, turning the copy into a rename
+	CursorKey     string                  // Destination key holding the SCAN cursor so interrupted migrations resume
+	DryRun        bool                    // Count keys and bytes without writing anything
+}
+
+// MigrateReport summarizes a migration
+type MigrateReport struct {
+	Scanned   int64 // Source keys matched by the pattern
+	Copied    int64 // Keys copied, or that would be copied in a dry run
+	Skipped   int64 // Keys left alone: conflicts under ConflictSkip, empty transforms and keys that expired
+	Conflicts int64 // Destination keys that already existed
+	Bytes     int64 // Serialized size of the copied keys
+	Resumed   bool  // The migration continued from a persisted cursor
+}
+
+// errKeyGone reports a source key that expired or was deleted while migrating
+var errKeyGone = errors.New("source key no longer exists")
+
+// migration holds the state of a single Migrate call
+type migration struct {
+	src, dst *RedisCache
+	opts     MigrateOptions
+	report   MigrateReport
+	dump     bool // Copy with DUMP/RESTORE until the destination rejects a payload
+}
+
+// Migrate copies keys matching opts.Pattern from src to dst, which may be the same cache
+// when only the key schema changes. Remaining TTLs are preserved. Values are copied with
+// DUMP/RESTORE, falling back to Get/Set for string values when the instances' payload
+// formats are incompatible. With CursorKey set, progress is saved after every SCAN batch
+// and a later call with the same options continues where an interrupted one stopped.
+func Migrate(ctx context.Context, src, dst *RedisCache, opts MigrateOptions) (MigrateReport, error) {
+	if opts.Pattern == "" {
+		opts.Pattern = "*"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	m := &migration{src: src, dst: dst, opts: opts, dump: true}
+
+	var cursor uint64
+	if opts.CursorKey != "" {
+		saved, err := dst.client.Get(ctx, opts.CursorKey).Result()
+		if err != nil && err != redis.Nil {
+			return m.report, err
+		}
+		if err == nil {
+			cursor, err = strconv.ParseUint(saved, 10, 64)
+			if err != nil {
+				return m.report, fmt.Errorf("invalid migration cursor %q: %w", saved, err)
+			}
+			m.report.Resumed = true
+		}
+	}
+
+	var tick <-chan time.Time
+	if opts.KeysPerSecond > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.KeysPerSecond))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		keys, next, err := src.client.Scan(ctx, cursor, opts.Pattern, opts.BatchSize).Result()
+		if err != nil {
+			return m.report, err
+		}
+
+		for _, key := range keys {
+			if src == dst && key == opts.CursorKey {
+				continue
+			}
+			if tick != nil {
+				select {
+				case <-ctx.Done():
+					return m.report, ctx.Err()
+				case <-tick:
+				}
+			}
+			if err := m.migrateKey(ctx, key); err != nil {
+				return m.report, err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+		if opts.CursorKey != "" && !opts.DryRun {
+			if err := dst.client.Set(ctx, opts.CursorKey, cursor, 0).Err(); err != nil {
+				return m.report, err
+			}
+		}
+	}
+
+	if opts.CursorKey != "" && !opts.DryRun {
+		if err := dst.client.Del(ctx, opts.CursorKey).Err(); err != nil {
+			return m.report, err
+		}
+	}
+	return m.report, nil
+}
+
+// migrateKey copies a single key according to the options
+func (m *migration) migrateKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.report.Scanned++
+
+	dstKey := key
+	if m.opts.Transform != nil {
+		dstKey = m.opts.Transform(key)
+	}
+	if dstKey == "" || (m.src == m.dst && dstKey == key) {
+		m.report.Skipped++
+		return nil
+	}
+
+	exists, err := m.dst.client.Exists(ctx, dstKey).Result()
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		m.report.Conflicts++
+		switch m.opts.OnConflict {
+		case ConflictSkip:
+			m.report.Skipped++
+			return nil
+		case ConflictError:
+			return fmt.Errorf("%w: %s", ErrMigrateConflict, dstKey)
+		}
+	}
+
+	ttl, err := m.src.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if ttl == -2 { // Expired since it was scanned
+		m.report.Skipped++
+		return nil
+	}
+	if ttl < 0 {
+		ttl = 0 // No expiry
+	}
+
+	var size int64
+	if m.opts.DryRun {
+		size, err = m.size(ctx, key)
+	} else {
+		size, err = m.copy(ctx, key, dstKey, ttl)
+	}
+	if err == errKeyGone {
+		m.report.Skipped++
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if m.opts.DeleteSource && !m.opts.DryRun {
+		if err := m.src.client.Del(ctx, key).Err(); err != nil {
+			return err
+		}
+	}
+
+	m.report.Copied++
+	m.report.Bytes += size
+	return nil
+}
+
+// size returns the serialized size of a key without copying it
+func (m *migration) size(ctx context.Context, key string) (int64, error) {
+	payload, err := m.src.client.Dump(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, errKeyGone
+	} else if err != nil {
+		return 0, err
+	}
+	return int64(len(payload)), nil
+}
+
+// copy writes key to dstKey in the destination with the given TTL and returns the bytes copied
+func (m *migration) copy(ctx context.Context, key, dstKey string, ttl time.Duration) (int64, error) {
+	if m.dump {
+		payload, err := m.src.client.Dump(ctx, key).Result()
+		if err == redis.Nil {
+			return 0, errKeyGone
+		} else if err != nil {
+			return 0, err
+		}
+
+		// Conflicts were resolved above, so any existing key is meant to be replaced
+		err = m.dst.client.RestoreReplace(ctx, dstKey, ttl, payload).Err()
+		if err == nil {
+			return int64(len(payload)), nil
+		}
+		if !isIncompatibleDump(err) {
+			return 0, err
+		}
+		m.dump = false
+	}
+
+	value, err := m.src.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, errKeyGone
+	} else if err != nil {
+		return 0, fmt.Errorf("copying %s: %w", key, err)
+	}
+	if err := m.dst.client.Set(ctx, dstKey, value, ttl).Err(); err != nil {
+		return 0, err
+	}
+	return int64(len(value)), nil
+}
+
+// isIncompatibleDump reports whether RESTORE rejected a payload from another Redis version
+func isIncompatibleDump(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "DUMP payload") || strings.Contains(msg, "Bad data format")
+}
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// v1ToV2 renames v1: keys to v2:
+func v1ToV2(key string) string {
+	return "v2:" + strings.TrimPrefix(key, "v1:")
+}
+
+// storedLen returns the size of a key's value as stored in Redis
+func storedLen(t *testing.T, mr *miniredis.Miniredis, key string) int64 {
+	t.Helper()
+	value, err := mr.Get(key)
+	require.NoError(t, err)
+	return int64(len(value))
+}
+
+func TestMigrate_PreservesTTLAndRenames(t *testing.T) {
+	src, srcMR := newTestCache(t)
+	dst, dstMR := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, src.Set(ctx, "v1:user:1", "alice", time.Hour))
+	require.NoError(t, src.Set(ctx, "v1:user:2", "bob", 0))
+	require.NoError(t, src.Set(ctx, "other", "ignored", 0))
+
+	report, err := Migrate(ctx, src, dst, MigrateOptions{Pattern: "v1:*", Transform: v1ToV2})
+	require.NoError(t, err)
+	assert.Equal(t, MigrateReport{Scanned: 2, Copied: 2, Bytes: storedLen(t, srcMR, "v1:user:1") + storedLen(t, srcMR, "v1:user:2")}, report)
+
+	var name string
+	require.NoError(t, dst.Get(ctx, "v2:user:1", &name))
+	assert.Equal(t, "alice", name)
+	assert.Equal(t, time.Hour, dstMR.TTL("v2:user:1"))
+	require.NoError(t, dst.Get(ctx, "v2:user:2", &name))
+	assert.Equal(t, "bob", name)
+	assert.Zero(t, dstMR.TTL("v2:user:2"))
+
+	assert.False(t, dstMR.Exists("other"))
+	assert.False(t, dstMR.Exists("v1:user:1"))
+
+	// The source is left intact
+	require.NoError(t, src.Get(ctx, "v1:user:1", &name))
+}
+
+func TestMigrate_RenameInPlace(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "v1:a", 1, time.Minute))
+	require.NoError(t, c.Set(ctx, "v1:b", 2, time.Minute))
+
+	report, err := Migrate(ctx, c, c, MigrateOptions{Pattern: "v1:*", Transform: v1ToV2, DeleteSource: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Copied)
+
+	assert.ElementsMatch(t, []string{"v2:a", "v2:b"}, mr.Keys())
+	assert.Equal(t, time.Minute, mr.TTL("v2:a"))
+}
+
+func TestMigrate_ConflictPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*RedisCache, *RedisCache) {
+		src, _ := newTestCache(t)
+		dst, _ := newTestCache(t)
+		require.NoError(t, src.Set(ctx, "a", "new", 0))
+		require.NoError(t, src.Set(ctx, "b", "new", 0))
+		require.NoError(t, dst.Set(ctx, "a", "old", 0))
+		return src, dst
+	}
+	value := func(c *RedisCache, key string) string {
+		var v string
+		require.NoError(t, c.Get(ctx, key, &v))
+		return v
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		src, dst := setup(t)
+		report, err := Migrate(ctx, src, dst, MigrateOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), report.Conflicts)
+		assert.Equal(t, int64(1), report.Skipped)
+		assert.Equal(t, int64(1), report.Copied)
+		assert.Equal(t, "old", value(dst, "a"))
+		assert.Equal(t, "new", value(dst, "b"))
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		src, dst := setup(t)
+		report, err := Migrate(ctx, src, dst, MigrateOptions{OnConflict: ConflictOverwrite})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), report.Conflicts)
+		assert.Equal(t, int64(2), report.Copied)
+		assert.Equal(t, "new", value(dst, "a"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		src, dst := setup(t)
+		_, err := Migrate(ctx, src, dst, MigrateOptions{Pattern: "a", OnConflict: ConflictError})
+		assert.ErrorIs(t, err, ErrMigrateConflict)
+		assert.Equal(t, "old", value(dst, "a"))
+	})
+}
+
+func TestMigrate_ResumeAfterInterruption(t *testing.T) {
+	src, _ := newTestCache(t)
+	dst, dstMR := newTestCache(t)
+
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		require.NoError(t, src.Set(context.Background(), key, key, time.Hour))
+	}
+
+	// Interrupt the first run while it handles the third key
+	ctx, cancel := context.WithCancel(context.Background())
+	seen := 0
+	opts := MigrateOptions{CursorKey: "migrate:cursor", Transform: func(key string) string {
+		if seen++; seen == 3 {
+			cancel()
+		}
+		return key
+	}}
+	report, err := Migrate(ctx, src, dst, opts)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(2), report.Copied)
+
+	// The rerun skips what was already copied and clears the cursor when done
+	opts.Transform = nil
+	report, err = Migrate(context.Background(), src, dst, opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), report.Copied)
+	assert.Equal(t, int64(2), report.Skipped)
+	assert.False(t, dstMR.Exists("migrate:cursor"))
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		assert.True(t, dstMR.Exists(key), key)
+	}
+
+	// A persisted cursor is picked up by the next run
+	dstMR.Set("migrate:cursor", "42")
+	report, err = Migrate(context.Background(), src, dst, opts)
+	require.NoError(t, err)
+	assert.True(t, report.Resumed)
+	assert.False(t, dstMR.Exists("migrate:cursor"))
+
+	dstMR.Set("migrate:cursor", "not-a-number")
+	_, err = Migrate(context.Background(), src, dst, opts)
+	assert.ErrorContains(t, err, "invalid migration cursor")
+}
+
+func TestMigrate_DryRun(t *testing.T) {
+	src, srcMR := newTestCache(t)
+	dst, dstMR := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, src.Set(ctx, "v1:a", "12345", time.Hour))
+	require.NoError(t, src.Set(ctx, "v1:b", []int{1, 2, 3}, 0))
+	require.NoError(t, dst.Set(ctx, "v2:b", "taken", 0))
+
+	report, err := Migrate(ctx, src, dst, MigrateOptions{Pattern: "v1:*", Transform: v1ToV2, DryRun: true, CursorKey: "cursor"})
+	require.NoError(t, err)
+	assert.Equal(t, MigrateReport{Scanned: 2, Copied: 1, Skipped: 1, Conflicts: 1, Bytes: storedLen(t, srcMR, "v1:a")}, report)
+
+	// Nothing was written
+	assert.Equal(t, []string{"v2:b"}, dstMR.Keys())
+}
+
+func TestMigrate_Throttled(t *testing.T) {
+	src, _ := newTestCache(t)
+	dst, _ := newTestCache(t)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, src.Set(context.Background(), key, key, 0))
+	}
+
+	start := time.Now()
+	report, err := Migrate(context.Background(), src, dst, MigrateOptions{KeysPerSecond: 50})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), report.Copied)
+	assert.GreaterOrEqual(t, time.Since(start), 4*20*time.Millisecond)
+
+	// Throttled migrations still stop promptly when cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_, err = Migrate(ctx, src, dst, MigrateOptions{KeysPerSecond: 1, OnConflict: ConflictOverwrite})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestIsIncompatibleDump(t *testing.T) {
+	assert.True(t, isIncompatibleDump(errors.New("ERR DUMP payload version or checksum are wrong")))
+	assert.True(t, isIncompatibleDump(errors.New("ERR Bad data format")))
+	assert.False(t, isIncompatibleDump(errors.New("BUSYKEY Target key name already exists.")))
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// startOp starts an operation with the configured defaults: it applies DefaultOpTimeout when
+// ctx has no deadline, starts a trace span, and reports the operation to SlowOpCallback if it
+// takes at least SlowOpThreshold. The returned function must be called when the operation ends.
+func startOp(ctx context.Context, config *RedisConfig, op, key string) (context.Context, func(err error)) {
+	cancel := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && config.DefaultOpTimeout > 0 {
+		// An existing deadline is never extended or shortened
+		ctx, cancel = context.WithTimeout(ctx, config.DefaultOpTimeout)
+	}
+
+	ctx, endSpan := startSpan(ctx, config.Tracer, op, key)
+	start := time.Now()
+	return ctx, func(err error) {
+		duration := time.Since(start)
+		endSpan(err)
+		cancel()
+		if config.SlowOpThreshold > 0 && duration >= config.SlowOpThreshold && config.SlowOpCallback != nil {
+			config.SlowOpCallback(op, key, duration, err)
+		}
+	}
+}
+
+// logSlowOp is the default SlowOpCallback. It logs the key as stored, so keys hashed for
+// privacy stay out of the logs.
+func (r *RedisCache) logSlowOp(op, key string, duration time.Duration, err error) {
+	args := []interface{}{"op", op, "key", r.key(key), "duration", duration}
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	r.config.Logger.Warn("slow cache operation", args...)
+}
@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingProxy forwards connections to miniredis until hung, after which requests are
+// swallowed and never answered, like a Redis server stuck in a bad state
+type hangingProxy struct {
+	listener net.Listener
+	target   string
+	hung     atomic.Bool
+}
+
+func newHangingProxy(t *testing.T) *hangingProxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	p := &hangingProxy{listener: listener, target: miniredis.RunT(t).Addr()}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.forward(conn)
+		}
+	}()
+	return p
+}
+
+func (p *hangingProxy) forward(client net.Conn) {
+	defer client.Close()
+	server, err := net.Dial("tcp", p.target)
+	if err != nil {
+		return
+	}
+	defer server.Close()
+
+	go io.Copy(client, server)
+	buf := make([]byte, 4096)
+	for {
+		n, err := client.Read(buf)
+		if err != nil {
+			return
+		}
+		if p.hung.Load() {
+			continue
+		}
+		if _, err := server.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+func (p *hangingProxy) addr() string {
+	return p.listener.Addr().String()
+}
+
+// slowOp is a SlowOpCallback invocation
+type slowOp struct {
+	op, key  string
+	duration time.Duration
+	err      error
+}
+
+// slowOpRecorder collects SlowOpCallback invocations
+type slowOpRecorder struct {
+	mu  sync.Mutex
+	ops []slowOp
+}
+
+func (r *slowOpRecorder) record(op, key string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, slowOp{op: op, key: key, duration: duration, err: err})
+}
+
+func (r *slowOpRecorder) recorded() []slowOp {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]slowOp(nil), r.ops...)
+}
+
+func TestDefaultOpTimeout_UndeadlinedGetFails(t *testing.T) {
+	proxy := newHangingProxy(t)
+	c, err := NewRedisCache(RedisConfig{Address: proxy.addr(), DefaultOpTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer c.Close()
+
+	proxy.hung.Store(true)
+	start := time.Now()
+	var got string
+	err = c.Get(context.Background(), "greeting", &got)
+	elapsed := time.Since(start)
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrKeyNotFound)
+	assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond)
+	assert.Less(t, elapsed, time.Second, "the default read timeout is 3s")
+}
+
+func TestDefaultOpTimeout_KeepsCallerDeadline(t *testing.T) {
+	proxy := newHangingProxy(t)
+	c, err := NewRedisCache(RedisConfig{Address: proxy.addr(), DefaultOpTimeout: 150 * time.Millisecond})
+	require.NoError(t, err)
+	defer c.Close()
+	proxy.hung.Store(true)
+
+	// A shorter deadline is honored
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.Error(t, c.Set(ctx, "greeting", "hello", time.Minute))
+	assert.Less(t, time.Since(start), 120*time.Millisecond)
+
+	// A longer deadline is not shortened to the default
+	ctx, cancel = context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	require.Error(t, c.Delete(ctx, "greeting"))
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestDefaultOpTimeout_LockAndRateLimiter(t *testing.T) {
+	proxy := newHangingProxy(t)
+	c, err := NewRedisCache(RedisConfig{Address: proxy.addr(), DefaultOpTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer c.Close()
+	proxy.hung.Store(true)
+
+	start := time.Now()
+	assert.Error(t, c.NewDistributedLock("job", time.Minute).Acquire(context.Background()))
+	_, err = c.NewRateLimiter(time.Minute, 10).Allow(context.Background(), "client")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSlowOpCallback_ReportsDuration(t *testing.T) {
+	proxy := newHangingProxy(t)
+	recorder := &slowOpRecorder{}
+	c, err := NewRedisCache(RedisConfig{
+		Address:          proxy.addr(),
+		DefaultOpTimeout: 200 * time.Millisecond,
+		SlowOpThreshold:  100 * time.Millisecond,
+		SlowOpCallback:   recorder.record,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+	ctx := context.Background()
+
+	// Fast operations are not reported
+	require.NoError(t, c.Set(ctx, "greeting", "hello", time.Minute))
+	assert.Empty(t, recorder.recorded())
+
+	proxy.hung.Store(true)
+	start := time.Now()
+	var got string
+	getErr := c.Get(ctx, "greeting", &got)
+	elapsed := time.Since(start)
+	require.Error(t, getErr)
+
+	ops := recorder.recorded()
+	require.Len(t, ops, 1)
+	assert.Equal(t, OpGet, ops[0].op)
+	assert.Equal(t, "greeting", ops[0].key)
+	assert.Equal(t, getErr, ops[0].err)
+	assert.GreaterOrEqual(t, ops[0].duration, 200*time.Millisecond)
+	assert.LessOrEqual(t, ops[0].duration, elapsed)
+}
+
+// captureLogger records warnings
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) Warn(message string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(append([]interface{}{message}, args...)...))
+}
+
+func TestSlowOpCallback_DefaultLogsStoredKey(t *testing.T) {
+	proxy := newHangingProxy(t)
+	logger := &captureLogger{}
+	c, err := NewRedisCache(RedisConfig{
+		Address:           proxy.addr(),
+		DefaultOpTimeout:  50 * time.Millisecond,
+		SlowOpThreshold:   time.Millisecond,
+		SensitivePrefixes: []string{"email:"},
+		Logger:            logger,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	proxy.hung.Store(true)
+	_, err = c.Exists(context.Background(), "email:alice@example.com")
+	require.Error(t, err)
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	require.Len(t, logger.lines, 1)
+	assert.Contains(t, logger.lines[0], "slow cache operation")
+	assert.Contains(t, logger.lines[0], c.key("email:alice@example.com"))
+	assert.NotContains(t, logger.lines[0], "alice@example.com", "sensitive keys are logged hashed")
+}
+
+func TestNewRedisCache_RejectsNegativeOpTimeout(t *testing.T) {
+	_, err := NewRedisCache(RedisConfig{Address: "localhost:0", DefaultOpTimeout: -time.Second})
+	assert.ErrorContains(t, err, "DefaultOpTimeout must not be negative")
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes set by OTelTracer in addition to the database semantic conventions
+const (
+	AttrCacheKey = attribute.Key("cache.key")
+	AttrCacheHit = attribute.Key("cache.hit")
+)
+
+// tracerName identifies the cache instrumentation
+const tracerName = "huba/cache"
+
+// OTelTracer is a TraceStarter that records cache operations as OpenTelemetry client spans
+// named "cache.<op>"
+type OTelTracer struct {
+	Tracer   trace.Tracer
+	HashKeys bool // Record the SHA-256 of keys instead of the keys themselves
+}
+
+// NewOTelTracer creates a tracer from the given provider, or the global provider when nil
+func NewOTelTracer(provider trace.TracerProvider) *OTelTracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &OTelTracer{Tracer: provider.Tracer(tracerName)}
+}
+
+// Start implements TraceStarter
+func (t *OTelTracer) Start(ctx context.Context, op, key string) (context.Context, func(err error)) {
+	if t.HashKeys {
+		key = SHA256KeyHasher(key)
+	}
+
+	ctx, span := t.Tracer.Start(ctx, "cache."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBOperationName(op),
+			AttrCacheKey.String(key),
+		),
+	)
+
+	return ctx, func(err error) {
+		defer span.End()
+
+		switch {
+		case errors.Is(err, ErrKeyNotFound):
+			span.SetAttributes(AttrCacheHit.Bool(false))
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case isLookup(op):
+			span.SetAttributes(AttrCacheHit.Bool(true))
+		}
+	}
+}
+
+// isLookup reports whether an operation has a hit or miss outcome
+func isLookup(op string) bool {
+	return op == OpGet || op == OpExists || op == OpCacheAside
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTracedCache returns a test cache whose spans are captured by the returned recorder
+func newTracedCache(t *testing.T, hashKeys bool) (*RedisCache, *miniredis.Miniredis, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := NewOTelTracer(provider)
+	tracer.HashKeys = hashKeys
+
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Address: mr.Addr(), Tracer: tracer})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	return c, mr, recorder
+}
+
+// spanAttrs returns a span's attributes by key
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// spanNames returns the names of ended spans in the order they ended
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func TestOTelTracer_HitAndMiss(t *testing.T) {
+	c, _, recorder := newTracedCache(t, false)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "user:1", "alice", time.Minute))
+	var got string
+	require.NoError(t, c.Get(ctx, "user:1", &got))
+	assert.ErrorIs(t, c.Get(ctx, "user:2", &got), ErrKeyNotFound)
+
+	spans := recorder.Ended()
+	require.Equal(t, []string{"cache.set", "cache.get", "cache.get"}, spanNames(recorder))
+
+	set := spanAttrs(spans[0])
+	assert.Equal(t, "redis", set["db.system"].AsString())
+	assert.Equal(t, OpSet, set["db.operation.name"].AsString())
+	assert.Equal(t, "user:1", set[AttrCacheKey].AsString())
+	assert.NotContains(t, set, AttrCacheHit)
+
+	hit := spanAttrs(spans[1])
+	assert.True(t, hit[AttrCacheHit].AsBool())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+
+	miss := spanAttrs(spans[2])
+	assert.Equal(t, "user:2", miss[AttrCacheKey].AsString())
+	assert.False(t, miss[AttrCacheHit].AsBool())
+	// A miss is not an error
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+	assert.Empty(t, spans[2].Events())
+}
+
+func TestOTelTracer_CacheAsideAndExists(t *testing.T) {
+	c, _, recorder := newTracedCache(t, false)
+	ctx := context.Background()
+
+	loader := func(ctx context.Context, key string) (interface{}, error) { return "loaded", nil }
+	var got interface{}
+	require.NoError(t, c.CacheAside(ctx, "k", &got, time.Minute, loader))
+	require.NoError(t, c.CacheAside(ctx, "k", &got, time.Minute, loader))
+
+	exists, err := c.Exists(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	var asides []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "cache.cache_aside":
+			asides = append(asides, span)
+		case "cache.exists":
+			assert.False(t, spanAttrs(span)[AttrCacheHit].AsBool())
+		}
+	}
+	require.Len(t, asides, 2)
+	assert.False(t, spanAttrs(asides[0])[AttrCacheHit].AsBool())
+	assert.True(t, spanAttrs(asides[1])[AttrCacheHit].AsBool())
+
+	// The inner get and set are children of the cache-aside span
+	for _, span := range recorder.Ended() {
+		if span.Name() == "cache.get" || span.Name() == "cache.set" {
+			assert.True(t, span.Parent().IsValid())
+		}
+	}
+}
+
+func TestOTelTracer_RecordsErrors(t *testing.T) {
+	c, mr, recorder := newTracedCache(t, false)
+	ctx := context.Background()
+
+	mr.SetError("injected failure")
+	var got string
+	assert.Error(t, c.Get(ctx, "user:1", &got))
+	assert.Error(t, c.NewDistributedLock("job", time.Second).Acquire(ctx))
+
+	spans := recorder.Ended()
+	require.Equal(t, []string{"cache.get", "cache.lock.acquire"}, spanNames(recorder))
+	for _, span := range spans {
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.Contains(t, span.Status().Description, "injected failure")
+		require.Len(t, span.Events(), 1)
+		assert.Equal(t, "exception", span.Events()[0].Name)
+		assert.NotContains(t, spanAttrs(span), AttrCacheHit)
+	}
+}
+
+func TestOTelTracer_HashKeys(t *testing.T) {
+	c, _, recorder := newTracedCache(t, true)
+
+	require.NoError(t, c.Delete(context.Background(), "alice@example.com"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, SHA256KeyHasher("alice@example.com"), spanAttrs(spans[0])[AttrCacheKey].AsString())
+}
+
+func TestRedisCache_NoTracer(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		startSpan(ctx, c.config.Tracer, OpGet, "k")
+	})
+	assert.Zero(t, allocs)
+}
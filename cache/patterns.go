@@ -3,16 +3,29 @@ package cache
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 )
 
 // LoaderFunc is a function that loads data when cache misses
 type LoaderFunc func(ctx context.Context, key string) (interface{}, error)
 
-// CacheAside implements the cache-aside pattern
-func (r *RedisCache) CacheAside(ctx context.Context, key string, dest interface{}, expiry time.Duration, loader LoaderFunc) error {
+// CacheAside implements the cache-aside pattern.
+// Loaded values are recorded under the given tags so they can be invalidated with InvalidateTag.
+func (r *RedisCache) CacheAside(ctx context.Context, key string, dest interface{}, expiry time.Duration, loader LoaderFunc, tags ...string) (err error) {
+	// Only traced: the Get and Set calls apply DefaultOpTimeout, and the loader is not bound by it
+	ctx, end := startSpan(ctx, r.config.Tracer, OpCacheAside, key)
+	loaded := false
+	defer func() {
+		if err == nil && loaded {
+			end(ErrKeyNotFound) // Report the miss that triggered the load
+		} else {
+			end(err)
+		}
+	}()
+
 	// Try to get from cache first
-	err := r.Get(ctx, key, dest)
+	err = r.Get(ctx, key, dest)
 	if err == nil {
 		// Cache hit
 		return nil
@@ -28,9 +41,15 @@ func (r *RedisCache) CacheAside(ctx context.Context, key string, dest interface{
 	if err != nil {
 		return err
 	}
+	loaded = true
 
 	// Store in cache for future requests
-	if err := r.Set(ctx, key, data, expiry); err != nil {
+	if len(tags) > 0 {
+		err = r.SetWithTags(ctx, key, data, expiry, tags)
+	} else {
+		err = r.Set(ctx, key, data, expiry)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -66,9 +85,12 @@ func (r *RedisCache) NewRateLimiter(window time.Duration, maxRequests int64) *Ra
 }
 
 // Allow checks if a request is allowed under rate limits
-func (rl *RateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+func (rl *RateLimiter) Allow(ctx context.Context, key string) (allowed bool, err error) {
+	ctx, end := startOp(ctx, &rl.cache.config, OpRateLimitAllow, key)
+	defer func() { end(err) }()
+
 	// Use a sliding window for rate limiting
-	limitKey := "ratelimit:" + key
+	limitKey := "ratelimit:" + rl.cache.key(key)
 
 	// Use Lua script for atomic operations
 	const script = `
@@ -108,7 +130,7 @@ func (rl *RateLimiter) Allow(ctx context.Context, key string) (bool, error) {
 	}
 
 	// Check if allowed
-	allowed := res.(int64) == 1
+	allowed = res.(int64) == 1
 	if !allowed {
 		return false, ErrRateLimitExceeded
 	}
@@ -117,16 +139,19 @@ func (rl *RateLimiter) Allow(ctx context.Context, key string) (bool, error) {
 }
 
 // RemainingQuota returns the number of remaining requests allowed
-func (rl *RateLimiter) RemainingQuota(ctx context.Context, key string) (int64, error) {
-	limitKey := "ratelimit:" + key
+func (rl *RateLimiter) RemainingQuota(ctx context.Context, key string) (remaining int64, err error) {
+	ctx, end := startOp(ctx, &rl.cache.config, OpRateLimitRemaining, key)
+	defer func() { end(err) }()
+
+	limitKey := "ratelimit:" + rl.cache.key(key)
 	now := time.Now().Unix()
 
 	// Remove expired entries
-	err := rl.cache.client.ZRemRangeByScore(
+	err = rl.cache.client.ZRemRangeByScore(
 		ctx,
 		limitKey,
 		"0",
-		string(now-int64(rl.window.Seconds())),
+		strconv.FormatInt(now-int64(rl.window.Seconds()), 10),
 	).Err()
 
 	if err != nil {
@@ -140,7 +165,7 @@ func (rl *RateLimiter) RemainingQuota(ctx context.Context, key string) (int64, e
 	}
 
 	// Calculate remaining
-	remaining := rl.maxRequests - count
+	remaining = rl.maxRequests - count
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -14,7 +15,10 @@ var ErrKeyNotFound = errors.New("key not found in cache")
 
 // RedisCache represents a Redis-backed distributed cache
 type RedisCache struct {
-	client *redis.Client
+	client   *redis.Client
+	config   RedisConfig
+	replicas *replicaSet // nil unless ReplicaAddresses is set
+	now      func() time.Time
 }
 
 // RedisConfig holds the configuration for the Redis cache
@@ -22,15 +26,60 @@ type RedisConfig struct {
 	Address  string
 	Password string
 	DB       int
+
+	// Connection pool tuning; zero values use the go-redis defaults
+	PoolSize     int           // Maximum connections per CPU (default 10 per GOMAXPROCS)
+	MinIdleConns int           // Idle connections kept open to absorb bursts
+	DialTimeout  time.Duration // Timeout for establishing connections (default 5s)
+	ReadTimeout  time.Duration // Timeout for socket reads (default 3s)
+	WriteTimeout time.Duration // Timeout for socket writes (default ReadTimeout)
+	PoolTimeout  time.Duration // How long to wait for a free connection (default ReadTimeout + 1s)
+
+	// Key hashing keeps long or sensitive keys (URLs, emails) out of Redis.
+	// It is enabled when HashKeysLongerThan or SensitivePrefixes is set.
+	KeyHasher          KeyHasher // Defaults to SHA256KeyHasher
+	HashKeysLongerThan int       // Hash keys longer than this many bytes (0 disables)
+	SensitivePrefixes  []string  // Always hash keys with these prefixes, keeping the prefix readable
+	StoreOriginalKeys  bool      // Record original keys for DebugResolveKey
+
+	// Read replicas serve Get and Exists; writes and scripts always go to the primary.
+	ReplicaAddresses     []string
+	MaxReplicaLag        int64         // Skip replicas more than this many bytes behind the primary (0 disables lag checks)
+	ReplicaCheckInterval time.Duration // How often lag is checked and how long failed replicas are skipped (default 5s)
+
+	// TagPrefix starts the keys of tag sets written by SetWithTags (default "tag:"). PruneTags only
+	// scans keys under it, so give each application sharing a Redis DB its own prefix.
+	TagPrefix string
+
+	// WriteTimestamps wraps values written by Set in an envelope recording the write time, so a
+	// Warmer can skip keys the application refreshed itself. Readers that don't use RedisCache,
+	// including older versions of it, see the envelope instead of the value, so only enable it
+	// once every reader of the keys understands the format.
+	WriteTimestamps bool
+
+	// UniqueRetention is how long unique counter buckets are kept after they end, and so the
+	// longest window CountUnique can answer (default 24h)
+	UniqueRetention time.Duration
+
+	// Tracer instruments cache, lock and rate limiter operations when set, e.g. with NewOTelTracer
+	Tracer TraceStarter
+
+	// Limits for Get, Set, Delete, Exists, lock and rate limiter operations. DefaultOpTimeout
+	// bounds operations whose context has no deadline; deadlines set by the caller are kept.
+	DefaultOpTimeout time.Duration                                           // 0 disables
+	SlowOpThreshold  time.Duration                                           // Report operations taking at least this long (0 disables)
+	SlowOpCallback   func(op, key string, duration time.Duration, err error) // Receives slow operations (default logs a warning)
+
+	// Logger receives diagnostics such as slow operations; defaults to the standard log package
+	Logger Logger
 }
 
 // NewRedisCache creates a new Redis cache client
 func NewRedisCache(config RedisConfig) (*RedisCache, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Address,
-		Password: config.Password,
-		DB:       config.DB,
-	})
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(config.clientOptions(config.Address))
 
 	// Test the connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -40,45 +89,173 @@ func NewRedisCache(config RedisConfig) (*RedisCache, error) {
 		return nil, err
 	}
 
-	return &RedisCache{
+	cache := &RedisCache{
 		client: client,
-	}, nil
+		config: config,
+		now:    time.Now,
+	}
+	if cache.config.Logger == nil {
+		cache.config.Logger = stdLogger{}
+	}
+	if cache.config.SlowOpCallback == nil {
+		cache.config.SlowOpCallback = cache.logSlowOp
+	}
+
+	if len(config.ReplicaAddresses) > 0 {
+		cache.replicas = newReplicaSet(config)
+		if config.MaxReplicaLag > 0 {
+			cache.replicas.wg.Add(1)
+			go cache.replicas.runLagChecks(client)
+		}
+	}
+
+	return cache, nil
+}
+
+// validate rejects negative pool and timeout settings
+func (c RedisConfig) validate() error {
+	settings := []struct {
+		name  string
+		value int64
+	}{
+		{"PoolSize", int64(c.PoolSize)},
+		{"MinIdleConns", int64(c.MinIdleConns)},
+		{"DialTimeout", int64(c.DialTimeout)},
+		{"ReadTimeout", int64(c.ReadTimeout)},
+		{"WriteTimeout", int64(c.WriteTimeout)},
+		{"PoolTimeout", int64(c.PoolTimeout)},
+		{"DefaultOpTimeout", int64(c.DefaultOpTimeout)},
+		{"SlowOpThreshold", int64(c.SlowOpThreshold)},
+	}
+	for _, setting := range settings {
+		if setting.value < 0 {
+			return fmt.Errorf("invalid redis config: %s must not be negative", setting.name)
+		}
+	}
+	return nil
+}
+
+// clientOptions returns the go-redis options for a server at addr
+func (c RedisConfig) clientOptions(addr string) *redis.Options {
+	return &redis.Options{
+		Addr:         addr,
+		Password:     c.Password,
+		DB:           c.DB,
+		PoolSize:     c.PoolSize,
+		MinIdleConns: c.MinIdleConns,
+		DialTimeout:  c.DialTimeout,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		PoolTimeout:  c.PoolTimeout,
+
+		// Let context deadlines, including DefaultOpTimeout, cut socket reads short
+		ContextTimeoutEnabled: true,
+	}
 }
 
 // Get retrieves a value from the cache
-func (r *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
-	val, err := r.client.Get(ctx, key).Result()
+func (r *RedisCache) Get(ctx context.Context, key string, dest interface{}) (err error) {
+	ctx, end := startOp(ctx, &r.config, OpGet, key)
+	defer func() { end(err) }()
+
+	var val string
+	err = r.read(ctx, func(client *redis.Client) error {
+		var err error
+		val, err = client.Get(ctx, r.key(key)).Result()
+		return err
+	})
 	if err == redis.Nil {
 		return ErrKeyNotFound
 	} else if err != nil {
 		return err
 	}
 
-	return json.Unmarshal([]byte(val), dest)
+	data, _ := decodeValue([]byte(val))
+	return json.Unmarshal(data, dest)
 }
 
 // Set stores a value in the cache with optional expiration
-func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	data, err := json.Marshal(value)
+func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) (err error) {
+	ctx, end := startOp(ctx, &r.config, OpSet, key)
+	defer func() { end(err) }()
+
+	data, err := r.encodeValue(value)
 	if err != nil {
 		return err
 	}
 
-	return r.client.Set(ctx, key, data, expiration).Err()
+	hashed := r.key(key)
+	if !r.config.StoreOriginalKeys || hashed == key {
+		return r.client.Set(ctx, hashed, data, expiration).Err()
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, hashed, data, expiration)
+		r.recordOriginalKey(ctx, pipe, key, hashed, expiration)
+		return nil
+	})
+	return err
 }
 
 // Delete removes a value from the cache
-func (r *RedisCache) Delete(ctx context.Context, key string) error {
-	return r.client.Del(ctx, key).Err()
+func (r *RedisCache) Delete(ctx context.Context, key string) (err error) {
+	ctx, end := startOp(ctx, &r.config, OpDelete, key)
+	defer func() { end(err) }()
+
+	return r.client.Del(ctx, r.key(key)).Err()
 }
 
 // Exists checks if a key exists in the cache
 func (r *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
-	res, err := r.client.Exists(ctx, key).Result()
+	ctx, end := startOp(ctx, &r.config, OpExists, key)
+
+	var res int64
+	err := r.read(ctx, func(client *redis.Client) error {
+		var err error
+		res, err = client.Exists(ctx, r.key(key)).Result()
+		return err
+	})
+	if err == nil && res == 0 {
+		end(ErrKeyNotFound)
+	} else {
+		end(err)
+	}
 	return res > 0, err
 }
 
-// Close closes the Redis client connection
+// Ping checks that the primary Redis server is reachable, e.g. for readiness probes
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// HealthStatus reports the primary connection's latency and pool usage
+type HealthStatus struct {
+	Latency    time.Duration // Round trip of a PING
+	TotalConns uint32        // Open connections in the pool
+	IdleConns  uint32        // Idle connections in the pool
+	Timeouts   uint32        // Times a caller waited PoolTimeout for a free connection
+}
+
+// HealthCheck pings the primary and reports the round trip latency with pool statistics
+func (r *RedisCache) HealthCheck(ctx context.Context) (HealthStatus, error) {
+	start := time.Now()
+	err := r.Ping(ctx)
+	latency := time.Since(start)
+
+	stats := r.client.PoolStats()
+	return HealthStatus{
+		Latency:    latency,
+		TotalConns: stats.TotalConns,
+		IdleConns:  stats.IdleConns,
+		Timeouts:   stats.Timeouts,
+	}, err
+}
+
+// Close closes the Redis client connection and any replica connections
 func (r *RedisCache) Close() error {
-	return r.client.Close()
+	err := r.client.Close()
+	if r.replicas != nil {
+		err = errors.Join(err, r.replicas.close())
+	}
+	return err
 }
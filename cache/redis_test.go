@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCache returns a RedisCache backed by an in-process miniredis server
+func newTestCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Address: mr.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	return c, mr
+}
+
+func TestRedisCache_SetGet(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "greeting", map[string]string{"hello": "world"}, time.Minute))
+
+	var got map[string]string
+	require.NoError(t, c.Get(ctx, "greeting", &got))
+	assert.Equal(t, "world", got["hello"])
+
+	mr.FastForward(2 * time.Minute)
+	assert.ErrorIs(t, c.Get(ctx, "greeting", &got), ErrKeyNotFound)
+}
+
+func TestNewRedisCache_PoolOptions(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{
+		Address:      mr.Addr(),
+		PoolSize:     42,
+		MinIdleConns: 3,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 750 * time.Millisecond,
+		PoolTimeout:  4 * time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	opts := c.client.Options()
+	assert.Equal(t, 42, opts.PoolSize)
+	assert.Equal(t, 3, opts.MinIdleConns)
+	assert.Equal(t, 2*time.Second, opts.DialTimeout)
+	assert.Equal(t, 500*time.Millisecond, opts.ReadTimeout)
+	assert.Equal(t, 750*time.Millisecond, opts.WriteTimeout)
+	assert.Equal(t, 4*time.Second, opts.PoolTimeout)
+}
+
+func TestNewRedisCache_RejectsNegativePoolOptions(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	_, err := NewRedisCache(RedisConfig{Address: mr.Addr(), PoolSize: -1})
+	assert.ErrorContains(t, err, "PoolSize")
+
+	_, err = NewRedisCache(RedisConfig{Address: mr.Addr(), ReadTimeout: -time.Second})
+	assert.ErrorContains(t, err, "ReadTimeout")
+}
+
+func TestRedisCache_Ping(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.Ping(ctx))
+
+	status, err := c.HealthCheck(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, status.Latency)
+	assert.Positive(t, status.TotalConns)
+
+	mr.Close()
+	assert.Error(t, c.Ping(ctx))
+	_, err = c.HealthCheck(ctx)
+	assert.Error(t, err)
+}
+
+func TestRedisCache_PingAfterClose(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Address: mr.Addr()})
+	require.NoError(t, err)
+
+	require.NoError(t, c.Close())
+	assert.Error(t, c.Ping(context.Background()))
+}
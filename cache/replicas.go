@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultReplicaCheckInterval is how often replica lag is checked, and how long a failed replica is skipped
+const defaultReplicaCheckInterval = 5 * time.Second
+
+// Consistency selects where reads are served from when replicas are configured
+type Consistency int
+
+const (
+	// Eventual reads may be served by a replica and can miss very recent writes
+	Eventual Consistency = iota
+
+	// Strong reads always go to the primary
+	Strong
+)
+
+// consistencyKey is the context key for the read consistency override
+type consistencyKey struct{}
+
+// WithConsistency returns a context whose reads use the given consistency.
+// Use Strong for read-your-writes after a Set.
+func WithConsistency(ctx context.Context, consistency Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, consistency)
+}
+
+// consistencyFrom returns the read consistency requested by the context
+func consistencyFrom(ctx context.Context) Consistency {
+	consistency, _ := ctx.Value(consistencyKey{}).(Consistency)
+	return consistency
+}
+
+// ReadStats breaks down reads by the server that answered them
+type ReadStats struct {
+	Primary   int64            // Reads served by the primary
+	Replicas  map[string]int64 // Reads served by each replica, by address
+	Failbacks int64            // Replica reads that failed and were retried on the primary
+}
+
+// replica is a read-only Redis connection
+type replica struct {
+	addr      string
+	client    *redis.Client
+	reads     int64
+	downUntil int64 // unix nanos; the replica is skipped until then
+}
+
+// available reports whether the replica can serve reads at now
+func (rep *replica) available(now time.Time) bool {
+	return atomic.LoadInt64(&rep.downUntil) <= now.UnixNano()
+}
+
+// replicaSet routes reads across replicas round-robin
+type replicaSet struct {
+	replicas      []*replica
+	next          uint32
+	primaryReads  int64
+	failbacks     int64
+	maxLag        int64
+	checkInterval time.Duration
+	lag           func(ctx context.Context, primary, replica *redis.Client) (int64, error)
+	stop          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// newReplicaSet connects to the configured replicas
+func newReplicaSet(config RedisConfig) *replicaSet {
+	set := &replicaSet{
+		maxLag:        config.MaxReplicaLag,
+		checkInterval: config.ReplicaCheckInterval,
+		lag:           replicationLag,
+		stop:          make(chan struct{}),
+	}
+	if set.checkInterval <= 0 {
+		set.checkInterval = defaultReplicaCheckInterval
+	}
+
+	for _, addr := range config.ReplicaAddresses {
+		set.replicas = append(set.replicas, &replica{
+			addr:   addr,
+			client: redis.NewClient(config.clientOptions(addr)),
+		})
+	}
+	return set
+}
+
+// pick returns the next available replica, or nil if none can serve reads
+func (s *replicaSet) pick(now time.Time) *replica {
+	n := len(s.replicas)
+	start := int(atomic.AddUint32(&s.next, 1))
+	for i := 0; i < n; i++ {
+		rep := s.replicas[(start+i)%n]
+		if rep.available(now) {
+			return rep
+		}
+	}
+	return nil
+}
+
+// markDown takes a replica out of rotation until the next check interval
+func (s *replicaSet) markDown(rep *replica) {
+	atomic.StoreInt64(&rep.downUntil, time.Now().Add(s.checkInterval).UnixNano())
+}
+
+// checkLag takes replicas that fall too far behind the primary out of rotation
+func (s *replicaSet) checkLag(ctx context.Context, primary *redis.Client) {
+	for _, rep := range s.replicas {
+		lag, err := s.lag(ctx, primary, rep.client)
+		if err != nil || lag > s.maxLag {
+			s.markDown(rep)
+		}
+	}
+}
+
+// runLagChecks checks replica lag every check interval until closed
+func (s *replicaSet) runLagChecks(primary *redis.Client) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.checkInterval)
+			s.checkLag(ctx, primary)
+			cancel()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// close stops lag checks and closes the replica connections
+func (s *replicaSet) close() error {
+	close(s.stop)
+	s.wg.Wait()
+
+	var errs []error
+	for _, rep := range s.replicas {
+		errs = append(errs, rep.client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// replicationLag returns how many bytes of the replication stream the replica is behind
+func replicationLag(ctx context.Context, primary, replica *redis.Client) (int64, error) {
+	primaryOffset, err := replicationOffset(ctx, primary, "master_repl_offset")
+	if err != nil {
+		return 0, err
+	}
+	replicaOffset, err := replicationOffset(ctx, replica, "slave_repl_offset")
+	if err != nil {
+		return 0, err
+	}
+	return primaryOffset - replicaOffset, nil
+}
+
+// replicationOffset reads a replication offset field from INFO replication
+func replicationOffset(ctx context.Context, client *redis.Client, field string) (int64, error) {
+	info, err := client.Info(ctx, "replication").Result()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), field+":"); ok {
+			return strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%s missing from INFO replication", field)
+}
+
+// read runs a read command against a replica when allowed, falling back to the primary
+// if no replica is available or the replica fails
+func (r *RedisCache) read(ctx context.Context, fn func(client *redis.Client) error) error {
+	if r.replicas == nil {
+		return fn(r.client)
+	}
+
+	if consistencyFrom(ctx) != Strong {
+		if rep := r.replicas.pick(time.Now()); rep != nil {
+			err := fn(rep.client)
+			if err == nil || err == redis.Nil || ctx.Err() != nil {
+				atomic.AddInt64(&rep.reads, 1)
+				return err
+			}
+
+			r.replicas.markDown(rep)
+			atomic.AddInt64(&r.replicas.failbacks, 1)
+		}
+	}
+
+	atomic.AddInt64(&r.replicas.primaryReads, 1)
+	return fn(r.client)
+}
+
+// ReadStats returns how many reads each server has answered.
+// Without replicas all reads go to the primary and are not counted.
+func (r *RedisCache) ReadStats() ReadStats {
+	stats := ReadStats{Replicas: make(map[string]int64)}
+	if r.replicas == nil {
+		return stats
+	}
+
+	stats.Primary = atomic.LoadInt64(&r.replicas.primaryReads)
+	stats.Failbacks = atomic.LoadInt64(&r.replicas.failbacks)
+	for _, rep := range r.replicas.replicas {
+		stats.Replicas[rep.addr] += atomic.LoadInt64(&rep.reads)
+	}
+	return stats
+}
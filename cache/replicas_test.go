@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReplicatedCache returns a cache over a miniredis primary and two miniredis replicas.
+// Nothing replicates between them, so tests seed each server separately to see where reads go.
+func newReplicatedCache(t *testing.T) (*RedisCache, *miniredis.Miniredis, []*miniredis.Miniredis) {
+	t.Helper()
+
+	primary := miniredis.RunT(t)
+	replicas := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+
+	c := newHashingCache(t, primary, RedisConfig{
+		ReplicaAddresses:     []string{replicas[0].Addr(), replicas[1].Addr()},
+		ReplicaCheckInterval: time.Minute,
+	})
+	return c, primary, replicas
+}
+
+func TestReplicas_ReadsRouteToReplicas(t *testing.T) {
+	c, primary, replicas := newReplicatedCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, primary.Set("k", `"primary"`))
+	require.NoError(t, replicas[0].Set("k", `"replica"`))
+	require.NoError(t, replicas[1].Set("k", `"replica"`))
+
+	for i := 0; i < 4; i++ {
+		var got string
+		require.NoError(t, c.Get(ctx, "k", &got))
+		assert.Equal(t, "replica", got)
+	}
+
+	exists, err := c.Exists(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Round-robin spreads reads over both replicas
+	stats := c.ReadStats()
+	assert.Zero(t, stats.Primary)
+	assert.Equal(t, int64(5), stats.Replicas[replicas[0].Addr()]+stats.Replicas[replicas[1].Addr()])
+	assert.Positive(t, stats.Replicas[replicas[0].Addr()])
+	assert.Positive(t, stats.Replicas[replicas[1].Addr()])
+
+	// A miss on a replica is a normal answer, not a failure
+	err = c.Get(ctx, "missing", new(string))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Zero(t, c.ReadStats().Failbacks)
+}
+
+func TestReplicas_WritesGoToPrimary(t *testing.T) {
+	c, primary, replicas := newReplicatedCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "k", "v", time.Minute))
+	assert.True(t, primary.Exists("k"))
+	assert.False(t, replicas[0].Exists("k"))
+	assert.False(t, replicas[1].Exists("k"))
+
+	lock := c.NewDistributedLock("resource", time.Minute)
+	require.NoError(t, lock.Acquire(ctx))
+	assert.True(t, primary.Exists("lock:resource"))
+}
+
+func TestReplicas_StrongConsistency(t *testing.T) {
+	c, primary, replicas := newReplicatedCache(t)
+
+	require.NoError(t, primary.Set("k", `"primary"`))
+	require.NoError(t, replicas[0].Set("k", `"replica"`))
+	require.NoError(t, replicas[1].Set("k", `"replica"`))
+
+	var got string
+	require.NoError(t, c.Get(WithConsistency(context.Background(), Strong), "k", &got))
+	assert.Equal(t, "primary", got)
+	assert.Equal(t, int64(1), c.ReadStats().Primary)
+}
+
+func TestReplicas_FailbackWhenReplicaStops(t *testing.T) {
+	c, primary, replicas := newReplicatedCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, primary.Set("k", `"primary"`))
+	replicas[0].Close()
+	replicas[1].Close()
+
+	// The first reads fail over to the primary and take the replicas out of rotation
+	for i := 0; i < 4; i++ {
+		var got string
+		require.NoError(t, c.Get(ctx, "k", &got))
+		assert.Equal(t, "primary", got)
+	}
+
+	stats := c.ReadStats()
+	assert.Equal(t, int64(2), stats.Failbacks)
+	assert.Equal(t, int64(4), stats.Primary)
+}
+
+func TestReplicas_LaggingReplicaSkipped(t *testing.T) {
+	c, _, replicas := newReplicatedCache(t)
+	require.NoError(t, replicas[0].Set("k", `"lagging"`))
+	require.NoError(t, replicas[1].Set("k", `"current"`))
+
+	lagging := c.replicas.replicas[0].client
+	c.replicas.maxLag = 100
+	c.replicas.lag = func(ctx context.Context, primary, replica *redis.Client) (int64, error) {
+		if replica == lagging {
+			return 1000, nil
+		}
+		return 0, nil
+	}
+	c.replicas.checkLag(context.Background(), c.client)
+
+	for i := 0; i < 4; i++ {
+		var got string
+		require.NoError(t, c.Get(context.Background(), "k", &got))
+		assert.Equal(t, "current", got)
+	}
+
+	// Lag check errors also take a replica out of rotation
+	c.replicas.lag = func(ctx context.Context, primary, replica *redis.Client) (int64, error) {
+		return 0, errors.New("INFO failed")
+	}
+	c.replicas.checkLag(context.Background(), c.client)
+	assert.Nil(t, c.replicas.pick(time.Now()))
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagBatchSize is the number of tag members processed per Lua invocation
+const tagBatchSize = 500
+
+// defaultTagPrefix starts tag set keys when RedisConfig.TagPrefix is empty
+const defaultTagPrefix = "tag:"
+
+// globEscaper escapes SCAN match metacharacters
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// tagKey returns the reverse-index key for a tag
+func (r *RedisCache) tagKey(tag string) string {
+	return r.tagPrefix() + tag
+}
+
+// tagPrefix returns the configured tag prefix or the default
+func (r *RedisCache) tagPrefix() string {
+	if r.config.TagPrefix == "" {
+		return defaultTagPrefix
+	}
+	return r.config.TagPrefix
+}
+
+// SetWithTags stores a value in the cache and records the key under each tag.
+// Tag sets live at least as long as their longest-lived member.
+//
+// Tag scripts pass every key they touch through KEYS, but a value and its tag sets are
+// updated together, so on Redis Cluster they must hash to the same slot (e.g. share a
+// {hash tag}). Tags are meant for a single non-cluster instance.
+func (r *RedisCache) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	data, err := r.encodeValue(value)
+	if err != nil {
+		return err
+	}
+
+	// Set the value and update every tag set in one atomic step
+	const script = `
+		local ttl = tonumber(ARGV[2])
+		if ttl > 0 then
+			redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+		else
+			redis.call("SET", KEYS[1], ARGV[1])
+		end
+
+		for i = 2, #KEYS do
+			local existed = redis.call("EXISTS", KEYS[i])
+			redis.call("SADD", KEYS[i], KEYS[1])
+
+			local current = redis.call("PTTL", KEYS[i])
+			if ttl <= 0 then
+				-- A member without expiry keeps the tag set alive forever
+				redis.call("PERSIST", KEYS[i])
+			elseif existed == 0 or (current >= 0 and current < ttl) then
+				redis.call("PEXPIRE", KEYS[i], ttl)
+			end
+		end
+
+		return 1
+	`
+
+	hashed := r.key(key)
+	keys := make([]string, 0, len(tags)+1)
+	keys = append(keys, hashed)
+	for _, tag := range tags {
+		keys = append(keys, r.tagKey(r.key(tag)))
+	}
+
+	if err := r.client.Eval(ctx, script, keys, data, ttl.Milliseconds()).Err(); err != nil {
+		return err
+	}
+
+	if r.config.StoreOriginalKeys && hashed != key {
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.recordOriginalKey(ctx, pipe, key, hashed, ttl)
+			return nil
+		})
+	}
+	return err
+}
+
+// InvalidateTag deletes every key recorded under the tag along with the tag set itself.
+// Members are read and deleted in batches so large tags don't block Redis. It returns
+// the number of keys that were deleted; members that already expired are pruned silently.
+func (r *RedisCache) InvalidateTag(ctx context.Context, tag string) (int64, error) {
+	// Delete a batch of members and remove them from the set, which Redis drops once empty
+	const script = `
+		local deleted = 0
+		for i = 2, #KEYS do
+			deleted = deleted + redis.call("DEL", KEYS[i])
+			redis.call("SREM", KEYS[1], KEYS[i])
+		end
+		return deleted
+	`
+
+	key := r.tagKey(r.key(tag))
+	var deleted int64
+
+	for {
+		members, err := r.client.SRandMemberN(ctx, key, tagBatchSize).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if len(members) == 0 {
+			return deleted, nil
+		}
+
+		n, err := r.client.Eval(ctx, script, append([]string{key}, members...)).Int64()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+}
+
+// PruneTags removes members whose keys have expired from every tag set under the
+// configured TagPrefix. It returns the number of members removed.
+func (r *RedisCache) PruneTags(ctx context.Context) (int64, error) {
+	// Remove members of a batch that no longer exist
+	const pruneScript = `
+		local removed = 0
+		for i = 2, #KEYS do
+			if redis.call("EXISTS", KEYS[i]) == 0 then
+				removed = removed + redis.call("SREM", KEYS[1], KEYS[i])
+			end
+		end
+		return removed
+	`
+
+	var removed int64
+	iter := r.client.Scan(ctx, 0, globEscaper.Replace(r.tagPrefix())+"*", tagBatchSize).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		err := r.scanTag(ctx, key, func(members []string) error {
+			n, err := r.client.Eval(ctx, pruneScript, append([]string{key}, members...)).Int64()
+			if err != nil {
+				return err
+			}
+			removed += n
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, iter.Err()
+}
+
+// RunTagJanitor prunes expired tag members every interval until the context is cancelled.
+// Failed runs are reported through the configured Logger.
+func (r *RedisCache) RunTagJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := r.PruneTags(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.config.Logger.Warn("tag janitor failed", "prefix", r.tagPrefix(), "removed", removed, "error", err)
+			}
+		}
+	}
+}
+
+// scanTag iterates over the members of a tag set in batches using SSCAN
+func (r *RedisCache) scanTag(ctx context.Context, key string, fn func(members []string) error) error {
+	var cursor uint64
+	for {
+		members, next, err := r.client.SScan(ctx, key, cursor, "", tagBatchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(members) > 0 {
+			if err := fn(members); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWithTags_MultiTagKeys(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.SetWithTags(ctx, "profile:42", "p", time.Minute, []string{"user:42", "profiles"}))
+	require.NoError(t, c.SetWithTags(ctx, "orders:42", "o", time.Hour, []string{"user:42"}))
+	require.NoError(t, c.SetWithTags(ctx, "profile:7", "p", time.Minute, []string{"user:7", "profiles"}))
+
+	members, err := mr.SMembers("tag:user:42")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"profile:42", "orders:42"}, members)
+
+	// The tag set lives as long as its longest-lived member
+	assert.Equal(t, time.Hour, mr.TTL("tag:user:42"))
+	assert.Equal(t, time.Minute, mr.TTL("tag:profiles"))
+
+	deleted, err := c.InvalidateTag(ctx, "user:42")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	assert.False(t, mr.Exists("profile:42"))
+	assert.False(t, mr.Exists("orders:42"))
+	assert.False(t, mr.Exists("tag:user:42"))
+
+	// Keys under other tags are untouched
+	assert.True(t, mr.Exists("profile:7"))
+
+	// Deleting via one tag leaves the member dangling in the other tag; invalidation tolerates it
+	deleted, err = c.InvalidateTag(ctx, "profiles")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.False(t, mr.Exists("tag:profiles"))
+}
+
+func TestSetWithTags_NoExpiryPersistsTag(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.SetWithTags(ctx, "a", 1, time.Minute, []string{"t"}))
+	require.NoError(t, c.SetWithTags(ctx, "b", 2, 0, []string{"t"}))
+	assert.Equal(t, time.Duration(0), mr.TTL("tag:t"))
+
+	// A shorter-lived member must not shorten a persistent tag set
+	require.NoError(t, c.SetWithTags(ctx, "c", 3, time.Second, []string{"t"}))
+	assert.Equal(t, time.Duration(0), mr.TTL("tag:t"))
+}
+
+func TestInvalidateTag_PartialExpiry(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.SetWithTags(ctx, "short", 1, time.Second, []string{"t"}))
+	require.NoError(t, c.SetWithTags(ctx, "long", 2, time.Hour, []string{"t"}))
+
+	mr.FastForward(2 * time.Second)
+	require.False(t, mr.Exists("short"))
+
+	// The janitor prunes the expired member
+	removed, err := c.PruneTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	members, err := mr.SMembers("tag:t")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"long"}, members)
+
+	// Expired members are cleaned lazily and not counted as deleted
+	require.NoError(t, c.SetWithTags(ctx, "short2", 1, time.Second, []string{"t"}))
+	mr.FastForward(2 * time.Second)
+	deleted, err := c.InvalidateTag(ctx, "t")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.False(t, mr.Exists("tag:t"))
+}
+
+func TestInvalidateTag_LargeTag(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	const members = 10000
+	for i := 0; i < members; i++ {
+		require.NoError(t, c.SetWithTags(ctx, fmt.Sprintf("item:%d", i), i, time.Hour, []string{"big"}))
+	}
+
+	deleted, err := c.InvalidateTag(ctx, "big")
+	require.NoError(t, err)
+	assert.Equal(t, int64(members), deleted)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestCacheAside_WithTags(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		return "loaded", nil
+	}
+
+	var dest string
+	require.NoError(t, c.CacheAside(ctx, "k", &dest, time.Minute, loader, "group"))
+	assert.Equal(t, "loaded", dest)
+
+	members, err := mr.SMembers("tag:group")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k"}, members)
+}
+
+func TestPruneTags_OnlyScansOwnPrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Address: mr.Addr(), TagPrefix: "app:tag:"})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	ctx := context.Background()
+
+	require.NoError(t, c.SetWithTags(ctx, "short", 1, time.Second, []string{"t"}))
+	require.NoError(t, c.SetWithTags(ctx, "long", 2, time.Hour, []string{"t"}))
+	assert.True(t, mr.Exists("app:tag:t"))
+
+	// A set of plain strings owned by another application in the same DB
+	_, err = mr.SAdd("tag:other", "not-a-key")
+	require.NoError(t, err)
+
+	mr.FastForward(2 * time.Second)
+	removed, err := c.PruneTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	members, err := mr.SMembers("tag:other")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"not-a-key"}, members)
+}
+
+func TestRunTagJanitor_LogsFailures(t *testing.T) {
+	mr := miniredis.RunT(t)
+	logger := &captureLogger{}
+	c, err := NewRedisCache(RedisConfig{Address: mr.Addr(), Logger: logger})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	require.NoError(t, c.SetWithTags(context.Background(), "k", 1, time.Hour, []string{"t"}))
+	mr.SetError("LOADING")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	c.RunTagJanitor(ctx, 20*time.Millisecond)
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	require.NotEmpty(t, logger.lines)
+	assert.Contains(t, logger.lines[0], "tag janitor failed")
+	assert.Contains(t, logger.lines[0], "LOADING")
+}
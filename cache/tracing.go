@@ -0,0 +1,36 @@
+package cache
+
+import "context"
+
+// Operation names passed to TraceStarter.Start
+const (
+	OpGet                = "get"
+	OpSet                = "set"
+	OpDelete             = "delete"
+	OpExists             = "exists"
+	OpCacheAside         = "cache_aside"
+	OpLockAcquire        = "lock.acquire"
+	OpLockRelease        = "lock.release"
+	OpLockExtend         = "lock.extend"
+	OpRateLimitAllow     = "ratelimit.allow"
+	OpRateLimitRemaining = "ratelimit.remaining"
+)
+
+// TraceStarter instruments cache operations. Start is called before an operation with the
+// caller's key, and the returned function is called with the operation's outcome when it ends.
+// Lookups that miss end with ErrKeyNotFound even when the operation itself succeeds, such as
+// Exists returning false or CacheAside loading the value.
+type TraceStarter interface {
+	Start(ctx context.Context, op, key string) (context.Context, func(err error))
+}
+
+// endNoop is returned when tracing is disabled
+func endNoop(error) {}
+
+// startSpan starts tracing an operation when a TraceStarter is configured
+func startSpan(ctx context.Context, tracer TraceStarter, op, key string) (context.Context, func(err error)) {
+	if tracer == nil {
+		return ctx, endNoop
+	}
+	return tracer.Start(ctx, op, key)
+}
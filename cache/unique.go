@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultUniqueRetention is how long unique counter buckets are kept when
+// RedisConfig.UniqueRetention is not set
+const defaultUniqueRetention = 24 * time.Hour
+
+// maxUniqueBuckets bounds how many buckets a single count merges
+const maxUniqueBuckets = 10000
+
+// uniqueBucketKey returns the HyperLogLog key for one bucket of a counter. The hash tag
+// keeps a counter's buckets in one cluster slot so they can be counted together.
+func uniqueBucketKey(counter string, index int64) string {
+	return "unique:{" + counter + "}:" + strconv.FormatInt(index, 10)
+}
+
+// uniqueMetaKey returns the key recording a counter's bucket size
+func uniqueMetaKey(counter string) string {
+	return "unique:{" + counter + "}:bucket"
+}
+
+// uniqueMergeKey returns the transient key buckets are merged into for counting
+func uniqueMergeKey(counter string) string {
+	return "unique:{" + counter + "}:merge"
+}
+
+// AddUnique records member in the counter's HyperLogLog for the current bucket, e.g. an
+// hourly bucket for "unique visitors in the last 24h". Buckets are kept for
+// UniqueRetention after they end. The bucket size is stored with the counter and must not
+// change while old buckets are still being counted.
+func (r *RedisCache) AddUnique(ctx context.Context, counter string, member string, bucket time.Duration) error {
+	if bucket < time.Millisecond {
+		return fmt.Errorf("invalid bucket size %v for unique counter %s", bucket, counter)
+	}
+
+	retention := r.uniqueRetention()
+	hashed := r.key(counter)
+	index := r.now().UnixMilli() / bucket.Milliseconds()
+	expireAt := time.UnixMilli((index + 1) * bucket.Milliseconds()).Add(retention)
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		key := uniqueBucketKey(hashed, index)
+		pipe.PFAdd(ctx, key, member)
+		pipe.PExpireAt(ctx, key, expireAt)
+		pipe.Set(ctx, uniqueMetaKey(hashed), bucket.Milliseconds(), retention+bucket)
+		return nil
+	})
+	return err
+}
+
+// CountUnique returns the approximate number of distinct members added to the counter
+// within the window ending now. The window is rounded up to whole buckets, and the
+// estimate has the HyperLogLog standard error of about 0.81%.
+func (r *RedisCache) CountUnique(ctx context.Context, counter string, window time.Duration) (int64, error) {
+	return r.CountUniqueAt(ctx, counter, r.now(), window)
+}
+
+// CountUniqueAt is like CountUnique for the window ending at the given time. Windows
+// reaching back further than UniqueRetention undercount, since older buckets have expired,
+// and are shortened to the retention period. Windows spanning more than 10000 buckets
+// are rejected.
+func (r *RedisCache) CountUniqueAt(ctx context.Context, counter string, at time.Time, window time.Duration) (int64, error) {
+	hashed := r.key(counter)
+
+	bucketMillis, err := r.client.Get(ctx, uniqueMetaKey(hashed)).Int64()
+	if err == redis.Nil {
+		// Nothing was added within the retention period
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if bucketMillis <= 0 {
+		return 0, fmt.Errorf("invalid bucket size %dms stored for unique counter %s", bucketMillis, counter)
+	}
+
+	// Buckets older than the retention period have expired, so don't look them up
+	if limit := r.uniqueRetention() + time.Duration(bucketMillis)*time.Millisecond; window > limit {
+		window = limit
+	}
+	if buckets := window.Milliseconds()/bucketMillis + 1; buckets > maxUniqueBuckets {
+		return 0, fmt.Errorf("window %v spans %d buckets of unique counter %s, more than %d", window, buckets, counter, maxUniqueBuckets)
+	}
+
+	// Count every bucket overlapping (at-window, at]
+	first := (at.UnixMilli() - window.Milliseconds()) / bucketMillis
+	last := at.UnixMilli() / bucketMillis
+	keys := make([]string, 0, last-min(first, last)+1)
+	for index := min(first, last); index <= last; index++ {
+		keys = append(keys, uniqueBucketKey(hashed, index))
+	}
+
+	// Merge the buckets into a transient key; MULTI keeps it from being seen or left behind
+	merged := uniqueMergeKey(hashed)
+	var count *redis.IntCmd
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.PFMerge(ctx, merged, keys...)
+		count = pipe.PFCount(ctx, merged)
+		pipe.Del(ctx, merged)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// uniqueRetention returns how long unique counter buckets are kept after they end
+func (r *RedisCache) uniqueRetention() time.Duration {
+	if r.config.UniqueRetention > 0 {
+		return r.config.UniqueRetention
+	}
+	return defaultUniqueRetention
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUniqueTestCache returns a cache whose clock and miniredis clock advance together
+func newUniqueTestCache(t *testing.T) (*RedisCache, *miniredis.Miniredis, func(d time.Duration)) {
+	t.Helper()
+	c, mr := newTestCache(t)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	mr.SetTime(now)
+
+	return c, mr, func(d time.Duration) {
+		now = now.Add(d)
+		mr.SetTime(now)
+		mr.FastForward(d)
+	}
+}
+
+func TestUniqueCounter_SlidingWindow(t *testing.T) {
+	c, mr, advance := newUniqueTestCache(t)
+	ctx := context.Background()
+	start := c.now()
+
+	// 10k distinct visitors spread over 30 hours, each seen a few times within its hour
+	const visitors, hours = 10000, 30
+	perHour := make([]int64, hours)
+	for hour := 0; hour < hours; hour++ {
+		for i := hour * visitors / hours; i < (hour+1)*visitors/hours; i++ {
+			for repeat := 0; repeat < 2; repeat++ {
+				require.NoError(t, c.AddUnique(ctx, "page:/home", fmt.Sprintf("visitor-%d", i), time.Hour))
+			}
+			perHour[hour]++
+		}
+		advance(time.Hour)
+	}
+	// Halfway through hour 30, with nothing added yet
+	advance(30 * time.Minute)
+
+	exact := func(from, to int) int64 {
+		var total int64
+		for hour := from; hour <= to; hour++ {
+			total += perHour[hour]
+		}
+		return total
+	}
+	assertEstimate := func(want, got int64) {
+		t.Helper()
+		// Allow 3% error, several times the HyperLogLog standard error
+		assert.InDelta(t, want, got, float64(want)*0.03, "estimate %d, exact %d", got, want)
+	}
+
+	// The last 24h covers hours 6 to 29, plus the partial bucket at the window's start
+	got, err := c.CountUnique(ctx, "page:/home", 24*time.Hour)
+	require.NoError(t, err)
+	assertEstimate(exact(6, 29), got)
+
+	got, err = c.CountUnique(ctx, "page:/home", 3*time.Hour)
+	require.NoError(t, err)
+	assertEstimate(exact(27, 29), got)
+
+	// Historical windows work while their buckets remain
+	got, err = c.CountUniqueAt(ctx, "page:/home", start.Add(12*time.Hour), 4*time.Hour)
+	require.NoError(t, err)
+	assertEstimate(exact(8, 12), got)
+
+	// Buckets expire a retention period after they end
+	hashed := c.key("page:/home")
+	firstHour := start.UnixMilli() / time.Hour.Milliseconds()
+	for hour := 0; hour < hours; hour++ {
+		assert.Equal(t, hour >= 6, mr.Exists(uniqueBucketKey(hashed, firstHour+int64(hour))), "hour %d", hour)
+	}
+}
+
+func TestUniqueCounter_DuplicatesAndEmpty(t *testing.T) {
+	c, _, advance := newUniqueTestCache(t)
+	ctx := context.Background()
+
+	count, err := c.CountUnique(ctx, "signups", time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, c.AddUnique(ctx, "signups", "alice", time.Minute))
+	}
+	require.NoError(t, c.AddUnique(ctx, "signups", "bob", time.Minute))
+	advance(5 * time.Minute)
+	require.NoError(t, c.AddUnique(ctx, "signups", "alice", time.Minute))
+
+	count, err = c.CountUnique(ctx, "signups", 10*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = c.CountUnique(ctx, "signups", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	assert.Error(t, c.AddUnique(ctx, "signups", "carol", 0))
+}
+
+func TestUniqueCounter_BoundedWindow(t *testing.T) {
+	c, _, advance := newUniqueTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.AddUnique(ctx, "logins", "alice", time.Minute))
+	advance(time.Hour)
+	require.NoError(t, c.AddUnique(ctx, "logins", "bob", time.Minute))
+
+	// Windows longer than the retention period only look at buckets that can still exist
+	count, err := c.CountUnique(ctx, "logins", 10*365*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Too many buckets for one count
+	require.NoError(t, c.AddUnique(ctx, "clicks", "alice", time.Millisecond))
+	_, err = c.CountUnique(ctx, "clicks", time.Hour)
+	assert.ErrorContains(t, err, "more than 10000")
+	count, err = c.CountUnique(ctx, "clicks", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
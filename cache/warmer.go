@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyListerFunc returns the current set of keys for a bulk warming registration
+type KeyListerFunc func(ctx context.Context) ([]string, error)
+
+// WarmerStats counts warming outcomes since the warmer was created
+type WarmerStats struct {
+	Refreshes int64 // Entries loaded and written
+	Failures  int64 // Loader, lister or write errors
+	Skips     int64 // Entries left alone because they were written recently
+}
+
+// warmEntry is a registered key or key set with its refresh schedule
+type warmEntry struct {
+	keys   KeyListerFunc
+	ttl    time.Duration
+	every  time.Duration
+	loader LoaderFunc
+	next   time.Time // Zero until the first refresh, so new entries are warmed right away
+}
+
+// Warmer keeps registered cache entries populated by reloading them before they expire.
+// Configure the exported fields before calling Run. Skipping keys the application wrote
+// recently requires RedisConfig.WriteTimestamps; without it every due key is reloaded.
+type Warmer struct {
+	// RefreshFraction is the fraction of the TTL after which an entry is refreshed
+	// when Register is called without refreshEvery (default 0.8)
+	RefreshFraction float64
+	// Concurrency bounds how many entries are loaded at once (default 4)
+	Concurrency int
+	// Jitter spreads refreshes by up to this fraction of the refresh interval (default 0.1)
+	Jitter float64
+	// TickInterval is how often Run checks for due entries (default 1s, also used when zero)
+	TickInterval time.Duration
+	// OnError is called for every failed refresh when set
+	OnError func(key string, err error)
+
+	cache   *RedisCache
+	mu      sync.Mutex
+	entries []*warmEntry
+	stats   WarmerStats
+	now     func() time.Time
+}
+
+// NewWarmer creates a warmer that writes to the given cache
+func NewWarmer(c *RedisCache) *Warmer {
+	return &Warmer{
+		RefreshFraction: 0.8,
+		Concurrency:     4,
+		Jitter:          0.1,
+		TickInterval:    time.Second,
+		cache:           c,
+		now:             time.Now,
+	}
+}
+
+// Register keeps key warm with values from loader, written with the given TTL.
+// The entry is refreshed every refreshEvery, or at RefreshFraction of the TTL when refreshEvery is zero.
+func (w *Warmer) Register(key string, ttl time.Duration, loader LoaderFunc, refreshEvery time.Duration) {
+	w.RegisterKeys(func(ctx context.Context) ([]string, error) {
+		return []string{key}, nil
+	}, ttl, loader, refreshEvery)
+}
+
+// RegisterKeys is like Register for a dynamic key set. The lister is called on every refresh,
+// so keys it stops returning are no longer warmed.
+func (w *Warmer) RegisterKeys(lister KeyListerFunc, ttl time.Duration, loader LoaderFunc, refreshEvery time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.entries = append(w.entries, &warmEntry{
+		keys:   lister,
+		ttl:    ttl,
+		every:  refreshEvery,
+		loader: loader,
+	})
+}
+
+// Run refreshes due entries every TickInterval until the context is cancelled.
+// It waits for in-flight refreshes before returning.
+func (w *Warmer) Run(ctx context.Context) {
+	interval := w.TickInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	w.refreshDue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.refreshDue(ctx)
+		}
+	}
+}
+
+// Stats returns the warming counters
+func (w *Warmer) Stats() WarmerStats {
+	return WarmerStats{
+		Refreshes: atomic.LoadInt64(&w.stats.Refreshes),
+		Failures:  atomic.LoadInt64(&w.stats.Failures),
+		Skips:     atomic.LoadInt64(&w.stats.Skips),
+	}
+}
+
+// interval returns how often an entry is refreshed
+func (w *Warmer) interval(e *warmEntry) time.Duration {
+	if e.every > 0 {
+		return e.every
+	}
+	return time.Duration(float64(e.ttl) * w.RefreshFraction)
+}
+
+// jittered offsets an interval by up to ±Jitter of its length
+func (w *Warmer) jittered(d time.Duration) time.Duration {
+	if w.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	spread := float64(d) * w.Jitter
+	return d + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// refreshDue refreshes every entry whose next refresh time has passed and waits for them to finish
+func (w *Warmer) refreshDue(ctx context.Context) {
+	now := w.now()
+
+	w.mu.Lock()
+	var due []*warmEntry
+	for _, e := range w.entries {
+		if !e.next.After(now) {
+			e.next = now.Add(w.jittered(w.interval(e)))
+			due = append(due, e)
+		}
+	}
+	w.mu.Unlock()
+
+	concurrency := w.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, e := range due {
+		keys, err := e.keys(ctx)
+		if err != nil {
+			w.fail("", err)
+			continue
+		}
+
+		for _, key := range keys {
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return
+			case sem <- struct{}{}:
+			}
+
+			wg.Add(1)
+			go func(e *warmEntry, key string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				w.refresh(ctx, e, key)
+			}(e, key)
+		}
+	}
+
+	wg.Wait()
+}
+
+// refresh reloads a single key unless it was written since its last scheduled refresh
+func (w *Warmer) refresh(ctx context.Context, e *warmEntry, key string) {
+	// Keys written within half a refresh interval are fresher than anything warming would load
+	started := w.now()
+	written, err := w.writtenAt(ctx, key)
+	if err != nil {
+		w.fail(key, err)
+		return
+	}
+	if !written.IsZero() && started.Sub(written) < w.interval(e)/2 {
+		atomic.AddInt64(&w.stats.Skips, 1)
+		return
+	}
+
+	value, err := e.loader(ctx, key)
+	if err != nil {
+		w.fail(key, err)
+		return
+	}
+
+	// Don't replace a value the application wrote while the loader was running
+	written, err = w.writtenAt(ctx, key)
+	if err != nil {
+		w.fail(key, err)
+		return
+	}
+	if written.After(started) {
+		atomic.AddInt64(&w.stats.Skips, 1)
+		return
+	}
+
+	if err := w.cache.Set(ctx, key, value, e.ttl); err != nil {
+		w.fail(key, err)
+		return
+	}
+	atomic.AddInt64(&w.stats.Refreshes, 1)
+}
+
+// writtenAt returns when a key was last written, or the zero time if it is missing
+// or was stored without a write timestamp
+func (w *Warmer) writtenAt(ctx context.Context, key string) (time.Time, error) {
+	data, err := w.cache.client.Get(ctx, w.cache.key(key)).Bytes()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	_, written := decodeValue(data)
+	return written, nil
+}
+
+// fail records a failed refresh
+func (w *Warmer) fail(key string, err error) {
+	atomic.AddInt64(&w.stats.Failures, 1)
+	if w.OnError != nil {
+		w.OnError(key, err)
+	}
+}
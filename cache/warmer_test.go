@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock for the warmer
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestWarmer(t *testing.T) (*Warmer, *fakeClock, *RedisCache, func(d time.Duration)) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Address: mr.Addr(), WriteTimestamps: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w := NewWarmer(c)
+	w.Jitter = 0
+	w.now = clock.Now
+	c.now = clock.Now
+
+	// Advance the warmer's clock and Redis TTLs together
+	advance := func(d time.Duration) {
+		clock.now = clock.now.Add(d)
+		mr.FastForward(d)
+	}
+	return w, clock, c, advance
+}
+
+func TestWarmer_RefreshTiming(t *testing.T) {
+	w, _, c, advance := newTestWarmer(t)
+	ctx := context.Background()
+
+	var loads int64
+	w.Register("config", 10*time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return atomic.AddInt64(&loads, 1), nil
+	}, 0)
+
+	// New entries are warmed immediately
+	w.refreshDue(ctx)
+	var got int64
+	require.NoError(t, c.Get(ctx, "config", &got))
+	assert.Equal(t, int64(1), got)
+
+	// Not due before 80% of the TTL
+	advance(7 * time.Minute)
+	w.refreshDue(ctx)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&loads))
+
+	// Refreshed once due, before the entry expires
+	advance(time.Minute)
+	w.refreshDue(ctx)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&loads))
+	require.NoError(t, c.Get(ctx, "config", &got))
+	assert.Equal(t, int64(2), got)
+
+	assert.Equal(t, WarmerStats{Refreshes: 2}, w.Stats())
+}
+
+func TestWarmer_SkipsRecentlyWritten(t *testing.T) {
+	w, _, c, advance := newTestWarmer(t)
+	ctx := context.Background()
+
+	w.Register("profile", 10*time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return "warmed", nil
+	}, 0)
+	w.refreshDue(ctx)
+
+	// The application writes fresher data shortly before the scheduled refresh
+	advance(7 * time.Minute)
+	require.NoError(t, c.Set(ctx, "profile", "fresh", 10*time.Minute))
+	advance(time.Minute)
+	w.refreshDue(ctx)
+
+	var got string
+	require.NoError(t, c.Get(ctx, "profile", &got))
+	assert.Equal(t, "fresh", got)
+	assert.Equal(t, WarmerStats{Refreshes: 1, Skips: 1}, w.Stats())
+
+	// Once the write is old, warming resumes
+	advance(8 * time.Minute)
+	w.refreshDue(ctx)
+	require.NoError(t, c.Get(ctx, "profile", &got))
+	assert.Equal(t, "warmed", got)
+}
+
+func TestWarmer_UsesWriteTime(t *testing.T) {
+	w, _, c, advance := newTestWarmer(t)
+	ctx := context.Background()
+
+	w.Register("long", 10*time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return "warmed", nil
+	}, 0)
+	w.Register("forever", 10*time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return "warmed", nil
+	}, 0)
+
+	// A long TTL or no expiry says nothing about when a key was written
+	require.NoError(t, c.Set(ctx, "long", "old", time.Hour))
+	require.NoError(t, c.Set(ctx, "forever", "old", 0))
+	advance(5 * time.Minute)
+	w.refreshDue(ctx)
+
+	var got string
+	require.NoError(t, c.Get(ctx, "long", &got))
+	assert.Equal(t, "warmed", got)
+	require.NoError(t, c.Get(ctx, "forever", &got))
+	assert.Equal(t, "warmed", got)
+
+	// A recent write without expiry is still skipped
+	advance(5 * time.Minute)
+	require.NoError(t, c.Set(ctx, "forever", "fresh", 0))
+	advance(3 * time.Minute)
+	w.refreshDue(ctx)
+	require.NoError(t, c.Get(ctx, "forever", &got))
+	assert.Equal(t, "fresh", got)
+	assert.Equal(t, WarmerStats{Refreshes: 3, Skips: 1}, w.Stats())
+}
+
+func TestWarmer_KeepsWriteDuringLoad(t *testing.T) {
+	w, clock, c, _ := newTestWarmer(t)
+	ctx := context.Background()
+
+	w.Register("profile", 10*time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		// The application writes while the loader is still fetching
+		clock.now = clock.now.Add(time.Second)
+		require.NoError(t, c.Set(ctx, key, "fresh", 10*time.Minute))
+		return "stale", nil
+	}, 0)
+	w.refreshDue(ctx)
+
+	var got string
+	require.NoError(t, c.Get(ctx, "profile", &got))
+	assert.Equal(t, "fresh", got)
+	assert.Equal(t, WarmerStats{Skips: 1}, w.Stats())
+}
+
+func TestWarmer_ReadsValuesWithoutWriteTime(t *testing.T) {
+	w, _, c, _ := newTestWarmer(t)
+	ctx := context.Background()
+
+	// Values written by other clients have no envelope and are always refreshed
+	require.NoError(t, c.client.Set(ctx, "plain", `"old"`, 0).Err())
+	var got string
+	require.NoError(t, c.Get(ctx, "plain", &got))
+	assert.Equal(t, "old", got)
+
+	w.Register("plain", 10*time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return "warmed", nil
+	}, 0)
+	w.refreshDue(ctx)
+	require.NoError(t, c.Get(ctx, "plain", &got))
+	assert.Equal(t, "warmed", got)
+}
+
+func TestWarmer_RegisterKeysAndFailures(t *testing.T) {
+	w, _, c, _ := newTestWarmer(t)
+	ctx := context.Background()
+
+	errLoad := errors.New("backend down")
+	var failed []string
+	w.OnError = func(key string, err error) {
+		failed = append(failed, key)
+		assert.ErrorIs(t, err, errLoad)
+	}
+	w.Concurrency = 1
+
+	w.RegisterKeys(func(ctx context.Context) ([]string, error) {
+		return []string{"user:1", "user:2", "user:3"}, nil
+	}, time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		if key == "user:2" {
+			return nil, errLoad
+		}
+		return key, nil
+	}, 30*time.Second)
+	w.refreshDue(ctx)
+
+	exists, err := c.Exists(ctx, "user:1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = c.Exists(ctx, "user:2")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, []string{"user:2"}, failed)
+	assert.Equal(t, WarmerStats{Refreshes: 2, Failures: 1}, w.Stats())
+}
+
+func TestWarmer_RunStopsOnCancel(t *testing.T) {
+	c, _ := newTestCache(t)
+	w := NewWarmer(c)
+	w.TickInterval = 10 * time.Millisecond
+
+	var loads int64
+	w.Register("k", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt64(&loads, 1)
+		return "v", nil
+	}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&loads) == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestWarmer_RunDefaultsTickInterval(t *testing.T) {
+	c, _ := newTestCache(t)
+	w := NewWarmer(c)
+	w.TickInterval = 0
+
+	w.Register("k", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return "v", nil
+	}, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	exists, err := c.Exists(context.Background(), "k")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestSet_PlainJSONByDefault(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	// Without WriteTimestamps other clients read exactly the marshaled value
+	require.NoError(t, c.Set(ctx, "k", map[string]int{"a": 1}, 0))
+	raw, err := mr.Get("k")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, raw)
+
+	// A warmer without write times reloads every due key
+	w := NewWarmer(c)
+	w.Register("k", 10*time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return map[string]int{"a": 2}, nil
+	}, 0)
+	w.refreshDue(ctx)
+	raw, err = mr.Get("k")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":2}`, raw)
+}
@@ -16,6 +16,7 @@ var (
 	ErrInvalidKey       = errors.New("hmac: key cannot be empty")
 	ErrInvalidMessage   = errors.New("hmac: message cannot be empty")
 	ErrInvalidSignature = errors.New("hmac: invalid signature")
+	ErrNoKeys           = errors.New("hmac: at least one key is required")
 )
 
 // HashAlgorithm represents supported hash algorithms
@@ -133,3 +134,54 @@ func (h *HMAC) decode(signature string) ([]byte, error) {
 		return hex.DecodeString(signature)
 	}
 }
+
+// KeyRing verifies signatures against several secrets, so a webhook secret can be rotated
+// without downtime: senders switch to the new secret while the old one is still accepted.
+type KeyRing struct {
+	keys []*HMAC
+}
+
+// NewKeyRing creates a key ring for the given secrets, typically the current secret first
+// followed by the ones being retired
+func NewKeyRing(keys [][]byte, algorithm HashAlgorithm, encoding Encoding) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	ring := &KeyRing{keys: make([]*HMAC, len(keys))}
+	for i, key := range keys {
+		if len(key) == 0 {
+			return nil, ErrInvalidKey
+		}
+		ring.keys[i] = &HMAC{key: key, algorithm: algorithm, encoding: encoding}
+	}
+	return ring, nil
+}
+
+// Verify checks the signature against every key and returns the index of the key that
+// produced it, or -1 and ErrInvalidSignature if none did. All keys are always checked and
+// the match is selected without branching, so the time taken depends only on the number of
+// keys, not on which one matched.
+func (k *KeyRing) Verify(message []byte, providedSignature string) (int, error) {
+	if len(message) == 0 {
+		return -1, ErrInvalidMessage
+	}
+
+	provided, err := k.keys[0].decode(providedSignature)
+	if err != nil || len(provided) == 0 {
+		return -1, ErrInvalidSignature
+	}
+
+	matched := -1
+	for i, h := range k.keys {
+		mac := hmac.New(h.getHashFunc(), h.key)
+		mac.Write(message)
+		equal := subtle.ConstantTimeCompare(mac.Sum(nil), provided)
+		matched = subtle.ConstantTimeSelect(equal, i, matched)
+	}
+
+	if matched < 0 {
+		return -1, ErrInvalidSignature
+	}
+	return matched, nil
+}
@@ -196,3 +196,112 @@ func TestHMAC_Verify(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyRing_Verify(t *testing.T) {
+	oldKey := []byte("old-webhook-secret")
+	newKey := []byte("new-webhook-secret")
+	message := []byte(`{"event":"user.created"}`)
+
+	sign := func(key []byte, encoding Encoding) string {
+		h, err := NewHMAC(key, SHA256, encoding)
+		if err != nil {
+			t.Fatalf("Failed to create HMAC: %v", err)
+		}
+		signature, err := h.Sign(message)
+		if err != nil {
+			t.Fatalf("Failed to sign message: %v", err)
+		}
+		return signature
+	}
+
+	tests := []struct {
+		name      string
+		keys      [][]byte
+		encoding  Encoding
+		signature string
+		verifyMsg []byte
+		wantIndex int
+		wantErr   bool
+	}{
+		{
+			name:      "New secret during overlap",
+			keys:      [][]byte{newKey, oldKey},
+			signature: sign(newKey, HEX),
+			verifyMsg: message,
+			wantIndex: 0,
+		},
+		{
+			name:      "Old secret during overlap",
+			keys:      [][]byte{newKey, oldKey},
+			signature: sign(oldKey, HEX),
+			verifyMsg: message,
+			wantIndex: 1,
+		},
+		{
+			name:      "Old secret with BASE64",
+			keys:      [][]byte{newKey, oldKey},
+			encoding:  BASE64,
+			signature: sign(oldKey, BASE64),
+			verifyMsg: message,
+			wantIndex: 1,
+		},
+		{
+			name:      "Old secret after rotation",
+			keys:      [][]byte{newKey},
+			signature: sign(oldKey, HEX),
+			verifyMsg: message,
+			wantIndex: -1,
+			wantErr:   true,
+		},
+		{
+			name:      "Unknown secret",
+			keys:      [][]byte{newKey, oldKey},
+			signature: sign([]byte("attacker-secret"), HEX),
+			verifyMsg: message,
+			wantIndex: -1,
+			wantErr:   true,
+		},
+		{
+			name:      "Tampered message",
+			keys:      [][]byte{newKey, oldKey},
+			signature: sign(oldKey, HEX),
+			verifyMsg: []byte(`{"event":"user.deleted"}`),
+			wantIndex: -1,
+			wantErr:   true,
+		},
+		{
+			name:      "Malformed signature",
+			keys:      [][]byte{newKey, oldKey},
+			signature: "not-hex",
+			verifyMsg: message,
+			wantIndex: -1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := NewKeyRing(tt.keys, SHA256, tt.encoding)
+			if err != nil {
+				t.Fatalf("Failed to create key ring: %v", err)
+			}
+
+			index, err := ring.Verify(tt.verifyMsg, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("KeyRing.Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if index != tt.wantIndex {
+				t.Errorf("KeyRing.Verify() index = %d, want %d", index, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestNewKeyRing(t *testing.T) {
+	if _, err := NewKeyRing(nil, SHA256, HEX); err != ErrNoKeys {
+		t.Errorf("NewKeyRing() with no keys error = %v, want %v", err, ErrNoKeys)
+	}
+	if _, err := NewKeyRing([][]byte{[]byte("key"), {}}, SHA256, HEX); err != ErrInvalidKey {
+		t.Errorf("NewKeyRing() with an empty key error = %v, want %v", err, ErrInvalidKey)
+	}
+}
@@ -0,0 +1,143 @@
+// Package nonce provides single-use token tracking for replay protection, such as
+// OAuth state values, OIDC nonces and webhook delivery IDs.
+package nonce
+
+import (
+	"sync"
+	"time"
+)
+
+// entry is a tracked token
+type entry struct {
+	expiresAt time.Time
+	consumed  bool
+}
+
+// Cache remembers tokens for a TTL so each one is accepted at most once.
+//
+// By default Consume accepts any token the first time it is seen, which suits nonces
+// generated by the other party. With RequireIssued set, only tokens recorded with Issue
+// are accepted, which suits values such as OAuth state that this service hands out.
+type Cache struct {
+	// RequireIssued makes Consume reject tokens that were not recorded with Issue
+	RequireIssued bool
+
+	ttl         time.Duration
+	mu          sync.Mutex
+	entries     map[string]entry
+	now         func() time.Time
+	stopCleanup chan struct{}
+	cleanupWg   sync.WaitGroup
+}
+
+// New creates a cache that remembers tokens for ttl
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		entries: make(map[string]entry),
+		now:     time.Now,
+	}
+}
+
+// Issue records a token that Consume accepts once until the TTL elapses
+func (c *Cache) Issue(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[token] = entry{expiresAt: c.now().Add(c.ttl)}
+}
+
+// Consume returns true the first time a valid token is presented and false on reuse.
+// Issued tokens are rejected after the TTL. Tokens that were not issued are accepted
+// once per TTL unless RequireIssued is set.
+func (c *Cache) Consume(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	e, ok := c.entries[token]
+	if ok && now.After(e.expiresAt) {
+		delete(c.entries, token)
+		ok = false
+	}
+
+	switch {
+	case ok && e.consumed:
+		return false
+	case ok:
+		// Keep the consumed token until it would have expired, so reuse is rejected
+		c.entries[token] = entry{expiresAt: e.expiresAt, consumed: true}
+		return true
+	case c.RequireIssued:
+		return false
+	default:
+		c.entries[token] = entry{expiresAt: now.Add(c.ttl), consumed: true}
+		return true
+	}
+}
+
+// Len returns the number of tracked tokens, including expired ones not yet removed
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// RemoveExpired deletes expired tokens and returns how many were removed
+func (c *Cache) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	removed := 0
+	for token, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, token)
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartCleanup starts a background goroutine that removes expired tokens every interval,
+// so abandoned tokens don't accumulate. Stop it with Close.
+func (c *Cache) StartCleanup(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopCleanup != nil {
+		return
+	}
+	c.stopCleanup = make(chan struct{})
+
+	c.cleanupWg.Add(1)
+	go func(stop chan struct{}) {
+		defer c.cleanupWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.RemoveExpired()
+			case <-stop:
+				return
+			}
+		}
+	}(c.stopCleanup)
+}
+
+// Close stops the cleanup goroutine if it is running
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	stop := c.stopCleanup
+	c.stopCleanup = nil
+	c.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		c.cleanupWg.Wait()
+	}
+	return nil
+}
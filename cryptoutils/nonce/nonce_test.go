@@ -0,0 +1,107 @@
+package nonce
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestCache returns a cache with a manually advanced clock
+func newTestCache(ttl time.Duration) (*Cache, func(d time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(ttl)
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestConsume_Once(t *testing.T) {
+	c, advance := newTestCache(time.Minute)
+
+	if !c.Consume("delivery-1") {
+		t.Fatal("first use was rejected")
+	}
+	if c.Consume("delivery-1") {
+		t.Error("reuse was accepted")
+	}
+	if !c.Consume("delivery-2") {
+		t.Error("a different token was rejected")
+	}
+
+	// Still rejected until the TTL elapses, then forgotten
+	advance(59 * time.Second)
+	if c.Consume("delivery-1") {
+		t.Error("reuse within the TTL was accepted")
+	}
+	advance(2 * time.Second)
+	if !c.Consume("delivery-1") {
+		t.Error("token was still remembered after the TTL")
+	}
+}
+
+func TestConsume_RequireIssued(t *testing.T) {
+	tests := []struct {
+		name    string
+		issue   bool
+		wait    time.Duration
+		consume int
+		want    []bool
+	}{
+		{name: "issued", issue: true, consume: 1, want: []bool{true}},
+		{name: "reused", issue: true, consume: 2, want: []bool{true, false}},
+		{name: "expired", issue: true, wait: 2 * time.Minute, consume: 1, want: []bool{false}},
+		{name: "never issued", consume: 1, want: []bool{false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, advance := newTestCache(time.Minute)
+			c.RequireIssued = true
+
+			if tt.issue {
+				c.Issue("state")
+			}
+			advance(tt.wait)
+
+			for i := 0; i < tt.consume; i++ {
+				if got := c.Consume("state"); got != tt.want[i] {
+					t.Errorf("Consume #%d = %v, want %v", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveExpired(t *testing.T) {
+	c, advance := newTestCache(time.Minute)
+
+	c.Issue("expired-1")
+	c.Consume("expired-2")
+	advance(30 * time.Second)
+	c.Issue("valid")
+	advance(31 * time.Second)
+
+	if removed := c.RemoveExpired(); removed != 2 {
+		t.Errorf("RemoveExpired() = %d, want 2", removed)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+	if !c.Consume("valid") {
+		t.Error("unexpired token was removed")
+	}
+}
+
+func TestStartCleanup(t *testing.T) {
+	c := New(time.Millisecond)
+	c.Issue("abandoned")
+
+	c.StartCleanup(5 * time.Millisecond)
+	defer c.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for c.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expired token was not removed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Partitioning strategies for KafkaConfig.Balancer
+const (
+	BalancerHash       = "hash"        // kafka-go FNV-1a hash of the key (default)
+	BalancerMurmur2    = "murmur2"     // Java client compatible murmur2 hash of the key
+	BalancerRoundRobin = "round-robin" // Spread messages evenly, ignoring keys
+	BalancerSticky     = "sticky"      // murmur2 for keyed messages, batches keyless ones on one partition
+	BalancerLeastBytes = "least-bytes" // Send to the partition that has received the fewest bytes
+)
+
+// defaultStickyBatch is how many keyless messages the sticky balancer sends to one partition
+const defaultStickyBatch = 100
+
+// newBalancer returns the balancer selected by the configuration.
+// CustomBalancer takes precedence over Balancer.
+func newBalancer(config *KafkaConfig) (kafka.Balancer, error) {
+	if config.CustomBalancer != nil {
+		return config.CustomBalancer, nil
+	}
+
+	switch config.Balancer {
+	case "", BalancerHash:
+		return &kafka.Hash{}, nil
+	case BalancerMurmur2:
+		return kafka.Murmur2Balancer{}, nil
+	case BalancerRoundRobin:
+		return &kafka.RoundRobin{}, nil
+	case BalancerSticky:
+		return &StickyBalancer{}, nil
+	case BalancerLeastBytes:
+		return &kafka.LeastBytes{}, nil
+	default:
+		return nil, fmt.Errorf("unknown balancer %q", config.Balancer)
+	}
+}
+
+// PartitionForKey returns the partition the configured balancer picks for key on a topic with
+// numPartitions partitions. It is meant for debugging co-partitioning, so it is only meaningful
+// for the key-hashing strategies; stateful strategies answer as if no message had been sent yet.
+func PartitionForKey(config *KafkaConfig, key []byte, numPartitions int) (int, error) {
+	if numPartitions <= 0 {
+		return 0, fmt.Errorf("invalid partition count %d", numPartitions)
+	}
+
+	balancer, err := newBalancer(config)
+	if err != nil {
+		return 0, err
+	}
+
+	partitions := make([]int, numPartitions)
+	for i := range partitions {
+		partitions[i] = i
+	}
+	return balancer.Balance(kafka.Message{Key: key}, partitions...), nil
+}
+
+// StickyBalancer hashes keyed messages with murmur2, like the Java client, and sends keyless
+// messages to the same partition for BatchSize messages before moving to the next one.
+// Keeping small keyless messages together lets the writer fill larger batches.
+type StickyBalancer struct {
+	BatchSize int // Keyless messages per partition before switching (default 100)
+
+	mu      sync.Mutex
+	current int
+	sent    int
+	keyed   kafka.Murmur2Balancer
+}
+
+// Balance implements kafka.Balancer
+func (b *StickyBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	if msg.Key != nil {
+		return b.keyed.Balance(msg, partitions...)
+	}
+
+	batchSize := b.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultStickyBatch
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sent >= batchSize {
+		b.current++
+		b.sent = 0
+	}
+	b.sent++
+	return partitions[b.current%len(partitions)]
+}
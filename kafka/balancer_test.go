@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// javaPartition mirrors the Java client's default partitioner: toPositive(murmur2(key)) % n
+func javaPartition(murmur2 int32, numPartitions int) int {
+	return int((uint32(murmur2) & 0x7fffffff) % uint32(numPartitions))
+}
+
+func TestPartitionForKey_Murmur2MatchesJava(t *testing.T) {
+	// Reference hashes from the Java client's Utils.murmur2 tests
+	reference := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+
+	config := NewDefaultConfig()
+	config.Balancer = BalancerMurmur2
+
+	for _, numPartitions := range []int{3, 6, 12, 1000} {
+		for key, hash := range reference {
+			got, err := PartitionForKey(config, []byte(key), numPartitions)
+			require.NoError(t, err)
+			assert.Equal(t, javaPartition(hash, numPartitions), got, "key %q on %d partitions", key, numPartitions)
+		}
+	}
+
+	// The sticky strategy places keyed messages exactly like murmur2
+	config.Balancer = BalancerSticky
+	for key, hash := range reference {
+		got, err := PartitionForKey(config, []byte(key), 6)
+		require.NoError(t, err)
+		assert.Equal(t, javaPartition(hash, 6), got)
+	}
+}
+
+func TestBalancer_RoundRobinDistributesEvenly(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Balancer = BalancerRoundRobin
+	balancer, err := newBalancer(config)
+	require.NoError(t, err)
+
+	partitions := []int{0, 1, 2, 3, 4, 5}
+	counts := make(map[int]int)
+	for i := 0; i < 600; i++ {
+		counts[balancer.Balance(kafka.Message{Value: []byte("event")}, partitions...)]++
+	}
+
+	require.Len(t, counts, 6)
+	for partition, count := range counts {
+		assert.Equal(t, 100, count, "partition %d", partition)
+	}
+}
+
+func TestStickyBalancer_BatchesKeylessMessages(t *testing.T) {
+	b := &StickyBalancer{BatchSize: 3}
+	partitions := []int{0, 1, 2}
+
+	var got []int
+	for i := 0; i < 9; i++ {
+		got = append(got, b.Balance(kafka.Message{}, partitions...))
+	}
+	assert.Equal(t, []int{0, 0, 0, 1, 1, 1, 2, 2, 2}, got)
+}
+
+func TestNewBalancer(t *testing.T) {
+	config := NewDefaultConfig()
+
+	balancer, err := newBalancer(config)
+	require.NoError(t, err)
+	assert.IsType(t, &kafka.Hash{}, balancer, "default keeps the previous behavior")
+
+	config.Balancer = BalancerLeastBytes
+	balancer, err = newBalancer(config)
+	require.NoError(t, err)
+	assert.IsType(t, &kafka.LeastBytes{}, balancer)
+
+	custom := &kafka.CRC32Balancer{}
+	config.CustomBalancer = custom
+	balancer, err = newBalancer(config)
+	require.NoError(t, err)
+	assert.Same(t, custom, balancer)
+
+	config.CustomBalancer = nil
+	config.Balancer = "random"
+	_, err = newBalancer(config)
+	assert.Error(t, err)
+
+	_, err = PartitionForKey(config, []byte("k"), 0)
+	assert.Error(t, err)
+}
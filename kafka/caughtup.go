@@ -0,0 +1,359 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetAdmin is the subset of the admin API used to find where each partition ends
+type offsetAdmin interface {
+	Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error)
+	ListOffsets(ctx context.Context, req *kafka.ListOffsetsRequest) (*kafka.ListOffsetsResponse, error)
+	OffsetFetch(ctx context.Context, req *kafka.OffsetFetchRequest) (*kafka.OffsetFetchResponse, error)
+}
+
+// partitionRange is the span of offsets the consumer group has yet to read from a partition
+type partitionRange struct {
+	next int64 // Next offset the group will read
+	end  int64 // High-water mark captured at the start of the run
+}
+
+// caughtUp reports whether the group has read everything up to the captured high-water mark
+func (r partitionRange) caughtUp() bool {
+	return r.next >= r.end
+}
+
+// partitionRanges captures the committed offset and high-water mark of every partition of the topic
+func partitionRanges(ctx context.Context, admin offsetAdmin, config *KafkaConfig) (map[int]partitionRange, error) {
+	metadata, err := admin.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{config.Topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic: %w", err)
+	}
+	if len(metadata.Topics) != 1 {
+		return nil, fmt.Errorf("failed to describe topic %s: not found", config.Topic)
+	}
+	if err := metadata.Topics[0].Error; err != nil {
+		return nil, fmt.Errorf("failed to describe topic %s: %w", config.Topic, err)
+	}
+
+	partitions := make([]int, len(metadata.Topics[0].Partitions))
+	requests := make([]kafka.OffsetRequest, 0, 2*len(partitions))
+	for i, partition := range metadata.Topics[0].Partitions {
+		partitions[i] = partition.ID
+		requests = append(requests, kafka.FirstOffsetOf(partition.ID), kafka.LastOffsetOf(partition.ID))
+	}
+
+	offsets, err := admin.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{config.Topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	committed, err := committedOffsets(ctx, admin, config, partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make(map[int]partitionRange, len(partitions))
+	for _, partition := range offsets.Topics[config.Topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets for partition %d: %w", partition.Partition, partition.Error)
+		}
+		ranges[partition.Partition] = partitionRange{next: partition.FirstOffset, end: partition.LastOffset}
+	}
+
+	// Groups without a committed offset start from the beginning of the partition
+	for partition, offset := range committed {
+		r, ok := ranges[partition]
+		if ok && offset > r.next {
+			r.next = offset
+			ranges[partition] = r
+		}
+	}
+
+	return ranges, nil
+}
+
+// committedOffsets returns the group's committed offset for each partition that has one,
+// read from the OffsetStore when one is configured
+func committedOffsets(ctx context.Context, admin offsetAdmin, config *KafkaConfig, partitions []int) (map[int]int64, error) {
+	offsets := make(map[int]int64, len(partitions))
+
+	if config.OffsetStore != nil {
+		for _, partition := range partitions {
+			offset, err := config.OffsetStore.Load(ctx, config.Topic, partition)
+			if errors.Is(err, ErrOffsetNotFound) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to load stored offset for partition %d: %w", partition, err)
+			}
+			offsets[partition] = offset
+		}
+		return offsets, nil
+	}
+
+	committed, err := admin.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: config.GroupID,
+		Topics:  map[string][]int{config.Topic: partitions},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", committed.Error)
+	}
+
+	for _, partition := range committed.Topics[config.Topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("failed to fetch committed offset for partition %d: %w", partition.Partition, partition.Error)
+		}
+		offsets[partition.Partition] = partition.CommittedOffset
+	}
+	return offsets, nil
+}
+
+// assignmentReader is a messageReader that knows which partitions the group assigned to it
+type assignmentReader interface {
+	messageReader
+	// Assignment waits for a running generation and returns this member's partitions of
+	// topic, with a context that is canceled when the generation ends
+	Assignment(ctx context.Context, topic string) ([]int, context.Context, error)
+}
+
+// ConsumeUntilCaughtUp consumes and commits messages until every partition assigned to this
+// member reaches the high-water mark captured when the call started, then returns nil.
+// Messages produced after the start are left for the next run. On each rebalance partitions
+// that were revoked stop counting, and the committed offsets of the assigned ones are read
+// again since other members may have consumed them in between. Partitions that didn't exist
+// at the start have their marks captured when they are first assigned.
+//
+// Consumers that read from Kafka's committed offsets leave the group for the call and rejoin
+// it with a reader that tracks the assignment, then switch back when it returns.
+func (c *Consumer) ConsumeUntilCaughtUp(ctx context.Context, handler MessageHandler) error {
+	reader, leave, err := c.assignedReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	ranges, err := partitionRanges(ctx, c.admin, c.config)
+	if err != nil {
+		return err
+	}
+
+	// Make sure everything handled is committed, even with auto-commit enabled
+	defer c.commitOffsets(context.Background())
+
+	var genCtx context.Context
+	var assigned, pending map[int]bool
+	for {
+		if genCtx == nil || genCtx.Err() != nil {
+			partitions, next, err := reader.Assignment(ctx, c.config.Topic)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("error waiting for partition assignment: %w", err)
+			}
+			genCtx = next
+			if assigned, pending, err = c.assign(ctx, ranges, partitions); err != nil {
+				return err
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		msg, err := fetchInGeneration(ctx, genCtx, reader)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if genCtx.Err() != nil {
+				// Rebalanced while waiting, pick up the new assignment
+				continue
+			}
+			return fmt.Errorf("error fetching message: %w", err)
+		}
+
+		// Messages fetched just before a revocation belong to the partition's new owner
+		if !assigned[msg.Partition] {
+			continue
+		}
+
+		// Messages past the captured mark belong to the next run and stay uncommitted.
+		// Reaching one also means the partition is done, even if compaction removed the last offsets.
+		r := ranges[msg.Partition]
+		if msg.Offset >= r.end {
+			delete(pending, msg.Partition)
+			continue
+		}
+
+		err = c.handle(ctx, withContext(handler), msg)
+		if err != nil && !c.skipFailed(ctx, msg, err) {
+			return fmt.Errorf("error handling message: %w", err)
+		}
+
+		c.commitMutex.Lock()
+		c.uncommitted = append(c.uncommitted, msg)
+		c.commitMutex.Unlock()
+
+		if !c.autoCommitter {
+			if err := c.commitOffsets(ctx); err != nil {
+				return fmt.Errorf("error committing offsets: %w", err)
+			}
+		}
+
+		if msg.Offset+1 >= r.end {
+			delete(pending, msg.Partition)
+		}
+	}
+}
+
+// assignedReader returns a reader that tracks this member's assignment, switching to one for
+// the call when the current reader doesn't. The returned func switches back.
+func (c *Consumer) assignedReader(ctx context.Context) (assignmentReader, func(), error) {
+	c.seekMu.Lock()
+	defer c.seekMu.Unlock()
+
+	if reader, ok := c.reader.(assignmentReader); ok {
+		c.active++
+		return reader, func() {
+			c.seekMu.Lock()
+			defer c.seekMu.Unlock()
+			c.active--
+		}, nil
+	}
+
+	// Replacing the reader is only safe while nothing else reads from it
+	if c.isConsuming || c.active > 0 {
+		return nil, nil, ErrConsumerActive
+	}
+	if c.openAssigned == nil || c.openReader == nil {
+		return nil, nil, errors.New("consumer cannot track its partition assignment")
+	}
+
+	reader := c.openAssigned()
+	if err := c.replaceReader(ctx, reader); err != nil {
+		reader.Close()
+		return nil, nil, err
+	}
+	c.active++
+
+	return reader, func() {
+		c.seekMu.Lock()
+		defer c.seekMu.Unlock()
+		c.active--
+		if err := c.replaceReader(context.Background(), c.openReader()); err != nil {
+			c.logger.Error("failed to switch back to the group reader", "topic", c.config.Topic, "group", c.config.GroupID, "error", err)
+		}
+	}, nil
+}
+
+// replaceReader commits what was handled, closes the reader and puts reader in its place.
+// The new reader is installed even if closing the old one fails.
+func (c *Consumer) replaceReader(ctx context.Context, reader messageReader) error {
+	if err := c.commitOffsets(ctx); err != nil {
+		return fmt.Errorf("error committing offsets before switching readers: %w", err)
+	}
+
+	c.commitMutex.Lock()
+	defer c.commitMutex.Unlock()
+	old := c.reader
+	c.reader = reader
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("error closing reader: %w", err)
+	}
+	return nil
+}
+
+// assign applies a new generation's partitions to the run. Marks are kept from the start of
+// the run; partitions that didn't exist then are captured now. Committed offsets are read
+// again, as other members may have consumed the partitions in between. It returns the
+// assigned partitions and those not caught up yet.
+func (c *Consumer) assign(ctx context.Context, ranges map[int]partitionRange, partitions []int) (map[int]bool, map[int]bool, error) {
+	unknown := slices.ContainsFunc(partitions, func(partition int) bool {
+		_, ok := ranges[partition]
+		return !ok
+	})
+	if unknown {
+		current, err := partitionRanges(ctx, c.admin, c.config)
+		if err != nil {
+			return nil, nil, err
+		}
+		for partition, r := range current {
+			if _, ok := ranges[partition]; !ok {
+				ranges[partition] = r
+			}
+		}
+	}
+
+	committed, err := committedOffsets(ctx, c.admin, c.config, partitions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assigned := make(map[int]bool, len(partitions))
+	pending := make(map[int]bool, len(partitions))
+	for _, partition := range partitions {
+		assigned[partition] = true
+		r, ok := ranges[partition]
+		if !ok {
+			// Created after the marks were listed, nothing in it counts for this run
+			continue
+		}
+		if offset, ok := committed[partition]; ok && offset > r.next {
+			r.next = offset
+			ranges[partition] = r
+		}
+		if !r.caughtUp() {
+			pending[partition] = true
+		}
+	}
+	return assigned, pending, nil
+}
+
+// fetchInGeneration fetches the next message, giving up when the generation ends
+func fetchInGeneration(ctx, genCtx context.Context, reader messageReader) (kafka.Message, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(genCtx, cancel)
+	defer stop()
+
+	return reader.FetchMessage(ctx)
+}
+
+// partitionEOF tracks which partitions have been consumed up to their high-water mark
+type partitionEOF struct {
+	mu    sync.Mutex
+	atEnd map[int]bool
+	onEOF func(topic string, partition int, offset int64)
+}
+
+// track records a handled message and fires the callback when its partition's lag reaches zero.
+// The callback fires again only after the partition has fallen behind in between.
+func (e *partitionEOF) track(msg kafka.Message) {
+	if e.onEOF == nil || msg.HighWaterMark <= 0 {
+		return
+	}
+
+	caughtUp := msg.Offset+1 >= msg.HighWaterMark
+
+	e.mu.Lock()
+	if e.atEnd == nil {
+		e.atEnd = make(map[int]bool)
+	}
+	fire := caughtUp && !e.atEnd[msg.Partition]
+	e.atEnd[msg.Partition] = caughtUp
+	e.mu.Unlock()
+
+	if fire {
+		e.onEOF(msg.Topic, msg.Partition, msg.Offset)
+	}
+}
@@ -0,0 +1,363 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOffsetAdmin is an in-memory offsetAdmin. Each partition maps to its high-water mark.
+type fakeOffsetAdmin struct {
+	mu        sync.Mutex
+	marks     map[int]int64
+	committed map[int]int64
+	calls     int
+}
+
+func (f *fakeOffsetAdmin) Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	topic := kafka.Topic{Name: req.Topics[0]}
+	for partition := range f.marks {
+		topic.Partitions = append(topic.Partitions, kafka.Partition{Topic: req.Topics[0], ID: partition})
+	}
+	return &kafka.MetadataResponse{Topics: []kafka.Topic{topic}}, nil
+}
+
+func (f *fakeOffsetAdmin) ListOffsets(ctx context.Context, req *kafka.ListOffsetsRequest) (*kafka.ListOffsetsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &kafka.ListOffsetsResponse{Topics: map[string][]kafka.PartitionOffsets{}}
+	for topic := range req.Topics {
+		for partition, mark := range f.marks {
+			resp.Topics[topic] = append(resp.Topics[topic], kafka.PartitionOffsets{Partition: partition, LastOffset: mark})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeOffsetAdmin) OffsetFetch(ctx context.Context, req *kafka.OffsetFetchRequest) (*kafka.OffsetFetchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &kafka.OffsetFetchResponse{Topics: map[string][]kafka.OffsetFetchPartition{}}
+	for topic, partitions := range req.Topics {
+		for _, partition := range partitions {
+			offset, ok := f.committed[partition]
+			if !ok {
+				offset = -1
+			}
+			resp.Topics[topic] = append(resp.Topics[topic], kafka.OffsetFetchPartition{Partition: partition, CommittedOffset: offset})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeOffsetAdmin) setMark(partition int, mark int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.marks[partition] = mark
+}
+
+// partitionMessages returns messages for offsets [from, to) of a partition
+func partitionMessages(partition int, from, to int64) []kafka.Message {
+	var msgs []kafka.Message
+	for offset := from; offset < to; offset++ {
+		msgs = append(msgs, kafka.Message{Topic: "jobs", Partition: partition, Offset: offset, HighWaterMark: to})
+	}
+	return msgs
+}
+
+// assignedFakeReader is a fakeReader assigned fixed partitions until reassign simulates a rebalance
+type assignedFakeReader struct {
+	*fakeReader
+	mu         sync.Mutex
+	partitions []int
+	genCtx     context.Context
+	end        context.CancelFunc
+}
+
+func newAssignedFakeReader(partitions []int, msgs ...kafka.Message) *assignedFakeReader {
+	r := &assignedFakeReader{fakeReader: newFakeReader(msgs...)}
+	r.reassign(partitions...)
+	return r
+}
+
+func (r *assignedFakeReader) Assignment(ctx context.Context, topic string) ([]int, context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.partitions, r.genCtx, nil
+}
+
+// reassign ends the current generation and starts one with the given partitions
+func (r *assignedFakeReader) reassign(partitions ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.end != nil {
+		r.end()
+	}
+	r.partitions = partitions
+	r.genCtx, r.end = context.WithCancel(context.Background())
+}
+
+// newCaughtUpConsumer creates a consumer assigned every partition the admin knows of
+func newCaughtUpConsumer(admin *fakeOffsetAdmin, msgs ...kafka.Message) (*Consumer, *assignedFakeReader) {
+	var partitions []int
+	for partition := range admin.marks {
+		partitions = append(partitions, partition)
+	}
+
+	config := NewDefaultConfig()
+	config.Topic = "jobs"
+	reader := newAssignedFakeReader(partitions, msgs...)
+	c := newConsumer(config, reader)
+	c.admin = admin
+	return c, reader
+}
+
+func TestConsumeUntilCaughtUp(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 3, 1: 2, 2: 0}}
+
+	// Offset 3 of partition 0 was produced after the marks were captured
+	msgs := append(partitionMessages(0, 0, 3), kafka.Message{Topic: "jobs", Partition: 0, Offset: 3, HighWaterMark: 4})
+	msgs = append(msgs, partitionMessages(1, 0, 2)...)
+	c, reader := newCaughtUpConsumer(admin, msgs...)
+
+	var handled []kafka.Message
+	err := c.ConsumeUntilCaughtUp(context.Background(), func(msg kafka.Message) error {
+		handled = append(handled, msg)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, handled, 5)
+	assert.Equal(t, []int64{0, 1, 2}, reader.committedOffsets(0))
+	assert.Equal(t, []int64{0, 1}, reader.committedOffsets(1))
+}
+
+func TestConsumeUntilCaughtUp_StopsWithoutNewMessages(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 2}}
+	c, reader := newCaughtUpConsumer(admin, partitionMessages(0, 0, 2)...)
+
+	// The reader would block forever on a third message; returning proves no more are required
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count := 0
+	require.NoError(t, c.ConsumeUntilCaughtUp(ctx, func(msg kafka.Message) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 2, count)
+	assert.NoError(t, ctx.Err())
+	assert.Equal(t, []int64{0, 1}, reader.committedOffsets(0))
+}
+
+func TestConsumeUntilCaughtUp_CommittedPartitionsAreSkipped(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 2, 1: 5}, committed: map[int]int64{1: 5}}
+	c, _ := newCaughtUpConsumer(admin, partitionMessages(0, 0, 2)...)
+
+	count := 0
+	require.NoError(t, c.ConsumeUntilCaughtUp(context.Background(), func(msg kafka.Message) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 2, count)
+}
+
+func TestConsumeUntilCaughtUp_RecapturesNewPartitions(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 2}}
+
+	// Partition 1 is created after the run starts and assigned by the rebalance that follows
+	msgs := []kafka.Message{{Topic: "jobs", Partition: 0, Offset: 0, HighWaterMark: 2}}
+	msgs = append(msgs, partitionMessages(1, 0, 2)...)
+	msgs = append(msgs, kafka.Message{Topic: "jobs", Partition: 0, Offset: 1, HighWaterMark: 2})
+	c, reader := newCaughtUpConsumer(admin, msgs...)
+
+	count := 0
+	err := c.ConsumeUntilCaughtUp(context.Background(), func(msg kafka.Message) error {
+		if count == 0 {
+			admin.setMark(1, 2)
+			reader.reassign(0, 1)
+		}
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, count)
+	assert.Equal(t, []int64{0, 1}, reader.committedOffsets(1))
+	// Marks were captured once at the start and once for the new partition
+	assert.Equal(t, 2, admin.calls)
+}
+
+func TestConsumeUntilCaughtUp_DropsRevokedPartitions(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 2, 1: 3}}
+
+	// Offset 1 of partition 1 was fetched just before the partition was revoked
+	msgs := append(partitionMessages(1, 0, 2), partitionMessages(0, 0, 2)...)
+	c, reader := newCaughtUpConsumer(admin, msgs...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var handled []kafka.Message
+	require.NoError(t, c.ConsumeUntilCaughtUp(ctx, func(msg kafka.Message) error {
+		if len(handled) == 0 {
+			reader.reassign(0)
+		}
+		handled = append(handled, msg)
+		return nil
+	}))
+
+	// Partition 1 is left to its new owner instead of being waited for
+	assert.Len(t, handled, 3)
+	assert.Equal(t, []int64{0}, reader.committedOffsets(1))
+	assert.Equal(t, []int64{0, 1}, reader.committedOffsets(0))
+}
+
+func TestConsumeUntilCaughtUp_RereadsCommittedOffsetsOnRebalance(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 2, 1: 4}, committed: map[int]int64{}}
+	c, reader := newCaughtUpConsumer(admin, partitionMessages(0, 0, 2)...)
+	reader.reassign(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count := 0
+	require.NoError(t, c.ConsumeUntilCaughtUp(ctx, func(msg kafka.Message) error {
+		if count == 0 {
+			// Another member finished partition 1 before it was assigned here
+			admin.mu.Lock()
+			admin.committed[1] = 4
+			admin.mu.Unlock()
+			reader.reassign(0, 1)
+		}
+		count++
+		return nil
+	}))
+	assert.Equal(t, 2, count)
+	assert.NoError(t, ctx.Err())
+}
+
+func TestConsumeUntilCaughtUp_TwoMembers(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 3, 1: 2}}
+	log := newFakePartitionLog(map[int]int64{0: 3, 1: 2})
+
+	// Each member of the group is assigned one of the topic's partitions
+	run := func(gen *fakeGeneration) ([]int64, error) {
+		config := NewDefaultConfig()
+		config.Topic = "test-topic"
+		assigner := newFakeAssigner()
+		assigner.gens <- gen
+		c := newConsumer(config, newGenerationReader(assigner, nil, log.open, &captureLogger{}))
+		c.admin = admin
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var handled []int64
+		err := c.ConsumeUntilCaughtUp(ctx, func(msg kafka.Message) error {
+			handled = append(handled, msg.Offset)
+			return nil
+		})
+		return handled, err
+	}
+
+	first, second := newTopicGeneration("test-topic", nil, 0), newTopicGeneration("test-topic", nil, 1)
+	var wg sync.WaitGroup
+	var firstHandled, secondHandled []int64
+	var firstErr, secondErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		firstHandled, firstErr = run(first)
+	}()
+	go func() {
+		defer wg.Done()
+		secondHandled, secondErr = run(second)
+	}()
+	wg.Wait()
+
+	// Neither member waits for the partition owned by the other
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, []int64{0, 1, 2}, firstHandled)
+	assert.Equal(t, []int64{0, 1}, secondHandled)
+	assert.Equal(t, map[int]int64{0: 3}, first.committed())
+	assert.Equal(t, map[int]int64{1: 2}, second.committed())
+}
+
+func TestConsumeUntilCaughtUp_SwitchesToAssignedReader(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 2}}
+	c, _ := newCaughtUpConsumer(admin)
+	c.reader = newFakeReader()
+
+	assigned := newAssignedFakeReader([]int{0}, partitionMessages(0, 0, 2)...)
+	rejoined := newFakeReader()
+	c.openAssigned = func() assignmentReader { return assigned }
+	c.openReader = func() messageReader { return rejoined }
+
+	require.NoError(t, c.ConsumeUntilCaughtUp(context.Background(), func(msg kafka.Message) error {
+		assert.Same(t, assigned, c.reader)
+		return nil
+	}))
+
+	// Offsets were committed through the assigned reader before switching back
+	assert.Equal(t, []int64{0, 1}, assigned.committedOffsets(0))
+	assert.Same(t, rejoined, c.reader)
+}
+
+func TestConsumeUntilCaughtUp_ContextExpires(t *testing.T) {
+	admin := &fakeOffsetAdmin{marks: map[int]int64{0: 5}}
+	c, _ := newCaughtUpConsumer(admin, partitionMessages(0, 0, 2)...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := c.ConsumeUntilCaughtUp(ctx, func(msg kafka.Message) error { return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConsumer_OnPartitionEOF(t *testing.T) {
+	var mu sync.Mutex
+	var eofs []int64
+
+	config := NewDefaultConfig()
+	config.OnPartitionEOF = func(topic string, partition int, offset int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		eofs = append(eofs, offset)
+	}
+
+	msgs := partitionMessages(0, 0, 3)
+	// Steady state: each new message is caught up as soon as it is handled
+	msgs = append(msgs, kafka.Message{Partition: 0, Offset: 3, HighWaterMark: 4})
+	// Falling behind and catching up again fires once more
+	msgs = append(msgs,
+		kafka.Message{Partition: 0, Offset: 4, HighWaterMark: 6},
+		kafka.Message{Partition: 0, Offset: 5, HighWaterMark: 6},
+	)
+	c := newConsumer(config, newFakeReader(msgs...))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := c.Consume(ctx, func(msg kafka.Message) error {
+		count++
+		if count == len(msgs) {
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int64{2, 5}, eofs)
+}
@@ -1,7 +1,11 @@
 package kafka
 
 import (
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/segmentio/kafka-go"
 )
 
 // KafkaConfig holds the configuration for Kafka broker
@@ -17,22 +21,59 @@ type KafkaConfig struct {
 	// Retention configuration
 	RetentionPeriod time.Duration // Retention period in time
 	RetentionSize   int64         // Retention size in bytes
+	CleanupPolicy   string        // "delete", "compact" or "compact,delete" (empty leaves the broker default)
+	ApplyChanges    bool          // Let EnsureTopicConfig alter existing topics instead of only reporting drift
 
 	// Producer configuration
-	MaxRetries        int           // Number of retries for producer
-	RetryBackoff      time.Duration // Backoff time between retries
-	EnableIdempotence bool          // Enable idempotent producer
-	ClientID          string        // Client ID for the producer
-	AsyncProducer     bool          // Enable asynchronous producer mode
+	MaxRetries        int            // Number of retries for producer
+	RetryBackoff      time.Duration  // Backoff time between retries
+	EnableIdempotence bool           // Enable idempotent producer
+	ClientID          string         // Client ID for the producer
+	AsyncProducer     bool           // Enable asynchronous producer mode
+	Balancer          string         // Partitioning strategy, one of the Balancer* constants (default "hash")
+	CustomBalancer    kafka.Balancer // Overrides Balancer when set
+	CloseTimeout      time.Duration  // How long Close waits for pending async writes (default 10s)
 
 	// Consumer configuration
 	GroupID             string        // Consumer group ID
 	AutoCommit          bool          // Auto commit offsets
-	CommitInterval      time.Duration // Commit interval for manual commits
+	CommitInterval      time.Duration // Interval between auto-commits (default 5s when zero)
 	AsyncConsumer       bool          // Enable asynchronous consumer mode
 	ConsumerConcurrency int           // Number of concurrent message processors when in async mode
+	MessageTimeout      time.Duration // Time limit for each handler call in ConsumeAsync (0 disables)
+	DeadLetterTopic     string        // Topic receiving messages that could not be handled (empty disables)
+	HandlerErrorPolicy  ErrorPolicy   // What to do when a handler fails (default ErrorPolicyStop)
+
+	// TrackInFlight makes ConsumeAsync record each fetched message and commit a partition only
+	// up to its oldest message still being handled, instead of whatever has been handled
+	TrackInFlight  bool
+	MaxInFlightAge time.Duration // Warn when a tracked message is in flight longer than this (0 disables)
+
+	// OffsetStore keeps offsets outside Kafka when set. The consumer starts each assigned
+	// partition at its stored offset, reloads offsets after every rebalance and never commits
+	// to Kafka; handlers save offsets, e.g. with SQLOffsetStore in their own transaction.
+	OffsetStore OffsetStore
+
+	// OnPartitionEOF is called when a handled message brings a partition's lag to zero,
+	// and again each time the partition catches up after falling behind
+	OnPartitionEOF func(topic string, partition int, offset int64)
+
+	// OnMessageLatency is called after each successfully handled message with its end-to-end
+	// latency: the time since the message's timestamp. Messages without a timestamp are skipped.
+	OnMessageLatency func(msg kafka.Message, latency time.Duration)
+
+	// Watchdog configuration
+	MaxHandlerDuration  time.Duration // Warn when a handler runs longer than this (0 disables)
+	HardCancelStuck     bool          // Cancel stuck handlers and move past the message
+	ProgressLogInterval time.Duration // Interval between consumer progress logs (0 disables)
+
+	// Logger receives internal diagnostics; defaults to stdout when nil
+	Logger Logger
 }
 
+// ErrInvalidConfig is wrapped by every error returned from KafkaConfig.Validate
+var ErrInvalidConfig = errors.New("invalid kafka config")
+
 // NewDefaultConfig returns a default configuration
 func NewDefaultConfig() *KafkaConfig {
 	return &KafkaConfig{
@@ -53,3 +94,84 @@ func NewDefaultConfig() *KafkaConfig {
 		ConsumerConcurrency: 3,     // Default to 3 workers for async mode
 	}
 }
+
+// Validate checks the configuration for values that would fail later at runtime, returning
+// every problem found. NewProducer and NewConsumer log these errors; NewValidatedProducer,
+// NewValidatedConsumer and CreateTopic return them.
+func (c *KafkaConfig) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	if len(c.Brokers) == 0 {
+		invalid("at least one broker is required")
+	}
+	for i, broker := range c.Brokers {
+		if broker == "" {
+			invalid("broker %d is empty", i)
+		}
+	}
+	if c.Topic == "" {
+		invalid("topic is required")
+	}
+
+	if c.MaxRetries < 0 {
+		invalid("MaxRetries must not be negative, got %d", c.MaxRetries)
+	}
+	if c.RetryBackoff < 0 {
+		invalid("RetryBackoff must not be negative, got %v", c.RetryBackoff)
+	}
+	if c.CloseTimeout < 0 {
+		invalid("CloseTimeout must not be negative, got %v", c.CloseTimeout)
+	}
+	if _, err := newBalancer(c); err != nil {
+		invalid("%v", err)
+	}
+
+	if c.CommitInterval < 0 {
+		invalid("CommitInterval must not be negative, got %v", c.CommitInterval)
+	}
+	if c.AsyncConsumer && c.ConsumerConcurrency < 1 {
+		invalid("ConsumerConcurrency must be positive with AsyncConsumer, got %d", c.ConsumerConcurrency)
+	}
+	switch c.HandlerErrorPolicy {
+	case "", ErrorPolicyStop, ErrorPolicyContinue:
+	case ErrorPolicyDeadLetter:
+		if c.DeadLetterTopic == "" {
+			invalid("HandlerErrorPolicy %q requires DeadLetterTopic", c.HandlerErrorPolicy)
+		}
+	default:
+		invalid("unknown HandlerErrorPolicy %q", c.HandlerErrorPolicy)
+	}
+	if c.MessageTimeout < 0 {
+		invalid("MessageTimeout must not be negative, got %v", c.MessageTimeout)
+	}
+	if c.MaxInFlightAge < 0 {
+		invalid("MaxInFlightAge must not be negative, got %v", c.MaxInFlightAge)
+	}
+	if c.MaxInFlightAge > 0 && !c.TrackInFlight {
+		invalid("MaxInFlightAge requires TrackInFlight")
+	}
+	if c.MaxHandlerDuration < 0 {
+		invalid("MaxHandlerDuration must not be negative, got %v", c.MaxHandlerDuration)
+	}
+	if c.HardCancelStuck && c.MaxHandlerDuration == 0 {
+		invalid("HardCancelStuck requires MaxHandlerDuration")
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateTopic checks the settings only used to create a topic, which producers and consumers
+// of an existing topic may leave unset
+func (c *KafkaConfig) validateTopic() error {
+	var errs []error
+	if c.NumPartitions < 1 {
+		errs = append(errs, fmt.Errorf("%w: NumPartitions must be positive, got %d", ErrInvalidConfig, c.NumPartitions))
+	}
+	if c.ReplicationFactor < 1 {
+		errs = append(errs, fmt.Errorf("%w: ReplicationFactor must be positive, got %d", ErrInvalidConfig, c.ReplicationFactor))
+	}
+	return errors.Join(errs...)
+}
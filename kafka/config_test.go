@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() *KafkaConfig {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	return config
+}
+
+func TestValidate_DefaultConfigWithTopic(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+}
+
+func TestValidate_MissingBrokers(t *testing.T) {
+	config := validConfig()
+	config.Brokers = nil
+	err := config.Validate()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, "at least one broker is required")
+
+	config.Brokers = []string{"localhost:9092", ""}
+	assert.ErrorContains(t, config.Validate(), "broker 1 is empty")
+}
+
+func TestValidate_EmptyTopic(t *testing.T) {
+	config := validConfig()
+	config.Topic = ""
+	err := config.Validate()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, "topic is required")
+}
+
+func TestValidate_CommitInterval(t *testing.T) {
+	config := validConfig()
+	config.AutoCommit = true
+	config.CommitInterval = 0
+	assert.NoError(t, config.Validate(), "auto-commit falls back to the default interval")
+
+	config.CommitInterval = -time.Second
+	err := config.Validate()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, "CommitInterval must not be negative, got -1s")
+}
+
+func TestValidate_InFlightTracking(t *testing.T) {
+	config := validConfig()
+	config.MaxInFlightAge = time.Minute
+	assert.ErrorContains(t, config.Validate(), "MaxInFlightAge requires TrackInFlight")
+
+	config.TrackInFlight = true
+	assert.NoError(t, config.Validate())
+
+	config.MaxInFlightAge = -time.Minute
+	assert.ErrorContains(t, config.Validate(), "MaxInFlightAge must not be negative, got -1m0s")
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	config := &KafkaConfig{
+		AsyncConsumer:      true,
+		Balancer:           "random",
+		HandlerErrorPolicy: ErrorPolicyDeadLetter,
+		HardCancelStuck:    true,
+		MessageTimeout:     -time.Second,
+		RetryBackoff:       -time.Second,
+	}
+	err := config.Validate()
+	require.Error(t, err)
+
+	for _, problem := range []string{
+		"at least one broker is required",
+		"topic is required",
+		"RetryBackoff must not be negative, got -1s",
+		`unknown balancer "random"`,
+		"ConsumerConcurrency must be positive with AsyncConsumer, got 0",
+		`HandlerErrorPolicy "dead-letter" requires DeadLetterTopic`,
+		"MessageTimeout must not be negative, got -1s",
+		"HardCancelStuck requires MaxHandlerDuration",
+	} {
+		assert.ErrorContains(t, err, problem)
+	}
+
+	assert.NotContains(t, err.Error(), "NumPartitions", "partitions only matter when creating a topic")
+
+	config.HandlerErrorPolicy = "retry"
+	assert.ErrorContains(t, config.Validate(), `unknown HandlerErrorPolicy "retry"`)
+}
+
+func TestCreateTopic_RejectsInvalidConfig(t *testing.T) {
+	config := validConfig()
+	config.Brokers = nil
+
+	// Without validation this would index the empty broker list
+	err := CreateTopic(context.Background(), config)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	// The topic settings are checked along with the rest
+	config.NumPartitions = 0
+	config.ReplicationFactor = 0
+	err = CreateTopic(context.Background(), config)
+	assert.ErrorContains(t, err, "at least one broker is required")
+	assert.ErrorContains(t, err, "NumPartitions must be positive, got 0")
+	assert.ErrorContains(t, err, "ReplicationFactor must be positive, got 0")
+}
+
+func TestNewValidated_ReturnsConfigError(t *testing.T) {
+	config := validConfig()
+	config.Topic = ""
+
+	consumer, err := NewValidatedConsumer(config)
+	assert.Nil(t, consumer)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	producer, err := NewValidatedProducer(config)
+	assert.Nil(t, producer)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
@@ -2,20 +2,63 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
+// defaultCommitInterval is used for auto-commit when CommitInterval is not set
+const defaultCommitInterval = 5 * time.Second
+
+// ErrMessageTimeout is returned when a handler in ConsumeAsync exceeds MessageTimeout
+var ErrMessageTimeout = errors.New("message handler timed out")
+
+// ErrHandlerStuck is returned when the watchdog abandons a handler that exceeded MaxHandlerDuration
+var ErrHandlerStuck = errors.New("message handler exceeded max duration")
+
+// ErrorPolicy decides what the consumer does when a handler returns an error
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyStop makes Consume return the error, leaving the message uncommitted.
+	// ConsumeAsync logs the error and moves on without committing the message.
+	ErrorPolicyStop ErrorPolicy = "stop"
+	// ErrorPolicyContinue logs the error and commits the message
+	ErrorPolicyContinue ErrorPolicy = "continue"
+	// ErrorPolicyDeadLetter writes the message to DeadLetterTopic and commits it. If the
+	// write fails the message is handled as with ErrorPolicyStop.
+	ErrorPolicyDeadLetter ErrorPolicy = "dead-letter"
+)
+
 // MessageHandler is a function that processes a Kafka message
 type MessageHandler func(msg kafka.Message) error
 
+// ContextMessageHandler is a MessageHandler that receives a per-message context.
+// The context is canceled when the watchdog abandons the handler.
+type ContextMessageHandler func(ctx context.Context, msg kafka.Message) error
+
+// messageReader is the subset of kafka.Reader used by the consumer
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 // Consumer represents a Kafka consumer
 type Consumer struct {
-	reader        *kafka.Reader
+	reader        messageReader
+	openReader    func() messageReader    // Replaces the reader after a group seek
+	openAssigned  func() assignmentReader // Follows the group's assignment while catching up
+	admin         offsetAdmin
 	config        *KafkaConfig
+	logger        Logger
+	deadLetter    messageWriter
 	commitMutex   sync.Mutex
 	uncommitted   []kafka.Message
 	lastCommit    time.Time
@@ -25,12 +68,65 @@ type Consumer struct {
 	stopConsume   chan struct{}
 	isConsuming   bool
 	consumeWg     sync.WaitGroup
+	seekMu        sync.Mutex // Serializes seeks with consume calls starting and stopping
+	active        int        // Synchronous consume calls in progress, guarded by seekMu
+	eof           partitionEOF
+
+	// Progress tracking
+	handled       int64 // Number of messages handled
+	lastProgress  int64 // Unix nanoseconds of the last handled message
+	lastCommitted map[int]int64
+	stopProgress  chan struct{}
+	progressWg    sync.WaitGroup
+	latency       latencyTracker
+	inFlight      inFlightTracker
+}
+
+// NewValidatedConsumer creates a new Kafka consumer like NewConsumer, but returns the
+// config's validation error instead of logging it
+func NewValidatedConsumer(config *KafkaConfig) (*Consumer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return openConsumer(config), nil
 }
 
-// NewConsumer creates a new Kafka consumer with the given configuration
+// NewConsumer creates a new Kafka consumer with the given configuration.
+// An invalid config is logged; use NewValidatedConsumer to get the error instead.
 func NewConsumer(config *KafkaConfig) *Consumer {
-	// Configure the reader
-	reader := kafka.NewReader(kafka.ReaderConfig{
+	if err := config.Validate(); err != nil {
+		loggerFor(config).Error("consumer created with invalid config", "error", err)
+	}
+	return openConsumer(config)
+}
+
+// openConsumer creates a consumer connected to the configured brokers
+func openConsumer(config *KafkaConfig) *Consumer {
+	consumer := newConsumer(config, newReader(config))
+	consumer.admin = &kafka.Client{Addr: kafka.TCP(config.Brokers...)}
+	consumer.openReader = func() messageReader { return newReader(config) }
+	consumer.openAssigned = func() assignmentReader { return newGroupReader(config) }
+
+	// Route unhandled messages to the dead-letter topic if configured
+	if config.DeadLetterTopic != "" {
+		consumer.deadLetter = &kafka.Writer{
+			Addr:         kafka.TCP(config.Brokers...),
+			Topic:        config.DeadLetterTopic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		}
+	}
+
+	return consumer
+}
+
+// newReader configures the reader for the consumer
+func newReader(config *KafkaConfig) messageReader {
+	if config.OffsetStore != nil {
+		// Partitions are assigned by the group but read from the stored offsets
+		return newGroupReader(config)
+	}
+	return kafka.NewReader(kafka.ReaderConfig{
 		Brokers:     config.Brokers,
 		Topic:       config.Topic,
 		GroupID:     config.GroupID,
@@ -40,16 +136,25 @@ func NewConsumer(config *KafkaConfig) *Consumer {
 		// Disable auto commit, we'll handle it manually
 		CommitInterval: 0,
 	})
+}
 
+// newConsumer creates a consumer around the given reader and starts its background loops
+func newConsumer(config *KafkaConfig, reader messageReader) *Consumer {
+	now := time.Now()
 	consumer := &Consumer{
 		reader:        reader,
 		config:        config,
+		logger:        loggerFor(config),
 		uncommitted:   make([]kafka.Message, 0),
-		lastCommit:    time.Now(),
+		lastCommit:    now,
 		stopCommit:    make(chan struct{}),
 		stopConsume:   make(chan struct{}),
 		isConsuming:   false,
 		autoCommitter: config.AutoCommit,
+		lastProgress:  now.UnixNano(),
+		lastCommitted: make(map[int]int64),
+		stopProgress:  make(chan struct{}),
+		eof:           partitionEOF{onEOF: config.OnPartitionEOF},
 	}
 
 	// Start auto-commit goroutine if enabled
@@ -58,19 +163,35 @@ func NewConsumer(config *KafkaConfig) *Consumer {
 		go consumer.autoCommitLoop()
 	}
 
+	// Start progress logging if enabled
+	if config.ProgressLogInterval > 0 {
+		consumer.progressWg.Add(1)
+		go consumer.progressLoop()
+	}
+
 	return consumer
 }
 
+// commitInterval returns the auto-commit interval, falling back to the default when unset
+func (c *Consumer) commitInterval() time.Duration {
+	if c.config.CommitInterval <= 0 {
+		return defaultCommitInterval
+	}
+	return c.config.CommitInterval
+}
+
 // autoCommitLoop periodically commits offsets if auto-commit is enabled
 func (c *Consumer) autoCommitLoop() {
 	defer c.commitWg.Done()
-	ticker := time.NewTicker(c.config.CommitInterval)
+	ticker := time.NewTicker(c.commitInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			c.commitOffsets(context.Background())
+			if err := c.commitOffsets(context.Background()); err != nil {
+				c.logger.Error("failed to auto-commit offsets", "topic", c.config.Topic, "group", c.config.GroupID, "error", err)
+			}
 		case <-c.stopCommit:
 			return
 		}
@@ -80,15 +201,30 @@ func (c *Consumer) autoCommitLoop() {
 // ConsumeAsync starts consuming messages asynchronously
 // The provided handler will be called for each message in a separate goroutine
 func (c *Consumer) ConsumeAsync(ctx context.Context, handler MessageHandler, concurrency int) error {
+	return c.ConsumeAsyncContext(ctx, withContext(handler), concurrency)
+}
+
+// ConsumeAsyncContext is like ConsumeAsync but passes a per-message context to the handler
+func (c *Consumer) ConsumeAsyncContext(ctx context.Context, handler ContextMessageHandler, concurrency int) error {
+	c.seekMu.Lock()
 	if c.isConsuming {
+		c.seekMu.Unlock()
 		return fmt.Errorf("consumer is already consuming messages")
 	}
 
 	c.isConsuming = true
 	c.stopConsume = make(chan struct{})
+	c.seekMu.Unlock()
 
 	// Create a channel to pass messages to workers
 	messageChan := make(chan kafka.Message, concurrency)
+	handler = c.withTimeout(handler)
+
+	// Warn about messages holding back commits
+	if c.config.TrackInFlight && c.config.MaxInFlightAge > 0 {
+		c.consumeWg.Add(1)
+		go c.ageLoop(ctx, c.stopConsume)
+	}
 
 	// Start worker goroutines
 	for i := 0; i < concurrency; i++ {
@@ -103,20 +239,43 @@ func (c *Consumer) ConsumeAsync(ctx context.Context, handler MessageHandler, con
 					}
 
 					// Process message with handler
-					if err := handler(msg); err != nil {
-						fmt.Printf("Error handling message: %v\n", err)
-						continue
+					if err := c.handle(ctx, handler, msg); err != nil {
+						if !c.skipFailed(ctx, msg, err) {
+							c.logger.Error("message handler failed",
+								"topic", msg.Topic,
+								"partition", msg.Partition,
+								"offset", msg.Offset,
+								"error", err,
+							)
+							// The partition isn't committed past a failed message
+							if c.config.TrackInFlight {
+								c.inFlight.fail(msg)
+							}
+							continue
+						}
 					}
 
-					// Add to uncommitted messages
+					// Add to uncommitted messages, or with in-flight tracking the partition's
+					// new low-water mark if this message advanced it
 					c.commitMutex.Lock()
-					c.uncommitted = append(c.uncommitted, msg)
+					if c.config.TrackInFlight {
+						if low, ok := c.inFlight.complete(msg); ok {
+							c.uncommitted = append(c.uncommitted, low)
+						}
+					} else {
+						c.uncommitted = append(c.uncommitted, msg)
+					}
 					c.commitMutex.Unlock()
 
 					// If not using auto-commit, commit immediately
 					if !c.autoCommitter {
 						if err := c.commitOffsets(context.Background()); err != nil {
-							fmt.Printf("Error committing offsets: %v\n", err)
+							c.logger.Error("failed to commit offsets",
+								"topic", msg.Topic,
+								"partition", msg.Partition,
+								"offset", msg.Offset,
+								"error", err,
+							)
 						}
 					}
 				case <-c.stopConsume:
@@ -143,12 +302,15 @@ func (c *Consumer) ConsumeAsync(ctx context.Context, handler MessageHandler, con
 				msg, err := c.reader.FetchMessage(ctx)
 				if err != nil {
 					if ctx.Err() == nil {
-						fmt.Printf("Error fetching message: %v\n", err)
+						c.logger.Error("failed to fetch message", "topic", c.config.Topic, "group", c.config.GroupID, "error", err)
 					}
 					// Backoff a bit on errors
 					time.Sleep(100 * time.Millisecond)
 					continue
 				}
+				if c.config.TrackInFlight {
+					c.inFlight.add(msg, time.Now())
+				}
 
 				// Send message to workers
 				select {
@@ -168,17 +330,35 @@ func (c *Consumer) ConsumeAsync(ctx context.Context, handler MessageHandler, con
 
 // StopConsumeAsync stops the asynchronous consumption of messages
 func (c *Consumer) StopConsumeAsync() {
+	c.seekMu.Lock()
 	if !c.isConsuming {
+		c.seekMu.Unlock()
 		return
 	}
+	// A concurrent call may already have signalled the workers
+	select {
+	case <-c.stopConsume:
+	default:
+		close(c.stopConsume)
+	}
+	c.seekMu.Unlock()
 
-	close(c.stopConsume)
+	// The lock isn't held while waiting, so handlers can still reach the consumer
 	c.consumeWg.Wait()
+	c.seekMu.Lock()
 	c.isConsuming = false
+	c.seekMu.Unlock()
 }
 
 // Consume reads and processes messages from Kafka synchronously
 func (c *Consumer) Consume(ctx context.Context, handler MessageHandler) error {
+	return c.ConsumeContext(ctx, withContext(handler))
+}
+
+// ConsumeContext is like Consume but passes a per-message context to the handler
+func (c *Consumer) ConsumeContext(ctx context.Context, handler ContextMessageHandler) error {
+	defer c.enter()()
+
 	for {
 		// Check if context is done
 		select {
@@ -195,8 +375,8 @@ func (c *Consumer) Consume(ctx context.Context, handler MessageHandler) error {
 		}
 
 		// Process message with handler
-		err = handler(msg)
-		if err != nil {
+		err = c.handle(ctx, handler, msg)
+		if err != nil && !c.skipFailed(ctx, msg, err) {
 			return fmt.Errorf("error handling message: %w", err)
 		}
 
@@ -229,6 +409,13 @@ func (c *Consumer) commitOffsets(ctx context.Context) error {
 		return err
 	}
 
+	// Record the highest committed offset per partition
+	for _, msg := range c.uncommitted {
+		if offset, ok := c.lastCommitted[msg.Partition]; !ok || msg.Offset > offset {
+			c.lastCommitted[msg.Partition] = msg.Offset
+		}
+	}
+
 	// Clear uncommitted messages and update last commit time
 	c.uncommitted = make([]kafka.Message, 0)
 	c.lastCommit = time.Now()
@@ -238,9 +425,7 @@ func (c *Consumer) commitOffsets(ctx context.Context) error {
 // Close stops the consumer and commits any remaining offsets
 func (c *Consumer) Close() error {
 	// Stop async consumption if running
-	if c.isConsuming {
-		c.StopConsumeAsync()
-	}
+	c.StopConsumeAsync()
 
 	// Stop auto-commit goroutine if running
 	if c.autoCommitter {
@@ -248,11 +433,248 @@ func (c *Consumer) Close() error {
 		c.commitWg.Wait()
 	}
 
+	// Stop progress logging if running
+	if c.config.ProgressLogInterval > 0 {
+		close(c.stopProgress)
+		c.progressWg.Wait()
+	}
+
 	// Commit any remaining offsets
 	if err := c.commitOffsets(context.Background()); err != nil {
 		return fmt.Errorf("error committing final offsets: %w", err)
 	}
 
+	// Close the dead-letter writer
+	if c.deadLetter != nil {
+		if err := c.deadLetter.Close(); err != nil {
+			return fmt.Errorf("error closing dead-letter writer: %w", err)
+		}
+	}
+
 	// Close the reader
 	return c.reader.Close()
 }
+
+// withContext adapts a MessageHandler to a ContextMessageHandler
+func withContext(handler MessageHandler) ContextMessageHandler {
+	return func(_ context.Context, msg kafka.Message) error {
+		return handler(msg)
+	}
+}
+
+// withTimeout limits each handler call to MessageTimeout. The handler runs on its own goroutine
+// so one that ignores its context can't hold the worker; after the timeout it is left running
+// and ErrMessageTimeout is returned, which the error policy handles like any handler error.
+func (c *Consumer) withTimeout(handler ContextMessageHandler) ContextMessageHandler {
+	timeout := c.config.MessageTimeout
+	if timeout <= 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, msg kafka.Message) error {
+		msgCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- handler(msgCtx, msg)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-msgCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w after %v: topic=%s partition=%d offset=%d", ErrMessageTimeout, timeout, msg.Topic, msg.Partition, msg.Offset)
+		}
+	}
+}
+
+// handle runs the handler for a single message under the watchdog and records progress
+func (c *Consumer) handle(ctx context.Context, handler ContextMessageHandler, msg kafka.Message) error {
+	err := c.watch(ctx, handler, msg)
+	if err == nil {
+		now := time.Now()
+		atomic.AddInt64(&c.handled, 1)
+		atomic.StoreInt64(&c.lastProgress, now.UnixNano())
+		c.eof.track(msg)
+		c.recordLatency(msg, now)
+	}
+	return err
+}
+
+// watch runs the handler and reports it if it exceeds MaxHandlerDuration.
+// In hard-cancel mode the handler's context is canceled and ErrHandlerStuck is returned.
+func (c *Consumer) watch(ctx context.Context, handler ContextMessageHandler, msg kafka.Message) error {
+	if c.config.MaxHandlerDuration <= 0 {
+		return handler(ctx, msg)
+	}
+
+	handlerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(handlerCtx, msg)
+	}()
+
+	timer := time.NewTimer(c.config.MaxHandlerDuration)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+	}
+
+	// The handler is stuck, dump goroutines so the blocking call can be found
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	c.logger.Warn("message handler exceeded max duration",
+		"topic", msg.Topic,
+		"partition", msg.Partition,
+		"offset", msg.Offset,
+		"elapsed", time.Since(start),
+		"goroutines", string(buf),
+	)
+
+	if c.config.HardCancelStuck {
+		cancel()
+		return fmt.Errorf("%w: topic=%s partition=%d offset=%d", ErrHandlerStuck, msg.Topic, msg.Partition, msg.Offset)
+	}
+
+	return <-done
+}
+
+// skipFailed applies the error policy to a message whose handler failed, and abandons
+// messages canceled by the watchdog. It reports whether the message should be committed
+// and consumption continue.
+func (c *Consumer) skipFailed(ctx context.Context, msg kafka.Message, err error) bool {
+	if errors.Is(err, ErrHandlerStuck) {
+		return c.abandon(ctx, msg, err)
+	}
+
+	switch c.config.HandlerErrorPolicy {
+	case ErrorPolicyContinue:
+		c.logger.Error("message handler failed, skipping message",
+			"topic", msg.Topic,
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+			"error", err,
+		)
+		return true
+	case ErrorPolicyDeadLetter:
+		if c.deadLetter == nil {
+			c.logger.Error("dead-letter error policy requires DeadLetterTopic",
+				"topic", msg.Topic,
+				"partition", msg.Partition,
+				"offset", msg.Offset,
+			)
+			return false
+		}
+		if !c.writeDeadLetter(ctx, msg, err) {
+			return false
+		}
+		c.logger.Warn("dead-lettered failed message",
+			"topic", msg.Topic,
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+			"error", err,
+		)
+		return true
+	default:
+		return false
+	}
+}
+
+// abandon moves past a message whose handler was canceled by the watchdog,
+// writing it to the dead-letter topic if one is configured. It reports whether
+// the message should be committed.
+func (c *Consumer) abandon(ctx context.Context, msg kafka.Message, err error) bool {
+	if c.deadLetter != nil && !c.writeDeadLetter(ctx, msg, err) {
+		return false
+	}
+
+	c.logger.Warn("abandoned stuck message",
+		"topic", msg.Topic,
+		"partition", msg.Partition,
+		"offset", msg.Offset,
+	)
+	return true
+}
+
+// writeDeadLetter writes a failed message to the dead-letter topic and reports whether it succeeded
+func (c *Consumer) writeDeadLetter(ctx context.Context, msg kafka.Message, err error) bool {
+	if dlqErr := c.deadLetter.WriteMessages(ctx, deadLetterMessage(msg, err)); dlqErr != nil {
+		c.logger.Error("failed to write message to dead-letter topic",
+			"topic", msg.Topic,
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+			"error", dlqErr,
+		)
+		return false
+	}
+	return true
+}
+
+// deadLetterMessage copies a message for the dead-letter topic, recording its origin in headers
+func deadLetterMessage(msg kafka.Message, err error) kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers)+4)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: "x-original-topic", Value: []byte(msg.Topic)},
+		kafka.Header{Key: "x-original-partition", Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: "x-original-offset", Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: "x-error", Value: []byte(err.Error())},
+	)
+
+	return kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+		Time:    time.Now(),
+	}
+}
+
+// LastProgress returns the time the consumer last handled a message successfully,
+// or the time it was created if no message has been handled yet
+func (c *Consumer) LastProgress() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.lastProgress))
+}
+
+// progressLoop periodically logs consumer progress
+func (c *Consumer) progressLoop() {
+	defer c.progressWg.Done()
+	ticker := time.NewTicker(c.config.ProgressLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.logProgress()
+		case <-c.stopProgress:
+			return
+		}
+	}
+}
+
+// logProgress logs the number of handled messages, committed offsets and idle time
+func (c *Consumer) logProgress() {
+	c.commitMutex.Lock()
+	committed := make(map[int]int64, len(c.lastCommitted))
+	for partition, offset := range c.lastCommitted {
+		committed[partition] = offset
+	}
+	c.commitMutex.Unlock()
+
+	c.logger.Info("consumer progress",
+		"topic", c.config.Topic,
+		"group", c.config.GroupID,
+		"handled", atomic.LoadInt64(&c.handled),
+		"committed_offsets", committed,
+		"since_last_message", time.Since(c.LastProgress()),
+	)
+}
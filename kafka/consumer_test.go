@@ -0,0 +1,545 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReader implements messageReader over an in-memory channel
+type fakeReader struct {
+	msgs      chan kafka.Message
+	mu        sync.Mutex
+	committed []kafka.Message
+}
+
+func newFakeReader(msgs ...kafka.Message) *fakeReader {
+	r := &fakeReader{msgs: make(chan kafka.Message, 1024)}
+	for _, msg := range msgs {
+		r.msgs <- msg
+	}
+	return r
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-r.msgs:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Close() error {
+	return nil
+}
+
+func (r *fakeReader) committedOffsets(partition int) []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var offsets []int64
+	for _, msg := range r.committed {
+		if msg.Partition == partition {
+			offsets = append(offsets, msg.Offset)
+		}
+	}
+	return offsets
+}
+
+// fakeWriter implements messageWriter by recording written messages
+type fakeWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	err      error
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+func (w *fakeWriter) written() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]kafka.Message(nil), w.messages...)
+}
+
+// logEntry is a single entry recorded by captureLogger
+type logEntry struct {
+	level   string
+	message string
+	fields  map[string]interface{}
+}
+
+// captureLogger implements Logger by recording entries
+type captureLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *captureLogger) Info(message string, args ...interface{})  { l.add("INFO", message, args) }
+func (l *captureLogger) Warn(message string, args ...interface{})  { l.add("WARN", message, args) }
+func (l *captureLogger) Error(message string, args ...interface{}) { l.add("ERROR", message, args) }
+
+func (l *captureLogger) add(level, message string, args []interface{}) {
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[fmt.Sprint(args[i])] = args[i+1]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, logEntry{level: level, message: message, fields: fields})
+}
+
+func (l *captureLogger) find(message string) []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var found []logEntry
+	for _, e := range l.entries {
+		if e.message == message {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func testMessage(partition int, offset int64) kafka.Message {
+	return kafka.Message{
+		Topic:     "test-topic",
+		Partition: partition,
+		Offset:    offset,
+		Key:       []byte(fmt.Sprintf("key-%d-%d", partition, offset)),
+		Value:     []byte("value"),
+	}
+}
+
+func TestConsumer_WatchdogWarnsOnStuckHandler(t *testing.T) {
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.MaxHandlerDuration = 50 * time.Millisecond
+	config.Logger = logger
+
+	reader := newFakeReader(testMessage(0, 7))
+	c := newConsumer(config, reader)
+
+	release := make(chan struct{})
+	handler := func(msg kafka.Message) error {
+		<-release
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.ConsumeAsync(ctx, handler, 1))
+
+	require.Eventually(t, func() bool {
+		return len(logger.find("message handler exceeded max duration")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	entry := logger.find("message handler exceeded max duration")[0]
+	assert.Equal(t, "WARN", entry.level)
+	assert.Equal(t, "test-topic", entry.fields["topic"])
+	assert.Equal(t, 0, entry.fields["partition"])
+	assert.Equal(t, int64(7), entry.fields["offset"])
+	assert.GreaterOrEqual(t, entry.fields["elapsed"].(time.Duration), config.MaxHandlerDuration)
+	assert.Contains(t, entry.fields["goroutines"], "goroutine")
+
+	// Without hard-cancel the handler keeps running and the message is committed once it returns
+	assert.Empty(t, reader.committedOffsets(0))
+	close(release)
+	require.Eventually(t, func() bool {
+		return len(reader.committedOffsets(0)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, c.Close())
+}
+
+func TestConsumer_HardCancelCommitsPastStuckMessage(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.MaxHandlerDuration = 50 * time.Millisecond
+	config.HardCancelStuck = true
+	config.Logger = &captureLogger{}
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1))
+	c := newConsumer(config, reader)
+	dlq := &fakeWriter{}
+	c.deadLetter = dlq
+
+	handlerCanceled := make(chan struct{})
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		if msg.Offset == 0 {
+			// Block forever until the watchdog cancels us
+			<-ctx.Done()
+			close(handlerCanceled)
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.ConsumeContext(ctx, handler)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(reader.committedOffsets(0)) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int64{0, 1}, reader.committedOffsets(0))
+
+	// The stuck handler's context was canceled and the message dead-lettered
+	select {
+	case <-handlerCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("stuck handler context was not canceled")
+	}
+	written := dlq.written()
+	require.Len(t, written, 1)
+	assert.Equal(t, []byte("key-0-0"), written[0].Key)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	require.NoError(t, c.Close())
+}
+
+func TestConsumer_HardCancelWithoutDeadLetterFailureStops(t *testing.T) {
+	config := NewDefaultConfig()
+	config.MaxHandlerDuration = 20 * time.Millisecond
+	config.HardCancelStuck = true
+	config.Logger = &captureLogger{}
+
+	reader := newFakeReader(testMessage(0, 0))
+	c := newConsumer(config, reader)
+	c.deadLetter = &fakeWriter{err: fmt.Errorf("broker unavailable")}
+
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err := c.ConsumeContext(context.Background(), handler)
+	assert.ErrorIs(t, err, ErrHandlerStuck)
+	assert.Empty(t, reader.committedOffsets(0))
+}
+
+func TestConsumer_ProgressUpdatesForHealthyPartitions(t *testing.T) {
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.MaxHandlerDuration = 20 * time.Millisecond
+	config.ProgressLogInterval = 20 * time.Millisecond
+	config.Logger = logger
+
+	reader := newFakeReader(testMessage(0, 0))
+	c := newConsumer(config, reader)
+
+	release := make(chan struct{})
+	handler := func(msg kafka.Message) error {
+		if msg.Partition == 0 {
+			<-release
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.ConsumeAsync(ctx, handler, 2))
+
+	start := c.LastProgress()
+	for i := int64(0); i < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+		reader.msgs <- testMessage(1, i)
+	}
+
+	require.Eventually(t, func() bool {
+		return len(reader.committedOffsets(1)) == 5
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, c.LastProgress().After(start))
+
+	require.Eventually(t, func() bool {
+		for _, e := range logger.find("consumer progress") {
+			committed := e.fields["committed_offsets"].(map[int]int64)
+			if committed[1] == 4 && e.fields["handled"] == int64(5) {
+				return true
+			}
+		}
+		return false
+	}, 2*time.Second, 10*time.Millisecond)
+
+	close(release)
+	cancel()
+	require.NoError(t, c.Close())
+}
+
+// failingHandler fails on the given offsets
+func failingHandler(offsets ...int64) MessageHandler {
+	return func(msg kafka.Message) error {
+		for _, offset := range offsets {
+			if msg.Offset == offset {
+				return fmt.Errorf("cannot process offset %d", offset)
+			}
+		}
+		return nil
+	}
+}
+
+// consumeAll runs Consume until the message at lastOffset is committed or Consume returns
+func consumeAll(t *testing.T, c *Consumer, reader *fakeReader, handler MessageHandler, lastOffset int64) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(ctx, handler)
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Millisecond):
+			committed := reader.committedOffsets(0)
+			if len(committed) > 0 && committed[len(committed)-1] == lastOffset {
+				cancel()
+			}
+		}
+	}
+}
+
+func TestConsumer_AutoCommitDefaultsZeroInterval(t *testing.T) {
+	config := NewDefaultConfig()
+	config.AutoCommit = true
+	config.CommitInterval = 0
+
+	// time.NewTicker(0) would panic in the auto-commit loop
+	var c *Consumer
+	require.NotPanics(t, func() { c = newConsumer(config, newFakeReader()) })
+	defer c.Close()
+	assert.Equal(t, 5*time.Second, c.commitInterval())
+
+	// Changing the config under the running loop would race, so check a set interval separately
+	configured := NewDefaultConfig()
+	configured.CommitInterval = time.Second
+	assert.Equal(t, time.Second, (&Consumer{config: configured}).commitInterval())
+}
+
+func TestConsumer_ErrorPolicyStop(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.Logger = &captureLogger{}
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1), testMessage(0, 2))
+	c := newConsumer(config, reader)
+
+	err := consumeAll(t, c, reader, failingHandler(1), 2)
+	assert.ErrorContains(t, err, "cannot process offset 1")
+	assert.Equal(t, []int64{0}, reader.committedOffsets(0))
+}
+
+func TestConsumer_ErrorPolicyContinue(t *testing.T) {
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.HandlerErrorPolicy = ErrorPolicyContinue
+	config.Logger = logger
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1), testMessage(0, 2))
+	c := newConsumer(config, reader)
+
+	err := consumeAll(t, c, reader, failingHandler(1), 2)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []int64{0, 1, 2}, reader.committedOffsets(0))
+	assert.Len(t, logger.find("message handler failed, skipping message"), 1)
+}
+
+func TestConsumer_ErrorPolicyDeadLetter(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.HandlerErrorPolicy = ErrorPolicyDeadLetter
+	config.Logger = &captureLogger{}
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1), testMessage(0, 2))
+	c := newConsumer(config, reader)
+	dlq := &fakeWriter{}
+	c.deadLetter = dlq
+
+	err := consumeAll(t, c, reader, failingHandler(0, 2), 2)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []int64{0, 1, 2}, reader.committedOffsets(0))
+
+	written := dlq.written()
+	require.Len(t, written, 2)
+	assert.Equal(t, []byte("key-0-0"), written[0].Key)
+	assert.Equal(t, []byte("key-0-2"), written[1].Key)
+}
+
+func TestConsumer_ErrorPolicyDeadLetterFailureStops(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.HandlerErrorPolicy = ErrorPolicyDeadLetter
+	config.Logger = &captureLogger{}
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1))
+	c := newConsumer(config, reader)
+	c.deadLetter = &fakeWriter{err: fmt.Errorf("broker unavailable")}
+
+	err := consumeAll(t, c, reader, failingHandler(0), 1)
+	assert.ErrorContains(t, err, "cannot process offset 0")
+	assert.Empty(t, reader.committedOffsets(0))
+}
+
+func TestConsumer_ErrorPolicyAsync(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.HandlerErrorPolicy = ErrorPolicyContinue
+	config.Logger = &captureLogger{}
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1), testMessage(0, 2))
+	c := newConsumer(config, reader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, c.ConsumeAsync(ctx, failingHandler(1), 1))
+	defer func() {
+		cancel()
+		c.StopConsumeAsync()
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(reader.committedOffsets(0)) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []int64{0, 1, 2}, reader.committedOffsets(0))
+}
+
+func TestConsumer_AsyncHandlerErrorIsLogged(t *testing.T) {
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.Logger = logger
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1))
+	c := newConsumer(config, reader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, c.ConsumeAsync(ctx, failingHandler(1), 1))
+	defer func() {
+		cancel()
+		c.StopConsumeAsync()
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(logger.find("message handler failed")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	entry := logger.find("message handler failed")[0]
+	assert.Equal(t, "test-topic", entry.fields["topic"])
+	assert.Equal(t, 0, entry.fields["partition"])
+	assert.Equal(t, int64(1), entry.fields["offset"])
+	assert.Error(t, entry.fields["error"].(error))
+}
+
+func TestConsumer_AsyncMessageTimeout(t *testing.T) {
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.HandlerErrorPolicy = ErrorPolicyContinue
+	config.MessageTimeout = 50 * time.Millisecond
+	config.Logger = logger
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1), testMessage(0, 2))
+	c := newConsumer(config, reader)
+
+	// The handler ignores its context and never returns for offset 0
+	block := make(chan struct{})
+	defer close(block)
+	var handled sync.Map
+	handler := func(msg kafka.Message) error {
+		if msg.Offset == 0 {
+			<-block
+		}
+		handled.Store(msg.Offset, true)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, c.ConsumeAsync(ctx, handler, 1))
+	defer func() {
+		cancel()
+		c.StopConsumeAsync()
+	}()
+
+	// The only worker recovers and handles the messages after the stuck one
+	require.Eventually(t, func() bool {
+		return len(reader.committedOffsets(0)) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []int64{0, 1, 2}, reader.committedOffsets(0))
+	_, ok := handled.Load(int64(2))
+	assert.True(t, ok)
+
+	entries := logger.find("message handler failed, skipping message")
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(0), entries[0].fields["offset"])
+	assert.ErrorIs(t, entries[0].fields["error"].(error), ErrMessageTimeout)
+}
+
+func TestConsumer_AsyncMessageTimeoutDeadLetter(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.HandlerErrorPolicy = ErrorPolicyDeadLetter
+	config.MessageTimeout = 20 * time.Millisecond
+	config.Logger = &captureLogger{}
+
+	reader := newFakeReader(testMessage(0, 0), testMessage(0, 1))
+	c := newConsumer(config, reader)
+	dlq := &fakeWriter{}
+	c.deadLetter = dlq
+
+	// A cooperative handler sees its context expire
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		if msg.Offset == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, c.ConsumeAsyncContext(ctx, handler, 1))
+	defer func() {
+		cancel()
+		c.StopConsumeAsync()
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(reader.committedOffsets(0)) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	written := dlq.written()
+	require.Len(t, written, 1)
+	assert.Equal(t, []byte("key-0-1"), written[0].Key)
+}
@@ -0,0 +1,346 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrGroupActive is returned when resetting the offsets of a group that still has members
+var ErrGroupActive = errors.New("consumer group has active members")
+
+// TopicPartition identifies a partition of a topic
+type TopicPartition struct {
+	Topic     string
+	Partition int
+}
+
+// OffsetInfo describes a consumer group's position in a partition
+type OffsetInfo struct {
+	Committed int64 // Next offset the group will read, or -1 when nothing is committed
+	LogStart  int64 // Earliest offset still in the log
+	LogEnd    int64 // High-water mark
+	Lag       int64 // Messages between the group's position and the log end
+}
+
+// ResetTarget selects where ResetGroupOffsets moves a group
+type ResetTarget string
+
+// Reset targets for ResetSpec
+const (
+	ResetEarliest  ResetTarget = "earliest"  // The start of the log
+	ResetLatest    ResetTarget = "latest"    // The log end, skipping everything unread
+	ResetTimestamp ResetTarget = "timestamp" // The first message at or after ResetSpec.Timestamp
+	ResetOffsets   ResetTarget = "offsets"   // The offsets in ResetSpec.Offsets
+)
+
+// ResetSpec describes an offset reset for the configured consumer group
+type ResetSpec struct {
+	Target     ResetTarget
+	Topics     []string                 // Topics to reset (default config.Topic)
+	Partitions []int                    // Partitions to reset in each topic (default all)
+	Timestamp  time.Time                // For ResetTimestamp
+	Offsets    map[TopicPartition]int64 // For ResetOffsets; only these partitions are reset
+	DryRun     bool                     // Return the plan without committing it
+	Force      bool                     // Apply even while the group has active members
+}
+
+// PartitionReset is the planned move of one partition
+type PartitionReset struct {
+	TopicPartition
+	Current  int64 // Committed offset, or -1 when nothing is committed
+	Proposed int64
+}
+
+// Plan lists the offsets a reset moves the group from and to, ordered by topic and partition
+type Plan struct {
+	GroupID    string
+	Partitions []PartitionReset
+	Applied    bool // Whether the proposed offsets were committed
+}
+
+// groupAdmin is the subset of the admin API used to inspect and manage consumer groups
+type groupAdmin interface {
+	offsetAdmin
+	DescribeGroups(ctx context.Context, req *kafka.DescribeGroupsRequest) (*kafka.DescribeGroupsResponse, error)
+	OffsetCommit(ctx context.Context, req *kafka.OffsetCommitRequest) (*kafka.OffsetCommitResponse, error)
+	DeleteGroups(ctx context.Context, req *kafka.DeleteGroupsRequest) (*kafka.DeleteGroupsResponse, error)
+}
+
+// ListGroupOffsets returns the committed offset, log bounds and lag of every partition of
+// config.Topic for config.GroupID
+func ListGroupOffsets(ctx context.Context, config *KafkaConfig) (map[TopicPartition]OffsetInfo, error) {
+	client := &kafka.Client{Addr: kafka.TCP(config.Brokers...)}
+	return groupOffsets(ctx, client, config.GroupID, []string{config.Topic})
+}
+
+// ResetGroupOffsets moves config.GroupID to the offsets selected by spec and returns the plan.
+// With spec.DryRun the plan is only returned. Applying returns ErrGroupActive while the group
+// has members, since they would overwrite the new offsets, unless spec.Force is set.
+func ResetGroupOffsets(ctx context.Context, config *KafkaConfig, spec ResetSpec) (Plan, error) {
+	client := &kafka.Client{Addr: kafka.TCP(config.Brokers...)}
+	return resetGroupOffsets(ctx, client, config, spec)
+}
+
+// DeleteGroup deletes a consumer group and its committed offsets. The broker refuses to
+// delete groups with active members.
+func DeleteGroup(ctx context.Context, config *KafkaConfig, groupID string) error {
+	client := &kafka.Client{Addr: kafka.TCP(config.Brokers...)}
+	return deleteGroup(ctx, client, groupID)
+}
+
+// groupOffsets implements ListGroupOffsets for the given topics
+func groupOffsets(ctx context.Context, admin groupAdmin, groupID string, topics []string) (map[TopicPartition]OffsetInfo, error) {
+	metadata, err := admin.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topics: %w", err)
+	}
+
+	partitions := make(map[string][]int)
+	requests := make(map[string][]kafka.OffsetRequest)
+	for _, topic := range metadata.Topics {
+		if topic.Error != nil {
+			return nil, fmt.Errorf("failed to describe topic %s: %w", topic.Name, topic.Error)
+		}
+		for _, partition := range topic.Partitions {
+			partitions[topic.Name] = append(partitions[topic.Name], partition.ID)
+			requests[topic.Name] = append(requests[topic.Name], kafka.FirstOffsetOf(partition.ID), kafka.LastOffsetOf(partition.ID))
+		}
+	}
+	for _, topic := range topics {
+		if _, ok := partitions[topic]; !ok {
+			return nil, fmt.Errorf("failed to describe topic %s: not found", topic)
+		}
+	}
+
+	offsets, err := admin.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: requests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	committed, err := admin.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: groupID, Topics: partitions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", committed.Error)
+	}
+
+	infos := make(map[TopicPartition]OffsetInfo)
+	for topic, partitionOffsets := range offsets.Topics {
+		for _, p := range partitionOffsets {
+			if p.Error != nil {
+				return nil, fmt.Errorf("failed to list offsets for %s/%d: %w", topic, p.Partition, p.Error)
+			}
+			infos[TopicPartition{Topic: topic, Partition: p.Partition}] = OffsetInfo{
+				Committed: -1,
+				LogStart:  p.FirstOffset,
+				LogEnd:    p.LastOffset,
+			}
+		}
+	}
+
+	for topic, partitionOffsets := range committed.Topics {
+		for _, p := range partitionOffsets {
+			if p.Error != nil {
+				return nil, fmt.Errorf("failed to fetch committed offset for %s/%d: %w", topic, p.Partition, p.Error)
+			}
+			tp := TopicPartition{Topic: topic, Partition: p.Partition}
+			if info, ok := infos[tp]; ok {
+				info.Committed = p.CommittedOffset
+				infos[tp] = info
+			}
+		}
+	}
+
+	// Groups without a committed offset are behind by the whole log
+	for tp, info := range infos {
+		position := info.Committed
+		if position < info.LogStart {
+			position = info.LogStart
+		}
+		info.Lag = max(info.LogEnd-position, 0)
+		infos[tp] = info
+	}
+
+	return infos, nil
+}
+
+// resetGroupOffsets implements ResetGroupOffsets against the given admin API
+func resetGroupOffsets(ctx context.Context, admin groupAdmin, config *KafkaConfig, spec ResetSpec) (Plan, error) {
+	plan := Plan{GroupID: config.GroupID}
+
+	topics := spec.Topics
+	if spec.Target == ResetOffsets {
+		topics = nil
+		seen := make(map[string]bool)
+		for tp := range spec.Offsets {
+			if !seen[tp.Topic] {
+				seen[tp.Topic] = true
+				topics = append(topics, tp.Topic)
+			}
+		}
+	}
+	if len(topics) == 0 {
+		topics = []string{config.Topic}
+	}
+
+	infos, err := groupOffsets(ctx, admin, config.GroupID, topics)
+	if err != nil {
+		return plan, err
+	}
+
+	selected := make(map[int]bool)
+	for _, partition := range spec.Partitions {
+		selected[partition] = true
+	}
+
+	var timestamps map[TopicPartition]int64
+	if spec.Target == ResetTimestamp {
+		if timestamps, err = offsetsForTime(ctx, admin, infos, spec.Timestamp); err != nil {
+			return plan, err
+		}
+	}
+
+	for tp, info := range infos {
+		if len(selected) > 0 && !selected[tp.Partition] {
+			continue
+		}
+
+		var proposed int64
+		switch spec.Target {
+		case ResetEarliest:
+			proposed = info.LogStart
+		case ResetLatest:
+			proposed = info.LogEnd
+		case ResetTimestamp:
+			proposed = timestamps[tp]
+		case ResetOffsets:
+			offset, ok := spec.Offsets[tp]
+			if !ok {
+				continue
+			}
+			// Offsets outside the log would be reset again by the consumer's fallback policy
+			proposed = min(max(offset, info.LogStart), info.LogEnd)
+		default:
+			return plan, fmt.Errorf("unknown reset target %q", spec.Target)
+		}
+
+		plan.Partitions = append(plan.Partitions, PartitionReset{TopicPartition: tp, Current: info.Committed, Proposed: proposed})
+	}
+
+	for tp := range spec.Offsets {
+		if _, ok := infos[tp]; !ok {
+			return plan, fmt.Errorf("partition %s/%d not found", tp.Topic, tp.Partition)
+		}
+	}
+
+	sort.Slice(plan.Partitions, func(i, j int) bool {
+		a, b := plan.Partitions[i], plan.Partitions[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+
+	if spec.DryRun || len(plan.Partitions) == 0 {
+		return plan, nil
+	}
+
+	if !spec.Force {
+		if err := ensureGroupInactive(ctx, admin, config.GroupID); err != nil {
+			return plan, err
+		}
+	}
+
+	commits := make(map[string][]kafka.OffsetCommit)
+	for _, p := range plan.Partitions {
+		commits[p.Topic] = append(commits[p.Topic], kafka.OffsetCommit{Partition: p.Partition, Offset: p.Proposed})
+	}
+
+	// Commits outside a group generation are accepted by the broker for groups without members
+	resp, err := admin.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      config.GroupID,
+		GenerationID: -1,
+		Topics:       commits,
+	})
+	if err != nil {
+		return plan, fmt.Errorf("failed to commit offsets: %w", err)
+	}
+	for topic, partitions := range resp.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return plan, fmt.Errorf("failed to commit offset for %s/%d: %w", topic, p.Partition, p.Error)
+			}
+		}
+	}
+
+	plan.Applied = true
+	return plan, nil
+}
+
+// offsetsForTime returns the first offset at or after t in each partition, or the log end
+// for partitions without such a message
+func offsetsForTime(ctx context.Context, admin offsetAdmin, infos map[TopicPartition]OffsetInfo, t time.Time) (map[TopicPartition]int64, error) {
+	requests := make(map[string][]kafka.OffsetRequest)
+	for tp := range infos {
+		requests[tp.Topic] = append(requests[tp.Topic], kafka.TimeOffsetOf(tp.Partition, t))
+	}
+
+	resp, err := admin.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: requests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up offsets for %s: %w", t.Format(time.RFC3339), err)
+	}
+
+	offsets := make(map[TopicPartition]int64)
+	for tp, info := range infos {
+		offsets[tp] = info.LogEnd
+	}
+	for topic, partitions := range resp.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return nil, fmt.Errorf("failed to look up offset for %s/%d: %w", topic, p.Partition, p.Error)
+			}
+			for offset := range p.Offsets {
+				if offset >= 0 {
+					offsets[TopicPartition{Topic: topic, Partition: p.Partition}] = offset
+				}
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// ensureGroupInactive returns ErrGroupActive if the group has members
+func ensureGroupInactive(ctx context.Context, admin groupAdmin, groupID string) error {
+	resp, err := admin.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{groupID}})
+	if err != nil {
+		return fmt.Errorf("failed to describe group: %w", err)
+	}
+
+	for _, group := range resp.Groups {
+		if group.Error != nil {
+			return fmt.Errorf("failed to describe group %s: %w", groupID, group.Error)
+		}
+		if len(group.Members) > 0 {
+			return fmt.Errorf("%w: group %s has %d members (state %s)", ErrGroupActive, groupID, len(group.Members), group.GroupState)
+		}
+	}
+	return nil
+}
+
+// deleteGroup implements DeleteGroup against the given admin API
+func deleteGroup(ctx context.Context, admin groupAdmin, groupID string) error {
+	resp, err := admin.DeleteGroups(ctx, &kafka.DeleteGroupsRequest{GroupIDs: []string{groupID}})
+	if err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+	if err := resp.Errors[groupID]; err != nil {
+		return fmt.Errorf("failed to delete group %s: %w", groupID, err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLog is a partition whose message at offset o was written o minutes after logEpoch
+type fakeLog struct {
+	start, end int64
+}
+
+var logEpoch = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+// fakeGroupAdmin is an in-memory groupAdmin for one consumer group
+type fakeGroupAdmin struct {
+	logs      map[string][]fakeLog
+	committed map[TopicPartition]int64
+	members   int
+	commits   []*kafka.OffsetCommitRequest
+	deleted   []string
+}
+
+func (f *fakeGroupAdmin) Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error) {
+	resp := &kafka.MetadataResponse{}
+	for _, name := range req.Topics {
+		logs, ok := f.logs[name]
+		if !ok {
+			continue
+		}
+		topic := kafka.Topic{Name: name}
+		for partition := range logs {
+			topic.Partitions = append(topic.Partitions, kafka.Partition{Topic: name, ID: partition})
+		}
+		resp.Topics = append(resp.Topics, topic)
+	}
+	return resp, nil
+}
+
+func (f *fakeGroupAdmin) ListOffsets(ctx context.Context, req *kafka.ListOffsetsRequest) (*kafka.ListOffsetsResponse, error) {
+	resp := &kafka.ListOffsetsResponse{Topics: map[string][]kafka.PartitionOffsets{}}
+	for topic, requests := range req.Topics {
+		merged := make(map[int]kafka.PartitionOffsets)
+		for _, r := range requests {
+			log := f.logs[topic][r.Partition]
+			p, ok := merged[r.Partition]
+			if !ok {
+				p = kafka.PartitionOffsets{Partition: r.Partition, FirstOffset: -1, LastOffset: -1, Offsets: map[int64]time.Time{}}
+			}
+			switch r.Timestamp {
+			case kafka.FirstOffset:
+				p.FirstOffset = log.start
+			case kafka.LastOffset:
+				p.LastOffset = log.end
+			default:
+				// First offset written at or after the timestamp, -1 if none
+				offset := int64(-1)
+				for o := log.start; o < log.end; o++ {
+					if !logEpoch.Add(time.Duration(o) * time.Minute).Before(time.UnixMilli(r.Timestamp)) {
+						offset = o
+						break
+					}
+				}
+				p.Offsets[offset] = time.UnixMilli(r.Timestamp)
+			}
+			merged[r.Partition] = p
+		}
+		for _, p := range merged {
+			resp.Topics[topic] = append(resp.Topics[topic], p)
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeGroupAdmin) OffsetFetch(ctx context.Context, req *kafka.OffsetFetchRequest) (*kafka.OffsetFetchResponse, error) {
+	resp := &kafka.OffsetFetchResponse{Topics: map[string][]kafka.OffsetFetchPartition{}}
+	for topic, partitions := range req.Topics {
+		for _, partition := range partitions {
+			offset, ok := f.committed[TopicPartition{Topic: topic, Partition: partition}]
+			if !ok {
+				offset = -1
+			}
+			resp.Topics[topic] = append(resp.Topics[topic], kafka.OffsetFetchPartition{Partition: partition, CommittedOffset: offset})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeGroupAdmin) DescribeGroups(ctx context.Context, req *kafka.DescribeGroupsRequest) (*kafka.DescribeGroupsResponse, error) {
+	group := kafka.DescribeGroupsResponseGroup{GroupID: req.GroupIDs[0], GroupState: "Empty"}
+	if f.members > 0 {
+		group.GroupState = "Stable"
+		group.Members = make([]kafka.DescribeGroupsResponseMember, f.members)
+	}
+	return &kafka.DescribeGroupsResponse{Groups: []kafka.DescribeGroupsResponseGroup{group}}, nil
+}
+
+func (f *fakeGroupAdmin) OffsetCommit(ctx context.Context, req *kafka.OffsetCommitRequest) (*kafka.OffsetCommitResponse, error) {
+	f.commits = append(f.commits, req)
+	for topic, commits := range req.Topics {
+		for _, c := range commits {
+			f.committed[TopicPartition{Topic: topic, Partition: c.Partition}] = c.Offset
+		}
+	}
+	return &kafka.OffsetCommitResponse{}, nil
+}
+
+func (f *fakeGroupAdmin) DeleteGroups(ctx context.Context, req *kafka.DeleteGroupsRequest) (*kafka.DeleteGroupsResponse, error) {
+	f.deleted = append(f.deleted, req.GroupIDs...)
+	return &kafka.DeleteGroupsResponse{}, nil
+}
+
+func newFakeGroupAdmin() *fakeGroupAdmin {
+	return &fakeGroupAdmin{
+		logs: map[string][]fakeLog{
+			"orders": {{start: 0, end: 100}, {start: 20, end: 50}, {start: 0, end: 0}},
+		},
+		committed: map[TopicPartition]int64{
+			{Topic: "orders", Partition: 0}: 90,
+			{Topic: "orders", Partition: 1}: 10, // Older than the log start after retention
+		},
+	}
+}
+
+func groupTestConfig() *KafkaConfig {
+	config := NewDefaultConfig()
+	config.Topic = "orders"
+	config.GroupID = "billing"
+	return config
+}
+
+func TestGroupOffsets(t *testing.T) {
+	infos, err := groupOffsets(context.Background(), newFakeGroupAdmin(), "billing", []string{"orders"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[TopicPartition]OffsetInfo{
+		{Topic: "orders", Partition: 0}: {Committed: 90, LogStart: 0, LogEnd: 100, Lag: 10},
+		{Topic: "orders", Partition: 1}: {Committed: 10, LogStart: 20, LogEnd: 50, Lag: 30},
+		{Topic: "orders", Partition: 2}: {Committed: -1, LogStart: 0, LogEnd: 0, Lag: 0},
+	}, infos)
+
+	_, err = groupOffsets(context.Background(), newFakeGroupAdmin(), "billing", []string{"missing"})
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestResetGroupOffsets_DryRun(t *testing.T) {
+	admin := newFakeGroupAdmin()
+	admin.members = 2 // Dry runs are allowed while the group is active
+
+	plan, err := resetGroupOffsets(context.Background(), admin, groupTestConfig(), ResetSpec{Target: ResetEarliest, DryRun: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, Plan{GroupID: "billing", Partitions: []PartitionReset{
+		{TopicPartition: TopicPartition{Topic: "orders", Partition: 0}, Current: 90, Proposed: 0},
+		{TopicPartition: TopicPartition{Topic: "orders", Partition: 1}, Current: 10, Proposed: 20},
+		{TopicPartition: TopicPartition{Topic: "orders", Partition: 2}, Current: -1, Proposed: 0},
+	}}, plan)
+	assert.Empty(t, admin.commits)
+	assert.Equal(t, int64(90), admin.committed[TopicPartition{Topic: "orders", Partition: 0}])
+}
+
+func TestResetGroupOffsets_Timestamp(t *testing.T) {
+	admin := newFakeGroupAdmin()
+
+	// 30 minutes after the epoch is offset 30 in partition 0 and 1; partition 2 is empty
+	plan, err := resetGroupOffsets(context.Background(), admin, groupTestConfig(), ResetSpec{
+		Target:    ResetTimestamp,
+		Timestamp: logEpoch.Add(30 * time.Minute),
+	})
+	require.NoError(t, err)
+	assert.True(t, plan.Applied)
+
+	require.Len(t, admin.commits, 1)
+	assert.Equal(t, "billing", admin.commits[0].GroupID)
+	assert.Equal(t, -1, admin.commits[0].GenerationID)
+	assert.Equal(t, map[TopicPartition]int64{
+		{Topic: "orders", Partition: 0}: 30,
+		{Topic: "orders", Partition: 1}: 30,
+		{Topic: "orders", Partition: 2}: 0,
+	}, admin.committed)
+}
+
+func TestResetGroupOffsets_SelectedPartitionsAndOffsets(t *testing.T) {
+	admin := newFakeGroupAdmin()
+	config := groupTestConfig()
+
+	plan, err := resetGroupOffsets(context.Background(), admin, config, ResetSpec{Target: ResetLatest, Partitions: []int{1}})
+	require.NoError(t, err)
+	require.Len(t, plan.Partitions, 1)
+	assert.Equal(t, PartitionReset{TopicPartition: TopicPartition{Topic: "orders", Partition: 1}, Current: 10, Proposed: 50}, plan.Partitions[0])
+	assert.Equal(t, int64(90), admin.committed[TopicPartition{Topic: "orders", Partition: 0}])
+
+	// Specific offsets are clamped to the log
+	plan, err = resetGroupOffsets(context.Background(), admin, config, ResetSpec{
+		Target:  ResetOffsets,
+		Offsets: map[TopicPartition]int64{{Topic: "orders", Partition: 0}: 42, {Topic: "orders", Partition: 1}: 500},
+	})
+	require.NoError(t, err)
+	require.Len(t, plan.Partitions, 2)
+	assert.Equal(t, int64(42), plan.Partitions[0].Proposed)
+	assert.Equal(t, int64(50), plan.Partitions[1].Proposed)
+
+	_, err = resetGroupOffsets(context.Background(), admin, config, ResetSpec{
+		Target:  ResetOffsets,
+		Offsets: map[TopicPartition]int64{{Topic: "orders", Partition: 9}: 1},
+	})
+	assert.ErrorContains(t, err, "orders/9 not found")
+
+	_, err = resetGroupOffsets(context.Background(), admin, config, ResetSpec{Target: "yesterday"})
+	assert.ErrorContains(t, err, "unknown reset target")
+}
+
+func TestResetGroupOffsets_ActiveMemberGuard(t *testing.T) {
+	admin := newFakeGroupAdmin()
+	admin.members = 1
+
+	plan, err := resetGroupOffsets(context.Background(), admin, groupTestConfig(), ResetSpec{Target: ResetLatest})
+	assert.ErrorIs(t, err, ErrGroupActive)
+	assert.False(t, plan.Applied)
+	assert.Empty(t, admin.commits)
+
+	plan, err = resetGroupOffsets(context.Background(), admin, groupTestConfig(), ResetSpec{Target: ResetLatest, Force: true})
+	require.NoError(t, err)
+	assert.True(t, plan.Applied)
+	assert.Equal(t, int64(100), admin.committed[TopicPartition{Topic: "orders", Partition: 0}])
+}
+
+func TestDeleteGroup(t *testing.T) {
+	admin := newFakeGroupAdmin()
+	require.NoError(t, deleteGroup(context.Background(), admin, "billing"))
+	assert.Equal(t, []string{"billing"}, admin.deleted)
+}
+
+// TestResetGroupOffsets_Integration runs against a real broker when KAFKA_BROKERS is set,
+// e.g. KAFKA_BROKERS=localhost:9092 with the docker-compose setup.
+func TestResetGroupOffsets_Integration(t *testing.T) {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_BROKERS not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	config := NewDefaultConfig()
+	config.Brokers = strings.Split(brokers, ",")
+	config.Topic = fmt.Sprintf("reset-offsets-%d", time.Now().UnixNano())
+	config.GroupID = config.Topic + "-group"
+	config.NumPartitions = 1
+	require.NoError(t, CreateTopic(ctx, config))
+
+	writer := &kafka.Writer{Addr: kafka.TCP(config.Brokers...), Topic: config.Topic, AllowAutoTopicCreation: true}
+	defer writer.Close()
+	require.NoError(t, writer.WriteMessages(ctx, kafka.Message{Value: []byte("before")}))
+	middle := time.Now()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, writer.WriteMessages(ctx, kafka.Message{Value: []byte("after")}))
+
+	// Dry run proposes the log end without committing anything
+	plan, err := ResetGroupOffsets(ctx, config, ResetSpec{Target: ResetLatest, DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, plan.Partitions, 1)
+	assert.Equal(t, int64(-1), plan.Partitions[0].Current)
+	assert.Equal(t, int64(2), plan.Partitions[0].Proposed)
+
+	// An applied timestamp reset skips the message written before it
+	plan, err = ResetGroupOffsets(ctx, config, ResetSpec{Target: ResetTimestamp, Timestamp: middle})
+	require.NoError(t, err)
+	assert.True(t, plan.Applied)
+
+	offsets, err := ListGroupOffsets(ctx, config)
+	require.NoError(t, err)
+	info := offsets[TopicPartition{Topic: config.Topic, Partition: 0}]
+	assert.Equal(t, int64(1), info.Committed)
+	assert.Equal(t, int64(1), info.Lag)
+
+	// A running consumer blocks the reset
+	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: config.Brokers, Topic: config.Topic, GroupID: config.GroupID})
+	defer reader.Close()
+	_, err = reader.FetchMessage(ctx)
+	require.NoError(t, err)
+
+	_, err = ResetGroupOffsets(ctx, config, ResetSpec{Target: ResetEarliest})
+	assert.ErrorIs(t, err, ErrGroupActive)
+
+	require.NoError(t, reader.Close())
+	require.Eventually(t, func() bool { return DeleteGroup(ctx, config, config.GroupID) == nil }, 30*time.Second, time.Second)
+}
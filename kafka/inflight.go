@@ -0,0 +1,201 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// InFlightStats summarizes the messages ConsumeAsync has fetched but not yet committed
+type InFlightStats struct {
+	InFlight        int           // Messages fetched and not yet handled
+	Completed       int           // Messages handled but held back behind an older in-flight message
+	Failed          int           // Failed messages holding back their partition until it is read again
+	Stalled         int           // In-flight messages older than MaxInFlightAge
+	OldestAge       time.Duration // Time since the oldest in-flight message was fetched
+	OldestPartition int           // Partition of the oldest in-flight message, -1 when there is none
+	OldestOffset    int64         // Offset of the oldest in-flight message, -1 when there is none
+}
+
+// inFlightEntry is a fetched message awaiting its handler
+type inFlightEntry struct {
+	offset  int64
+	fetched time.Time
+	done    bool
+	failed  bool
+	warned  bool
+}
+
+// inFlightTracker records fetched messages per partition in offset order, so offsets are only
+// committed up to the contiguous prefix of handled messages. A failed message ends that prefix
+// until the partition is read again.
+type inFlightTracker struct {
+	mu         sync.Mutex
+	topic      string
+	partitions map[int][]*inFlightEntry
+}
+
+// add records a message before it is dispatched to a worker. A message at or below an offset
+// already tracked means the partition is being read again, e.g. after a rebalance, so the
+// partition's earlier entries are dropped.
+func (t *inFlightTracker) add(msg kafka.Message, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.partitions == nil {
+		t.partitions = make(map[int][]*inFlightEntry)
+	}
+	t.topic = msg.Topic
+
+	entries := t.partitions[msg.Partition]
+	if n := len(entries); n > 0 && msg.Offset <= entries[n-1].offset {
+		entries = nil
+	}
+	t.partitions[msg.Partition] = append(entries, &inFlightEntry{offset: msg.Offset, fetched: now})
+}
+
+// complete marks a message handled. If that advances the partition's low-water mark it
+// returns the last message of the handled prefix, which is safe to commit.
+func (t *inFlightTracker) complete(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries := t.mark(msg, func(entry *inFlightEntry) { entry.done = true })
+
+	n := 0
+	for n < len(entries) && entries[n].done {
+		n++
+	}
+	if n == 0 {
+		return kafka.Message{}, false
+	}
+
+	low := entries[n-1].offset
+	t.partitions[msg.Partition] = entries[n:]
+	return kafka.Message{Topic: msg.Topic, Partition: msg.Partition, Offset: low}, true
+}
+
+// fail marks a message whose handler failed without being skipped. The partition isn't
+// committed past it, so messages handled behind it are dropped rather than held.
+func (t *inFlightTracker) fail(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.mark(msg, func(entry *inFlightEntry) { entry.failed = true })
+}
+
+// mark applies set to the message's entry and returns the partition's entries. Past the first
+// failed entry only messages still being handled are kept, so a failure that is never
+// redelivered doesn't grow the partition. The caller must hold mu.
+func (t *inFlightTracker) mark(msg kafka.Message, set func(*inFlightEntry)) []*inFlightEntry {
+	entries := t.partitions[msg.Partition]
+	for _, entry := range entries {
+		if entry.offset == msg.Offset {
+			set(entry)
+			break
+		}
+	}
+
+	for i, entry := range entries {
+		if !entry.failed {
+			continue
+		}
+		kept := entries[:i+1]
+		for _, later := range entries[i+1:] {
+			if !later.done && !later.failed {
+				kept = append(kept, later)
+			}
+		}
+		entries = kept
+		t.partitions[msg.Partition] = entries
+		break
+	}
+	return entries
+}
+
+// stats summarizes the tracked messages as of now
+func (t *inFlightTracker) stats(now time.Time, maxAge time.Duration) InFlightStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := InFlightStats{OldestPartition: -1, OldestOffset: -1}
+	for partition, entries := range t.partitions {
+		for _, entry := range entries {
+			if entry.done {
+				stats.Completed++
+				continue
+			}
+			if entry.failed {
+				stats.Failed++
+				continue
+			}
+			stats.InFlight++
+
+			age := now.Sub(entry.fetched)
+			if maxAge > 0 && age > maxAge {
+				stats.Stalled++
+			}
+			if age > stats.OldestAge || stats.OldestOffset < 0 {
+				stats.OldestAge = age
+				stats.OldestPartition = partition
+				stats.OldestOffset = entry.offset
+			}
+		}
+	}
+	return stats
+}
+
+// overdue returns the in-flight messages older than maxAge that haven't been reported yet,
+// marking them reported
+func (t *inFlightTracker) overdue(now time.Time, maxAge time.Duration) []kafka.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var msgs []kafka.Message
+	for partition, entries := range t.partitions {
+		for _, entry := range entries {
+			if entry.done || entry.failed || entry.warned || now.Sub(entry.fetched) <= maxAge {
+				continue
+			}
+			entry.warned = true
+			msgs = append(msgs, kafka.Message{Topic: t.topic, Partition: partition, Offset: entry.offset, Time: entry.fetched})
+		}
+	}
+	return msgs
+}
+
+// ageLoop warns about messages in flight longer than MaxInFlightAge until ctx is done or
+// async consumption stops
+func (c *Consumer) ageLoop(ctx context.Context, stop <-chan struct{}) {
+	defer c.consumeWg.Done()
+	maxAge := c.config.MaxInFlightAge
+	ticker := time.NewTicker(maxAge / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			for _, msg := range c.inFlight.overdue(now, maxAge) {
+				// Commits for the partition can't advance past this message until it is handled
+				c.logger.Warn("message in flight too long",
+					"topic", msg.Topic,
+					"partition", msg.Partition,
+					"offset", msg.Offset,
+					"age", now.Sub(msg.Time),
+				)
+			}
+		}
+	}
+}
+
+// InFlightStats returns the number of messages ConsumeAsync is handling and the age of the
+// oldest, for alerting on stalled partitions. It is empty unless TrackInFlight is set.
+func (c *Consumer) InFlightStats() InFlightStats {
+	return c.inFlight.stats(time.Now(), c.config.MaxInFlightAge)
+}
@@ -0,0 +1,226 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInFlightTracker_CommitsContiguousPrefix(t *testing.T) {
+	var tracker inFlightTracker
+	now := time.Now()
+	for offset := int64(10); offset < 14; offset++ {
+		tracker.add(testMessage(0, offset), now)
+	}
+
+	// Completing out of order holds the low-water mark at the oldest unfinished offset
+	_, ok := tracker.complete(testMessage(0, 11))
+	assert.False(t, ok)
+	_, ok = tracker.complete(testMessage(0, 13))
+	assert.False(t, ok)
+
+	low, ok := tracker.complete(testMessage(0, 10))
+	require.True(t, ok)
+	assert.Equal(t, int64(11), low.Offset)
+	assert.Equal(t, 0, low.Partition)
+
+	stats := tracker.stats(now.Add(time.Second), 0)
+	assert.Equal(t, 1, stats.InFlight)
+	assert.Equal(t, 1, stats.Completed)
+	assert.Equal(t, int64(12), stats.OldestOffset)
+	assert.Equal(t, time.Second, stats.OldestAge)
+
+	low, ok = tracker.complete(testMessage(0, 12))
+	require.True(t, ok)
+	assert.Equal(t, int64(13), low.Offset)
+	assert.Equal(t, InFlightStats{OldestPartition: -1, OldestOffset: -1}, tracker.stats(now, 0))
+}
+
+func TestInFlightTracker_RereadResetsPartition(t *testing.T) {
+	var tracker inFlightTracker
+	now := time.Now()
+	tracker.add(testMessage(0, 5), now)
+	tracker.add(testMessage(0, 6), now)
+	tracker.add(testMessage(1, 0), now)
+
+	// After a rebalance the partition is read again from the last commit
+	tracker.add(testMessage(0, 5), now)
+	assert.Equal(t, 2, tracker.stats(now, 0).InFlight)
+
+	low, ok := tracker.complete(testMessage(0, 5))
+	require.True(t, ok)
+	assert.Equal(t, int64(5), low.Offset)
+}
+
+func TestInFlightTracker_FailureDropsLaterCompletions(t *testing.T) {
+	var tracker inFlightTracker
+	now := time.Now()
+	for offset := int64(0); offset < 5; offset++ {
+		tracker.add(testMessage(0, offset), now)
+	}
+
+	_, ok := tracker.complete(testMessage(0, 3))
+	assert.False(t, ok)
+	tracker.fail(testMessage(0, 1))
+
+	// Offset 0 can still be committed, but nothing past the failure
+	low, ok := tracker.complete(testMessage(0, 0))
+	require.True(t, ok)
+	assert.Equal(t, int64(0), low.Offset)
+	_, ok = tracker.complete(testMessage(0, 2))
+	assert.False(t, ok)
+
+	// Only the failure and the message still being handled are kept
+	stats := tracker.stats(now, 0)
+	assert.Equal(t, 1, stats.Failed)
+	assert.Equal(t, 1, stats.InFlight)
+	assert.Equal(t, 0, stats.Completed)
+	assert.Equal(t, int64(4), stats.OldestOffset)
+	overdue := tracker.overdue(now.Add(time.Hour), time.Minute)
+	require.Len(t, overdue, 1, "the failure isn't reported as in flight")
+	assert.Equal(t, int64(4), overdue[0].Offset)
+
+	// Reading the partition again clears the failure
+	tracker.add(testMessage(0, 1), now)
+	low, ok = tracker.complete(testMessage(0, 1))
+	require.True(t, ok)
+	assert.Equal(t, int64(1), low.Offset)
+}
+
+// stuckHandler never finishes the message at stuckOffset of partition 0 until released
+type stuckHandler struct {
+	stuckOffset int64
+	release     chan struct{}
+	mu          sync.Mutex
+	handled     map[int][]int64
+}
+
+func (h *stuckHandler) handle(msg kafka.Message) error {
+	if msg.Partition == 0 && msg.Offset == h.stuckOffset {
+		<-h.release
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handled[msg.Partition] = append(h.handled[msg.Partition], msg.Offset)
+	return nil
+}
+
+func (h *stuckHandler) count(partition int) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.handled[partition])
+}
+
+func maxOffset(offsets []int64) int64 {
+	max := int64(-1)
+	for _, offset := range offsets {
+		if offset > max {
+			max = offset
+		}
+	}
+	return max
+}
+
+func TestConsumer_InFlightCommitsStallAtStuckMessage(t *testing.T) {
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.TrackInFlight = true
+	config.MaxInFlightAge = 40 * time.Millisecond
+	config.Logger = logger
+
+	var msgs []kafka.Message
+	for offset := int64(0); offset < 6; offset++ {
+		msgs = append(msgs, testMessage(0, offset))
+	}
+	msgs = append(msgs, testMessage(1, 0), testMessage(1, 1))
+	reader := newFakeReader(msgs...)
+	c := newConsumer(config, reader)
+
+	handler := &stuckHandler{stuckOffset: 2, release: make(chan struct{}), handled: map[int][]int64{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, c.ConsumeAsync(ctx, handler.handle, 3))
+	defer func() {
+		cancel()
+		c.StopConsumeAsync()
+	}()
+
+	// The other workers handle every later message, but partition 0 commits stop before offset 2
+	require.Eventually(t, func() bool {
+		return handler.count(0) == 5 && maxOffset(reader.committedOffsets(1)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), maxOffset(reader.committedOffsets(0)))
+
+	stats := c.InFlightStats()
+	assert.Equal(t, 1, stats.InFlight)
+	assert.Equal(t, 3, stats.Completed)
+	assert.Equal(t, 0, stats.OldestPartition)
+	assert.Equal(t, int64(2), stats.OldestOffset)
+
+	// The stall is reported once
+	require.Eventually(t, func() bool {
+		return len(logger.find("message in flight too long")) > 0
+	}, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	entries := logger.find("message in flight too long")
+	require.Len(t, entries, 1)
+	assert.Equal(t, 0, entries[0].fields["partition"])
+	assert.Equal(t, int64(2), entries[0].fields["offset"])
+	assert.GreaterOrEqual(t, entries[0].fields["age"].(time.Duration), config.MaxInFlightAge)
+	assert.Equal(t, 1, c.InFlightStats().Stalled)
+
+	// Once the message is handled the commit jumps past everything handled behind it
+	close(handler.release)
+	require.Eventually(t, func() bool {
+		return maxOffset(reader.committedOffsets(0)) == 5
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, c.InFlightStats().InFlight)
+}
+
+func TestConsumer_InFlightFailureDoesNotGrowTracker(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.TrackInFlight = true
+	config.HandlerErrorPolicy = ErrorPolicyStop
+	config.Logger = &captureLogger{}
+
+	var msgs []kafka.Message
+	for offset := int64(0); offset < 500; offset++ {
+		msgs = append(msgs, testMessage(0, offset))
+	}
+	reader := newFakeReader(msgs...)
+	c := newConsumer(config, reader)
+
+	var handled sync.WaitGroup
+	handled.Add(len(msgs))
+	handler := failingHandler(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, c.ConsumeAsync(ctx, func(msg kafka.Message) error {
+		defer handled.Done()
+		return handler(msg)
+	}, 4))
+	defer func() {
+		cancel()
+		c.StopConsumeAsync()
+	}()
+
+	// Every later message is handled, but the partition is never committed past the failure
+	handled.Wait()
+	require.Eventually(t, func() bool {
+		return c.InFlightStats().InFlight == 0
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), maxOffset(reader.committedOffsets(0)))
+
+	stats := c.InFlightStats()
+	assert.Equal(t, 1, stats.Failed)
+	assert.Equal(t, 0, stats.Completed, "messages handled behind the failure are not held")
+
+	c.inFlight.mu.Lock()
+	assert.Len(t, c.inFlight.partitions[0], 1)
+	c.inFlight.mu.Unlock()
+}
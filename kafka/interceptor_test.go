@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyRing() *KeyRing {
+	return &KeyRing{
+		Current: "v1",
+		Keys: map[string][]byte{
+			"v1": bytes.Repeat([]byte{1}, 32),
+		},
+	}
+}
+
+func TestEncryptionInterceptor_RoundTrip(t *testing.T) {
+	keys := testKeyRing()
+	encrypt, err := NewEncryptionInterceptor(keys)
+	require.NoError(t, err)
+
+	writer := &fakeWriter{}
+	p := newProducer(NewDefaultConfig(), writer)
+	p.Use(encrypt)
+
+	plaintext := []byte(`{"card":"4111111111111111"}`)
+	require.NoError(t, p.Produce(context.Background(), []byte("order-1"), plaintext))
+
+	written := writer.written()
+	require.Len(t, written, 1)
+	encrypted := written[0]
+	// Without the middleware only ciphertext is visible
+	assert.NotEqual(t, plaintext, encrypted.Value)
+	assert.NotContains(t, string(encrypted.Value), "4111")
+	require.Len(t, encrypted.Headers, 1)
+	assert.Equal(t, KeyVersionHeader, encrypted.Headers[0].Key)
+	assert.Equal(t, "v1", string(encrypted.Headers[0].Value))
+
+	// The decryption middleware restores the plaintext and strips the header
+	var got kafka.Message
+	handler := DecryptingHandler(keys, func(msg kafka.Message) error {
+		got = msg
+		return nil
+	})
+	require.NoError(t, handler(encrypted))
+	assert.Equal(t, plaintext, got.Value)
+	assert.Empty(t, got.Headers)
+
+	// The wrong key fails authentication
+	wrongKeys := &KeyRing{Current: "v1", Keys: map[string][]byte{"v1": bytes.Repeat([]byte{2}, 32)}}
+	err = DecryptingHandler(wrongKeys, func(msg kafka.Message) error { return nil })(encrypted)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	// Tampering with the message key fails authentication
+	tampered := encrypted
+	tampered.Key = []byte("order-2")
+	err = handler(tampered)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestEncryptionInterceptor_KeyRotation(t *testing.T) {
+	keys := testKeyRing()
+	old := kafka.Message{Key: []byte("k"), Value: []byte("old")}
+	require.NoError(t, keys.EncryptMessage(&old))
+
+	keys.Keys["v2"] = bytes.Repeat([]byte{3}, 32)
+	keys.Current = "v2"
+	current := kafka.Message{Key: []byte("k"), Value: []byte("new")}
+	require.NoError(t, keys.EncryptMessage(&current))
+	assert.Equal(t, "v2", string(current.Headers[0].Value))
+
+	require.NoError(t, keys.DecryptMessage(&old))
+	require.NoError(t, keys.DecryptMessage(&current))
+	assert.Equal(t, "old", string(old.Value))
+	assert.Equal(t, "new", string(current.Value))
+
+	// Retired keys can no longer decrypt
+	stale := kafka.Message{Key: []byte("k"), Value: []byte("x")}
+	require.NoError(t, keys.EncryptMessage(&stale))
+	delete(keys.Keys, "v2")
+	assert.ErrorIs(t, keys.DecryptMessage(&stale), ErrUnknownKeyVersion)
+}
+
+func TestNewEncryptionInterceptor_InvalidKey(t *testing.T) {
+	_, err := NewEncryptionInterceptor(&KeyRing{Current: "missing"})
+	assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+
+	_, err = NewEncryptionInterceptor(&KeyRing{Current: "v1", Keys: map[string][]byte{"v1": []byte("short")}})
+	assert.Error(t, err)
+}
+
+func TestProducer_InterceptorErrorAbortsProduce(t *testing.T) {
+	writer := &fakeWriter{}
+	p := newProducer(NewDefaultConfig(), writer)
+
+	errRejected := errors.New("rejected")
+	p.Use(func(ctx context.Context, msg *kafka.Message) error {
+		if string(msg.Key) == "bad" {
+			return errRejected
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, p.Produce(context.Background(), []byte("bad"), []byte("v")), errRejected)
+
+	batch := []kafka.Message{{Key: []byte("good")}, {Key: []byte("bad")}}
+	assert.ErrorIs(t, p.ProduceBatch(context.Background(), batch), errRejected)
+	assert.Empty(t, writer.written())
+}
+
+func TestProducer_InterceptorsDoNotMutateCallerBatch(t *testing.T) {
+	writer := &fakeWriter{}
+	p := newProducer(NewDefaultConfig(), writer)
+	p.Use(func(ctx context.Context, msg *kafka.Message) error {
+		msg.Value = []byte(strings.ToUpper(string(msg.Value)))
+		return nil
+	})
+
+	batch := []kafka.Message{{Key: []byte("a"), Value: []byte("one")}, {Key: []byte("b"), Value: []byte("two")}}
+	require.NoError(t, p.ProduceBatch(context.Background(), batch))
+
+	assert.Equal(t, "one", string(batch[0].Value))
+	written := writer.written()
+	require.Len(t, written, 2)
+	assert.Equal(t, "ONE", string(written[0].Value))
+	assert.Equal(t, "TWO", string(written[1].Value))
+}
+
+func TestProducer_InterceptorsDoNotWriteIntoCallerHeaders(t *testing.T) {
+	encrypt, err := NewEncryptionInterceptor(testKeyRing())
+	require.NoError(t, err)
+
+	writer := &fakeWriter{}
+	p := newProducer(NewDefaultConfig(), writer)
+	p.Use(encrypt)
+
+	// Spare capacity lets an append write into the caller's backing array
+	headers := make([]kafka.Header, 1, 4)
+	headers[0] = kafka.Header{Key: "trace", Value: []byte("t1")}
+	batch := []kafka.Message{{Key: []byte("a"), Value: []byte("one"), Headers: headers}}
+	require.NoError(t, p.ProduceBatch(context.Background(), batch))
+
+	assert.Len(t, batch[0].Headers, 1)
+	assert.Equal(t, kafka.Header{}, headers[:2][1], "the caller's spare capacity is untouched")
+
+	written := writer.written()
+	require.Len(t, written, 1)
+	require.Len(t, written[0].Headers, 2)
+	assert.Equal(t, KeyVersionHeader, written[0].Headers[1].Key)
+}
+
+func TestAuditInterceptor(t *testing.T) {
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "payments"
+	config.Logger = logger
+
+	writer := &fakeWriter{}
+	p := newProducer(config, writer)
+	p.Use(NewAuditInterceptor(config))
+
+	require.NoError(t, p.Produce(context.Background(), []byte("customer-42"), []byte("12345")))
+
+	entries := logger.find("kafka produce")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "payments", entries[0].fields["topic"])
+	assert.Equal(t, 5, entries[0].fields["size"])
+	assert.Len(t, entries[0].fields["key_hash"], 16)
+	assert.NotContains(t, entries[0].fields["key_hash"], "customer-42")
+}
@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// latencyWeight is the weight of each new sample in the moving average latency
+const latencyWeight = 0.1
+
+// LatencyStats summarizes the end-to-end latency of handled messages
+type LatencyStats struct {
+	Count   int64         // Messages with a timestamp handled so far
+	Last    time.Duration // Latency of the most recent message
+	Average time.Duration // Exponentially weighted moving average, favoring recent messages
+	Max     time.Duration // Highest latency seen
+}
+
+// latencyTracker keeps rolling latency statistics
+type latencyTracker struct {
+	mu    sync.Mutex
+	stats LatencyStats
+}
+
+// record adds a latency sample
+func (t *latencyTracker) record(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stats.Count == 0 {
+		t.stats.Average = latency
+	} else {
+		t.stats.Average += time.Duration(latencyWeight * float64(latency-t.stats.Average))
+	}
+	t.stats.Count++
+	t.stats.Last = latency
+	if latency > t.stats.Max {
+		t.stats.Max = latency
+	}
+}
+
+// recordLatency measures how long ago msg was produced, as of now
+func (c *Consumer) recordLatency(msg kafka.Message, now time.Time) {
+	if msg.Time.IsZero() {
+		return
+	}
+
+	latency := now.Sub(msg.Time)
+	c.latency.record(latency)
+	if c.config.OnMessageLatency != nil {
+		c.config.OnMessageLatency(msg, latency)
+	}
+}
+
+// LatencyStats returns the end-to-end latency of the messages handled so far, which grows
+// when the consumer falls behind producers
+func (c *Consumer) LatencyStats() LatencyStats {
+	c.latency.mu.Lock()
+	defer c.latency.mu.Unlock()
+	return c.latency.stats
+}
@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_MessageLatency(t *testing.T) {
+	var mu sync.Mutex
+	latencies := make(map[int64]time.Duration)
+
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.OnMessageLatency = func(msg kafka.Message, latency time.Duration) {
+		mu.Lock()
+		latencies[msg.Offset] = latency
+		mu.Unlock()
+	}
+
+	// Messages produced 10s, 5s and 1s ago, plus one without a timestamp
+	now := time.Now()
+	msgs := []kafka.Message{testMessage(0, 0), testMessage(0, 1), testMessage(0, 2), testMessage(0, 3)}
+	msgs[0].Time = now.Add(-10 * time.Second)
+	msgs[1].Time = now.Add(-5 * time.Second)
+	msgs[2].Time = now.Add(-time.Second)
+
+	c := newConsumer(config, newFakeReader(msgs...))
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := c.Consume(ctx, func(msg kafka.Message) error {
+		if msg.Offset == 3 {
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, latencies, 3)
+	for offset, want := range map[int64]time.Duration{0: 10 * time.Second, 1: 5 * time.Second, 2: time.Second} {
+		assert.GreaterOrEqual(t, latencies[offset], want)
+		assert.Less(t, latencies[offset], want+time.Second)
+	}
+
+	stats := c.LatencyStats()
+	assert.Equal(t, int64(3), stats.Count)
+	assert.Equal(t, latencies[2], stats.Last)
+	assert.Equal(t, latencies[0], stats.Max)
+	assert.Greater(t, stats.Average, latencies[2])
+	assert.Less(t, stats.Average, latencies[0])
+}
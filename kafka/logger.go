@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Logger defines the interface used by the kafka package for diagnostics.
+// Arguments after the message are alternating key/value pairs.
+type Logger interface {
+	Info(message string, args ...interface{})
+	Warn(message string, args ...interface{})
+	Error(message string, args ...interface{})
+}
+
+// stdoutLogger is the default Logger, printing key=value lines to stdout
+type stdoutLogger struct{}
+
+func (stdoutLogger) Info(message string, args ...interface{})  { printLog("INFO", message, args) }
+func (stdoutLogger) Warn(message string, args ...interface{})  { printLog("WARN", message, args) }
+func (stdoutLogger) Error(message string, args ...interface{}) { printLog("ERROR", message, args) }
+
+// printLog formats a log line as "<time> <level> <message> key=value ..."
+func printLog(level, message string, args []interface{}) {
+	var b strings.Builder
+	b.WriteString(time.Now().Format(time.RFC3339))
+	b.WriteString(" ")
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(message)
+
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+
+	fmt.Println(b.String())
+}
+
+// loggerFor returns the configured logger or the stdout default
+func loggerFor(config *KafkaConfig) Logger {
+	if config.Logger != nil {
+		return config.Logger
+	}
+	return stdoutLogger{}
+}
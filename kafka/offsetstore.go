@@ -0,0 +1,370 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrOffsetNotFound is returned by OffsetStore.Load when no offset is stored for a partition
+var ErrOffsetNotFound = errors.New("no stored offset for partition")
+
+// OffsetStore keeps consumer offsets outside Kafka, typically in the database the consumer
+// writes its results to. Offsets are the next offset to read: the offset of the last
+// processed message plus one.
+type OffsetStore interface {
+	// Load returns the stored offset for a partition, or ErrOffsetNotFound
+	Load(ctx context.Context, topic string, partition int) (int64, error)
+	// Save stores the offset for a partition
+	Save(ctx context.Context, topic string, partition int, offset int64) error
+}
+
+// generation is one consumer group generation: the partitions assigned to this member
+// until the next rebalance
+type generation interface {
+	// Assignments returns the assigned partitions of each topic with the group's committed
+	// offset for each, or kafka.FirstOffset when none was committed
+	Assignments() map[string]map[int]int64
+	// Start runs fn in a goroutine whose context is canceled when the generation ends
+	Start(fn func(ctx context.Context))
+	// CommitOffsets commits the next offset to read of each partition to the group
+	CommitOffsets(offsets map[string]map[int]int64) error
+}
+
+// partitionAssigner joins a consumer group and returns its generations in turn
+type partitionAssigner interface {
+	Next(ctx context.Context) (generation, error)
+	Close() error
+}
+
+// partitionReader reads a single partition from a chosen offset
+type partitionReader interface {
+	SetOffset(offset int64) error
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+// groupAssigner is a partitionAssigner backed by a kafka-go consumer group
+type groupAssigner struct {
+	group *kafka.ConsumerGroup
+}
+
+func (a *groupAssigner) Next(ctx context.Context) (generation, error) {
+	gen, err := a.group.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return kafkaGeneration{gen: gen}, nil
+}
+
+func (a *groupAssigner) Close() error {
+	return a.group.Close()
+}
+
+// kafkaGeneration adapts kafka.Generation to the generation interface
+type kafkaGeneration struct {
+	gen *kafka.Generation
+}
+
+func (g kafkaGeneration) Assignments() map[string]map[int]int64 {
+	assignments := make(map[string]map[int]int64, len(g.gen.Assignments))
+	for topic, partitions := range g.gen.Assignments {
+		assignments[topic] = make(map[int]int64, len(partitions))
+		for _, p := range partitions {
+			assignments[topic][p.ID] = p.Offset
+		}
+	}
+	return assignments
+}
+
+func (g kafkaGeneration) Start(fn func(ctx context.Context)) {
+	g.gen.Start(fn)
+}
+
+func (g kafkaGeneration) CommitOffsets(offsets map[string]map[int]int64) error {
+	return g.gen.CommitOffsets(offsets)
+}
+
+// generationReader consumes the partitions assigned to this group member, following the
+// assignment from one generation to the next. With an OffsetStore each partition starts at
+// its stored offset and CommitMessages is a no-op: Kafka only coordinates partition
+// assignment, and the application saves offsets itself, usually in the same transaction as
+// its results. Without one, partitions start at the group's committed offsets and commits
+// go to the group.
+type generationReader struct {
+	assigner partitionAssigner
+	store    OffsetStore // Optional
+	open     func(topic string, partition int) partitionReader
+	logger   Logger
+	messages chan kafka.Message
+	cancel   context.CancelFunc
+	done     chan struct{}
+	err      error // Set when the consumer group could not be created
+
+	mu      sync.Mutex
+	current generation
+	genCtx  context.Context // Canceled when the current generation ends
+	changed chan struct{}   // Closed when a new generation starts
+}
+
+// newGroupReader creates a generationReader for the configured topic and group
+func newGroupReader(config *KafkaConfig) *generationReader {
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:      config.GroupID,
+		Brokers: config.Brokers,
+		Topics:  []string{config.Topic},
+		// Rebalance when partitions are added so they get assigned
+		WatchPartitionChanges: true,
+	})
+	if err != nil {
+		return &generationReader{err: err}
+	}
+
+	open := func(topic string, partition int) partitionReader {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   config.Brokers,
+			Topic:     topic,
+			Partition: partition,
+			MinBytes:  10e3, // 10KB
+			MaxBytes:  10e6, // 10MB
+		})
+	}
+
+	return newGenerationReader(&groupAssigner{group: group}, config.OffsetStore, open, loggerFor(config))
+}
+
+// newGenerationReader starts following the assigner's generations. store may be nil to use
+// the group's committed offsets.
+func newGenerationReader(assigner partitionAssigner, store OffsetStore, open func(topic string, partition int) partitionReader, logger Logger) *generationReader {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &generationReader{
+		assigner: assigner,
+		store:    store,
+		open:     open,
+		logger:   logger,
+		messages: make(chan kafka.Message),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		changed:  make(chan struct{}),
+	}
+
+	go r.run(ctx)
+	return r
+}
+
+// run reads the assigned partitions of each generation until the reader is closed
+func (r *generationReader) run(ctx context.Context) {
+	defer close(r.done)
+
+	for {
+		gen, err := r.assigner.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, kafka.ErrGroupClosed) {
+				return
+			}
+			r.logger.Error("failed to join consumer group", "error", err)
+			if !sleepContext(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+		r.setGeneration(gen)
+
+		// Offsets are loaded again for every generation, since partitions may have been
+		// processed by other members since this one last owned them
+		for topic, partitions := range gen.Assignments() {
+			for partition, committed := range partitions {
+				gen.Start(func(genCtx context.Context) {
+					r.readPartition(ctx, genCtx, topic, partition, committed)
+				})
+			}
+		}
+	}
+}
+
+// setGeneration makes gen the current generation and wakes up callers waiting in Assignment
+func (r *generationReader) setGeneration(gen generation) {
+	started := make(chan context.Context, 1)
+	gen.Start(func(genCtx context.Context) {
+		started <- genCtx
+		<-genCtx.Done()
+	})
+	genCtx := <-started
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current, r.genCtx = gen, genCtx
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Assignment waits for a running generation and returns the partitions of topic it assigned
+// to this member, with a context that is canceled when the generation ends
+func (r *generationReader) Assignment(ctx context.Context, topic string) ([]int, context.Context, error) {
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+
+	for {
+		r.mu.Lock()
+		gen, genCtx, changed := r.current, r.genCtx, r.changed
+		r.mu.Unlock()
+
+		if gen != nil && genCtx.Err() == nil {
+			partitions := make([]int, 0, len(gen.Assignments()[topic]))
+			for partition := range gen.Assignments()[topic] {
+				partitions = append(partitions, partition)
+			}
+			slices.Sort(partitions)
+			return partitions, genCtx, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-r.done:
+			return nil, nil, io.EOF
+		}
+	}
+}
+
+// readPartition forwards a partition's messages from its starting offset until the generation
+// ends or the reader is closed
+func (r *generationReader) readPartition(ctx, genCtx context.Context, topic string, partition int, committed int64) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(genCtx, cancel)
+	defer stop()
+
+	offset := committed
+	for r.store != nil {
+		var err error
+		offset, err = r.store.Load(ctx, topic, partition)
+		if errors.Is(err, ErrOffsetNotFound) {
+			offset = kafka.FirstOffset
+			break
+		}
+		if err == nil {
+			break
+		}
+		r.logger.Error("failed to load stored offset", "topic", topic, "partition", partition, "error", err)
+		if !sleepContext(ctx, time.Second) {
+			return
+		}
+	}
+
+	reader := r.open(topic, partition)
+	defer reader.Close()
+	if err := reader.SetOffset(offset); err != nil {
+		r.logger.Error("failed to seek to starting offset", "topic", topic, "partition", partition, "offset", offset, "error", err)
+		return
+	}
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Error("failed to fetch message", "topic", topic, "partition", partition, "error", err)
+			if !sleepContext(ctx, 100*time.Millisecond) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case r.messages <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// FetchMessage returns the next message from any assigned partition
+func (r *generationReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if r.err != nil {
+		return kafka.Message{}, r.err
+	}
+
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	case <-r.done:
+		return kafka.Message{}, io.EOF
+	}
+}
+
+// CommitMessages commits the messages' offsets to the group. With an OffsetStore it does
+// nothing: offsets are saved to the store by the application.
+func (r *generationReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if r.store != nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	gen, genCtx := r.current, r.genCtx
+	r.mu.Unlock()
+	if gen == nil {
+		return nil
+	}
+
+	// Only partitions that are still assigned can be committed
+	assigned := gen.Assignments()
+	offsets := make(map[string]map[int]int64)
+	for _, msg := range msgs {
+		if _, ok := assigned[msg.Topic][msg.Partition]; !ok {
+			continue
+		}
+		if offsets[msg.Topic] == nil {
+			offsets[msg.Topic] = make(map[int]int64)
+		}
+		if next, ok := offsets[msg.Topic][msg.Partition]; !ok || msg.Offset+1 > next {
+			offsets[msg.Topic][msg.Partition] = msg.Offset + 1
+		}
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	err := gen.CommitOffsets(offsets)
+	if err != nil && genCtx.Err() != nil {
+		// A rebalance ended the generation first; the partitions' next owners resume from the
+		// last commit that went through
+		return nil
+	}
+	return err
+}
+
+// Close leaves the consumer group and stops reading
+func (r *generationReader) Close() error {
+	if r.err != nil {
+		return nil
+	}
+
+	r.cancel()
+	err := r.assigner.Close()
+	<-r.done
+	return err
+}
+
+// sleepContext waits for d and reports whether ctx is still active
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,372 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOffsetStore implements OffsetStore in memory
+type memoryOffsetStore struct {
+	mu      sync.Mutex
+	offsets map[string]int64
+}
+
+func newMemoryOffsetStore() *memoryOffsetStore {
+	return &memoryOffsetStore{offsets: make(map[string]int64)}
+}
+
+func (s *memoryOffsetStore) Load(ctx context.Context, topic string, partition int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset, ok := s.offsets[fmt.Sprintf("%s/%d", topic, partition)]
+	if !ok {
+		return 0, ErrOffsetNotFound
+	}
+	return offset, nil
+}
+
+func (s *memoryOffsetStore) Save(ctx context.Context, topic string, partition int, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offsets[fmt.Sprintf("%s/%d", topic, partition)] = offset
+	return nil
+}
+
+// fakeGeneration runs partition readers until ended and records commits
+type fakeGeneration struct {
+	assignments map[string]map[int]int64
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	commits     map[int]int64
+}
+
+// newFakeGeneration assigns partitions of test-topic with no committed offsets
+func newFakeGeneration(partitions ...int) *fakeGeneration {
+	return newTopicGeneration("test-topic", nil, partitions...)
+}
+
+// newTopicGeneration assigns partitions of topic starting at their committed offsets
+func newTopicGeneration(topic string, committed map[int]int64, partitions ...int) *fakeGeneration {
+	ctx, cancel := context.WithCancel(context.Background())
+	offsets := make(map[int]int64, len(partitions))
+	for _, partition := range partitions {
+		offset, ok := committed[partition]
+		if !ok {
+			offset = kafka.FirstOffset
+		}
+		offsets[partition] = offset
+	}
+	return &fakeGeneration{
+		assignments: map[string]map[int]int64{topic: offsets},
+		ctx:         ctx,
+		cancel:      cancel,
+		commits:     make(map[int]int64),
+	}
+}
+
+func (g *fakeGeneration) Assignments() map[string]map[int]int64 {
+	return g.assignments
+}
+
+func (g *fakeGeneration) CommitOffsets(offsets map[string]map[int]int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, partitions := range offsets {
+		for partition, offset := range partitions {
+			g.commits[partition] = offset
+		}
+	}
+	return nil
+}
+
+// committed returns the offsets committed during the generation
+func (g *fakeGeneration) committed() map[int]int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return maps.Clone(g.commits)
+}
+
+func (g *fakeGeneration) Start(fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+// end simulates a rebalance, waiting for the generation's readers to stop
+func (g *fakeGeneration) end() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+// fakeAssigner hands out generations sent on gens
+type fakeAssigner struct {
+	gens    chan *fakeGeneration
+	mu      sync.Mutex
+	current *fakeGeneration
+}
+
+func newFakeAssigner() *fakeAssigner {
+	return &fakeAssigner{gens: make(chan *fakeGeneration, 1)}
+}
+
+func (a *fakeAssigner) Next(ctx context.Context) (generation, error) {
+	select {
+	case gen := <-a.gens:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.current != nil {
+			// Like kafka-go, the previous generation ends before the next one starts
+			a.current.end()
+		}
+		a.current = gen
+		return gen, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (a *fakeAssigner) generation() *fakeGeneration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+func (a *fakeAssigner) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.current != nil {
+		a.current.end()
+	}
+	return nil
+}
+
+// fakePartitionLog holds the messages of a topic's partitions
+type fakePartitionLog struct {
+	mu   sync.Mutex
+	msgs map[int][]kafka.Message
+}
+
+func newFakePartitionLog(partitions map[int]int64) *fakePartitionLog {
+	l := &fakePartitionLog{msgs: make(map[int][]kafka.Message)}
+	for partition, count := range partitions {
+		for offset := int64(0); offset < count; offset++ {
+			l.msgs[partition] = append(l.msgs[partition], testMessage(partition, offset))
+		}
+	}
+	return l
+}
+
+func (l *fakePartitionLog) open(topic string, partition int) partitionReader {
+	return &fakePartitionReader{log: l, partition: partition}
+}
+
+// fakePartitionReader reads one partition of a fakePartitionLog
+type fakePartitionReader struct {
+	log       *fakePartitionLog
+	partition int
+	next      int64
+}
+
+func (r *fakePartitionReader) SetOffset(offset int64) error {
+	if offset == kafka.FirstOffset {
+		offset = 0
+	}
+	r.next = offset
+	return nil
+}
+
+func (r *fakePartitionReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.log.mu.Lock()
+	msgs := r.log.msgs[r.partition]
+	r.log.mu.Unlock()
+
+	if r.next < int64(len(msgs)) {
+		msg := msgs[r.next]
+		r.next++
+		return msg, nil
+	}
+	// Block like a reader at the end of the partition
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakePartitionReader) Close() error {
+	return nil
+}
+
+// fetchN reads n messages from the reader
+func fetchN(t *testing.T, reader messageReader, n int) []kafka.Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	msgs := make([]kafka.Message, 0, n)
+	for len(msgs) < n {
+		msg, err := reader.FetchMessage(ctx)
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// offsetsByPartition groups message offsets by partition
+func offsetsByPartition(msgs []kafka.Message) map[int][]int64 {
+	offsets := make(map[int][]int64)
+	for _, msg := range msgs {
+		offsets[msg.Partition] = append(offsets[msg.Partition], msg.Offset)
+	}
+	return offsets
+}
+
+// assertNoMoreMessages checks that the reader has nothing left to return
+func assertNoMoreMessages(t *testing.T, reader messageReader) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := reader.FetchMessage(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGenerationReader_StartsAtStoredOffsets(t *testing.T) {
+	store := newMemoryOffsetStore()
+	require.NoError(t, store.Save(context.Background(), "test-topic", 0, 3))
+	log := newFakePartitionLog(map[int]int64{0: 5, 1: 2})
+
+	assigner := newFakeAssigner()
+	assigner.gens <- newFakeGeneration(0, 1)
+	reader := newGenerationReader(assigner, store, log.open, &captureLogger{})
+	defer reader.Close()
+
+	// Partition 0 resumes at the stored offset, partition 1 has none and starts at the beginning
+	msgs := fetchN(t, reader, 4)
+	assert.Equal(t, map[int][]int64{0: {3, 4}, 1: {0, 1}}, offsetsByPartition(msgs))
+	assertNoMoreMessages(t, reader)
+
+	// Kafka commits are skipped, the store is left to the application
+	require.NoError(t, reader.CommitMessages(context.Background(), msgs...))
+	offset, err := store.Load(context.Background(), "test-topic", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), offset)
+}
+
+func TestGenerationReader_ReloadsOffsetsOnRebalance(t *testing.T) {
+	store := newMemoryOffsetStore()
+	log := newFakePartitionLog(map[int]int64{0: 4, 1: 4})
+
+	assigner := newFakeAssigner()
+	assigner.gens <- newFakeGeneration(0)
+	reader := newGenerationReader(assigner, store, log.open, &captureLogger{})
+	defer reader.Close()
+
+	msgs := fetchN(t, reader, 2)
+	assert.Equal(t, map[int][]int64{0: {0, 1}}, offsetsByPartition(msgs))
+	require.NoError(t, store.Save(context.Background(), "test-topic", 0, 2))
+
+	// Meanwhile another member processed part of partition 1
+	require.NoError(t, store.Save(context.Background(), "test-topic", 1, 3))
+
+	// After the rebalance both partitions resume from the store, not from what was fetched
+	rebalanced := newFakeGeneration(0, 1)
+	assigner.gens <- rebalanced
+	require.Eventually(t, func() bool { return assigner.generation() == rebalanced }, time.Second, 5*time.Millisecond)
+	msgs = fetchN(t, reader, 3)
+	assert.Equal(t, map[int][]int64{0: {2, 3}, 1: {3}}, offsetsByPartition(msgs))
+	assertNoMoreMessages(t, reader)
+}
+
+// failingOnceStore fails the first load, like a database that is briefly unavailable
+type failingOnceStore struct {
+	OffsetStore
+	failed bool
+}
+
+func (s *failingOnceStore) Load(ctx context.Context, topic string, partition int) (int64, error) {
+	if !s.failed {
+		s.failed = true
+		return 0, errors.New("connection refused")
+	}
+	return s.OffsetStore.Load(ctx, topic, partition)
+}
+
+func TestGenerationReader_RetriesLoadErrors(t *testing.T) {
+	store := newMemoryOffsetStore()
+	require.NoError(t, store.Save(context.Background(), "test-topic", 0, 1))
+	log := newFakePartitionLog(map[int]int64{0: 2})
+	logger := &captureLogger{}
+
+	assigner := newFakeAssigner()
+	assigner.gens <- newFakeGeneration(0)
+	reader := newGenerationReader(assigner, &failingOnceStore{OffsetStore: store}, log.open, logger)
+	defer reader.Close()
+
+	// The message before the stored offset must never be delivered
+	msgs := fetchN(t, reader, 1)
+	assert.Equal(t, int64(1), msgs[0].Offset)
+	assert.Len(t, logger.find("failed to load stored offset"), 1)
+}
+
+func TestGenerationReader_Close(t *testing.T) {
+	assigner := newFakeAssigner()
+	assigner.gens <- newFakeGeneration(0)
+	reader := newGenerationReader(assigner, newMemoryOffsetStore(), newFakePartitionLog(nil).open, &captureLogger{})
+
+	require.NoError(t, reader.Close())
+	_, err := reader.FetchMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestConsumer_OffsetStoreResumesAfterRestart(t *testing.T) {
+	store := newMemoryOffsetStore()
+	log := newFakePartitionLog(map[int]int64{0: 6})
+
+	var mu sync.Mutex
+	var processed []int64
+
+	// run consumes until the handler has seen the message at stopAt, saving offsets as it goes
+	run := func(stopAt int64) {
+		config := NewDefaultConfig()
+		config.Topic = "test-topic"
+		config.OffsetStore = store
+
+		assigner := newFakeAssigner()
+		assigner.gens <- newFakeGeneration(0)
+		c := newConsumer(config, newGenerationReader(assigner, store, log.open, &captureLogger{}))
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		err := c.Consume(ctx, func(msg kafka.Message) error {
+			mu.Lock()
+			processed = append(processed, msg.Offset)
+			mu.Unlock()
+			if err := store.Save(ctx, msg.Topic, msg.Partition, msg.Offset+1); err != nil {
+				return err
+			}
+			if msg.Offset == stopAt {
+				cancel()
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	}
+
+	run(2)
+	run(5)
+
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5}, processed)
+}
@@ -2,36 +2,125 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
+// defaultCloseTimeout is how long Close waits for async writes when CloseTimeout is not set
+const defaultCloseTimeout = 10 * time.Second
+
+// ErrProducerClosed is returned when producing on a closed producer
+var ErrProducerClosed = errors.New("producer is closed")
+
+// messageWriter is the subset of kafka.Writer used for producing messages
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 // Producer represents a Kafka producer
 type Producer struct {
-	writer *kafka.Writer
-	config *KafkaConfig
+	writer       messageWriter
+	config       *KafkaConfig
+	logger       Logger
+	interceptors []ProducerInterceptor
+
+	// Async writes still in flight, which Close waits for
+	mu       sync.Mutex
+	closed   bool
+	pending  sync.WaitGroup
+	inFlight int64
 }
 
-// NewProducer creates a new Kafka producer with the given configuration
+// NewValidatedProducer creates a new Kafka producer like NewProducer, but returns the
+// config's validation error instead of logging it
+func NewValidatedProducer(config *KafkaConfig) (*Producer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return openProducer(config), nil
+}
+
+// NewProducer creates a new Kafka producer with the given configuration.
+// An invalid config is logged; use NewValidatedProducer to get the error instead.
 func NewProducer(config *KafkaConfig) *Producer {
+	if err := config.Validate(); err != nil {
+		loggerFor(config).Error("producer created with invalid config", "error", err)
+	}
+	return openProducer(config)
+}
+
+// openProducer creates a producer writing to the configured brokers
+func openProducer(config *KafkaConfig) *Producer {
+	balancer, err := newBalancer(config)
+	if err != nil {
+		loggerFor(config).Warn("falling back to hash balancer", "error", err)
+		balancer = &kafka.Hash{}
+	}
+
 	// Configure the writer with retry and idempotence settings
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(config.Brokers...),
 		Topic:        config.Topic,
-		Balancer:     &kafka.Hash{},
+		Balancer:     balancer,
 		RequiredAcks: kafka.RequireAll, // Wait for all replicas to acknowledge
 		MaxAttempts:  config.MaxRetries,
 		Async:        config.AsyncProducer, // Use the configuration value
 	}
 
+	return newProducer(config, writer)
+}
+
+// newProducer creates a producer around the given writer
+func newProducer(config *KafkaConfig, writer messageWriter) *Producer {
 	return &Producer{
 		writer: writer,
 		config: config,
+		logger: loggerFor(config),
 	}
 }
 
+// Use appends interceptors that are applied, in order, to every message before it is written.
+// It must be called before the producer is used.
+func (p *Producer) Use(interceptors ...ProducerInterceptor) {
+	p.interceptors = append(p.interceptors, interceptors...)
+}
+
+// intercept runs the interceptor chain over a message
+func (p *Producer) intercept(ctx context.Context, msg *kafka.Message) error {
+	for _, interceptor := range p.interceptors {
+		if err := interceptor(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interceptBatch runs the interceptor chain over a copy of the messages,
+// leaving the caller's slice and headers untouched
+func (p *Producer) interceptBatch(ctx context.Context, messages []kafka.Message) ([]kafka.Message, error) {
+	if len(p.interceptors) == 0 {
+		return messages, nil
+	}
+
+	intercepted := make([]kafka.Message, len(messages))
+	copy(intercepted, messages)
+	for i := range intercepted {
+		// Headers are cloned so appends can't write into spare capacity of the caller's slice
+		intercepted[i].Headers = slices.Clone(intercepted[i].Headers)
+		if err := p.intercept(ctx, &intercepted[i]); err != nil {
+			return nil, err
+		}
+	}
+	return intercepted, nil
+}
+
 // Produce sends a message to Kafka with retries and backoff
 func (p *Producer) Produce(ctx context.Context, key, value []byte) error {
 	msg := kafka.Message{
@@ -40,6 +129,10 @@ func (p *Producer) Produce(ctx context.Context, key, value []byte) error {
 		Time:  time.Now(),
 	}
 
+	if err := p.intercept(ctx, &msg); err != nil {
+		return err
+	}
+
 	// If async is enabled, use WriteMessages directly without retry handling
 	// as the kafka-go library will handle retries internally for async mode
 	if p.config.AsyncProducer {
@@ -82,24 +175,38 @@ func (p *Producer) ProduceAsync(ctx context.Context, key, value []byte) {
 		Time:  time.Now(),
 	}
 
+	if err := p.intercept(ctx, &msg); err != nil {
+		p.logger.Error("message rejected by producer interceptor", "error", err)
+		return
+	}
+
 	// Write message asynchronously
+	if !p.startAsync() {
+		p.logger.Error("message dropped", "error", ErrProducerClosed)
+		return
+	}
 	go func() {
+		defer p.finishAsync()
 		if err := p.writer.WriteMessages(ctx, msg); err != nil {
-			// Log error or handle it as needed
-			fmt.Printf("Error in async message production: %v\n", err)
+			// Keys can hold user data, so only their size is logged
+			p.logger.Error("async produce failed", "topic", p.config.Topic, "key_bytes", len(msg.Key), "error", err)
 		}
 	}()
 }
 
 // ProduceBatch sends multiple messages to Kafka with retries and backoff
 func (p *Producer) ProduceBatch(ctx context.Context, messages []kafka.Message) error {
+	messages, err := p.interceptBatch(ctx, messages)
+	if err != nil {
+		return err
+	}
+
 	// If async is enabled, use WriteMessages directly without retry handling
 	if p.config.AsyncProducer {
 		return p.writer.WriteMessages(ctx, messages...)
 	}
 
 	// Synchronous mode with retries and backoff
-	var err error
 	for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
 		// Try to write the messages
 		err = p.writer.WriteMessages(ctx, messages...)
@@ -127,16 +234,70 @@ func (p *Producer) ProduceBatch(ctx context.Context, messages []kafka.Message) e
 
 // ProduceBatchAsync sends multiple messages to Kafka asynchronously
 func (p *Producer) ProduceBatchAsync(ctx context.Context, messages []kafka.Message) {
+	messages, err := p.interceptBatch(ctx, messages)
+	if err != nil {
+		p.logger.Error("batch rejected by producer interceptor", "error", err)
+		return
+	}
+
 	// Write messages asynchronously
+	if !p.startAsync() {
+		p.logger.Error("batch dropped", "error", ErrProducerClosed, "messages", len(messages))
+		return
+	}
 	go func() {
+		defer p.finishAsync()
 		if err := p.writer.WriteMessages(ctx, messages...); err != nil {
-			// Log error or handle it as needed
-			fmt.Printf("Error in async batch production: %v\n", err)
+			p.logger.Error("async batch produce failed", "topic", p.config.Topic, "messages", len(messages), "error", err)
 		}
 	}()
 }
 
-// Close closes the producer
+// startAsync registers an async write, reporting false once the producer is closed
+func (p *Producer) startAsync() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.pending.Add(1)
+	atomic.AddInt64(&p.inFlight, 1)
+	return true
+}
+
+// finishAsync marks an async write as done
+func (p *Producer) finishAsync() {
+	atomic.AddInt64(&p.inFlight, -1)
+	p.pending.Done()
+}
+
+// Close waits up to CloseTimeout for messages produced with ProduceAsync and
+// ProduceBatchAsync to be written, then closes the writer, flushing its buffers. Writes
+// still pending after the timeout fail with the closed writer and are reported as lost.
 func (p *Producer) Close() error {
-	return p.writer.Close()
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	timeout := p.config.CloseTimeout
+	if timeout <= 0 {
+		timeout = defaultCloseTimeout
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(done)
+	}()
+
+	var pendingErr error
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		pending := atomic.LoadInt64(&p.inFlight)
+		p.logger.Error("closing producer with async writes pending", "pending", pending, "timeout", timeout)
+		pendingErr = fmt.Errorf("%d async writes still pending after %v", pending, timeout)
+	}
+
+	return errors.Join(pendingErr, p.writer.Close())
 }
@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowWriter delays every write until its delay passes or release is closed
+type slowWriter struct {
+	fakeWriter
+	delay   time.Duration
+	release chan struct{}
+	closed  bool
+}
+
+func (w *slowWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	select {
+	case <-time.After(w.delay):
+	case <-w.release:
+	}
+	return w.fakeWriter.WriteMessages(ctx, msgs...)
+}
+
+func (w *slowWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func TestProducer_CloseWaitsForAsyncWrites(t *testing.T) {
+	writer := &slowWriter{delay: 50 * time.Millisecond}
+	p := newProducer(NewDefaultConfig(), writer)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		p.ProduceAsync(ctx, []byte(fmt.Sprintf("key-%d", i)), []byte("value"))
+	}
+	p.ProduceBatchAsync(ctx, []kafka.Message{{Value: []byte("a")}, {Value: []byte("b")}})
+
+	require.NoError(t, p.Close())
+	assert.Len(t, writer.written(), 12, "every async message is delivered before Close returns")
+	assert.True(t, writer.closed)
+}
+
+func TestProducer_CloseTimesOut(t *testing.T) {
+	writer := &slowWriter{delay: time.Hour, release: make(chan struct{})}
+	defer close(writer.release)
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.CloseTimeout = 50 * time.Millisecond
+	config.Logger = logger
+	p := newProducer(config, writer)
+
+	p.ProduceAsync(context.Background(), []byte("key"), []byte("value"))
+
+	start := time.Now()
+	err := p.Close()
+	assert.ErrorContains(t, err, "1 async writes still pending after 50ms")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.True(t, writer.closed, "the writer is closed even when writes are pending")
+	assert.Len(t, logger.find("closing producer with async writes pending"), 1)
+}
+
+func TestProducer_ProduceAsyncAfterClose(t *testing.T) {
+	writer := &slowWriter{}
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Logger = logger
+	p := newProducer(config, writer)
+	require.NoError(t, p.Close())
+
+	p.ProduceAsync(context.Background(), []byte("key"), []byte("value"))
+	p.ProduceBatchAsync(context.Background(), []kafka.Message{{Value: []byte("a")}})
+
+	assert.Empty(t, writer.written())
+	assert.Len(t, logger.find("message dropped"), 1)
+	assert.Len(t, logger.find("batch dropped"), 1)
+}
+
+func TestProducer_AsyncErrorIsLogged(t *testing.T) {
+	writer := &slowWriter{fakeWriter: fakeWriter{err: errors.New("broker unavailable")}}
+	logger := &captureLogger{}
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.Logger = logger
+	p := newProducer(config, writer)
+
+	p.ProduceAsync(context.Background(), []byte("order-42"), []byte("value"))
+	p.ProduceBatchAsync(context.Background(), []kafka.Message{{Value: []byte("a")}, {Value: []byte("b")}})
+	require.NoError(t, p.Close())
+
+	entries := logger.find("async produce failed")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "ERROR", entries[0].level)
+	assert.Equal(t, "test-topic", entries[0].fields["topic"])
+	assert.Equal(t, 8, entries[0].fields["key_bytes"])
+	assert.NotContains(t, entries[0].fields, "key", "the key itself is not logged")
+	assert.EqualError(t, entries[0].fields["error"].(error), "broker unavailable")
+
+	entries = logger.find("async batch produce failed")
+	require.Len(t, entries, 1)
+	assert.Equal(t, 2, entries[0].fields["messages"])
+}
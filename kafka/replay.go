@@ -0,0 +1,347 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// replayBatchSize is the number of messages written to the destination at once
+const replayBatchSize = 100
+
+// Headers added to every replayed message, recording where it was copied from
+const (
+	HeaderReplaySourceTopic     = "x-replay-source-topic"
+	HeaderReplaySourcePartition = "x-replay-source-partition"
+	HeaderReplaySourceOffset    = "x-replay-source-offset"
+	HeaderReplaySourceTime      = "x-replay-source-time" // RFC 3339 timestamp of the original message
+)
+
+// ReplaySpec describes a range of a topic to copy into another topic
+type ReplaySpec struct {
+	SourceTopic      string
+	DestinationTopic string
+	Partitions       []int // Source partitions to replay (default all)
+
+	// The range is [start, end) in every partition. Times select the first message written
+	// at or after them; StartOffsets and EndOffsets override the times for their partitions.
+	// The range defaults to everything in the log when Replay starts.
+	StartTime    time.Time
+	EndTime      time.Time
+	StartOffsets map[int]int64
+	EndOffsets   map[int]int64
+
+	Filter            func(msg kafka.Message) bool                   // Messages to copy (default all)
+	Transform         func(msg kafka.Message) (kafka.Message, error) // Rewrites a message before it is written
+	MessagesPerSecond int                                            // Limits the produce rate (default unlimited)
+	DryRun            bool                                           // Count matching messages without writing them
+	OnProgress        func(progress PartitionReplay)                 // Called after each written batch and finished partition
+}
+
+// PartitionReplay counts the messages replayed from one source partition
+type PartitionReplay struct {
+	Partition int
+	Start     int64 // First offset of the range
+	End       int64 // Offset after the range
+	Next      int64 // Next offset to read; equals End once the partition is done
+	Read      int64
+	Matched   int64 // Messages accepted by the filter
+	Produced  int64 // Messages written to the destination; 0 in a dry run
+}
+
+// ReplayReport summarizes a replay, with partitions ordered by number
+type ReplayReport struct {
+	Partitions []PartitionReplay
+	Read       int64
+	Matched    int64
+	Produced   int64
+	DryRun     bool
+}
+
+// Replay copies messages in a range of spec.SourceTopic to spec.DestinationTopic, e.g. to
+// re-drive an hour of history through a fixed consumer. Each partition is read without a
+// consumer group, so no offsets are committed. Keys and headers are kept, provenance headers
+// are added, and the destination partition is chosen by config's balancer. The report is
+// returned with the counts so far if the replay fails part way.
+func Replay(ctx context.Context, config *KafkaConfig, spec ReplaySpec) (ReplayReport, error) {
+	balancer, err := newBalancer(config)
+	if err != nil {
+		return ReplayReport{DryRun: spec.DryRun}, err
+	}
+
+	client := &kafka.Client{Addr: kafka.TCP(config.Brokers...)}
+	open := func(topic string, partition int) partitionReader {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   config.Brokers,
+			Topic:     topic,
+			Partition: partition,
+			MinBytes:  10e3, // 10KB
+			MaxBytes:  10e6, // 10MB
+		})
+	}
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Topic:        spec.DestinationTopic,
+		Balancer:     balancer,
+		RequiredAcks: kafka.RequireAll,
+		MaxAttempts:  config.MaxRetries,
+		BatchSize:    replayBatchSize,
+		BatchTimeout: 10 * time.Millisecond, // Batches are assembled before each write
+	}
+	defer writer.Close()
+
+	return replay(ctx, client, open, writer, spec)
+}
+
+// replay implements Replay against the given admin API, readers and writer
+func replay(ctx context.Context, admin offsetAdmin, open func(topic string, partition int) partitionReader, writer messageWriter, spec ReplaySpec) (ReplayReport, error) {
+	report := ReplayReport{DryRun: spec.DryRun}
+
+	switch {
+	case spec.SourceTopic == "" || spec.DestinationTopic == "":
+		return report, errors.New("replay requires a source and destination topic")
+	case spec.SourceTopic == spec.DestinationTopic:
+		return report, fmt.Errorf("replay source and destination are both %s", spec.SourceTopic)
+	case !spec.StartTime.IsZero() && !spec.EndTime.IsZero() && spec.EndTime.Before(spec.StartTime):
+		return report, errors.New("replay end time is before its start time")
+	case spec.MessagesPerSecond < 0:
+		return report, fmt.Errorf("invalid replay rate %d messages per second", spec.MessagesPerSecond)
+	}
+
+	ranges, err := replayRanges(ctx, admin, spec)
+	if err != nil {
+		return report, err
+	}
+
+	pacer := &replayPacer{rate: spec.MessagesPerSecond}
+	for _, r := range ranges {
+		progress, err := replayPartition(ctx, open, writer, pacer, spec, r)
+		report.Partitions = append(report.Partitions, progress)
+		report.Read += progress.Read
+		report.Matched += progress.Matched
+		report.Produced += progress.Produced
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// replayRanges resolves the offset range of each selected partition, ordered by partition
+func replayRanges(ctx context.Context, admin offsetAdmin, spec ReplaySpec) ([]PartitionReplay, error) {
+	metadata, err := admin.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{spec.SourceTopic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic: %w", err)
+	}
+	if len(metadata.Topics) != 1 {
+		return nil, fmt.Errorf("failed to describe topic %s: not found", spec.SourceTopic)
+	}
+	if err := metadata.Topics[0].Error; err != nil {
+		return nil, fmt.Errorf("failed to describe topic %s: %w", spec.SourceTopic, err)
+	}
+
+	selected := make(map[int]bool)
+	for _, partition := range spec.Partitions {
+		selected[partition] = true
+	}
+	var requests []kafka.OffsetRequest
+	for _, partition := range metadata.Topics[0].Partitions {
+		if len(selected) == 0 || selected[partition.ID] {
+			requests = append(requests, kafka.FirstOffsetOf(partition.ID), kafka.LastOffsetOf(partition.ID))
+			delete(selected, partition.ID)
+		}
+	}
+	if len(selected) > 0 {
+		missing := make([]int, 0, len(selected))
+		for partition := range selected {
+			missing = append(missing, partition)
+		}
+		sort.Ints(missing)
+		return nil, fmt.Errorf("partitions %v of topic %s not found", missing, spec.SourceTopic)
+	}
+
+	offsets, err := admin.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{spec.SourceTopic: requests}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+	infos := make(map[TopicPartition]OffsetInfo)
+	for _, p := range offsets.Topics[spec.SourceTopic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets for %s/%d: %w", spec.SourceTopic, p.Partition, p.Error)
+		}
+		infos[TopicPartition{Topic: spec.SourceTopic, Partition: p.Partition}] = OffsetInfo{Committed: -1, LogStart: p.FirstOffset, LogEnd: p.LastOffset}
+	}
+
+	var starts, ends map[TopicPartition]int64
+	if !spec.StartTime.IsZero() {
+		if starts, err = offsetsForTime(ctx, admin, infos, spec.StartTime); err != nil {
+			return nil, err
+		}
+	}
+	if !spec.EndTime.IsZero() {
+		if ends, err = offsetsForTime(ctx, admin, infos, spec.EndTime); err != nil {
+			return nil, err
+		}
+	}
+
+	ranges := make([]PartitionReplay, 0, len(infos))
+	for tp, info := range infos {
+		start, end := info.LogStart, info.LogEnd
+		if offset, ok := starts[tp]; ok {
+			start = offset
+		}
+		if offset, ok := spec.StartOffsets[tp.Partition]; ok {
+			start = offset
+		}
+		if offset, ok := ends[tp]; ok {
+			end = offset
+		}
+		if offset, ok := spec.EndOffsets[tp.Partition]; ok {
+			end = offset
+		}
+
+		// Only offsets still in the log can be read
+		start = min(max(start, info.LogStart), info.LogEnd)
+		end = max(min(end, info.LogEnd), start)
+		ranges = append(ranges, PartitionReplay{Partition: tp.Partition, Start: start, End: end, Next: start})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Partition < ranges[j].Partition })
+	return ranges, nil
+}
+
+// replayPartition copies the partition's range, returning its counts
+func replayPartition(ctx context.Context, open func(topic string, partition int) partitionReader, writer messageWriter, pacer *replayPacer, spec ReplaySpec, progress PartitionReplay) (PartitionReplay, error) {
+	report := func() {
+		if spec.OnProgress != nil {
+			spec.OnProgress(progress)
+		}
+	}
+
+	// An empty range has nothing to fetch, and fetching would wait for new messages
+	if progress.Start >= progress.End {
+		report()
+		return progress, nil
+	}
+
+	reader := open(spec.SourceTopic, progress.Partition)
+	defer reader.Close()
+	if err := reader.SetOffset(progress.Start); err != nil {
+		return progress, fmt.Errorf("failed to seek %s/%d to offset %d: %w", spec.SourceTopic, progress.Partition, progress.Start, err)
+	}
+
+	batch := make([]kafka.Message, 0, pacer.batchSize())
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := writer.WriteMessages(ctx, batch...); err != nil {
+			return fmt.Errorf("failed to write replayed messages to %s: %w", spec.DestinationTopic, err)
+		}
+		progress.Produced += int64(len(batch))
+		batch = batch[:0]
+		report()
+		return nil
+	}
+
+	for progress.Next < progress.End {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			return progress, fmt.Errorf("failed to read %s/%d at offset %d: %w", spec.SourceTopic, progress.Partition, progress.Next, err)
+		}
+		// Compaction can leave gaps, so the range ends at the first offset past it
+		if msg.Offset >= progress.End {
+			break
+		}
+		progress.Next = msg.Offset + 1
+		progress.Read++
+
+		if spec.Filter != nil && !spec.Filter(msg) {
+			continue
+		}
+		progress.Matched++
+
+		replayed := replayMessage(msg)
+		if spec.Transform != nil {
+			if replayed, err = spec.Transform(replayed); err != nil {
+				return progress, fmt.Errorf("failed to transform %s/%d at offset %d: %w", spec.SourceTopic, progress.Partition, msg.Offset, err)
+			}
+		}
+		if spec.DryRun {
+			continue
+		}
+
+		if err := pacer.wait(ctx); err != nil {
+			return progress, err
+		}
+		batch = append(batch, replayed)
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return progress, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return progress, err
+	}
+	progress.Next = progress.End
+	report()
+	return progress, nil
+}
+
+// replayMessage copies a message for the destination topic, recording its origin in headers.
+// The timestamp is left for the producer to set, so destination retention counts from the
+// replay rather than from the original write.
+func replayMessage(msg kafka.Message) kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers)+4)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderReplaySourceTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderReplaySourcePartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderReplaySourceOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderReplaySourceTime, Value: []byte(msg.Time.UTC().Format(time.RFC3339Nano))},
+	)
+
+	return kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
+
+// replayPacer spaces out produced messages to stay within a rate
+type replayPacer struct {
+	rate int // Messages per second, 0 for unlimited
+	next time.Time
+}
+
+// batchSize returns how many messages to write at once; batches never hold more than a
+// second's worth, so a slow rate still writes steadily
+func (p *replayPacer) batchSize() int {
+	if p.rate > 0 {
+		return min(p.rate, replayBatchSize)
+	}
+	return replayBatchSize
+}
+
+// wait blocks until the next message may be produced
+func (p *replayPacer) wait(ctx context.Context) error {
+	if p.rate == 0 {
+		return nil
+	}
+	now := time.Now()
+	if p.next.After(now) {
+		if !sleepContext(ctx, p.next.Sub(now)) {
+			return ctx.Err()
+		}
+	} else {
+		p.next = now
+	}
+	p.next = p.next.Add(time.Second / time.Duration(p.rate))
+	return nil
+}
@@ -0,0 +1,269 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTimeline returns an admin and log for the "orders" topic, where the message at offset o
+// was written o minutes after logEpoch and even offsets carry "order-" keys
+func newTimeline(partitions, count int) (*fakeGroupAdmin, *fakePartitionLog) {
+	admin := &fakeGroupAdmin{logs: map[string][]fakeLog{}}
+	log := &fakePartitionLog{msgs: make(map[int][]kafka.Message)}
+	for partition := 0; partition < partitions; partition++ {
+		admin.logs["orders"] = append(admin.logs["orders"], fakeLog{start: 0, end: int64(count)})
+		for offset := int64(0); offset < int64(count); offset++ {
+			key := fmt.Sprintf("order-%d-%d", partition, offset)
+			if offset%2 == 1 {
+				key = fmt.Sprintf("user-%d-%d", partition, offset)
+			}
+			log.msgs[partition] = append(log.msgs[partition], kafka.Message{
+				Topic:     "orders",
+				Partition: partition,
+				Offset:    offset,
+				Key:       []byte(key),
+				Value:     []byte("value"),
+				Headers:   []kafka.Header{{Key: "trace-id", Value: []byte(key)}},
+				Time:      logEpoch.Add(time.Duration(offset) * time.Minute),
+			})
+		}
+	}
+	return admin, log
+}
+
+// header returns the value of the message's header, or "" if it has none
+func header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func hasOrderKey(msg kafka.Message) bool {
+	return bytes.HasPrefix(msg.Key, []byte("order-"))
+}
+
+func TestReplay_TimeRangeWithFilter(t *testing.T) {
+	admin, log := newTimeline(2, 10)
+	writer := &fakeWriter{}
+
+	var progress []PartitionReplay
+	report, err := replay(context.Background(), admin, log.open, writer, ReplaySpec{
+		SourceTopic:      "orders",
+		DestinationTopic: "orders-reprocess",
+		StartTime:        logEpoch.Add(2 * time.Minute),
+		EndTime:          logEpoch.Add(6 * time.Minute),
+		Filter:           hasOrderKey,
+		OnProgress:       func(p PartitionReplay) { progress = append(progress, p) },
+	})
+	require.NoError(t, err)
+
+	// Offsets 2-5 are in range in each partition, of which 2 and 4 have order keys
+	assert.Equal(t, []PartitionReplay{
+		{Partition: 0, Start: 2, End: 6, Next: 6, Read: 4, Matched: 2, Produced: 2},
+		{Partition: 1, Start: 2, End: 6, Next: 6, Read: 4, Matched: 2, Produced: 2},
+	}, report.Partitions)
+	assert.Equal(t, int64(8), report.Read)
+	assert.Equal(t, int64(4), report.Matched)
+	assert.Equal(t, int64(4), report.Produced)
+
+	written := writer.written()
+	require.Len(t, written, 4)
+	var keys []string
+	for _, msg := range written {
+		keys = append(keys, string(msg.Key))
+		assert.Empty(t, msg.Topic, "the writer sets the destination topic")
+		assert.True(t, msg.Time.IsZero(), "the destination timestamp is the replay time")
+		assert.Equal(t, string(msg.Key), header(msg, "trace-id"), "original headers are kept")
+		assert.Equal(t, "orders", header(msg, HeaderReplaySourceTopic))
+	}
+	assert.Equal(t, []string{"order-0-2", "order-0-4", "order-1-2", "order-1-4"}, keys)
+
+	first := written[0]
+	assert.Equal(t, "0", header(first, HeaderReplaySourcePartition))
+	assert.Equal(t, "2", header(first, HeaderReplaySourceOffset))
+	assert.Equal(t, logEpoch.Add(2*time.Minute).Format(time.RFC3339Nano), header(first, HeaderReplaySourceTime))
+
+	// Each partition reports its written batch and its completion
+	require.Len(t, progress, 4)
+	assert.Equal(t, report.Partitions[1], progress[3])
+}
+
+func TestReplay_DryRunCountsMatches(t *testing.T) {
+	admin, log := newTimeline(1, 10)
+	writer := &fakeWriter{}
+
+	report, err := replay(context.Background(), admin, log.open, writer, ReplaySpec{
+		SourceTopic:      "orders",
+		DestinationTopic: "orders-reprocess",
+		StartTime:        logEpoch.Add(5 * time.Minute),
+		Filter:           hasOrderKey,
+		DryRun:           true,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(5), report.Read)
+	assert.Equal(t, int64(2), report.Matched)
+	assert.Zero(t, report.Produced)
+	assert.Empty(t, writer.written())
+}
+
+func TestReplay_OffsetsOverrideTimes(t *testing.T) {
+	admin, log := newTimeline(3, 10)
+	writer := &fakeWriter{}
+
+	report, err := replay(context.Background(), admin, log.open, writer, ReplaySpec{
+		SourceTopic:      "orders",
+		DestinationTopic: "orders-reprocess",
+		Partitions:       []int{1, 2},
+		StartTime:        logEpoch.Add(8 * time.Minute),
+		StartOffsets:     map[int]int64{1: 3},
+		EndOffsets:       map[int]int64{1: 5, 2: 50},
+		Transform: func(msg kafka.Message) (kafka.Message, error) {
+			msg.Value = bytes.ToUpper(msg.Value)
+			return msg, nil
+		},
+	})
+	require.NoError(t, err)
+
+	// Partition 1 uses its offsets, partition 2 starts at the time and ends at the log end
+	assert.Equal(t, []PartitionReplay{
+		{Partition: 1, Start: 3, End: 5, Next: 5, Read: 2, Matched: 2, Produced: 2},
+		{Partition: 2, Start: 8, End: 10, Next: 10, Read: 2, Matched: 2, Produced: 2},
+	}, report.Partitions)
+	for _, msg := range writer.written() {
+		assert.Equal(t, "VALUE", string(msg.Value))
+		assert.NotEmpty(t, header(msg, HeaderReplaySourceOffset), "the transform sees the provenance headers")
+	}
+}
+
+func TestReplay_EmptyRangeDoesNotRead(t *testing.T) {
+	admin, log := newTimeline(1, 10)
+
+	// Reading an empty range would block waiting for new messages
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	report, err := replay(ctx, admin, log.open, &fakeWriter{}, ReplaySpec{
+		SourceTopic:      "orders",
+		DestinationTopic: "orders-reprocess",
+		StartTime:        logEpoch.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []PartitionReplay{{Partition: 0, Start: 10, End: 10, Next: 10}}, report.Partitions)
+}
+
+func TestReplay_RateLimit(t *testing.T) {
+	admin, log := newTimeline(1, 20)
+	writer := &fakeWriter{}
+
+	start := time.Now()
+	report, err := replay(context.Background(), admin, log.open, writer, ReplaySpec{
+		SourceTopic:       "orders",
+		DestinationTopic:  "orders-reprocess",
+		MessagesPerSecond: 200,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(20), report.Produced)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestReplay_InvalidSpec(t *testing.T) {
+	admin, log := newTimeline(1, 10)
+	replayWith := func(spec ReplaySpec) error {
+		_, err := replay(context.Background(), admin, log.open, &fakeWriter{}, spec)
+		return err
+	}
+
+	assert.Error(t, replayWith(ReplaySpec{SourceTopic: "orders"}))
+	assert.Error(t, replayWith(ReplaySpec{SourceTopic: "orders", DestinationTopic: "orders"}))
+	assert.Error(t, replayWith(ReplaySpec{SourceTopic: "orders", DestinationTopic: "copy", StartTime: logEpoch, EndTime: logEpoch.Add(-time.Minute)}))
+	assert.Error(t, replayWith(ReplaySpec{SourceTopic: "orders", DestinationTopic: "copy", MessagesPerSecond: -1}))
+	assert.Error(t, replayWith(ReplaySpec{SourceTopic: "missing", DestinationTopic: "copy"}))
+	assert.ErrorContains(t, replayWith(ReplaySpec{SourceTopic: "orders", DestinationTopic: "copy", Partitions: []int{0, 4}}), "partitions [4]")
+}
+
+func TestReplay_WriteErrorReturnsPartialReport(t *testing.T) {
+	admin, log := newTimeline(2, 3)
+	writer := &fakeWriter{err: fmt.Errorf("broker unavailable")}
+
+	report, err := replay(context.Background(), admin, log.open, writer, ReplaySpec{
+		SourceTopic:      "orders",
+		DestinationTopic: "orders-reprocess",
+	})
+	assert.ErrorContains(t, err, "broker unavailable")
+	require.Len(t, report.Partitions, 1, "the replay stops at the failing partition")
+	assert.Equal(t, int64(3), report.Read)
+	assert.Zero(t, report.Produced)
+}
+
+// TestReplay_Integration runs against a real broker when KAFKA_BROKERS is set,
+// e.g. KAFKA_BROKERS=localhost:9092 with the docker-compose setup.
+func TestReplay_Integration(t *testing.T) {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_BROKERS not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	config := NewDefaultConfig()
+	config.Brokers = strings.Split(brokers, ",")
+	config.Topic = fmt.Sprintf("replay-source-%d", time.Now().UnixNano())
+	config.NumPartitions = 1
+	require.NoError(t, CreateTopic(ctx, config))
+	destination := config.Topic + "-copy"
+
+	// A timeline of three phases; only order messages from the middle one are replayed
+	writer := &kafka.Writer{Addr: kafka.TCP(config.Brokers...), Topic: config.Topic, AllowAutoTopicCreation: true}
+	defer writer.Close()
+	write := func(phase string) {
+		require.NoError(t, writer.WriteMessages(ctx,
+			kafka.Message{Key: []byte("order-" + phase), Value: []byte(phase), Headers: []kafka.Header{{Key: "phase", Value: []byte(phase)}}},
+			kafka.Message{Key: []byte("user-" + phase), Value: []byte(phase)},
+		))
+		time.Sleep(50 * time.Millisecond)
+	}
+	write("before")
+	start := time.Now()
+	write("during")
+	end := time.Now()
+	write("after")
+
+	destConfig := *config
+	destConfig.Topic = destination
+	require.NoError(t, CreateTopic(ctx, &destConfig))
+
+	report, err := Replay(ctx, config, ReplaySpec{
+		SourceTopic:      config.Topic,
+		DestinationTopic: destination,
+		StartTime:        start,
+		EndTime:          end,
+		Filter:           hasOrderKey,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Read)
+	assert.Equal(t, int64(1), report.Produced)
+
+	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: config.Brokers, Topic: destination})
+	defer reader.Close()
+	msg, err := reader.ReadMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "order-during", string(msg.Key))
+	assert.Equal(t, "during", header(msg, "phase"))
+	assert.Equal(t, config.Topic, header(msg, HeaderReplaySourceTopic))
+	assert.Equal(t, "2", header(msg, HeaderReplaySourceOffset))
+}
@@ -0,0 +1,139 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrConsumerActive is returned when seeking while the consumer is consuming
+var ErrConsumerActive = errors.New("consumer is actively consuming")
+
+// offsetSeeker is implemented by readers that can move their position, such as a kafka.Reader
+// without a GroupID
+type offsetSeeker interface {
+	SetOffset(offset int64) error
+	SetOffsetAt(ctx context.Context, t time.Time) error
+}
+
+// enter records a synchronous consume call so seeks are refused while it runs.
+// The returned function records its end.
+func (c *Consumer) enter() func() {
+	c.seekMu.Lock()
+	c.active++
+	c.seekMu.Unlock()
+
+	return func() {
+		c.seekMu.Lock()
+		c.active--
+		c.seekMu.Unlock()
+	}
+}
+
+// SeekToOffset rewinds or advances the consumer so the next message read from the partition is
+// at offset, e.g. to reprocess a window of messages. It returns ErrConsumerActive while a
+// consume call is running.
+//
+// Without a GroupID the reader is moved with kafka-go's SetOffset. With a GroupID, offsets
+// handled so far are committed, the reader leaves the group, the group's offset for the
+// partition is reset and a new reader joins. As with ResetGroupOffsets, this fails with
+// ErrGroupActive while other members of the group are running. Offsets outside the log are
+// clamped to its bounds.
+func (c *Consumer) SeekToOffset(ctx context.Context, partition int, offset int64) error {
+	c.seekMu.Lock()
+	defer c.seekMu.Unlock()
+
+	if err := c.seekable(); err != nil {
+		return err
+	}
+
+	if c.config.GroupID == "" {
+		if partition != 0 {
+			return fmt.Errorf("consumer without a group reads partition 0, cannot seek partition %d", partition)
+		}
+		return c.seekReader(func(seeker offsetSeeker) error {
+			return seeker.SetOffset(offset)
+		})
+	}
+
+	tp := TopicPartition{Topic: c.config.Topic, Partition: partition}
+	return c.seekGroup(ctx, ResetSpec{Target: ResetOffsets, Offsets: map[TopicPartition]int64{tp: offset}})
+}
+
+// SeekToTimestamp moves the consumer in every partition to the first message at or after t,
+// or to the log end where there is none. It follows the same rules as SeekToOffset, using
+// kafka-go's SetOffsetAt without a GroupID.
+func (c *Consumer) SeekToTimestamp(ctx context.Context, t time.Time) error {
+	c.seekMu.Lock()
+	defer c.seekMu.Unlock()
+
+	if err := c.seekable(); err != nil {
+		return err
+	}
+
+	if c.config.GroupID == "" {
+		return c.seekReader(func(seeker offsetSeeker) error {
+			return seeker.SetOffsetAt(ctx, t)
+		})
+	}
+	return c.seekGroup(ctx, ResetSpec{Target: ResetTimestamp, Timestamp: t})
+}
+
+// seekable returns an error if the consumer's position can't be moved. The caller must hold seekMu.
+func (c *Consumer) seekable() error {
+	if c.isConsuming || c.active > 0 {
+		return ErrConsumerActive
+	}
+	if c.config.OffsetStore != nil {
+		return errors.New("cannot seek a consumer with an OffsetStore, save the offsets to the store instead")
+	}
+	return nil
+}
+
+// seekReader moves a reader that isn't in a consumer group
+func (c *Consumer) seekReader(seek func(offsetSeeker) error) error {
+	seeker, ok := c.reader.(offsetSeeker)
+	if !ok {
+		return errors.New("reader does not support seeking")
+	}
+
+	// Messages handled before the seek can't be committed without a group
+	c.commitMutex.Lock()
+	c.uncommitted = make([]kafka.Message, 0)
+	c.commitMutex.Unlock()
+
+	if err := seek(seeker); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+	return nil
+}
+
+// seekGroup resets the group's offsets between closing the reader and opening a new one
+func (c *Consumer) seekGroup(ctx context.Context, spec ResetSpec) error {
+	admin, ok := c.admin.(groupAdmin)
+	if !ok || c.openReader == nil {
+		return errors.New("consumer cannot reset its group's offsets")
+	}
+
+	// Commit what was handled so the reset is the last word on the group's position
+	if err := c.commitOffsets(ctx); err != nil {
+		return fmt.Errorf("error committing offsets before seeking: %w", err)
+	}
+
+	// Leave the group so the reset isn't refused, or overwritten by this reader.
+	// A new reader joins whether or not the reset succeeds.
+	defer func() {
+		c.reader = c.openReader()
+	}()
+	if err := c.reader.Close(); err != nil {
+		return fmt.Errorf("error closing reader before seeking: %w", err)
+	}
+
+	if _, err := resetGroupOffsets(ctx, admin, c.config, spec); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+	return nil
+}
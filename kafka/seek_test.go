@@ -0,0 +1,194 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seekingReader is a fakeReader that records SetOffset and SetOffsetAt calls
+type seekingReader struct {
+	*fakeReader
+	offset int64
+	at     time.Time
+	closed bool
+}
+
+func (r *seekingReader) SetOffset(offset int64) error {
+	r.offset = offset
+	return nil
+}
+
+func (r *seekingReader) SetOffsetAt(ctx context.Context, t time.Time) error {
+	r.at = t
+	return nil
+}
+
+func (r *seekingReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+// newGroupSeekConsumer returns a group consumer whose reader is replaced on every seek
+func newGroupSeekConsumer(admin *fakeGroupAdmin) (*Consumer, *[]*seekingReader) {
+	config := NewDefaultConfig()
+	config.Topic = "orders"
+	readers := []*seekingReader{{fakeReader: newFakeReader()}}
+	c := newConsumer(config, readers[0])
+	c.admin = admin
+	c.openReader = func() messageReader {
+		readers = append(readers, &seekingReader{fakeReader: newFakeReader()})
+		return readers[len(readers)-1]
+	}
+	return c, &readers
+}
+
+func newSeekAdmin() *fakeGroupAdmin {
+	return &fakeGroupAdmin{
+		logs: map[string][]fakeLog{"orders": {{start: 0, end: 50}, {start: 10, end: 80}}},
+		committed: map[TopicPartition]int64{
+			{Topic: "orders", Partition: 0}: 50,
+			{Topic: "orders", Partition: 1}: 80,
+		},
+	}
+}
+
+func TestSeekToOffset_WithoutGroup(t *testing.T) {
+	config := NewDefaultConfig()
+	config.GroupID = ""
+	reader := &seekingReader{fakeReader: newFakeReader()}
+	c := newConsumer(config, reader)
+	c.uncommitted = append(c.uncommitted, kafka.Message{Offset: 9})
+
+	require.NoError(t, c.SeekToOffset(context.Background(), 0, 42))
+	assert.Equal(t, int64(42), reader.offset)
+	assert.Empty(t, c.uncommitted, "offsets from before the seek are dropped")
+
+	assert.ErrorContains(t, c.SeekToOffset(context.Background(), 1, 42), "cannot seek partition 1")
+}
+
+func TestSeekToTimestamp_WithoutGroup(t *testing.T) {
+	config := NewDefaultConfig()
+	config.GroupID = ""
+	reader := &seekingReader{fakeReader: newFakeReader()}
+	c := newConsumer(config, reader)
+
+	require.NoError(t, c.SeekToTimestamp(context.Background(), logEpoch))
+	assert.Equal(t, logEpoch, reader.at)
+}
+
+func TestSeekToOffset_Group(t *testing.T) {
+	admin := newSeekAdmin()
+	c, readers := newGroupSeekConsumer(admin)
+	first := (*readers)[0]
+	c.uncommitted = append(c.uncommitted, kafka.Message{Topic: "orders", Partition: 0, Offset: 49})
+
+	require.NoError(t, c.SeekToOffset(context.Background(), 1, 20))
+
+	assert.Equal(t, []int64{49}, first.committedOffsets(0), "handled messages are committed before leaving the group")
+	assert.True(t, first.closed)
+	require.Len(t, *readers, 2)
+	assert.Same(t, (*readers)[1], c.reader, "a new reader joins from the reset offset")
+	assert.Equal(t, int64(20), admin.committed[TopicPartition{Topic: "orders", Partition: 1}])
+	assert.Equal(t, int64(50), admin.committed[TopicPartition{Topic: "orders", Partition: 0}], "other partitions keep their offsets")
+
+	// Offsets before the log start are clamped
+	require.NoError(t, c.SeekToOffset(context.Background(), 1, 0))
+	assert.Equal(t, int64(10), admin.committed[TopicPartition{Topic: "orders", Partition: 1}])
+}
+
+func TestSeekToTimestamp_Group(t *testing.T) {
+	admin := newSeekAdmin()
+	c, _ := newGroupSeekConsumer(admin)
+
+	require.NoError(t, c.SeekToTimestamp(context.Background(), logEpoch.Add(30*time.Minute)))
+	assert.Equal(t, int64(30), admin.committed[TopicPartition{Topic: "orders", Partition: 0}])
+	assert.Equal(t, int64(30), admin.committed[TopicPartition{Topic: "orders", Partition: 1}])
+}
+
+func TestSeek_GroupWithOtherMembers(t *testing.T) {
+	admin := newSeekAdmin()
+	admin.members = 1
+	c, readers := newGroupSeekConsumer(admin)
+
+	err := c.SeekToOffset(context.Background(), 0, 0)
+	assert.ErrorIs(t, err, ErrGroupActive)
+	assert.Empty(t, admin.commits)
+	assert.Len(t, *readers, 2, "the consumer rejoins even though the reset failed")
+}
+
+func TestSeek_RefusedWhileConsuming(t *testing.T) {
+	config := NewDefaultConfig()
+	config.GroupID = ""
+	reader := &seekingReader{fakeReader: newFakeReader()}
+	c := newConsumer(config, reader)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Asynchronous consumption
+	require.NoError(t, c.ConsumeAsync(ctx, func(msg kafka.Message) error { return nil }, 1))
+	assert.ErrorIs(t, c.SeekToOffset(ctx, 0, 5), ErrConsumerActive)
+	assert.ErrorIs(t, c.SeekToTimestamp(ctx, logEpoch), ErrConsumerActive)
+	c.StopConsumeAsync()
+	require.NoError(t, c.SeekToOffset(ctx, 0, 5))
+
+	// Synchronous consumption
+	consumeCtx, stop := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(consumeCtx, func(msg kafka.Message) error { return nil })
+	}()
+	require.Eventually(t, func() bool {
+		return errors.Is(c.SeekToOffset(ctx, 0, 6), ErrConsumerActive)
+	}, time.Second, time.Millisecond)
+	stop()
+	<-done
+	require.NoError(t, c.SeekToOffset(ctx, 0, 6))
+	assert.Equal(t, int64(6), reader.offset)
+}
+
+func TestSeek_ConcurrentWithStop(t *testing.T) {
+	config := NewDefaultConfig()
+	config.GroupID = ""
+	c := newConsumer(config, &seekingReader{fakeReader: newFakeReader()})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.ConsumeAsync(ctx, func(msg kafka.Message) error { return nil }, 1))
+
+	// Stopping from several goroutines while seeking neither races nor closes twice
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.StopConsumeAsync()
+		}()
+		go func() {
+			defer wg.Done()
+			err := c.SeekToOffset(context.Background(), 0, 5)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrConsumerActive)
+			}
+		}()
+	}
+	// The fetcher waits on the reader until the context ends
+	cancel()
+	wg.Wait()
+
+	require.NoError(t, c.Close())
+	require.NoError(t, c.SeekToOffset(context.Background(), 0, 5))
+}
+
+func TestSeek_OffsetStoreUnsupported(t *testing.T) {
+	config := NewDefaultConfig()
+	config.OffsetStore = NewSQLOffsetStore(nil, "kafka_offsets", config.GroupID)
+	c := newConsumer(config, newFakeReader())
+
+	assert.ErrorContains(t, c.SeekToOffset(context.Background(), 0, 0), "OffsetStore")
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrOffsetConflict is returned by TxOffsetSaver.SaveMessage when the stored offset is already
+// past the message, meaning it was processed before, e.g. by another consumer after a rebalance.
+// The transaction should be rolled back.
+var ErrOffsetConflict = errors.New("message was already processed")
+
+// sqlExecer is the subset of *sql.DB and *sql.Tx used by the offset store
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// SQLOffsetStore is an OffsetStore keeping offsets in a database/sql table, so they can be
+// saved in the same transaction as the consumer's results. Queries use $n placeholders and
+// INSERT ... ON CONFLICT, as supported by PostgreSQL and SQLite.
+type SQLOffsetStore struct {
+	db    *sql.DB
+	table string
+	group string
+}
+
+// NewSQLOffsetStore creates a store for the consumer group's offsets in the given table.
+// The table name is used verbatim in queries and must not come from untrusted input.
+func NewSQLOffsetStore(db *sql.DB, table, group string) *SQLOffsetStore {
+	return &SQLOffsetStore{db: db, table: table, group: group}
+}
+
+// CreateTable creates the offsets table if it does not exist
+func (s *SQLOffsetStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		consumer_group TEXT NOT NULL,
+		topic TEXT NOT NULL,
+		partition_id INTEGER NOT NULL,
+		next_offset BIGINT NOT NULL,
+		PRIMARY KEY (consumer_group, topic, partition_id)
+	)`, s.table))
+	return err
+}
+
+// Load returns the stored offset for a partition, or ErrOffsetNotFound
+func (s *SQLOffsetStore) Load(ctx context.Context, topic string, partition int) (int64, error) {
+	var offset int64
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT next_offset FROM %s WHERE consumer_group = $1 AND topic = $2 AND partition_id = $3`, s.table),
+		s.group, topic, partition,
+	).Scan(&offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrOffsetNotFound
+	}
+	return offset, err
+}
+
+// Save stores the offset for a partition outside any transaction
+func (s *SQLOffsetStore) Save(ctx context.Context, topic string, partition int, offset int64) error {
+	_, err := s.upsert(ctx, s.db, topic, partition, offset, "")
+	return err
+}
+
+// Tx returns a saver writing offsets through the application's transaction
+func (s *SQLOffsetStore) Tx(tx *sql.Tx) *TxOffsetSaver {
+	return &TxOffsetSaver{store: s, tx: tx}
+}
+
+// upsert writes an offset, applying the optional condition to existing rows
+func (s *SQLOffsetStore) upsert(ctx context.Context, db sqlExecer, topic string, partition int, offset int64, where string, args ...interface{}) (sql.Result, error) {
+	query := fmt.Sprintf(`INSERT INTO %s AS o (consumer_group, topic, partition_id, next_offset) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (consumer_group, topic, partition_id) DO UPDATE SET next_offset = excluded.next_offset`, s.table)
+	if where != "" {
+		query += " WHERE " + where
+	}
+	return db.ExecContext(ctx, query, append([]interface{}{s.group, topic, partition, offset}, args...)...)
+}
+
+// TxOffsetSaver saves offsets in an application's database transaction, so results and
+// offsets are committed or rolled back together
+type TxOffsetSaver struct {
+	store *SQLOffsetStore
+	tx    *sql.Tx
+}
+
+// SaveMessage records msg as processed. It returns ErrOffsetConflict if the stored offset is
+// already past msg, in which case the transaction should be rolled back.
+func (t *TxOffsetSaver) SaveMessage(ctx context.Context, msg kafka.Message) error {
+	res, err := t.store.upsert(ctx, t.tx, msg.Topic, msg.Partition, msg.Offset+1, "o.next_offset <= $5", msg.Offset)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: topic=%s partition=%d offset=%d", ErrOffsetConflict, msg.Topic, msg.Partition, msg.Offset)
+	}
+	return nil
+}
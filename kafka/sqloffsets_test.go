@@ -0,0 +1,188 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB opens a SQLite database with a results table and an offsets table
+func openTestDB(t *testing.T) (*sql.DB, *SQLOffsetStore) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "consumer.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	_, err = db.ExecContext(ctx, `CREATE TABLE results (partition_id INTEGER, msg_offset BIGINT, PRIMARY KEY (partition_id, msg_offset))`)
+	require.NoError(t, err)
+
+	store := NewSQLOffsetStore(db, "kafka_offsets", "test-group")
+	require.NoError(t, store.CreateTable(ctx))
+	return db, store
+}
+
+// processInTx writes the message's result and offset in one transaction
+func processInTx(ctx context.Context, db *sql.DB, store *SQLOffsetStore, msg kafka.Message) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO results (partition_id, msg_offset) VALUES ($1, $2)`, msg.Partition, msg.Offset); err != nil {
+		return err
+	}
+	if err := store.Tx(tx).SaveMessage(ctx, msg); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func TestSQLOffsetStore_LoadSave(t *testing.T) {
+	_, store := openTestDB(t)
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, "test-topic", 0)
+	assert.ErrorIs(t, err, ErrOffsetNotFound)
+
+	require.NoError(t, store.Save(ctx, "test-topic", 0, 10))
+	require.NoError(t, store.Save(ctx, "test-topic", 0, 4))
+	offset, err := store.Load(ctx, "test-topic", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), offset, "Save overwrites unconditionally")
+
+	// Groups sharing the table are independent
+	other := NewSQLOffsetStore(store.db, "kafka_offsets", "other-group")
+	_, err = other.Load(ctx, "test-topic", 0)
+	assert.ErrorIs(t, err, ErrOffsetNotFound)
+}
+
+func TestTxOffsetSaver_RollbackKeepsOffset(t *testing.T) {
+	db, store := openTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, processInTx(ctx, db, store, testMessage(0, 0)))
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.Tx(tx).SaveMessage(ctx, testMessage(0, 1)))
+	require.NoError(t, tx.Rollback())
+
+	offset, err := store.Load(ctx, "test-topic", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), offset)
+}
+
+func TestTxOffsetSaver_RejectsReprocessing(t *testing.T) {
+	db, store := openTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, processInTx(ctx, db, store, testMessage(0, 0)))
+	require.NoError(t, processInTx(ctx, db, store, testMessage(0, 1)))
+
+	// A stale consumer delivering an old message must not move the offset back
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	err = store.Tx(tx).SaveMessage(ctx, testMessage(0, 0))
+	assert.ErrorIs(t, err, ErrOffsetConflict)
+
+	offset, err := store.Load(ctx, "test-topic", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), offset)
+}
+
+func TestConsumer_SQLOffsetStoreExactlyOnce(t *testing.T) {
+	db, store := openTestDB(t)
+	log := newFakePartitionLog(map[int]int64{0: 5, 1: 5})
+	errCrash := errors.New("crash")
+
+	// run consumes until the handler has seen count messages, then crashes in the middle
+	// of a transaction
+	run := func(count int) {
+		config := NewDefaultConfig()
+		config.Topic = "test-topic"
+		config.OffsetStore = store
+
+		assigner := newFakeAssigner()
+		assigner.gens <- newFakeGeneration(0, 1)
+		c := newConsumer(config, newGenerationReader(assigner, store, log.open, &captureLogger{}))
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		seen := 0
+		err := c.Consume(ctx, func(msg kafka.Message) error {
+			seen++
+			if seen < count {
+				return processInTx(ctx, db, store, msg)
+			}
+
+			// The result is written but the process dies before committing
+			tx, err := db.BeginTx(ctx, nil)
+			require.NoError(t, err)
+			_, err = tx.ExecContext(ctx, `INSERT INTO results (partition_id, msg_offset) VALUES ($1, $2)`, msg.Partition, msg.Offset)
+			require.NoError(t, err)
+			require.NoError(t, tx.Rollback())
+			return errCrash
+		})
+		require.ErrorIs(t, err, errCrash)
+	}
+
+	run(4)
+	run(3)
+
+	// Finish the remaining messages
+	config := NewDefaultConfig()
+	config.Topic = "test-topic"
+	config.OffsetStore = store
+	assigner := newFakeAssigner()
+	assigner.gens <- newFakeGeneration(0, 1)
+	c := newConsumer(config, newGenerationReader(assigner, store, log.open, &captureLogger{}))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	remaining := 10 - 3 - 2
+	err := c.Consume(ctx, func(msg kafka.Message) error {
+		if err := processInTx(ctx, db, store, msg); err != nil {
+			return err
+		}
+		remaining--
+		if remaining == 0 {
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Every message was applied exactly once, with no gaps
+	rows, err := db.Query(`SELECT partition_id, msg_offset FROM results ORDER BY partition_id, msg_offset`)
+	require.NoError(t, err)
+	defer rows.Close()
+	results := make(map[int][]int64)
+	for rows.Next() {
+		var partition int
+		var offset int64
+		require.NoError(t, rows.Scan(&partition, &offset))
+		results[partition] = append(results[partition], offset)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, map[int][]int64{0: {0, 1, 2, 3, 4}, 1: {0, 1, 2, 3, 4}}, results)
+
+	for _, partition := range []int{0, 1} {
+		offset, err := store.Load(context.Background(), "test-topic", partition)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), offset)
+	}
+}
@@ -2,7 +2,9 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
@@ -11,6 +13,10 @@ import (
 
 // CreateTopic creates a Kafka topic with the specified configuration
 func CreateTopic(ctx context.Context, config *KafkaConfig) error {
+	if err := errors.Join(config.Validate(), config.validateTopic()); err != nil {
+		return err
+	}
+
 	// Connect to the first broker to create the topic
 	conn, err := kafka.DialContext(ctx, "tcp", config.Brokers[0])
 	if err != nil {
@@ -33,6 +39,12 @@ func CreateTopic(ctx context.Context, config *KafkaConfig) error {
 			ConfigValue: retentionBytes,
 		},
 	}
+	if config.CleanupPolicy != "" {
+		topicConfigs = append(topicConfigs, kafka.ConfigEntry{
+			ConfigName:  "cleanup.policy",
+			ConfigValue: config.CleanupPolicy,
+		})
+	}
 
 	err = conn.CreateTopics(kafka.TopicConfig{
 		Topic:             config.Topic,
@@ -47,3 +59,182 @@ func CreateTopic(ctx context.Context, config *KafkaConfig) error {
 
 	return nil
 }
+
+// ErrPartitionDecrease is returned when the configured partition count is lower than the topic's
+var ErrPartitionDecrease = errors.New("kafka does not support decreasing the partition count")
+
+// ConfigEntryDiff describes a topic config entry that differs from the desired value
+type ConfigEntryDiff struct {
+	Name    string
+	Current string
+	Desired string
+}
+
+// ConfigDiff describes how an existing topic differs from its KafkaConfig
+type ConfigDiff struct {
+	Topic             string
+	CurrentPartitions int
+	DesiredPartitions int
+	Entries           []ConfigEntryDiff
+	Applied           bool // Whether the differences were altered on the broker
+}
+
+// HasChanges reports whether the topic differs from the desired configuration
+func (d ConfigDiff) HasChanges() bool {
+	return d.CurrentPartitions != d.DesiredPartitions || len(d.Entries) > 0
+}
+
+// topicAdmin is the subset of the admin API used to reconcile topic configuration
+type topicAdmin interface {
+	Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error)
+	DescribeConfigs(ctx context.Context, req *kafka.DescribeConfigsRequest) (*kafka.DescribeConfigsResponse, error)
+	IncrementalAlterConfigs(ctx context.Context, req *kafka.IncrementalAlterConfigsRequest) (*kafka.IncrementalAlterConfigsResponse, error)
+	CreatePartitions(ctx context.Context, req *kafka.CreatePartitionsRequest) (*kafka.CreatePartitionsResponse, error)
+}
+
+// desiredTopicConfig returns the config entries managed by KafkaConfig. Unset values are left
+// alone, since applying a zero retention would have the broker delete the topic's data.
+func desiredTopicConfig(config *KafkaConfig) map[string]string {
+	desired := make(map[string]string)
+	if config.RetentionPeriod > 0 {
+		desired["retention.ms"] = strconv.FormatInt(int64(config.RetentionPeriod/time.Millisecond), 10)
+	}
+	if config.RetentionSize > 0 {
+		desired["retention.bytes"] = strconv.FormatInt(config.RetentionSize, 10)
+	}
+	if config.CleanupPolicy != "" {
+		desired["cleanup.policy"] = config.CleanupPolicy
+	}
+	return desired
+}
+
+// describeTopicConfig returns the topic's current values of the named config entries
+func describeTopicConfig(ctx context.Context, admin topicAdmin, topic string, names []string) (map[string]string, error) {
+	current := make(map[string]string)
+	if len(names) == 0 {
+		// An empty request would describe every entry
+		return current, nil
+	}
+
+	configs, err := admin.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topic,
+			ConfigNames:  names,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic config: %w", err)
+	}
+
+	for _, resource := range configs.Resources {
+		if resource.Error != nil {
+			return nil, fmt.Errorf("failed to describe topic config: %w", resource.Error)
+		}
+		for _, entry := range resource.ConfigEntries {
+			current[entry.ConfigName] = entry.ConfigValue
+		}
+	}
+	return current, nil
+}
+
+// EnsureTopicConfig compares an existing topic's partition count and retention settings with the
+// configuration. When config.ApplyChanges is set the differences are altered on the broker;
+// otherwise the diff is only returned so it can be logged or alerted on.
+func EnsureTopicConfig(ctx context.Context, config *KafkaConfig) (ConfigDiff, error) {
+	client := &kafka.Client{Addr: kafka.TCP(config.Brokers...)}
+	return ensureTopicConfig(ctx, client, config)
+}
+
+// ensureTopicConfig implements EnsureTopicConfig against the given admin API
+func ensureTopicConfig(ctx context.Context, admin topicAdmin, config *KafkaConfig) (ConfigDiff, error) {
+	diff := ConfigDiff{Topic: config.Topic, DesiredPartitions: config.NumPartitions}
+
+	// Describe the topic's partitions
+	metadata, err := admin.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{config.Topic}})
+	if err != nil {
+		return diff, fmt.Errorf("failed to describe topic: %w", err)
+	}
+	if len(metadata.Topics) != 1 {
+		return diff, fmt.Errorf("failed to describe topic %s: not found", config.Topic)
+	}
+	if err := metadata.Topics[0].Error; err != nil {
+		return diff, fmt.Errorf("failed to describe topic %s: %w", config.Topic, err)
+	}
+	diff.CurrentPartitions = len(metadata.Topics[0].Partitions)
+
+	// The partition count is only managed when NumPartitions is set
+	if diff.DesiredPartitions < 1 {
+		diff.DesiredPartitions = diff.CurrentPartitions
+	}
+	if diff.DesiredPartitions < diff.CurrentPartitions {
+		return diff, fmt.Errorf("%w: topic %s has %d partitions, config wants %d",
+			ErrPartitionDecrease, config.Topic, diff.CurrentPartitions, diff.DesiredPartitions)
+	}
+
+	// Describe the managed config entries
+	desired := desiredTopicConfig(config)
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	current, err := describeTopicConfig(ctx, admin, config.Topic, names)
+	if err != nil {
+		return diff, err
+	}
+
+	for _, name := range names {
+		if current[name] != desired[name] {
+			diff.Entries = append(diff.Entries, ConfigEntryDiff{Name: name, Current: current[name], Desired: desired[name]})
+		}
+	}
+
+	if !config.ApplyChanges || !diff.HasChanges() {
+		return diff, nil
+	}
+
+	// Apply the differences
+	if len(diff.Entries) > 0 {
+		alterations := make([]kafka.IncrementalAlterConfigsRequestConfig, len(diff.Entries))
+		for i, entry := range diff.Entries {
+			alterations[i] = kafka.IncrementalAlterConfigsRequestConfig{
+				Name:            entry.Name,
+				Value:           entry.Desired,
+				ConfigOperation: kafka.ConfigOperationSet,
+			}
+		}
+
+		resp, err := admin.IncrementalAlterConfigs(ctx, &kafka.IncrementalAlterConfigsRequest{
+			Resources: []kafka.IncrementalAlterConfigsRequestResource{{
+				ResourceType: kafka.ResourceTypeTopic,
+				ResourceName: config.Topic,
+				Configs:      alterations,
+			}},
+		})
+		if err != nil {
+			return diff, fmt.Errorf("failed to alter topic config: %w", err)
+		}
+		for _, resource := range resp.Resources {
+			if resource.Error != nil {
+				return diff, fmt.Errorf("failed to alter topic config: %w", resource.Error)
+			}
+		}
+	}
+
+	if diff.DesiredPartitions > diff.CurrentPartitions {
+		resp, err := admin.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{
+			Topics: []kafka.TopicPartitionsConfig{{Name: config.Topic, Count: int32(diff.DesiredPartitions)}},
+		})
+		if err != nil {
+			return diff, fmt.Errorf("failed to add partitions: %w", err)
+		}
+		if err := resp.Errors[config.Topic]; err != nil {
+			return diff, fmt.Errorf("failed to add partitions: %w", err)
+		}
+	}
+
+	diff.Applied = true
+	return diff, nil
+}
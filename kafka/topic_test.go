@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAdmin is an in-memory topicAdmin holding a single topic
+type fakeAdmin struct {
+	partitions int
+	configs    map[string]string
+	altered    []kafka.IncrementalAlterConfigsRequestConfig
+}
+
+func (f *fakeAdmin) Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error) {
+	return &kafka.MetadataResponse{Topics: []kafka.Topic{{
+		Name:       req.Topics[0],
+		Partitions: make([]kafka.Partition, f.partitions),
+	}}}, nil
+}
+
+func (f *fakeAdmin) DescribeConfigs(ctx context.Context, req *kafka.DescribeConfigsRequest) (*kafka.DescribeConfigsResponse, error) {
+	var entries []kafka.DescribeConfigResponseConfigEntry
+	for _, name := range req.Resources[0].ConfigNames {
+		entries = append(entries, kafka.DescribeConfigResponseConfigEntry{ConfigName: name, ConfigValue: f.configs[name]})
+	}
+	return &kafka.DescribeConfigsResponse{Resources: []kafka.DescribeConfigResponseResource{{ConfigEntries: entries}}}, nil
+}
+
+func (f *fakeAdmin) IncrementalAlterConfigs(ctx context.Context, req *kafka.IncrementalAlterConfigsRequest) (*kafka.IncrementalAlterConfigsResponse, error) {
+	for _, c := range req.Resources[0].Configs {
+		f.altered = append(f.altered, c)
+		f.configs[c.Name] = c.Value
+	}
+	return &kafka.IncrementalAlterConfigsResponse{}, nil
+}
+
+func (f *fakeAdmin) CreatePartitions(ctx context.Context, req *kafka.CreatePartitionsRequest) (*kafka.CreatePartitionsResponse, error) {
+	f.partitions = int(req.Topics[0].Count)
+	return &kafka.CreatePartitionsResponse{}, nil
+}
+
+func topicTestConfig() *KafkaConfig {
+	config := NewDefaultConfig()
+	config.Topic = "orders"
+	config.NumPartitions = 3
+	config.RetentionPeriod = time.Hour
+	config.RetentionSize = 1024
+	config.CleanupPolicy = "delete"
+	return config
+}
+
+func TestEnsureTopicConfig_DiffOnly(t *testing.T) {
+	admin := &fakeAdmin{partitions: 2, configs: map[string]string{
+		"retention.ms":    "60000",
+		"retention.bytes": "1024",
+		"cleanup.policy":  "compact",
+	}}
+
+	diff, err := ensureTopicConfig(context.Background(), admin, topicTestConfig())
+	require.NoError(t, err)
+
+	assert.True(t, diff.HasChanges())
+	assert.False(t, diff.Applied)
+	assert.Equal(t, 2, diff.CurrentPartitions)
+	assert.Equal(t, 3, diff.DesiredPartitions)
+	assert.Equal(t, []ConfigEntryDiff{
+		{Name: "cleanup.policy", Current: "compact", Desired: "delete"},
+		{Name: "retention.ms", Current: "60000", Desired: "3600000"},
+	}, diff.Entries)
+
+	// Nothing was altered
+	assert.Empty(t, admin.altered)
+	assert.Equal(t, 2, admin.partitions)
+}
+
+func TestEnsureTopicConfig_Apply(t *testing.T) {
+	admin := &fakeAdmin{partitions: 2, configs: map[string]string{
+		"retention.ms":    "60000",
+		"retention.bytes": "1024",
+		"cleanup.policy":  "delete",
+	}}
+	config := topicTestConfig()
+	config.ApplyChanges = true
+
+	diff, err := ensureTopicConfig(context.Background(), admin, config)
+	require.NoError(t, err)
+	assert.True(t, diff.Applied)
+	require.Len(t, admin.altered, 1)
+	assert.Equal(t, kafka.ConfigOperationSet, admin.altered[0].ConfigOperation)
+	assert.Equal(t, "3600000", admin.configs["retention.ms"])
+	assert.Equal(t, 3, admin.partitions)
+
+	// A second run finds nothing to change
+	diff, err = ensureTopicConfig(context.Background(), admin, config)
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+	assert.False(t, diff.Applied)
+}
+
+func TestEnsureTopicConfig_RejectsPartitionDecrease(t *testing.T) {
+	admin := &fakeAdmin{partitions: 6, configs: map[string]string{}}
+	config := topicTestConfig()
+	config.ApplyChanges = true
+
+	_, err := ensureTopicConfig(context.Background(), admin, config)
+	assert.ErrorIs(t, err, ErrPartitionDecrease)
+	assert.Empty(t, admin.altered)
+}
+
+func TestEnsureTopicConfig_LeavesUnsetValuesAlone(t *testing.T) {
+	admin := &fakeAdmin{partitions: 6, configs: map[string]string{
+		"retention.ms":    "604800000",
+		"retention.bytes": "-1",
+	}}
+	config := NewDefaultConfig()
+	config.Topic = "orders"
+	config.NumPartitions = 0
+	config.RetentionPeriod = 0
+	config.RetentionSize = 0
+	config.ApplyChanges = true
+
+	// A config without topic settings, e.g. a consumer's, changes nothing
+	diff, err := ensureTopicConfig(context.Background(), admin, config)
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+	assert.Equal(t, 6, diff.DesiredPartitions)
+	assert.Empty(t, admin.altered)
+	assert.Equal(t, "604800000", admin.configs["retention.ms"])
+
+	// Only the values that are set are managed
+	config.RetentionSize = 1024
+	diff, err = ensureTopicConfig(context.Background(), admin, config)
+	require.NoError(t, err)
+	assert.Equal(t, []ConfigEntryDiff{{Name: "retention.bytes", Current: "-1", Desired: "1024"}}, diff.Entries)
+	require.Len(t, admin.altered, 1)
+	assert.Equal(t, "604800000", admin.configs["retention.ms"])
+	assert.Equal(t, 6, admin.partitions)
+}
+
+// TestEnsureTopicConfig_Integration runs against a real broker when KAFKA_BROKERS is set,
+// e.g. KAFKA_BROKERS=localhost:9092 with the docker-compose setup.
+func TestEnsureTopicConfig_Integration(t *testing.T) {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_BROKERS not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	config := NewDefaultConfig()
+	config.Brokers = strings.Split(brokers, ",")
+	config.Topic = fmt.Sprintf("ensure-config-%d", time.Now().UnixNano())
+	config.NumPartitions = 1
+	config.RetentionPeriod = time.Minute
+	config.RetentionSize = 1024 * 1024
+	require.NoError(t, CreateTopic(ctx, config))
+
+	// Ask for longer retention and more partitions without applying
+	config.RetentionPeriod = time.Hour
+	config.NumPartitions = 2
+	require.Eventually(t, func() bool {
+		diff, err := EnsureTopicConfig(ctx, config)
+		return err == nil && diff.CurrentPartitions == 1
+	}, 10*time.Second, 200*time.Millisecond)
+
+	diff, err := EnsureTopicConfig(ctx, config)
+	require.NoError(t, err)
+	assert.False(t, diff.Applied)
+	assert.Equal(t, []ConfigEntryDiff{{Name: "retention.ms", Current: "60000", Desired: "3600000"}}, diff.Entries)
+
+	// Apply, then expect no drift
+	config.ApplyChanges = true
+	diff, err = EnsureTopicConfig(ctx, config)
+	require.NoError(t, err)
+	assert.True(t, diff.Applied)
+
+	require.Eventually(t, func() bool {
+		diff, err := EnsureTopicConfig(ctx, config)
+		return err == nil && !diff.HasChanges()
+	}, 10*time.Second, 200*time.Millisecond)
+
+	config.NumPartitions = 1
+	_, err = EnsureTopicConfig(ctx, config)
+	assert.ErrorIs(t, err, ErrPartitionDecrease)
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Define a custom type for context keys to avoid collisions.
@@ -27,16 +36,97 @@ func withRequestID(next http.Handler) http.Handler {
     })
 }
 
-// loggingMiddleware logs basic request information and execution time.
-func loggingMiddleware(next http.Handler) http.Handler {
+// accessLogFormat selects how loggingMiddleware writes access logs.
+type accessLogFormat int
+
+const (
+    // accessLogText writes a human-readable line per request.
+    accessLogText accessLogFormat = iota
+    // accessLogJSON writes a JSON object per request for log pipelines.
+    accessLogJSON
+)
+
+// accessLogFormatEnv selects the access log format at startup; "json" enables JSON output.
+const accessLogFormatEnv = "ACCESS_LOG_FORMAT"
+
+// accessLogEntry is a request logged in JSON format.
+type accessLogEntry struct {
+    Method     string  `json:"method"`
+    Path       string  `json:"path"`
+    Status     int     `json:"status"`
+    Bytes      int64   `json:"bytes"`
+    DurationMS float64 `json:"duration_ms"`
+    RemoteAddr string  `json:"remote_addr"`
+    TraceID    string  `json:"trace_id"`
+}
+
+// statusWriter records the status code and body size written by a handler.
+type statusWriter struct {
+    http.ResponseWriter
+    status int
+    bytes  int64
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+    if sw.status == 0 {
+        sw.status = status
+    }
+    sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+    if sw.status == 0 {
+        sw.status = http.StatusOK
+    }
+    n, err := sw.ResponseWriter.Write(p)
+    sw.bytes += int64(n)
+    return n, err
+}
+
+// traceID returns the OpenTelemetry trace ID of the request, or its request ID when untraced.
+func traceID(r *http.Request) string {
+    if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+        return sc.TraceID().String()
+    }
+    reqID, _ := r.Context().Value(requestIDKey).(string)
+    return reqID
+}
+
+// loggingMiddleware logs basic request information and execution time to logger
+// in the given format.
+func loggingMiddleware(format accessLogFormat, logger *log.Logger, next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         start := time.Now()
+        sw := &statusWriter{ResponseWriter: w}
         // Call the next handler in the chain.
-        next.ServeHTTP(w, r)
+        next.ServeHTTP(sw, r)
         duration := time.Since(start)
+
+        if format == accessLogJSON {
+            status := sw.status
+            if status == 0 {
+                status = http.StatusOK
+            }
+            entry, err := json.Marshal(accessLogEntry{
+                Method:     r.Method,
+                Path:       r.URL.Path,
+                Status:     status,
+                Bytes:      sw.bytes,
+                DurationMS: float64(duration) / float64(time.Millisecond),
+                RemoteAddr: r.RemoteAddr,
+                TraceID:    traceID(r),
+            })
+            if err != nil {
+                logger.Printf("Failed to encode access log: %v", err)
+                return
+            }
+            logger.Print(string(entry))
+            return
+        }
+
         // Retrieve the request ID from context.
         reqID, _ := r.Context().Value(requestIDKey).(string)
-        log.Printf("RequestID=%s Method=%s URL=%s Duration=%s", reqID, r.Method, r.URL.Path, duration)
+        logger.Printf("RequestID=%s Method=%s URL=%s Duration=%s", reqID, r.Method, r.URL.Path, duration)
     })
 }
 
@@ -53,6 +143,135 @@ func recoveryMiddleware(next http.Handler) http.Handler {
     })
 }
 
+// etagMaxBodySize is the largest response body buffered to compute an ETag.
+const etagMaxBodySize = 1 << 20
+
+// etagWriter buffers a response so an ETag can be computed from its body.
+// Once the body grows past maxSize it stops buffering and streams the rest.
+type etagWriter struct {
+    http.ResponseWriter
+    maxSize   int
+    status    int
+    buf       bytes.Buffer
+    streaming bool
+}
+
+func (ew *etagWriter) WriteHeader(status int) {
+    if ew.status == 0 {
+        ew.status = status
+    }
+}
+
+func (ew *etagWriter) Write(p []byte) (int, error) {
+    if ew.status == 0 {
+        ew.status = http.StatusOK
+    }
+    if ew.streaming {
+        return ew.ResponseWriter.Write(p)
+    }
+    if ew.buf.Len()+len(p) > ew.maxSize {
+        // Too large to buffer: send what we have and stream from here on
+        ew.streaming = true
+        ew.ResponseWriter.WriteHeader(ew.status)
+        if _, err := ew.ResponseWriter.Write(ew.buf.Bytes()); err != nil {
+            return 0, err
+        }
+        ew.buf.Reset()
+        return ew.ResponseWriter.Write(p)
+    }
+    return ew.buf.Write(p)
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag. If-None-Match
+// uses weak comparison, so a W/ prefix on either side is ignored.
+func etagMatches(ifNoneMatch, etag string) bool {
+    etag = strings.TrimPrefix(etag, "W/")
+    for _, candidate := range strings.Split(ifNoneMatch, ",") {
+        candidate = strings.TrimSpace(candidate)
+        if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+            return true
+        }
+    }
+    return false
+}
+
+// etagMiddleware adds a strong ETag to successful GET and HEAD responses up to maxSize
+// bytes and answers matching If-None-Match requests with 304 Not Modified.
+func etagMiddleware(maxSize int, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead {
+            next.ServeHTTP(w, r)
+            return
+        }
+
+        ew := &etagWriter{ResponseWriter: w, maxSize: maxSize}
+        next.ServeHTTP(ew, r)
+
+        if ew.streaming {
+            return
+        }
+        if ew.status == 0 {
+            ew.status = http.StatusOK
+        }
+        if ew.status != http.StatusOK {
+            w.WriteHeader(ew.status)
+            w.Write(ew.buf.Bytes())
+            return
+        }
+
+        // Keep an ETag set by the handler, otherwise hash the body
+        etag := w.Header().Get("ETag")
+        if etag == "" {
+            sum := sha256.Sum256(ew.buf.Bytes())
+            etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+            w.Header().Set("ETag", etag)
+        }
+
+        if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+            w.Header().Del("Content-Length")
+            w.Header().Del("Content-Type")
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+
+        w.WriteHeader(ew.status)
+        w.Write(ew.buf.Bytes())
+    })
+}
+
+// requestTimeoutHeader lets clients ask for a shorter processing budget.
+const requestTimeoutHeader = "X-Request-Timeout"
+
+// maxRequestTimeout caps the processing budget a client can ask for.
+const maxRequestTimeout = 30 * time.Second
+
+// parseRequestTimeout reads a timeout header value as a Go duration ("500ms")
+// or a number of seconds ("2.5").
+func parseRequestTimeout(value string) (time.Duration, bool) {
+    if d, err := time.ParseDuration(value); err == nil {
+        return d, d > 0
+    }
+    if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+        return time.Duration(seconds * float64(time.Second)), true
+    }
+    return 0, false
+}
+
+// requestTimeoutMiddleware applies a deadline to the request context from the
+// X-Request-Timeout header, clamped to max. Requests without a valid header get max.
+func requestTimeoutMiddleware(max time.Duration, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        timeout := max
+        if d, ok := parseRequestTimeout(r.Header.Get(requestTimeoutHeader)); ok && d < max {
+            timeout = d
+        }
+
+        ctx, cancel := context.WithTimeout(r.Context(), timeout)
+        defer cancel()
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
 // mainHandler is a simple HTTP handler for demonstration.
 func mainHandler(w http.ResponseWriter, r *http.Request) {
     // Uncomment the next line to simulate a panic.
@@ -84,7 +303,12 @@ func startPeriodicLogging() {
 func main() {
     startPeriodicLogging() // Start periodic logging
     baseHandler := http.HandlerFunc(mainHandler)
-    handler := recoveryMiddleware(withRequestID(loggingMiddleware(baseHandler)))
+    accessLogger, format := log.Default(), accessLogText
+    if os.Getenv(accessLogFormatEnv) == "json" {
+        // JSON lines carry their own fields, so skip the log prefix
+        accessLogger, format = log.New(os.Stdout, "", 0), accessLogJSON
+    }
+    handler := recoveryMiddleware(withRequestID(loggingMiddleware(format, accessLogger, requestTimeoutMiddleware(maxRequestTimeout, etagMiddleware(etagMaxBodySize, baseHandler)))))
     http.Handle("/", handler)
     log.Println("Starting server on :8080")
     if err := http.ListenAndServe(":8080", nil); err != nil {
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestETagMiddleware_ConditionalRequest(t *testing.T) {
+	handler := etagMiddleware(1024, http.HandlerFunc(mainHandler))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "Hello, Production-Grade HTTP Interceptor!", rec.Body.String())
+
+	// The same body produces the same ETag
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+
+	// A conditional re-request gets 304 with no body
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"stale", `+etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+
+	// A stale ETag gets the full response
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Body.String())
+}
+
+func TestETagMiddleware_HandlerWeakETag(t *testing.T) {
+	handler := etagMiddleware(1024, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"v1"`)
+		w.Write([]byte("versioned"))
+	}))
+
+	// The handler's weak ETag is kept and matches both weak and strong forms
+	for _, match := range []string{`W/"v1"`, `"v1"`} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", match)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotModified, rec.Code, match)
+		assert.Equal(t, `W/"v1"`, rec.Header().Get("ETag"))
+		assert.Empty(t, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `W/"v0"`)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "versioned", rec.Body.String())
+}
+
+func TestETagMiddleware_SkipsLargeAndNonGET(t *testing.T) {
+	large := strings.Repeat("x", 100)
+	handler := etagMiddleware(64, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(large[:50]))
+		w.Write([]byte(large[50:]))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Equal(t, large, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	etagMiddleware(1024, http.HandlerFunc(mainHandler)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestETagMiddleware_ErrorResponsesPassThrough(t *testing.T) {
+	handler := etagMiddleware(1024, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Equal(t, "not found\n", rec.Body.String())
+}
+
+// deadlineOf runs the timeout middleware and returns the remaining budget seen by the handler
+func deadlineOf(t *testing.T, header string) time.Duration {
+	t.Helper()
+
+	var remaining time.Duration
+	handler := requestTimeoutMiddleware(10*time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		require.True(t, ok)
+		remaining = time.Until(deadline)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(requestTimeoutHeader, header)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	return remaining
+}
+
+func TestRequestTimeoutMiddleware(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"500ms", 500 * time.Millisecond},
+		{"2", 2 * time.Second},
+		{"1.5", 1500 * time.Millisecond},
+		{"1m", 10 * time.Second},   // clamped to the server maximum
+		{"", 10 * time.Second},     // no header uses the maximum
+		{"soon", 10 * time.Second}, // invalid values are ignored
+		{"-1s", 10 * time.Second},  // so are non-positive ones
+	}
+
+	for _, tt := range tests {
+		remaining := deadlineOf(t, tt.header)
+		assert.LessOrEqual(t, remaining, tt.want, tt.header)
+		assert.Greater(t, remaining, tt.want-time.Second, tt.header)
+	}
+}
+
+func TestLoggingMiddleware_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	handler := withRequestID(loggingMiddleware(accessLogJSON, log.New(&buf, "", 0), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/users?id=1", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Decode generically so the JSON types are checked, not just the values
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+	assert.Equal(t, "POST", fields["method"])
+	assert.Equal(t, "/users", fields["path"])
+	assert.Equal(t, float64(http.StatusCreated), fields["status"])
+	assert.Equal(t, float64(len("created")), fields["bytes"])
+	assert.IsType(t, float64(0), fields["duration_ms"])
+	assert.GreaterOrEqual(t, fields["duration_ms"], float64(0))
+	assert.Equal(t, "10.0.0.1:5000", fields["remote_addr"])
+	// Untraced requests fall back to the request ID
+	assert.IsType(t, "", fields["trace_id"])
+	assert.NotEmpty(t, fields["trace_id"])
+	assert.Len(t, fields, 7)
+}
+
+func TestLoggingMiddleware_JSONTraceID(t *testing.T) {
+	var buf bytes.Buffer
+	handler := loggingMiddleware(accessLogJSON, log.New(&buf, "", 0), http.HandlerFunc(mainHandler))
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	var entry accessLogEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry.TraceID)
+	assert.Equal(t, http.StatusOK, entry.Status)
+}
+
+func TestLoggingMiddleware_TextIsDefault(t *testing.T) {
+	var buf bytes.Buffer
+	handler := withRequestID(loggingMiddleware(accessLogText, log.New(&buf, "", 0), http.HandlerFunc(mainHandler)))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	assert.Regexp(t, `^RequestID=\d+ Method=GET URL=/hello Duration=\S+\n$`, buf.String())
+	assert.Equal(t, accessLogText, accessLogFormat(0))
+}
@@ -0,0 +1,49 @@
+package oauth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+var (
+	// ErrStateInvalid is returned when the callback state token is unknown, reused or expired
+	ErrStateInvalid = errors.New("invalid or expired state token")
+
+	// ErrTokenExchange is returned when the authorization code can't be exchanged for a token
+	ErrTokenExchange = errors.New("token exchange failed")
+
+	// ErrUserInfo is returned when the user's profile can't be fetched from the provider
+	ErrUserInfo = errors.New("failed to get user info")
+
+	// ErrSession is returned when the session can't be saved or cleared
+	ErrSession = errors.New("session error")
+
+	// ErrAccessDenied is returned by a SuccessHook that rejects an authenticated user on purpose,
+	// e.g. one that isn't provisioned or whose domain isn't allowed. It maps to 403 Forbidden.
+	ErrAccessDenied = errors.New("access denied")
+)
+
+// ErrorResponse maps an error to the HTTP status and message that are safe to show clients.
+// Unrecognised errors map to a generic 500 so internal detail never reaches the response.
+func ErrorResponse(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrStateInvalid):
+		return http.StatusBadRequest, "Invalid or expired state token"
+	case errors.Is(err, ErrTokenExchange):
+		return http.StatusUnauthorized, "Authentication failed"
+	case errors.Is(err, ErrUserInfo):
+		return http.StatusBadGateway, "Failed to get user info"
+	case errors.Is(err, ErrAccessDenied):
+		return http.StatusForbidden, "Access denied"
+	default:
+		return http.StatusInternalServerError, "Internal server error"
+	}
+}
+
+// writeError logs the full error and responds with its safe mapping
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, message := ErrorResponse(err)
+	log.Printf("OAuth error: method=%s path=%s status=%d error=%v", r.Method, r.URL.Path, status, err)
+	http.Error(w, message, status)
+}
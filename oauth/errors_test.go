@@ -0,0 +1,99 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// roundTripFunc lets tests stand in for the Google endpoints
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// callback runs the callback handler with a valid state, sending provider traffic to transport
+func callback(h *GoogleOAuthHandler, transport roundTripFunc) *httptest.ResponseRecorder {
+	h.StateStore.Issue("state")
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=state&code=code", nil).WithContext(ctx)
+
+	rec := httptest.NewRecorder()
+	h.CallbackHandler(rec, req)
+	return rec
+}
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{ErrStateInvalid, http.StatusBadRequest},
+		{fmt.Errorf("%w: bad code", ErrTokenExchange), http.StatusUnauthorized},
+		{fmt.Errorf("%w: status 500", ErrUserInfo), http.StatusBadGateway},
+		{fmt.Errorf("%w: cookie too large", ErrSession), http.StatusInternalServerError},
+		{fmt.Errorf("%w: domain not allowed", ErrAccessDenied), http.StatusForbidden},
+		{errors.New("something else"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		status, message := ErrorResponse(tt.err)
+		assert.Equal(t, tt.status, status, tt.err.Error())
+		assert.NotContains(t, message, ":", tt.err.Error())
+	}
+}
+
+func TestCallbackHandler_InvalidState(t *testing.T) {
+	h := newTestHandler()
+
+	rec := httptest.NewRecorder()
+	h.CallbackHandler(rec, httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=unknown&code=code", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid or expired state token")
+}
+
+func TestCallbackHandler_TokenExchangeFailureHidesDetail(t *testing.T) {
+	h := newTestHandler()
+
+	rec := callback(h, func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusBadRequest, `{"error":"invalid_grant","error_description":"internal-detail-1234"}`), nil
+	})
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "internal-detail-1234")
+	assert.NotContains(t, rec.Body.String(), "invalid_grant")
+}
+
+func TestCallbackHandler_UserInfoFailureHidesDetail(t *testing.T) {
+	h := newTestHandler()
+
+	rec := callback(h, func(r *http.Request) (*http.Response, error) {
+		if strings.Contains(r.URL.Path, "token") {
+			return jsonResponse(http.StatusOK, `{"access_token":"access-token-5678","token_type":"Bearer","expires_in":3600}`), nil
+		}
+		return jsonResponse(http.StatusInternalServerError, `{"error":"backend-detail-9999"}`), nil
+	})
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "backend-detail-9999")
+	assert.NotContains(t, rec.Body.String(), "access-token-5678")
+}
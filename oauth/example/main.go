@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"huba/oauth"
 )
@@ -35,6 +36,17 @@ func ExampleSetup() {
 	// Create Google OAuth handler
 	googleHandler := oauth.NewGoogleOAuthHandler(googleConfig, sessionManager)
 
+	// Reap state tokens from abandoned logins
+	googleHandler.StartStateCleanup(time.Minute)
+	defer googleHandler.Close()
+
+	// Send users to the dashboard after login
+	googleHandler.OnSuccess = func(result *oauth.CallbackResult, w http.ResponseWriter, r *http.Request) error {
+		log.Printf("Login: %s", result.UserInfo.Email)
+		result.RedirectURL = "/dashboard"
+		return nil
+	}
+
 	// Create auth middleware
 	authMiddleware := oauth.NewAuthMiddleware(os.Getenv("SESSION_COOKIE_NAME"), "/auth/google/login")
 
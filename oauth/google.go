@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -70,7 +71,7 @@ func HandleGoogleCallback(ctx context.Context, oauthConfig *oauth2.Config, state
 	// Exchange the authorization code for a token
 	token, err := oauthConfig.Exchange(ctx, code)
 	if err != nil {
-		return nil, fmt.Errorf("code exchange failed: %s", err.Error())
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
 	}
 	return token, nil
 }
@@ -83,19 +84,23 @@ func GetGoogleUserInfo(ctx context.Context, token *oauth2.Token, oauthConfig *oa
 	// Fetch user info from Google API
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
 	if err != nil {
-		return nil, fmt.Errorf("failed getting user info: %s", err.Error())
+		return nil, fmt.Errorf("%w: %v", ErrUserInfo, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrUserInfo, resp.StatusCode)
+	}
+
 	// Read and parse the response
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed reading response body: %s", err.Error())
+		return nil, fmt.Errorf("%w: failed reading response body: %v", ErrUserInfo, err)
 	}
 
 	var userInfo GoogleUserInfo
 	if err := json.Unmarshal(data, &userInfo); err != nil {
-		return nil, fmt.Errorf("failed parsing user info: %s", err.Error())
+		return nil, fmt.Errorf("%w: failed parsing user info: %v", ErrUserInfo, err)
 	}
 
 	return &userInfo, nil
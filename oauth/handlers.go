@@ -1,13 +1,22 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
+
+	"huba/cryptoutils/nonce"
+
+	"golang.org/x/oauth2"
 )
 
+// stateTTL is how long a login has to complete before its state token expires
+const stateTTL = 10 * time.Minute
+
 // SessionManager interface for managing user sessions
 type SessionManager interface {
 	SaveSession(w http.ResponseWriter, userID string, email string, name string) error
@@ -82,19 +91,40 @@ func NewDefaultSessionManager(cookieName, cookieDomain, cookiePath string, maxAg
 	}
 }
 
+// CallbackResult holds the outcome of a successful OAuth callback
+type CallbackResult struct {
+	UserInfo    *GoogleUserInfo
+	Token       *oauth2.Token
+	RedirectURL string // Where the user is sent next; hooks may change it
+}
+
+// SuccessHook runs after the user is authenticated and before the session is saved.
+// It may change result.RedirectURL, or return an error to abort the login. Wrap
+// ErrAccessDenied to reject the user with 403; other errors are treated as server faults.
+type SuccessHook func(result *CallbackResult, w http.ResponseWriter, r *http.Request) error
+
+// ErrorHook handles a failed callback in place of the default error response
+type ErrorHook func(err error, w http.ResponseWriter, r *http.Request)
+
 // GoogleOAuthHandler handles Google OAuth2 authentication
 type GoogleOAuthHandler struct {
 	Config         GoogleOAuthConfig
 	SessionManager SessionManager
-	StateStore     map[string]time.Time // Simple in-memory state storage
+	StateStore     *nonce.Cache // Single-use state tokens for CSRF protection
+	OnSuccess      SuccessHook  // Optional, runs before the post-login redirect
+	OnError        ErrorHook    // Optional, replaces the default callback error response
+	HTTPClient     *http.Client // Optional client for the token exchange and user info calls
 }
 
 // NewGoogleOAuthHandler creates a new GoogleOAuthHandler
 func NewGoogleOAuthHandler(config GoogleOAuthConfig, sessionManager SessionManager) *GoogleOAuthHandler {
+	states := nonce.New(stateTTL)
+	states.RequireIssued = true
+
 	return &GoogleOAuthHandler{
 		Config:         config,
 		SessionManager: sessionManager,
-		StateStore:     make(map[string]time.Time),
+		StateStore:     states,
 	}
 }
 
@@ -103,12 +133,12 @@ func (h *GoogleOAuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request
 	// Generate a state token for CSRF protection
 	state, err := GenerateStateToken()
 	if err != nil {
-		http.Error(w, "Failed to generate state token", http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("failed to generate state token: %w", err))
 		return
 	}
 
-	// Store the state token with an expiration time (e.g., 10 minutes)
-	h.StateStore[state] = time.Now().Add(10 * time.Minute)
+	// Store the state token until the login completes or it expires
+	h.StateStore.Issue(state)
 
 	// Create the OAuth2 config
 	oauthConfig := NewGoogleOAuth(h.Config)
@@ -124,45 +154,74 @@ func (h *GoogleOAuthHandler) CallbackHandler(w http.ResponseWriter, r *http.Requ
 	state := r.URL.Query().Get("state")
 	code := r.URL.Query().Get("code")
 
-	// Validate state token to prevent CSRF
-	expirationTime, exists := h.StateStore[state]
-	if !exists || time.Now().After(expirationTime) {
-		http.Error(w, "Invalid or expired state token", http.StatusBadRequest)
+	// Validate state token to prevent CSRF, consuming it so it can't be reused
+	if !h.StateStore.Consume(state) {
+		h.callbackError(w, r, ErrStateInvalid)
 		return
 	}
 
-	// Remove the used state token
-	delete(h.StateStore, state)
-
 	// Create the OAuth2 config
 	oauthConfig := NewGoogleOAuth(h.Config)
 
+	// Send calls to Google through the configured client, if any
+	ctx := r.Context()
+	if h.HTTPClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, h.HTTPClient)
+	}
+
 	// Exchange the authorization code for a token
-	token, err := HandleGoogleCallback(r.Context(), oauthConfig, state, code)
+	token, err := HandleGoogleCallback(ctx, oauthConfig, state, code)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to exchange token: %v", err), http.StatusInternalServerError)
+		h.callbackError(w, r, err)
 		return
 	}
 
 	// Get the user info
-	userInfo, err := GetGoogleUserInfo(r.Context(), token, oauthConfig)
+	userInfo, err := GetGoogleUserInfo(ctx, token, oauthConfig)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get user info: %v", err), http.StatusInternalServerError)
+		h.callbackError(w, r, err)
 		return
 	}
 
+	// Let the application provision or audit the user, and possibly change the redirect
+	result := &CallbackResult{UserInfo: userInfo, Token: token, RedirectURL: "/"}
+	if h.OnSuccess != nil {
+		if err := h.OnSuccess(result, w, r); err != nil {
+			h.callbackError(w, r, err)
+			return
+		}
+	}
+
 	// Save the user session
 	err = h.SessionManager.SaveSession(w, userInfo.ID, userInfo.Email, userInfo.Name)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to save session: %v", err), http.StatusInternalServerError)
+		h.callbackError(w, r, fmt.Errorf("%w: failed to save session: %v", ErrSession, err))
 		return
 	}
 
-	// Log the successful authentication
-	log.Printf("User authenticated: ID=%s, Email=%s, Name=%s", userInfo.ID, userInfo.Email, userInfo.Name)
+	// Log the successful authentication without the user's email in plaintext
+	log.Printf("User authenticated: ID=%s, Email=%s", userInfo.ID, maskEmail(userInfo.Email))
 
-	// Redirect to the home page or dashboard
-	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+	// Redirect to the home page, or wherever the success hook chose
+	http.Redirect(w, r, result.RedirectURL, http.StatusTemporaryRedirect)
+}
+
+// callbackError reports a callback failure through OnError, or the default error response
+func (h *GoogleOAuthHandler) callbackError(w http.ResponseWriter, r *http.Request, err error) {
+	if h.OnError != nil {
+		h.OnError(err, w, r)
+		return
+	}
+	writeError(w, r, err)
+}
+
+// maskEmail hides an email address for logs, keeping the first character and the domain
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
 }
 
 // LogoutHandler handles user logout
@@ -170,7 +229,7 @@ func (h *GoogleOAuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Reques
 	// Clear the session
 	err := h.SessionManager.ClearSession(w)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to clear session: %v", err), http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("%w: failed to clear session: %v", ErrSession, err))
 		return
 	}
 
@@ -178,6 +237,17 @@ func (h *GoogleOAuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Reques
 	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 }
 
+// StartStateCleanup starts a background goroutine that removes expired state tokens
+// every interval, so abandoned logins don't accumulate. Stop it with Close.
+func (h *GoogleOAuthHandler) StartStateCleanup(interval time.Duration) {
+	h.StateStore.StartCleanup(interval)
+}
+
+// Close stops the state cleanup goroutine if it is running
+func (h *GoogleOAuthHandler) Close() error {
+	return h.StateStore.Close()
+}
+
 // RegisterHandlers registers the OAuth handlers with the provided ServeMux
 func (h *GoogleOAuthHandler) RegisterHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/auth/google/login", h.LoginHandler)
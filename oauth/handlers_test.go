@@ -0,0 +1,198 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"huba/cryptoutils/nonce"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHandler() *GoogleOAuthHandler {
+	return NewGoogleOAuthHandler(GoogleOAuthConfig{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost/auth/google/callback",
+	}, NewDefaultSessionManager("session", "", "/", 3600, false, true))
+}
+
+func TestGoogleOAuthHandler_StateCleanupLoop(t *testing.T) {
+	h := newTestHandler()
+	h.StateStore = nonce.New(time.Millisecond)
+	h.StateStore.Issue("expired")
+
+	h.StartStateCleanup(10 * time.Millisecond)
+	// Starting twice is a no-op
+	h.StartStateCleanup(10 * time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return h.StateStore.Len() == 0
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, h.Close())
+	require.NoError(t, h.Close())
+
+	// Nothing is reaped after Close
+	h.StateStore.Issue("expired")
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 1, h.StateStore.Len())
+}
+
+func TestGoogleOAuthHandler_LoginStoresState(t *testing.T) {
+	h := newTestHandler()
+
+	rec := httptest.NewRecorder()
+	h.LoginHandler(rec, httptest.NewRequest(http.MethodGet, "/auth/google/login", nil))
+
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+	assert.Equal(t, 1, h.StateStore.Len())
+}
+
+func TestCallbackHandler_StateSingleUse(t *testing.T) {
+	h := newTestHandler()
+
+	rec := callback(h, googleTransport)
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+
+	// Replaying the callback with the same state is rejected
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=state&code=code", nil)
+	rec = httptest.NewRecorder()
+	h.CallbackHandler(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// So is a state that was never issued
+	req = httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=forged&code=code", nil)
+	rec = httptest.NewRecorder()
+	h.CallbackHandler(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// googleTransport answers token and user info requests like Google would
+func googleTransport(r *http.Request) (*http.Response, error) {
+	if strings.Contains(r.URL.Path, "token") {
+		return jsonResponse(http.StatusOK, `{"access_token":"access-token","token_type":"Bearer","expires_in":3600}`), nil
+	}
+	return jsonResponse(http.StatusOK, `{"id":"42","email":"alice@example.com","name":"Alice"}`), nil
+}
+
+func TestCallbackHandler_OnSuccessOverridesRedirect(t *testing.T) {
+	h := newTestHandler()
+
+	var got *CallbackResult
+	h.OnSuccess = func(result *CallbackResult, w http.ResponseWriter, r *http.Request) error {
+		got = result
+		result.RedirectURL = "/welcome"
+		return nil
+	}
+
+	rec := callback(h, googleTransport)
+
+	require.NotNil(t, got)
+	assert.Equal(t, "42", got.UserInfo.ID)
+	assert.Equal(t, "alice@example.com", got.UserInfo.Email)
+	assert.Equal(t, "access-token", got.Token.AccessToken)
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+	assert.Equal(t, "/welcome", rec.Header().Get("Location"))
+	assert.NotEmpty(t, rec.Result().Cookies())
+}
+
+func TestCallbackHandler_OnSuccessAbort(t *testing.T) {
+	h := newTestHandler()
+
+	errNotProvisioned := errors.New("user not allowed")
+	h.OnSuccess = func(result *CallbackResult, w http.ResponseWriter, r *http.Request) error {
+		return errNotProvisioned
+	}
+
+	var handled error
+	h.OnError = func(err error, w http.ResponseWriter, r *http.Request) {
+		handled = err
+		http.Error(w, "Access denied", http.StatusForbidden)
+	}
+
+	rec := callback(h, googleTransport)
+
+	assert.ErrorIs(t, handled, errNotProvisioned)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	// No session is created for an aborted login
+	assert.Empty(t, rec.Result().Cookies())
+}
+
+func TestCallbackHandler_OnSuccessAccessDenied(t *testing.T) {
+	h := newTestHandler()
+
+	h.OnSuccess = func(result *CallbackResult, w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("%w: %s is not provisioned", ErrAccessDenied, result.UserInfo.Email)
+	}
+
+	rec := callback(h, googleTransport)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Access denied")
+	assert.NotContains(t, rec.Body.String(), "alice@example.com")
+	assert.Empty(t, rec.Result().Cookies())
+}
+
+func TestCallbackHandler_UsesHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			fmt.Fprint(w, `{"access_token":"access-token","token_type":"Bearer","expires_in":3600}`)
+		case "/oauth2/v2/userinfo":
+			if r.Header.Get("Authorization") != "Bearer access-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"id":"42","email":"alice@example.com","verified_email":true,`+
+				`"name":"Alice Liddell","given_name":"Alice","family_name":"Liddell","locale":"en"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	h := newTestHandler()
+	// Send Google's endpoints to the test server
+	h.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		r.URL.Scheme = "http"
+		r.URL.Host = srv.Listener.Addr().String()
+		return srv.Client().Transport.RoundTrip(r)
+	})}
+
+	var got *GoogleUserInfo
+	h.OnSuccess = func(result *CallbackResult, w http.ResponseWriter, r *http.Request) error {
+		got = result.UserInfo
+		return nil
+	}
+
+	h.StateStore.Issue("state")
+	rec := httptest.NewRecorder()
+	h.CallbackHandler(rec, httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=state&code=code", nil))
+
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, &GoogleUserInfo{
+		ID:            "42",
+		Email:         "alice@example.com",
+		VerifiedEmail: true,
+		Name:          "Alice Liddell",
+		GivenName:     "Alice",
+		FamilyName:    "Liddell",
+		Locale:        "en",
+	}, got)
+}
+
+func TestMaskEmail(t *testing.T) {
+	assert.Equal(t, "a***@example.com", maskEmail("alice@example.com"))
+	assert.Equal(t, "***", maskEmail("@example.com"))
+	assert.Equal(t, "***", maskEmail("not-an-email"))
+}
@@ -0,0 +1,117 @@
+package userservice
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"huba/cache"
+)
+
+// CachingDatabase wraps a Database and caches QueryUser results in Redis using the
+// cache-aside pattern. Updates and deletes invalidate the cached user.
+// Cache failures are logged and counted; lookups then fall back to the wrapped Database.
+type CachingDatabase struct {
+	db                   Database
+	cache                *cache.RedisCache
+	ttl                  time.Duration
+	logger               Logger
+	cacheFailures        int64
+	invalidationFailures int64
+}
+
+// NewCachingDatabase creates a decorator that caches users from db for ttl
+func NewCachingDatabase(db Database, c *cache.RedisCache, ttl time.Duration, logger Logger) *CachingDatabase {
+	return &CachingDatabase{
+		db:     db,
+		cache:  c,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// userKey returns the cache key for a user ID
+func userKey(id string) string {
+	return "user:" + id
+}
+
+// QueryUser returns the cached user, loading it from the wrapped Database on a miss.
+// Lookup errors such as ErrUserNotFound are not cached. If the cache is unavailable the
+// user is read from the wrapped Database, so a Redis outage doesn't fail lookups.
+func (d *CachingDatabase) QueryUser(ctx context.Context, id string) (*User, error) {
+	var cached User
+	err := d.cache.Get(ctx, userKey(id), &cached)
+	if err == nil {
+		return &cached, nil
+	}
+	if !errors.Is(err, cache.ErrKeyNotFound) {
+		d.cacheFailed("Failed to read cached user", id, err)
+	}
+
+	user, err := d.db.QueryUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.cache.Set(ctx, userKey(id), user, d.ttl); err != nil {
+		d.cacheFailed("Failed to cache user", id, err)
+	}
+	return user, nil
+}
+
+// InsertUser implements Database
+func (d *CachingDatabase) InsertUser(ctx context.Context, user *User) error {
+	return d.db.InsertUser(ctx, user)
+}
+
+// UpdateUser updates the user and invalidates its cached copy. The cache is invalidated
+// even when the update fails, since a conflict means the cached copy is stale.
+// Only the database error is returned; a failed invalidation doesn't undo the write.
+func (d *CachingDatabase) UpdateUser(ctx context.Context, user *User, expectedVersion int64) error {
+	err := d.db.UpdateUser(ctx, user, expectedVersion)
+	d.invalidate(ctx, user.ID)
+	return err
+}
+
+// DeleteUser deletes the user and invalidates its cached copy
+func (d *CachingDatabase) DeleteUser(ctx context.Context, id string) error {
+	err := d.db.DeleteUser(ctx, id)
+	d.invalidate(ctx, id)
+	return err
+}
+
+// ListUsers implements Database. Pages are not cached.
+func (d *CachingDatabase) ListUsers(ctx context.Context, offset, limit int) ([]*User, int, error) {
+	return d.db.ListUsers(ctx, offset, limit)
+}
+
+// Close closes the wrapped Database. The cache is owned by the caller.
+func (d *CachingDatabase) Close() error {
+	return d.db.Close()
+}
+
+// CacheFailures returns how many cache reads and writes failed during lookups
+func (d *CachingDatabase) CacheFailures() int64 {
+	return atomic.LoadInt64(&d.cacheFailures)
+}
+
+// InvalidationFailures returns how many cached users could not be invalidated. Those
+// entries may be served stale until their TTL runs out.
+func (d *CachingDatabase) InvalidationFailures() int64 {
+	return atomic.LoadInt64(&d.invalidationFailures)
+}
+
+// invalidate removes a user from the cache, logging and counting failures
+func (d *CachingDatabase) invalidate(ctx context.Context, id string) {
+	if err := d.cache.Delete(ctx, userKey(id)); err != nil {
+		atomic.AddInt64(&d.invalidationFailures, 1)
+		d.logger.Error("Failed to invalidate cached user", "id", id, "error", err)
+	}
+}
+
+// cacheFailed logs and counts a cache failure during a lookup
+func (d *CachingDatabase) cacheFailed(message, id string, err error) {
+	atomic.AddInt64(&d.cacheFailures, 1)
+	d.logger.Error(message, "id", id, "error", err)
+}
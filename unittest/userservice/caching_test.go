@@ -0,0 +1,153 @@
+package userservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"huba/cache"
+)
+
+// newTestCachingDatabase returns a caching decorator over db backed by an in-process Redis
+func newTestCachingDatabase(t *testing.T, db Database, logger Logger) (*CachingDatabase, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	c, err := cache.NewRedisCache(cache.RedisConfig{Address: mr.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	return NewCachingDatabase(db, c, time.Minute, logger), mr
+}
+
+func TestCachingDatabase_QueryUserHit(t *testing.T) {
+	ctx := context.Background()
+	user := &User{ID: "123", Name: "Jane", Email: "jane@example.com", Version: 3}
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "123").Return(user, nil).Once()
+
+	db, mr := newTestCachingDatabase(t, mockDB, new(MockLogger))
+
+	first, err := db.QueryUser(ctx, "123")
+	require.NoError(t, err)
+	second, err := db.QueryUser(ctx, "123")
+	require.NoError(t, err)
+
+	assert.Equal(t, user, first)
+	assert.Equal(t, user, second)
+	assert.Equal(t, time.Minute, mr.TTL("user:123"))
+	// The second lookup was served from the cache
+	mockDB.AssertNumberOfCalls(t, "QueryUser", 1)
+}
+
+func TestCachingDatabase_NotFoundIsNotCached(t *testing.T) {
+	ctx := context.Background()
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "404").Return(nil, ErrUserNotFound)
+
+	db, mr := newTestCachingDatabase(t, mockDB, new(MockLogger))
+
+	for i := 0; i < 2; i++ {
+		_, err := db.QueryUser(ctx, "404")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	}
+	assert.False(t, mr.Exists("user:404"))
+	mockDB.AssertNumberOfCalls(t, "QueryUser", 2)
+}
+
+func TestCachingDatabase_WritesInvalidate(t *testing.T) {
+	ctx := context.Background()
+	user := &User{ID: "123", Name: "Jane", Email: "jane@example.com", Version: 1}
+	updated := &User{ID: "123", Name: "Janet", Email: "jane@example.com", Version: 2}
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "123").Return(user, nil).Once()
+	mockDB.On("UpdateUser", ctx, updated, int64(1)).Return(nil)
+	mockDB.On("QueryUser", ctx, "123").Return(updated, nil).Once()
+	mockDB.On("DeleteUser", ctx, "123").Return(nil)
+
+	db, mr := newTestCachingDatabase(t, mockDB, new(MockLogger))
+
+	_, err := db.QueryUser(ctx, "123")
+	require.NoError(t, err)
+	require.True(t, mr.Exists("user:123"))
+
+	require.NoError(t, db.UpdateUser(ctx, updated, 1))
+	assert.False(t, mr.Exists("user:123"))
+
+	got, err := db.QueryUser(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, "Janet", got.Name)
+
+	require.NoError(t, db.DeleteUser(ctx, "123"))
+	assert.False(t, mr.Exists("user:123"))
+	mockDB.AssertExpectations(t)
+}
+
+func TestCachingDatabase_ConflictInvalidates(t *testing.T) {
+	ctx := context.Background()
+	user := &User{ID: "123", Name: "Jane", Email: "jane@example.com", Version: 1}
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "123").Return(user, nil)
+	mockDB.On("UpdateUser", ctx, user, int64(1)).Return(ErrConflict)
+
+	db, mr := newTestCachingDatabase(t, mockDB, new(MockLogger))
+
+	_, err := db.QueryUser(ctx, "123")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, db.UpdateUser(ctx, user, 1), ErrConflict)
+	assert.False(t, mr.Exists("user:123"))
+}
+
+func TestCachingDatabase_InvalidationFailureKeepsWrite(t *testing.T) {
+	ctx := context.Background()
+	user := &User{ID: "123", Name: "Jane", Email: "jane@example.com", Version: 2}
+
+	mockDB := new(MockDatabase)
+	mockDB.On("UpdateUser", ctx, user, int64(1)).Return(nil)
+	mockDB.On("DeleteUser", ctx, "123").Return(nil)
+
+	mockLogger := new(MockLogger)
+	mockLogger.On("Error", "Failed to invalidate cached user", "id", "123", "error", mock.Anything).Return().Twice()
+
+	db, _ := newTestCachingDatabase(t, mockDB, mockLogger)
+	require.NoError(t, db.cache.Close())
+
+	// The writes succeeded, so a cache outage must not report them as failed
+	assert.NoError(t, db.UpdateUser(ctx, user, 1))
+	assert.NoError(t, db.DeleteUser(ctx, "123"))
+	assert.Equal(t, int64(2), db.InvalidationFailures())
+	mockDB.AssertExpectations(t)
+	mockLogger.AssertExpectations(t)
+}
+
+func TestCachingDatabase_QueryUserFallsBackWhenCacheIsDown(t *testing.T) {
+	ctx := context.Background()
+	user := &User{ID: "123", Name: "Jane", Email: "jane@example.com", Version: 1}
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "123").Return(user, nil)
+
+	mockLogger := new(MockLogger)
+	mockLogger.On("Error", "Failed to read cached user", "id", "123", "error", mock.Anything).Return()
+	mockLogger.On("Error", "Failed to cache user", "id", "123", "error", mock.Anything).Return()
+
+	db, mr := newTestCachingDatabase(t, mockDB, mockLogger)
+	mr.Close()
+
+	// The database is healthy, so the lookup succeeds without the cache
+	got, err := db.QueryUser(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+	assert.Equal(t, int64(2), db.CacheFailures())
+	mockLogger.AssertExpectations(t)
+}
@@ -0,0 +1,119 @@
+package userservice
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MethodStats holds the observations recorded for one Database method
+type MethodStats struct {
+	Calls         int64
+	Errors        int64
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+// AverageDuration returns the mean call duration
+func (s MethodStats) AverageDuration() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Calls)
+}
+
+// MetricsDatabase wraps a Database and records the duration and outcome of every call
+type MetricsDatabase struct {
+	db    Database
+	mu    sync.Mutex
+	stats map[string]MethodStats
+	now   func() time.Time
+}
+
+// NewMetricsDatabase creates a decorator that records metrics for db
+func NewMetricsDatabase(db Database) *MetricsDatabase {
+	return &MetricsDatabase{
+		db:    db,
+		stats: make(map[string]MethodStats),
+		now:   time.Now,
+	}
+}
+
+// Stats returns the observations recorded so far, keyed by method name
+func (m *MetricsDatabase) Stats() map[string]MethodStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]MethodStats, len(m.stats))
+	for method, s := range m.stats {
+		stats[method] = s
+	}
+	return stats
+}
+
+// observe records a call to method that started at start
+func (m *MetricsDatabase) observe(method string, start time.Time, err error) {
+	elapsed := m.now().Sub(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.stats[method]
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	s.TotalDuration += elapsed
+	if elapsed > s.MaxDuration {
+		s.MaxDuration = elapsed
+	}
+	m.stats[method] = s
+}
+
+// QueryUser implements Database
+func (m *MetricsDatabase) QueryUser(ctx context.Context, id string) (*User, error) {
+	start := m.now()
+	user, err := m.db.QueryUser(ctx, id)
+	m.observe("QueryUser", start, err)
+	return user, err
+}
+
+// InsertUser implements Database
+func (m *MetricsDatabase) InsertUser(ctx context.Context, user *User) error {
+	start := m.now()
+	err := m.db.InsertUser(ctx, user)
+	m.observe("InsertUser", start, err)
+	return err
+}
+
+// UpdateUser implements Database
+func (m *MetricsDatabase) UpdateUser(ctx context.Context, user *User, expectedVersion int64) error {
+	start := m.now()
+	err := m.db.UpdateUser(ctx, user, expectedVersion)
+	m.observe("UpdateUser", start, err)
+	return err
+}
+
+// DeleteUser implements Database
+func (m *MetricsDatabase) DeleteUser(ctx context.Context, id string) error {
+	start := m.now()
+	err := m.db.DeleteUser(ctx, id)
+	m.observe("DeleteUser", start, err)
+	return err
+}
+
+// ListUsers implements Database
+func (m *MetricsDatabase) ListUsers(ctx context.Context, offset, limit int) ([]*User, int, error) {
+	start := m.now()
+	users, total, err := m.db.ListUsers(ctx, offset, limit)
+	m.observe("ListUsers", start, err)
+	return users, total, err
+}
+
+// Close implements Database
+func (m *MetricsDatabase) Close() error {
+	start := m.now()
+	err := m.db.Close()
+	m.observe("Close", start, err)
+	return err
+}
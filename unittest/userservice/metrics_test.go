@@ -0,0 +1,98 @@
+package userservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMetricsDatabase returns a decorator whose clock advances by step on every reading
+func newTestMetricsDatabase(db Database, step time.Duration) *MetricsDatabase {
+	m := NewMetricsDatabase(db)
+	now := time.Unix(0, 0)
+	m.now = func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+	return m
+}
+
+func TestMetricsDatabase_ForwardsCalls(t *testing.T) {
+	ctx := context.Background()
+	user := &User{ID: "123", Name: "Jane", Email: "jane@example.com", Version: 2}
+	users := []*User{user}
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "123").Return(user, nil)
+	mockDB.On("InsertUser", ctx, user).Return(nil)
+	mockDB.On("UpdateUser", ctx, user, int64(1)).Return(nil)
+	mockDB.On("DeleteUser", ctx, "123").Return(nil)
+	mockDB.On("ListUsers", ctx, 10, 5).Return(users, 42, nil)
+	mockDB.On("Close").Return(nil)
+
+	db := newTestMetricsDatabase(mockDB, time.Millisecond)
+
+	got, err := db.QueryUser(ctx, "123")
+	require.NoError(t, err)
+	assert.Same(t, user, got)
+	require.NoError(t, db.InsertUser(ctx, user))
+	require.NoError(t, db.UpdateUser(ctx, user, 1))
+	require.NoError(t, db.DeleteUser(ctx, "123"))
+	list, total, err := db.ListUsers(ctx, 10, 5)
+	require.NoError(t, err)
+	assert.Equal(t, users, list)
+	assert.Equal(t, 42, total)
+	require.NoError(t, db.Close())
+
+	mockDB.AssertExpectations(t)
+
+	stats := db.Stats()
+	assert.Len(t, stats, 6)
+	for method, s := range stats {
+		assert.Equal(t, MethodStats{Calls: 1, TotalDuration: time.Millisecond, MaxDuration: time.Millisecond}, s, method)
+	}
+}
+
+func TestMetricsDatabase_RecordsErrors(t *testing.T) {
+	ctx := context.Background()
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "missing").Return(nil, ErrUserNotFound)
+	mockDB.On("DeleteUser", ctx, "123").Return(ErrDatabaseError).Once()
+	mockDB.On("DeleteUser", ctx, "123").Return(nil).Once()
+
+	db := newTestMetricsDatabase(mockDB, 2*time.Millisecond)
+
+	_, err := db.QueryUser(ctx, "missing")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, db.DeleteUser(ctx, "123"), ErrDatabaseError)
+	assert.NoError(t, db.DeleteUser(ctx, "123"))
+
+	stats := db.Stats()
+	assert.Equal(t, MethodStats{Calls: 1, Errors: 1, TotalDuration: 2 * time.Millisecond, MaxDuration: 2 * time.Millisecond}, stats["QueryUser"])
+	assert.Equal(t, int64(2), stats["DeleteUser"].Calls)
+	assert.Equal(t, int64(1), stats["DeleteUser"].Errors)
+	assert.Equal(t, 2*time.Millisecond, stats["DeleteUser"].AverageDuration())
+	assert.NotContains(t, stats, "InsertUser")
+}
+
+func TestMetricsDatabase_WithUserService(t *testing.T) {
+	ctx := context.Background()
+
+	mockDB := new(MockDatabase)
+	mockDB.On("QueryUser", ctx, "123").Return(nil, errors.New("connection reset"))
+	mockLogger := new(MockLogger)
+	mockLogger.On("Info", "Getting user").Return()
+	mockLogger.On("Error", "Failed to get user", "error", mock.Anything).Return()
+
+	db := NewMetricsDatabase(mockDB)
+	_, err := NewUserService(db, mockLogger).GetUser(ctx, "123")
+	assert.Error(t, err)
+
+	assert.Equal(t, int64(1), db.Stats()["QueryUser"].Errors)
+}
@@ -3,6 +3,8 @@ package userservice
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/mail"
 	"time"
 )
 
@@ -11,6 +13,7 @@ type User struct {
 	ID        string
 	Name      string
 	Email     string
+	Version   int64 // Incremented on every update for optimistic concurrency
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -20,14 +23,111 @@ var (
 	ErrUserNotFound = errors.New("user not found")
 	ErrInvalidUser  = errors.New("invalid user data")
 	ErrDatabaseError = errors.New("database error")
+	ErrConflict      = errors.New("user was modified concurrently")
 )
 
+// ValidationError describes a single invalid field. It matches ErrInvalidUser with errors.Is.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidUser, e.Field, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidUser
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidUser
+}
+
+// FieldErrors returns the validation errors contained in err, which may be a
+// single ValidationError or several joined with errors.Join
+func FieldErrors(err error) []*ValidationError {
+	var fieldErrs []*ValidationError
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			fieldErrs = append(fieldErrs, FieldErrors(e)...)
+		}
+		return fieldErrs
+	}
+
+	var fieldErr *ValidationError
+	if errors.As(err, &fieldErr) {
+		fieldErrs = append(fieldErrs, fieldErr)
+	}
+	return fieldErrs
+}
+
+// required returns a ValidationError if value is empty
+func required(field, value string) error {
+	if value == "" {
+		return &ValidationError{Field: field, Reason: "is required"}
+	}
+	return nil
+}
+
+// validEmail returns a ValidationError unless value is a bare email address
+func validEmail(value string) error {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return &ValidationError{Field: "email", Reason: "is not a valid email address"}
+	}
+	return nil
+}
+
+// validateNewUser checks the fields required to create a user
+func validateNewUser(user *User) error {
+	if user == nil {
+		return &ValidationError{Field: "user", Reason: "is required"}
+	}
+
+	emailErr := required("email", user.Email)
+	if emailErr == nil {
+		emailErr = validEmail(user.Email)
+	}
+	return errors.Join(required("name", user.Name), emailErr)
+}
+
+// validateExistingUser checks the fields required to update a user.
+// The email is only checked when it is set.
+func validateExistingUser(user *User) error {
+	if user == nil {
+		return &ValidationError{Field: "user", Reason: "is required"}
+	}
+
+	var emailErr error
+	if user.Email != "" {
+		emailErr = validEmail(user.Email)
+	}
+	return errors.Join(required("id", user.ID), emailErr)
+}
+
+// MaxPageSize is the largest limit accepted by ListUsers
+const MaxPageSize = 100
+
+// validatePage checks ListUsers pagination bounds
+func validatePage(offset, limit int) error {
+	var offsetErr, limitErr error
+	if offset < 0 {
+		offsetErr = &ValidationError{Field: "offset", Reason: "must not be negative"}
+	}
+	if limit < 1 || limit > MaxPageSize {
+		limitErr = &ValidationError{Field: "limit", Reason: fmt.Sprintf("must be between 1 and %d", MaxPageSize)}
+	}
+	return errors.Join(offsetErr, limitErr)
+}
+
 // Database defines the interface for data storage operations
 type Database interface {
 	QueryUser(ctx context.Context, id string) (*User, error)
 	InsertUser(ctx context.Context, user *User) error
-	UpdateUser(ctx context.Context, user *User) error
+	// UpdateUser stores user only if the stored version equals expectedVersion,
+	// returning ErrConflict otherwise. The stored version becomes expectedVersion+1.
+	UpdateUser(ctx context.Context, user *User, expectedVersion int64) error
 	DeleteUser(ctx context.Context, id string) error
+	ListUsers(ctx context.Context, offset, limit int) ([]*User, int, error)
 	Close() error
 }
 
@@ -52,13 +152,27 @@ func NewUserService(db Database, logger Logger) *UserService {
 	}
 }
 
+// checkContext returns the context's error if the request was cancelled or its deadline
+// passed, so no database work is started for it
+func (s *UserService) checkContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		s.logger.Error("Request cancelled", "error", err)
+		return err
+	}
+	return nil
+}
+
 // GetUser retrieves a user by ID
 func (s *UserService) GetUser(ctx context.Context, id string) (*User, error) {
 	s.logger.Info("Getting user")
 	
-	if id == "" {
-		s.logger.Error("Invalid user ID provided")
-		return nil, ErrInvalidUser
+	if err := required("id", id); err != nil {
+		s.logger.Error("Invalid user ID provided", "error", err)
+		return nil, err
+	}
+	
+	if err := s.checkContext(ctx); err != nil {
+		return nil, err
 	}
 	
 	user, err := s.db.QueryUser(ctx, id)
@@ -75,15 +189,20 @@ func (s *UserService) GetUser(ctx context.Context, id string) (*User, error) {
 func (s *UserService) CreateUser(ctx context.Context, user *User) error {
 	s.logger.Info("Creating user")
 	
-	if user == nil || user.Name == "" || user.Email == "" {
-		s.logger.Error("Invalid user data provided")
-		return ErrInvalidUser
+	if err := validateNewUser(user); err != nil {
+		s.logger.Error("Invalid user data provided", "error", err)
+		return err
+	}
+	
+	if err := s.checkContext(ctx); err != nil {
+		return err
 	}
 	
 	// Set timestamps
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
+	user.Version = 1
 	
 	err := s.db.InsertUser(ctx, user)
 	if err != nil {
@@ -95,20 +214,30 @@ func (s *UserService) CreateUser(ctx context.Context, user *User) error {
 	return nil
 }
 
-// UpdateUser updates an existing user
+// UpdateUser updates an existing user. It returns ErrConflict if the user was updated
+// since user.Version was read; callers should reload the user and retry.
 func (s *UserService) UpdateUser(ctx context.Context, user *User) error {
 	s.logger.Info("Updating user")
 	
-	if user == nil || user.ID == "" {
-		s.logger.Error("Invalid user data provided")
-		return ErrInvalidUser
+	if err := validateExistingUser(user); err != nil {
+		s.logger.Error("Invalid user data provided", "error", err)
+		return err
+	}
+	
+	if err := s.checkContext(ctx); err != nil {
+		return err
 	}
 	
 	// Update timestamp
 	user.UpdatedAt = time.Now()
 	
-	err := s.db.UpdateUser(ctx, user)
+	// The caller's version is the one it read; the update succeeds only if it is still current
+	expectedVersion := user.Version
+	user.Version = expectedVersion + 1
+	
+	err := s.db.UpdateUser(ctx, user, expectedVersion)
 	if err != nil {
+		user.Version = expectedVersion
 		s.logger.Error("Failed to update user", "error", err)
 		return err
 	}
@@ -121,9 +250,13 @@ func (s *UserService) UpdateUser(ctx context.Context, user *User) error {
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 	s.logger.Info("Deleting user")
 	
-	if id == "" {
-		s.logger.Error("Invalid user ID provided")
-		return ErrInvalidUser
+	if err := required("id", id); err != nil {
+		s.logger.Error("Invalid user ID provided", "error", err)
+		return err
+	}
+	
+	if err := s.checkContext(ctx); err != nil {
+		return err
 	}
 	
 	err := s.db.DeleteUser(ctx, id)
@@ -134,4 +267,27 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 	
 	s.logger.Info("User deleted successfully")
 	return nil
-}
\ No newline at end of file
+}
+
+// ListUsers returns a page of users starting at offset, along with the total number of users
+func (s *UserService) ListUsers(ctx context.Context, offset, limit int) ([]*User, int, error) {
+	s.logger.Info("Listing users", "offset", offset, "limit", limit)
+
+	if err := validatePage(offset, limit); err != nil {
+		s.logger.Error("Invalid pagination provided", "error", err)
+		return nil, 0, err
+	}
+
+	if err := s.checkContext(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	users, total, err := s.db.ListUsers(ctx, offset, limit)
+	if err != nil {
+		s.logger.Error("Failed to list users", "error", err)
+		return nil, 0, err
+	}
+
+	s.logger.Info("Users listed successfully", "count", len(users), "total", total)
+	return users, total, nil
+}
@@ -28,8 +28,8 @@ func (m *MockDatabase) InsertUser(ctx context.Context, user *User) error {
 	return args.Error(0)
 }
 
-func (m *MockDatabase) UpdateUser(ctx context.Context, user *User) error {
-	args := m.Called(ctx, user)
+func (m *MockDatabase) UpdateUser(ctx context.Context, user *User, expectedVersion int64) error {
+	args := m.Called(ctx, user, expectedVersion)
 	return args.Error(0)
 }
 
@@ -38,6 +38,14 @@ func (m *MockDatabase) DeleteUser(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
+func (m *MockDatabase) ListUsers(ctx context.Context, offset, limit int) ([]*User, int, error) {
+	args := m.Called(ctx, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*User), args.Int(1), args.Error(2)
+}
+
 func (m *MockDatabase) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -146,7 +154,7 @@ func TestUserService_GetUser(t *testing.T) {
 			userID: "",
 			mockSetup: func(db *MockDatabase, logger *MockLogger) {
 				infoCall := logger.On("Info", "Getting user").Return()
-				errorCall := logger.On("Error", "Invalid user ID provided").Return()
+				errorCall := logger.On("Error", "Invalid user ID provided", "error", mock.Anything).Return()
 
 				mock.InOrder(
 					infoCall,
@@ -180,7 +188,7 @@ func TestUserService_GetUser(t *testing.T) {
 			// Assert expectations
 			if tc.expectedErr != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tc.expectedErr, err)
+				assert.ErrorIs(t, err, tc.expectedErr)
 				assert.Nil(t, user)
 			} else {
 				assert.NoError(t, err)
@@ -335,7 +343,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 						u.Name == "Updated Name" &&
 						u.Email == "updated@example.com" &&
 						!u.UpdatedAt.IsZero()
-				})).Return(nil)
+				}), int64(0)).Return(nil)
 
 				infoSuccessCall := logger.On("Info", "User updated successfully").Return()
 
@@ -352,7 +360,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 			user: nil,
 			setupMocks: func(db *MockDatabase, logger *MockLogger) {
 				infoCall := logger.On("Info", "Updating user").Return()
-				errorCall := logger.On("Error", "Invalid user data provided").Return()
+				errorCall := logger.On("Error", "Invalid user data provided", "error", mock.Anything).Return()
 
 				mock.InOrder(
 					infoCall,
@@ -369,7 +377,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 			},
 			setupMocks: func(db *MockDatabase, logger *MockLogger) {
 				infoCall := logger.On("Info", "Updating user").Return()
-				errorCall := logger.On("Error", "Invalid user data provided").Return()
+				errorCall := logger.On("Error", "Invalid user data provided", "error", mock.Anything).Return()
 
 				mock.InOrder(
 					infoCall,
@@ -390,7 +398,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 
 				updateCall := db.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u *User) bool {
 					return u.ID == "456"
-				})).Return(ErrDatabaseError)
+				}), int64(0)).Return(ErrDatabaseError)
 
 				errorCall := logger.On("Error", "Failed to update user", "error", ErrDatabaseError).Return()
 
@@ -426,7 +434,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 			// Assert expectations
 			if tc.expectedErr != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tc.expectedErr, err)
+				assert.ErrorIs(t, err, tc.expectedErr)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -437,3 +445,328 @@ func TestUserService_UpdateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		call   func(s *UserService) error
+		fields map[string]string
+	}{
+		{
+			name:   "GetUser - empty ID",
+			call:   func(s *UserService) error { _, err := s.GetUser(context.Background(), ""); return err },
+			fields: map[string]string{"id": "is required"},
+		},
+		{
+			name:   "CreateUser - nil user",
+			call:   func(s *UserService) error { return s.CreateUser(context.Background(), nil) },
+			fields: map[string]string{"user": "is required"},
+		},
+		{
+			name: "CreateUser - empty name",
+			call: func(s *UserService) error {
+				return s.CreateUser(context.Background(), &User{Email: "jane@example.com"})
+			},
+			fields: map[string]string{"name": "is required"},
+		},
+		{
+			name:   "CreateUser - empty name and email",
+			call:   func(s *UserService) error { return s.CreateUser(context.Background(), &User{}) },
+			fields: map[string]string{"name": "is required", "email": "is required"},
+		},
+		{
+			name:   "UpdateUser - nil user",
+			call:   func(s *UserService) error { return s.UpdateUser(context.Background(), nil) },
+			fields: map[string]string{"user": "is required"},
+		},
+		{
+			name:   "UpdateUser - empty ID",
+			call:   func(s *UserService) error { return s.UpdateUser(context.Background(), &User{Name: "Jane"}) },
+			fields: map[string]string{"id": "is required"},
+		},
+		{
+			name:   "DeleteUser - empty ID",
+			call:   func(s *UserService) error { return s.DeleteUser(context.Background(), "") },
+			fields: map[string]string{"id": "is required"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockDB := new(MockDatabase)
+			mockLogger := new(MockLogger)
+			mockLogger.On("Info", mock.Anything).Return()
+			mockLogger.On("Error", mock.Anything, "error", mock.Anything).Return()
+
+			err := tc.call(NewUserService(mockDB, mockLogger))
+
+			// Field errors still match the generic sentinel
+			require.ErrorIs(t, err, ErrInvalidUser)
+
+			fields := make(map[string]string)
+			for _, fieldErr := range FieldErrors(err) {
+				fields[fieldErr.Field] = fieldErr.Reason
+			}
+			assert.Equal(t, tc.fields, fields)
+
+			// Invalid input never reaches the database
+			mockDB.AssertExpectations(t)
+			assert.Empty(t, mockDB.Calls)
+		})
+	}
+}
+
+func TestUserService_EmailValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		email  string
+		reason string // Empty for a valid address
+	}{
+		{name: "valid", email: "jane@example.com"},
+		{name: "valid subdomain and plus tag", email: "jane+news@mail.example.co.uk"},
+		{name: "missing @", email: "jane.example.com", reason: "is not a valid email address"},
+		{name: "missing domain", email: "jane@", reason: "is not a valid email address"},
+		{name: "display name form", email: "Jane <jane@example.com>", reason: "is not a valid email address"},
+		{name: "empty", email: "", reason: "is required"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockDB := new(MockDatabase)
+			mockLogger := new(MockLogger)
+			mockLogger.On("Info", mock.Anything).Return()
+			if tc.reason == "" {
+				mockDB.On("InsertUser", mock.Anything, mock.Anything).Return(nil)
+			} else {
+				mockLogger.On("Error", "Invalid user data provided", "error", mock.Anything).Return().Once()
+			}
+
+			err := NewUserService(mockDB, mockLogger).CreateUser(context.Background(), &User{Name: "Jane", Email: tc.email})
+
+			if tc.reason == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrInvalidUser)
+				fieldErrs := FieldErrors(err)
+				require.Len(t, fieldErrs, 1)
+				assert.Equal(t, "email", fieldErrs[0].Field)
+				assert.Equal(t, tc.reason, fieldErrs[0].Reason)
+				mockDB.AssertNotCalled(t, "InsertUser", mock.Anything, mock.Anything)
+			}
+			mockDB.AssertExpectations(t)
+			mockLogger.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_UpdateUserInvalidEmail(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockLogger := new(MockLogger)
+	mockLogger.On("Info", "Updating user").Return()
+	mockLogger.On("Error", "Invalid user data provided", "error", mock.Anything).Return()
+
+	err := NewUserService(mockDB, mockLogger).UpdateUser(context.Background(), &User{ID: "123", Email: "not-an-email"})
+
+	require.ErrorIs(t, err, ErrInvalidUser)
+	fieldErrs := FieldErrors(err)
+	require.Len(t, fieldErrs, 1)
+	assert.Equal(t, "email", fieldErrs[0].Field)
+	mockDB.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestUserService_ListUsers(t *testing.T) {
+	page := []*User{
+		{ID: "11", Name: "Alice", Email: "alice@example.com"},
+		{ID: "12", Name: "Bob", Email: "bob@example.com"},
+	}
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		setupMocks    func(*MockDatabase, *MockLogger)
+		expectedUsers []*User
+		expectedTotal int
+		expectedErr   error
+		invalidFields []string
+	}{
+		{
+			name:   "Success - page and total propagated",
+			offset: 10,
+			limit:  2,
+			setupMocks: func(db *MockDatabase, logger *MockLogger) {
+				mock.InOrder(
+					logger.On("Info", "Listing users", "offset", 10, "limit", 2).Return(),
+					db.On("ListUsers", mock.Anything, 10, 2).Return(page, 42, nil),
+					logger.On("Info", "Users listed successfully", "count", 2, "total", 42).Return(),
+				)
+			},
+			expectedUsers: page,
+			expectedTotal: 42,
+		},
+		{
+			name:   "Error - Database error",
+			offset: 0,
+			limit:  10,
+			setupMocks: func(db *MockDatabase, logger *MockLogger) {
+				mock.InOrder(
+					logger.On("Info", "Listing users", "offset", 0, "limit", 10).Return(),
+					db.On("ListUsers", mock.Anything, 0, 10).Return(nil, 0, ErrDatabaseError),
+					logger.On("Error", "Failed to list users", "error", ErrDatabaseError).Return(),
+				)
+			},
+			expectedErr: ErrDatabaseError,
+		},
+		{
+			name:          "Error - Negative offset",
+			offset:        -1,
+			limit:         10,
+			expectedErr:   ErrInvalidUser,
+			invalidFields: []string{"offset"},
+		},
+		{
+			name:          "Error - Zero limit",
+			offset:        0,
+			limit:         0,
+			expectedErr:   ErrInvalidUser,
+			invalidFields: []string{"limit"},
+		},
+		{
+			name:          "Error - Limit above maximum",
+			offset:        -5,
+			limit:         MaxPageSize + 1,
+			expectedErr:   ErrInvalidUser,
+			invalidFields: []string{"offset", "limit"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockDB := new(MockDatabase)
+			mockLogger := new(MockLogger)
+			if tc.setupMocks != nil {
+				tc.setupMocks(mockDB, mockLogger)
+			} else {
+				mockLogger.On("Info", "Listing users", "offset", tc.offset, "limit", tc.limit).Return()
+				mockLogger.On("Error", "Invalid pagination provided", "error", mock.Anything).Return()
+			}
+
+			users, total, err := NewUserService(mockDB, mockLogger).ListUsers(context.Background(), tc.offset, tc.limit)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, users)
+				assert.Zero(t, total)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedUsers, users)
+				assert.Equal(t, tc.expectedTotal, total)
+			}
+
+			var fields []string
+			for _, fieldErr := range FieldErrors(err) {
+				fields = append(fields, fieldErr.Field)
+			}
+			assert.Equal(t, tc.invalidFields, fields)
+
+			if tc.invalidFields != nil {
+				mockDB.AssertNotCalled(t, "ListUsers", mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockDB.AssertExpectations(t)
+			mockLogger.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_UpdateUserVersionConflict(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockLogger := new(MockLogger)
+
+	// The stored user is at version 4, so an update based on version 3 conflicts
+	mockLogger.On("Info", "Updating user").Return()
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything, int64(3)).Return(ErrConflict)
+	mockLogger.On("Error", "Failed to update user", "error", ErrConflict).Return()
+
+	user := &User{ID: "123", Name: "Stale", Email: "stale@example.com", Version: 3}
+	err := NewUserService(mockDB, mockLogger).UpdateUser(context.Background(), user)
+
+	assert.ErrorIs(t, err, ErrConflict)
+	// The caller's copy keeps the version it read so it can reload and retry
+	assert.Equal(t, int64(3), user.Version)
+	mockDB.AssertExpectations(t)
+	mockLogger.AssertExpectations(t)
+}
+
+func TestUserService_UpdateUserIncrementsVersion(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockLogger := new(MockLogger)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u *User) bool {
+		return u.Version == 4
+	}), int64(3)).Return(nil)
+
+	user := &User{ID: "123", Name: "Fresh", Email: "fresh@example.com", Version: 3}
+	require.NoError(t, NewUserService(mockDB, mockLogger).UpdateUser(context.Background(), user))
+
+	assert.Equal(t, int64(4), user.Version)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUserService_CancelledContext(t *testing.T) {
+	valid := func() *User { return &User{ID: "123", Name: "Jane", Email: "jane@example.com"} }
+
+	tests := []struct {
+		name string
+		call func(s *UserService, ctx context.Context) error
+	}{
+		{"GetUser", func(s *UserService, ctx context.Context) error { _, err := s.GetUser(ctx, "123"); return err }},
+		{"CreateUser", func(s *UserService, ctx context.Context) error { return s.CreateUser(ctx, valid()) }},
+		{"UpdateUser", func(s *UserService, ctx context.Context) error { return s.UpdateUser(ctx, valid()) }},
+		{"DeleteUser", func(s *UserService, ctx context.Context) error { return s.DeleteUser(ctx, "123") }},
+		{"ListUsers", func(s *UserService, ctx context.Context) error { _, _, err := s.ListUsers(ctx, 0, 10); return err }},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockDB := new(MockDatabase)
+			mockLogger := new(MockLogger)
+			mockLogger.On("Info", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+			mockLogger.On("Info", mock.Anything).Maybe().Return()
+			mockLogger.On("Error", "Request cancelled", "error", context.Canceled).Return().Once()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := tc.call(NewUserService(mockDB, mockLogger), ctx)
+
+			assert.ErrorIs(t, err, context.Canceled)
+			// No database method was called
+			assert.Empty(t, mockDB.Calls)
+			mockLogger.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_DeadlineExceeded(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockLogger := new(MockLogger)
+	mockLogger.On("Info", "Getting user").Return()
+	mockLogger.On("Error", "Request cancelled", "error", context.DeadlineExceeded).Return()
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	user, err := NewUserService(mockDB, mockLogger).GetUser(ctx, "123")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, user)
+	mockDB.AssertNotCalled(t, "QueryUser", mock.Anything, mock.Anything)
+}
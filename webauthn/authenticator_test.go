@@ -0,0 +1,208 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost"
+)
+
+// testAAGUID identifies the software authenticator in tests
+var testAAGUID = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+
+// softAuthenticator is a minimal software authenticator producing "none" attestations
+// and ES256 assertions, so the registration and login flows can be exercised end to end.
+// With an attestation key and chain it produces "packed" attestations instead.
+type softAuthenticator struct {
+	key            *ecdsa.PrivateKey
+	credentialID   []byte
+	aaguid         []byte
+	signCount      uint32
+	attestationKey *ecdsa.PrivateKey
+	x5c            [][]byte // DER certificates, leaf first
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+
+	return &softAuthenticator{key: key, credentialID: id, aaguid: testAAGUID}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// clientData builds the clientDataJSON for a ceremony
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": b64(challenge),
+		"origin":    testOrigin,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+// authData builds authenticator data, including attested credential data when attest is set
+func (a *softAuthenticator) authData(t *testing.T, attest bool) []byte {
+	t.Helper()
+
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attest {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attest {
+		publicKey, err := webauthncbor.Marshal(map[int]interface{}{
+			1:  2,  // kty: EC2
+			3:  -7, // alg: ES256
+			-1: 1,  // crv: P-256
+			-2: a.key.X.FillBytes(make([]byte, 32)),
+			-3: a.key.Y.FillBytes(make([]byte, 32)),
+		})
+		require.NoError(t, err)
+
+		data = append(data, a.aaguid...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, publicKey...)
+	}
+
+	return data
+}
+
+// registrationResponse answers a registration challenge
+func (a *softAuthenticator) registrationResponse(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+
+	authData := a.authData(t, true)
+	clientData := a.clientData(t, "webauthn.create", options.Response.Challenge)
+
+	format, statement := "none", map[string]interface{}{}
+	if a.attestationKey != nil {
+		clientDataHash := sha256.Sum256(clientData)
+		digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+		signature, err := ecdsa.SignASN1(rand.Reader, a.attestationKey, digest[:])
+		require.NoError(t, err)
+
+		x5c := make([]interface{}, len(a.x5c))
+		for i, cert := range a.x5c {
+			x5c[i] = cert
+		}
+		format, statement = "packed", map[string]interface{}{"alg": -7, "sig": signature, "x5c": x5c}
+	}
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      format,
+		"attStmt":  statement,
+		"authData": authData,
+	})
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientData),
+			"attestationObject": b64(attestation),
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+// loginResponse answers a login challenge, incrementing the signature counter
+func (a *softAuthenticator) loginResponse(t *testing.T, options *protocol.CredentialAssertion, userHandle []byte) []byte {
+	t.Helper()
+
+	a.signCount++
+	authData := a.authData(t, false)
+	clientData := a.clientData(t, "webauthn.get", options.Response.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientData),
+			"authenticatorData": b64(authData),
+			"signature":         b64(signature),
+			"userHandle":        b64(userHandle),
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	service, err := NewService(testRPID, testOrigin, "Test")
+	require.NoError(t, err)
+	t.Cleanup(func() { service.Close() })
+	return service
+}
+
+// finishRequest builds a finish request as a browser would send it
+func finishRequest(body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
+	req.RemoteAddr = "203.0.113.7:54321"
+	return req
+}
+
+// register runs a full registration for username with the authenticator
+func register(t *testing.T, service *Service, auth *softAuthenticator, username string) *User {
+	t.Helper()
+
+	options, user, err := service.BeginRegistration(username, username, ClientInfo{})
+	require.NoError(t, err)
+	require.NoError(t, service.FinishRegistration(username, finishRequest(auth.registrationResponse(t, options))))
+	return user
+}
+
+// login runs a full login for username with the authenticator
+func login(t *testing.T, service *Service, auth *softAuthenticator, user *User) error {
+	t.Helper()
+
+	options, err := service.BeginLogin(user.Name, ClientInfo{})
+	require.NoError(t, err)
+	return service.FinishLogin(user.Name, finishRequest(auth.loginResponse(t, options, user.ID)))
+}
@@ -0,0 +1,70 @@
+package webauthn
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight response
+const corsMaxAge = 10 * 60
+
+// corsPolicy lists the origins allowed to call the handlers from another origin
+type corsPolicy struct {
+	origins map[string]bool
+}
+
+// SetCORSOrigins allows front ends served from the given origins, such as
+// "https://app.example.com", to call the handlers registered by RegisterHandlers with
+// credentials. Each origin must be one of the service's RP origins, since browsers only
+// complete ceremonies for pages on those origins.
+func (h *Handlers) SetCORSOrigins(origins ...string) error {
+	rpOrigins := make(map[string]bool)
+	for _, origin := range h.service.webAuthn.Config.RPOrigins {
+		rpOrigins[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	policy := &corsPolicy{origins: make(map[string]bool)}
+	for _, origin := range origins {
+		origin = strings.TrimSuffix(origin, "/")
+		if !rpOrigins[origin] {
+			return fmt.Errorf("CORS origin %q is not a relying party origin", origin)
+		}
+		policy.origins[origin] = true
+	}
+
+	h.cors = policy
+	return nil
+}
+
+// withCORS adds CORS headers for allowed origins and answers preflight requests.
+// Requests from other origins get no CORS headers, so browsers block them.
+func (h *Handlers) withCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := h.cors != nil && origin != "" && h.cors.origins[origin]
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		w.Header().Add("Vary", "Origin")
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			next(w, r)
+			return
+		}
+
+		if !allowed || r.Header.Get("Access-Control-Request-Method") != http.MethodPost {
+			http.Error(w, "CORS request not allowed", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
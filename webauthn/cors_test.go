@@ -0,0 +1,146 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMux returns a mux serving the service's handlers with CORS enabled for testOrigin
+func newTestMux(t *testing.T, service *Service) *http.ServeMux {
+	t.Helper()
+
+	handlers := NewHandlers(service)
+	require.NoError(t, handlers.SetCORSOrigins(testOrigin))
+
+	mux := http.NewServeMux()
+	handlers.RegisterHandlers(mux)
+	return mux
+}
+
+func preflight(origin, method string) *http.Request {
+	req := httptest.NewRequest(http.MethodOptions, "/webauthn/register/begin", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	req.Header.Set("Access-Control-Request-Headers", "content-type")
+	return req
+}
+
+func TestHandlers_CORSPreflight(t *testing.T) {
+	mux := newTestMux(t, newTestService(t))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, preflight(testOrigin, http.MethodPost))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, testOrigin, rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+	assert.Equal(t, "Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+
+	// Only POST is allowed
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, preflight(testOrigin, http.MethodDelete))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestHandlers_CORSDisallowedOrigin(t *testing.T) {
+	mux := newTestMux(t, newTestService(t))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, preflight("https://evil.example", http.MethodPost))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// Simple requests are served but without CORS headers, so the browser hides the response
+	req := httptest.NewRequest(http.MethodPost, "/webauthn/login/begin", strings.NewReader(`{"username":"nobody"}`))
+	req.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestHandlers_SetCORSOriginsValidatesRPOrigins(t *testing.T) {
+	handlers := NewHandlers(newTestService(t))
+	assert.NoError(t, handlers.SetCORSOrigins(testOrigin+"/"))
+	assert.ErrorContains(t, handlers.SetCORSOrigins("https://app.example.com"), "not a relying party origin")
+}
+
+func TestHandlers_BeginRegistrationFormats(t *testing.T) {
+	mux := newTestMux(t, newTestService(t))
+
+	begin := func(query string) map[string]json.RawMessage {
+		req := httptest.NewRequest(http.MethodPost, "/webauthn/register/begin"+query, strings.NewReader(`{"username":"alice","displayName":"Alice"}`))
+		req.Header.Set("Origin", testOrigin)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, testOrigin, rec.Header().Get("Access-Control-Allow-Origin"))
+
+		var body map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body
+	}
+
+	// The default response wraps the options in publicKey
+	wrapped := begin("")
+	require.Contains(t, wrapped, "publicKey")
+	assert.NotContains(t, wrapped, "challenge")
+
+	// The Level 3 JSON format is the options themselves, with base64url binary fields
+	options := begin("?format=webauthn-json")
+	assert.NotContains(t, options, "publicKey")
+	for _, field := range []string{"rp", "user", "challenge", "pubKeyCredParams"} {
+		assert.Contains(t, options, field)
+	}
+
+	var challenge string
+	require.NoError(t, json.Unmarshal(options["challenge"], &challenge))
+	_, err := base64.RawURLEncoding.DecodeString(challenge)
+	assert.NoError(t, err)
+
+	var user struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	require.NoError(t, json.Unmarshal(options["user"], &user))
+	assert.Equal(t, "alice", user.Name)
+	_, err = base64.RawURLEncoding.DecodeString(user.ID)
+	assert.NoError(t, err)
+}
+
+func TestHandlers_BeginLoginWebAuthnJSON(t *testing.T) {
+	service := newTestService(t)
+	auth := newSoftAuthenticator(t)
+	register(t, service, auth, "alice")
+	mux := newTestMux(t, service)
+
+	req := httptest.NewRequest(http.MethodPost, "/webauthn/login/begin?format=webauthn-json", strings.NewReader(`{"username":"alice"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var options struct {
+		Challenge        string `json:"challenge"`
+		RPID             string `json:"rpId"`
+		AllowCredentials []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"allowCredentials"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &options))
+	assert.NotEmpty(t, options.Challenge)
+	assert.Equal(t, testRPID, options.RPID)
+	require.Len(t, options.AllowCredentials, 1)
+	assert.Equal(t, b64(auth.credentialID), options.AllowCredentials[0].ID)
+	assert.Equal(t, "public-key", options.AllowCredentials[0].Type)
+}
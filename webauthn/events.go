@@ -0,0 +1,171 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// eventQueueSize bounds the number of events waiting for delivery to callbacks
+const eventQueueSize = 256
+
+// EventType identifies what happened in a WebAuthn flow
+type EventType string
+
+const (
+	// EventRegistrationStarted fires when registration options are issued
+	EventRegistrationStarted EventType = "registration.started"
+	// EventRegistrationCompleted fires when a new credential is registered
+	EventRegistrationCompleted EventType = "registration.completed"
+	// EventLoginStarted fires when login options are issued
+	EventLoginStarted EventType = "login.started"
+	// EventLoginCompleted fires when a login assertion is verified
+	EventLoginCompleted EventType = "login.completed"
+	// EventLoginFailed fires when a login can't be completed
+	EventLoginFailed EventType = "login.failed"
+	// EventCredentialDeleted fires when a credential is removed from a user
+	EventCredentialDeleted EventType = "credential.deleted"
+)
+
+// Event describes a WebAuthn registration, login or credential change
+type Event struct {
+	Type         EventType `json:"type"`
+	Username     string    `json:"username"`
+	CredentialID string    `json:"credential_id,omitempty"` // base64url encoded
+	AAGUID       string    `json:"aaguid,omitempty"`
+	ClientIP     string    `json:"client_ip,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// ClientInfo identifies the client that triggered an event
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// ClientInfoFromRequest extracts the client's address and user agent from a request
+func ClientInfoFromRequest(r *http.Request) ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return ClientInfo{IP: ip, UserAgent: r.UserAgent()}
+}
+
+// eventDispatcher delivers events to callbacks on a bounded queue so slow callbacks
+// never block the WebAuthn flows. Events are dropped when the queue is full.
+type eventDispatcher struct {
+	mu        sync.RWMutex
+	callbacks []func(Event)
+	queue     chan Event
+	dropped   int64
+	closed    bool
+	startOnce sync.Once
+	done      chan struct{}
+}
+
+func newEventDispatcher() *eventDispatcher {
+	return &eventDispatcher{
+		queue: make(chan Event, eventQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// subscribe registers a callback and starts delivery on first use
+func (d *eventDispatcher) subscribe(callback func(Event)) {
+	d.mu.Lock()
+	d.callbacks = append(d.callbacks, callback)
+	d.mu.Unlock()
+
+	d.startOnce.Do(func() { go d.run() })
+}
+
+// emit queues an event without blocking
+func (d *eventDispatcher) emit(e Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed || len(d.callbacks) == 0 {
+		return
+	}
+
+	select {
+	case d.queue <- e:
+	default:
+		atomic.AddInt64(&d.dropped, 1)
+	}
+}
+
+// run delivers queued events until the dispatcher is closed
+func (d *eventDispatcher) run() {
+	defer close(d.done)
+	for e := range d.queue {
+		d.mu.RLock()
+		callbacks := d.callbacks
+		d.mu.RUnlock()
+
+		for _, callback := range callbacks {
+			d.deliver(callback, e)
+		}
+	}
+}
+
+// deliver runs a single callback, recovering from panics so one bad callback can't stop delivery
+func (d *eventDispatcher) deliver(callback func(Event), e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("WebAuthn event callback panicked: type=%s panic=%v", e.Type, r)
+		}
+	}()
+	callback(e)
+}
+
+// close stops accepting events and waits for queued events to be delivered
+func (d *eventDispatcher) close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	started := len(d.callbacks) > 0
+	close(d.queue)
+	d.mu.Unlock()
+
+	if started {
+		<-d.done
+	}
+}
+
+// newEvent builds an event stamped with the current time
+func newEvent(eventType EventType, username string, client ClientInfo) Event {
+	return Event{
+		Type:      eventType,
+		Username:  username,
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// encodeCredentialID encodes a credential ID the way WebAuthn clients do
+func encodeCredentialID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// formatAAGUID renders an authenticator AAGUID as a UUID string
+func formatAAGUID(aaguid []byte) string {
+	id, err := uuid.FromBytes(aaguid)
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
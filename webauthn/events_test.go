@@ -0,0 +1,148 @@
+package webauthn
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"huba/cryptoutils/hmac"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder collects delivered events
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) record(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) types() []EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	types := make([]EventType, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func (r *eventRecorder) last() Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[len(r.events)-1]
+}
+
+func TestService_EventsForEachFlow(t *testing.T) {
+	service := newTestService(t)
+	recorder := &eventRecorder{}
+	service.OnEvent(recorder.record)
+
+	auth := newSoftAuthenticator(t)
+	user := register(t, service, auth, "alice")
+	require.NoError(t, login(t, service, auth, user))
+
+	// A login with an unknown key fails
+	options, err := service.BeginLogin("alice", ClientInfo{})
+	require.NoError(t, err)
+	impostor := newSoftAuthenticator(t)
+	impostor.credentialID = auth.credentialID
+	assert.Error(t, service.FinishLogin("alice", finishRequest(impostor.loginResponse(t, options, user.ID))))
+
+	require.NoError(t, service.DeleteCredential("alice", auth.credentialID, ClientInfo{IP: "198.51.100.1"}))
+	assert.ErrorIs(t, service.DeleteCredential("alice", auth.credentialID, ClientInfo{}), ErrCredentialNotFound)
+
+	require.NoError(t, service.Close())
+
+	assert.Equal(t, []EventType{
+		EventRegistrationStarted,
+		EventRegistrationCompleted,
+		EventLoginStarted,
+		EventLoginCompleted,
+		EventLoginStarted,
+		EventLoginFailed,
+		EventCredentialDeleted,
+	}, recorder.types())
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	completed := recorder.events[1]
+	assert.Equal(t, "alice", completed.Username)
+	assert.Equal(t, b64(auth.credentialID), completed.CredentialID)
+	assert.Equal(t, "01020304-0506-0708-090a-0b0c0d0e0f10", completed.AAGUID)
+	assert.Equal(t, "203.0.113.7", completed.ClientIP)
+	assert.Equal(t, "test-agent", completed.UserAgent)
+	assert.False(t, completed.Timestamp.IsZero())
+
+	assert.Equal(t, b64(auth.credentialID), recorder.events[3].CredentialID)
+	assert.NotEmpty(t, recorder.events[5].Error)
+	assert.Equal(t, "198.51.100.1", recorder.events[6].ClientIP)
+}
+
+func TestService_SlowCallbackDropsEvents(t *testing.T) {
+	service := newTestService(t)
+
+	release := make(chan struct{})
+	service.OnEvent(func(e Event) { <-release })
+
+	start := time.Now()
+	for i := 0; i < eventQueueSize+50; i++ {
+		service.events.emit(newEvent(EventLoginStarted, "bob", ClientInfo{}))
+	}
+	// Emitting never blocks on the slow callback
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Positive(t, service.DroppedEvents())
+
+	close(release)
+}
+
+func TestWebhookSender_SignsPayload(t *testing.T) {
+	secret := []byte("webhook-secret")
+	verifier, err := hmac.NewHMAC(secret, hmac.SHA256, hmac.HEX)
+	require.NoError(t, err)
+
+	received := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- verifier.Verify(body, r.Header.Get(SignatureHeader))
+	}))
+	defer server.Close()
+
+	sender, err := NewWebhookSender(server.URL, secret)
+	require.NoError(t, err)
+
+	service := newTestService(t)
+	service.OnEvent(sender.Handle)
+	_, _, err = service.BeginRegistration("carol", "Carol", ClientInfo{})
+	require.NoError(t, err)
+
+	select {
+	case err := <-received:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+}
+
+func TestWebhookSender_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sender, err := NewWebhookSender(server.URL, []byte("secret"))
+	require.NoError(t, err)
+	assert.Error(t, sender.Send(newEvent(EventLoginStarted, "dave", ClientInfo{})))
+}
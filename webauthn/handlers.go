@@ -2,12 +2,19 @@ package webauthn
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
+// formatWebAuthnJSON is the format query value selecting the WebAuthn Level 3 JSON options,
+// which browsers parse with PublicKeyCredential.parseCreationOptionsFromJSON and
+// parseRequestOptionsFromJSON
+const formatWebAuthnJSON = "webauthn-json"
+
 // Handlers contains HTTP handlers for WebAuthn
 type Handlers struct {
 	service *Service
+	cors    *corsPolicy // nil unless SetCORSOrigins was called
 }
 
 // NewHandlers creates new WebAuthn handlers
@@ -37,14 +44,18 @@ func (h *Handlers) BeginRegistrationHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Begin registration
-	options, _, err := h.service.BeginRegistration(req.Username, req.DisplayName)
+	options, _, err := h.service.BeginRegistration(req.Username, req.DisplayName, ClientInfoFromRequest(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Return options
+	// Return options, unwrapped from the publicKey member for the Level 3 JSON format
 	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("format") == formatWebAuthnJSON {
+		json.NewEncoder(w).Encode(options.Response)
+		return
+	}
 	json.NewEncoder(w).Encode(options)
 }
 
@@ -65,6 +76,18 @@ func (h *Handlers) FinishRegistrationHandler(w http.ResponseWriter, r *http.Requ
 
 	// Finish registration
 	if err := h.service.FinishRegistration(username, r); err != nil {
+		var notAllowed *AuthenticatorNotAllowedError
+		if errors.As(err, &notAllowed) {
+			// Tell the UI which authenticator was rejected and why
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":  "authenticator_not_allowed",
+				"aaguid": notAllowed.AAGUID,
+				"reason": notAllowed.Reason,
+			})
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -93,14 +116,18 @@ func (h *Handlers) BeginLoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Begin login
-	options, err := h.service.BeginLogin(req.Username)
+	options, err := h.service.BeginLogin(req.Username, ClientInfoFromRequest(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Return options
+	// Return options, unwrapped from the publicKey member for the Level 3 JSON format
 	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("format") == formatWebAuthnJSON {
+		json.NewEncoder(w).Encode(options.Response)
+		return
+	}
 	json.NewEncoder(w).Encode(options)
 }
 
@@ -121,6 +148,10 @@ func (h *Handlers) FinishLoginHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Finish login
 	if err := h.service.FinishLogin(username, r); err != nil {
+		if errors.Is(err, ErrCloneDetected) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -130,10 +161,11 @@ func (h *Handlers) FinishLoginHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
-// RegisterHandlers registers the WebAuthn handlers
+// RegisterHandlers registers the WebAuthn handlers, with CORS support for the origins
+// set by SetCORSOrigins
 func (h *Handlers) RegisterHandlers(mux *http.ServeMux) {
-	mux.HandleFunc("/webauthn/register/begin", h.BeginRegistrationHandler)
-	mux.HandleFunc("/webauthn/register/finish", h.FinishRegistrationHandler)
-	mux.HandleFunc("/webauthn/login/begin", h.BeginLoginHandler)
-	mux.HandleFunc("/webauthn/login/finish", h.FinishLoginHandler)
+	mux.HandleFunc("/webauthn/register/begin", h.withCORS(h.BeginRegistrationHandler))
+	mux.HandleFunc("/webauthn/register/finish", h.withCORS(h.FinishRegistrationHandler))
+	mux.HandleFunc("/webauthn/login/begin", h.withCORS(h.BeginLoginHandler))
+	mux.HandleFunc("/webauthn/login/finish", h.withCORS(h.FinishLoginHandler))
 }
@@ -0,0 +1,118 @@
+package webauthn
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/metadata"
+	"github.com/google/uuid"
+)
+
+// defaultMetadataTTL is how long a loaded MDS BLOB is used before it is reloaded
+const defaultMetadataTTL = 24 * time.Hour
+
+// ErrMetadataNotFound is returned when the MDS BLOB has no entry for an authenticator
+var ErrMetadataNotFound = errors.New("authenticator not found in metadata")
+
+// MetadataStore loads the FIDO Metadata Service BLOB from a file or URL, verifies its
+// signature chain and caches the entries until the BLOB's next update or the TTL, whichever is sooner.
+type MetadataStore struct {
+	source  string
+	decoder *metadata.Decoder
+	client  *http.Client
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*metadata.Entry
+	expires time.Time
+}
+
+// NewMetadataStore creates a store for the BLOB at source, a file path or http(s) URL.
+// rootCertificate is the base64 DER trust anchor for the BLOB signature; empty uses the FIDO production root.
+func NewMetadataStore(source, rootCertificate string) (*MetadataStore, error) {
+	var opts []metadata.DecoderOption
+	if rootCertificate != "" {
+		opts = append(opts, metadata.WithRootCertificate(rootCertificate))
+	}
+	// Entries we can't parse are skipped rather than failing the whole BLOB
+	opts = append(opts, metadata.WithIgnoreEntryParsingErrors())
+
+	decoder, err := metadata.NewDecoder(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetadataStore{
+		source:  source,
+		decoder: decoder,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		ttl:     defaultMetadataTTL,
+	}, nil
+}
+
+// Lookup returns the metadata entry for an authenticator, loading the BLOB if needed
+func (m *MetadataStore) Lookup(aaguid uuid.UUID) (*metadata.Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entries == nil || time.Now().After(m.expires) {
+		if err := m.load(); err != nil {
+			return nil, fmt.Errorf("failed to load metadata: %w", err)
+		}
+	}
+
+	entry, ok := m.entries[aaguid]
+	if !ok {
+		return nil, ErrMetadataNotFound
+	}
+	return entry, nil
+}
+
+// load fetches, verifies and parses the BLOB
+func (m *MetadataStore) load() error {
+	raw, err := m.read()
+	if err != nil {
+		return err
+	}
+
+	payload, err := m.decoder.DecodeBytes(raw)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := m.decoder.Parse(payload)
+	if err != nil {
+		return err
+	}
+
+	m.entries = parsed.ToMap()
+	m.expires = time.Now().Add(m.ttl)
+	if next := parsed.Parsed.NextUpdate; !next.IsZero() && next.Before(m.expires) {
+		m.expires = next
+	}
+	return nil
+}
+
+// read returns the raw BLOB from the source
+func (m *MetadataStore) read() ([]byte, error) {
+	if !strings.HasPrefix(m.source, "http://") && !strings.HasPrefix(m.source, "https://") {
+		return os.ReadFile(m.source)
+	}
+
+	resp, err := m.client.Get(m.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata request returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,196 @@
+package webauthn
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/metadata"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// ErrAuthenticatorNotAllowed is returned when a registration fails the attestation policy
+var ErrAuthenticatorNotAllowed = errors.New("authenticator not allowed")
+
+// AuthenticatorNotAllowedError explains why an authenticator was rejected.
+// It matches ErrAuthenticatorNotAllowed with errors.Is.
+type AuthenticatorNotAllowedError struct {
+	AAGUID string
+	Reason string
+}
+
+// Error implements the error interface
+func (e *AuthenticatorNotAllowedError) Error() string {
+	return fmt.Sprintf("%s: aaguid=%s: %s", ErrAuthenticatorNotAllowed, e.AAGUID, e.Reason)
+}
+
+// Is reports whether target is ErrAuthenticatorNotAllowed
+func (e *AuthenticatorNotAllowedError) Is(target error) bool {
+	return target == ErrAuthenticatorNotAllowed
+}
+
+// CertificationLevel is a FIDO authenticator certification level, ordered from weakest to strongest
+type CertificationLevel int
+
+const (
+	CertificationNone CertificationLevel = iota
+	CertificationL1
+	CertificationL1Plus
+	CertificationL2
+	CertificationL2Plus
+	CertificationL3
+	CertificationL3Plus
+)
+
+// certificationLevels maps MDS statuses to certification levels
+var certificationLevels = map[metadata.AuthenticatorStatus]CertificationLevel{
+	metadata.FidoCertified:       CertificationL1,
+	metadata.FidoCertifiedL1:     CertificationL1,
+	metadata.FidoCertifiedL1plus: CertificationL1Plus,
+	metadata.FidoCertifiedL2:     CertificationL2,
+	metadata.FidoCertifiedL2plus: CertificationL2Plus,
+	metadata.FidoCertifiedL3:     CertificationL3,
+	metadata.FidoCertifiedL3plus: CertificationL3Plus,
+}
+
+// AttestationPolicy restricts which authenticators may be registered.
+//
+// The AAGUID is asserted by the authenticator itself, so AllowedAAGUIDs and MinCertificationLevel
+// only accept a credential whose attestation certificate chains to a root listed for that AAGUID
+// in Metadata. "none" and self attestation carry no certificate and are rejected under either option.
+type AttestationPolicy struct {
+	AllowedAAGUIDs        []string           // If set, only these authenticators are accepted; requires Metadata
+	BlockedAAGUIDs        []string           // These authenticators are always rejected
+	RequireAttestation    bool               // Reject "none" and self attestation, which carry no certificate
+	MinCertificationLevel CertificationLevel // Requires Metadata; authenticators without an entry are rejected
+	Metadata              *MetadataStore     // FIDO MDS lookup; entries with revoked or compromised statuses are rejected
+}
+
+// PolicyFunc returns the attestation policy for a user, or nil to accept any authenticator.
+// It lets policy differ per user group, e.g. stricter for admins.
+type PolicyFunc func(user *User) *AttestationPolicy
+
+// containsAAGUID reports whether the list contains the AAGUID, ignoring case
+func containsAAGUID(list []string, aaguid string) bool {
+	for _, candidate := range list {
+		if strings.EqualFold(candidate, aaguid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an AuthenticatorNotAllowedError if the credential doesn't satisfy the policy
+func (p *AttestationPolicy) Check(credential *webauthn.Credential) error {
+	aaguid := formatAAGUID(credential.Authenticator.AAGUID)
+	reject := func(reason string) error {
+		return &AuthenticatorNotAllowedError{AAGUID: aaguid, Reason: reason}
+	}
+
+	if containsAAGUID(p.BlockedAAGUIDs, aaguid) {
+		return reject("authenticator is blocked")
+	}
+	if len(p.AllowedAAGUIDs) > 0 && !containsAAGUID(p.AllowedAAGUIDs, aaguid) {
+		return reject("authenticator is not on the allowed list")
+	}
+
+	chain, err := attestationChain(credential)
+	if err != nil {
+		return reject(err.Error())
+	}
+
+	// An AAGUID can only be trusted once its attestation chain is verified against the MDS roots
+	verify := len(p.AllowedAAGUIDs) > 0 || p.MinCertificationLevel > CertificationNone
+	if (verify || p.RequireAttestation) && len(chain) == 0 {
+		return reject("attestation is required")
+	}
+
+	if p.Metadata == nil {
+		if verify {
+			return reject("no metadata configured to verify attestation")
+		}
+		return nil
+	}
+
+	id, err := uuid.FromBytes(credential.Authenticator.AAGUID)
+	if err != nil {
+		return reject("invalid aaguid")
+	}
+
+	entry, err := p.Metadata.Lookup(id)
+	if err != nil {
+		if errors.Is(err, ErrMetadataNotFound) && !verify {
+			return nil
+		}
+		return reject(err.Error())
+	}
+
+	level := CertificationNone
+	for _, report := range entry.StatusReports {
+		if metadata.IsUndesiredAuthenticatorStatus(report.Status) {
+			return reject(fmt.Sprintf("authenticator status is %s", report.Status))
+		}
+		if l, ok := certificationLevels[report.Status]; ok && l > level {
+			level = l
+		}
+	}
+
+	if level < p.MinCertificationLevel {
+		return reject("authenticator certification level is too low")
+	}
+
+	if len(chain) > 0 {
+		if err := verifyAttestationChain(entry, chain); err != nil {
+			return reject(err.Error())
+		}
+	}
+	return nil
+}
+
+// attestationChain returns the x5c certificates from the credential's attestation statement,
+// leaf first. It is empty for "none" and self attestation.
+func attestationChain(credential *webauthn.Credential) ([]*x509.Certificate, error) {
+	if len(credential.Attestation.Object) == 0 {
+		return nil, nil
+	}
+
+	var object struct {
+		AttStatement map[string]interface{} `cbor:"attStmt"`
+	}
+	if err := webauthncbor.Unmarshal(credential.Attestation.Object, &object); err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+
+	x5c, _ := object.AttStatement["x5c"].([]interface{})
+	chain := make([]*x509.Certificate, 0, len(x5c))
+	for _, raw := range x5c {
+		der, ok := raw.([]byte)
+		if !ok {
+			return nil, errors.New("invalid attestation certificate")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attestation certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
+
+// verifyAttestationChain checks that the attestation certificate chains to one of the entry's roots.
+// The attestation signature itself was already checked against the leaf during registration.
+func verifyAttestationChain(entry *metadata.Entry, chain []*x509.Certificate) error {
+	if len(entry.MetadataStatement.AttestationRootCertificates) == 0 {
+		return errors.New("metadata lists no attestation root certificates")
+	}
+
+	opts := entry.MetadataStatement.Verifier(chain[1:])
+	opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	if _, err := chain[0].Verify(opts); err != nil {
+		return fmt.Errorf("attestation certificate is not trusted: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAAGUIDString  = "01020304-0506-0708-090a-0b0c0d0e0f10"
+	otherAAGUIDString = "11111111-2222-3333-4444-555555555555"
+)
+
+// registerWithPolicy runs a registration with auth under the given policy and returns the error
+func registerWithPolicy(t *testing.T, policy *AttestationPolicy, auth *softAuthenticator) (*Service, error) {
+	t.Helper()
+
+	service := newTestService(t)
+	service.SetAttestationPolicy(func(user *User) *AttestationPolicy { return policy })
+
+	options, _, err := service.BeginRegistration("admin", "Admin", ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "direct", string(options.Response.Attestation))
+
+	return service, service.FinishRegistration("admin", finishRequest(auth.registrationResponse(t, options)))
+}
+
+func TestAttestationPolicy_AllowedAndBlocked(t *testing.T) {
+	ca := newAttestationCA(t)
+	signer := newMDSSigner(t)
+	store := signer.store(t, signer.blob(t, map[string]string{testAAGUIDString: "FIDO_CERTIFIED_L1"}, ca.root))
+
+	service, err := registerWithPolicy(t, &AttestationPolicy{AllowedAAGUIDs: []string{testAAGUIDString}, Metadata: store}, ca.authenticator(t))
+	require.NoError(t, err)
+	user, _ := service.userStore.GetUser("admin")
+	assert.Len(t, user.Credentials, 1)
+
+	service, err = registerWithPolicy(t, &AttestationPolicy{BlockedAAGUIDs: []string{testAAGUIDString}}, newSoftAuthenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+	var notAllowed *AuthenticatorNotAllowedError
+	require.True(t, errors.As(err, &notAllowed))
+	assert.Equal(t, testAAGUIDString, notAllowed.AAGUID)
+	user, _ = service.userStore.GetUser("admin")
+	assert.Empty(t, user.Credentials)
+
+	_, err = registerWithPolicy(t, &AttestationPolicy{AllowedAAGUIDs: []string{otherAAGUIDString}, Metadata: store}, ca.authenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+
+	// The soft authenticator only provides "none" attestation
+	_, err = registerWithPolicy(t, &AttestationPolicy{RequireAttestation: true}, newSoftAuthenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+	_, err = registerWithPolicy(t, &AttestationPolicy{RequireAttestation: true}, ca.authenticator(t))
+	assert.NoError(t, err)
+}
+
+func TestAttestationPolicy_AllowedRequiresVerifiedAttestation(t *testing.T) {
+	ca := newAttestationCA(t)
+	signer := newMDSSigner(t)
+	store := signer.store(t, signer.blob(t, map[string]string{testAAGUIDString: "FIDO_CERTIFIED_L1"}, ca.root))
+	policy := &AttestationPolicy{AllowedAAGUIDs: []string{testAAGUIDString}, Metadata: store}
+
+	// A "none" attestation claiming an allowed AAGUID is not trusted
+	_, err := registerWithPolicy(t, policy, newSoftAuthenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+	assert.Contains(t, err.Error(), "attestation is required")
+
+	// Nor is a certificate chain that doesn't lead to the authenticator's MDS root
+	_, err = registerWithPolicy(t, policy, newAttestationCA(t).authenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+	assert.Contains(t, err.Error(), "not trusted")
+
+	// Without metadata there is nothing to verify the chain against
+	_, err = registerWithPolicy(t, &AttestationPolicy{AllowedAAGUIDs: []string{testAAGUIDString}}, ca.authenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+}
+
+func TestAttestationPolicy_PerUserGroup(t *testing.T) {
+	service := newTestService(t)
+	service.SetAttestationPolicy(func(user *User) *AttestationPolicy {
+		if user.Name == "admin" {
+			return &AttestationPolicy{AllowedAAGUIDs: []string{otherAAGUIDString}}
+		}
+		return nil
+	})
+
+	auth := newSoftAuthenticator(t)
+	register(t, service, auth, "regular")
+
+	options, _, err := service.BeginRegistration("admin", "Admin", ClientInfo{})
+	require.NoError(t, err)
+	err = service.FinishRegistration("admin", finishRequest(newSoftAuthenticator(t).registrationResponse(t, options)))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+}
+
+func TestFinishRegistrationHandler_NotAllowed(t *testing.T) {
+	service := newTestService(t)
+	service.SetAttestationPolicy(func(user *User) *AttestationPolicy {
+		return &AttestationPolicy{BlockedAAGUIDs: []string{testAAGUIDString}}
+	})
+
+	options, _, err := service.BeginRegistration("admin", "Admin", ClientInfo{})
+	require.NoError(t, err)
+
+	req := finishRequest(newSoftAuthenticator(t).registrationResponse(t, options))
+	req.URL.RawQuery = "username=admin"
+	rec := httptest.NewRecorder()
+	NewHandlers(service).FinishRegistrationHandler(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "authenticator_not_allowed", body["error"])
+	assert.Equal(t, testAAGUIDString, body["aaguid"])
+}
+
+// issueCertificate creates a certificate signed by parent, or a self-signed one when parent is nil
+func issueCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// certificateTemplate returns an hour-valid certificate template
+func certificateTemplate(serial int64, name string, ca bool) *x509.Certificate {
+	cert := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+	}
+	if ca {
+		cert.KeyUsage |= x509.KeyUsageCertSign
+	}
+	return cert
+}
+
+// attestationCA is an authenticator vendor's attestation root
+type attestationCA struct {
+	root *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAttestationCA(t *testing.T) *attestationCA {
+	t.Helper()
+
+	root, key := issueCertificate(t, certificateTemplate(1, "Test Attestation Root", true), nil, nil)
+	return &attestationCA{root: root, key: key}
+}
+
+// authenticator returns a soft authenticator producing packed attestations under this root
+func (ca *attestationCA) authenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	template := certificateTemplate(2, "Test Authenticator", false)
+	template.Subject.Country = []string{"US"}
+	template.Subject.Organization = []string{"Test Vendor"}
+	template.Subject.OrganizationalUnit = []string{"Authenticator Attestation"}
+	leaf, leafKey := issueCertificate(t, template, ca.root, ca.key)
+
+	auth := newSoftAuthenticator(t)
+	auth.attestationKey = leafKey
+	auth.x5c = [][]byte{leaf.Raw}
+	return auth
+}
+
+// mdsSigner issues fake MDS BLOBs signed through a root -> intermediate -> leaf chain
+type mdsSigner struct {
+	root         string // base64 DER
+	intermediate string
+	leaf         string
+	leafKey      *ecdsa.PrivateKey
+}
+
+func newMDSSigner(t *testing.T) *mdsSigner {
+	t.Helper()
+
+	root, rootKey := issueCertificate(t, certificateTemplate(1, "Test MDS Root", true), nil, nil)
+	intermediate, intermediateKey := issueCertificate(t, certificateTemplate(2, "Test MDS Intermediate", true), root, rootKey)
+	leaf, leafKey := issueCertificate(t, certificateTemplate(3, "Test MDS Signer", false), intermediate, intermediateKey)
+
+	return &mdsSigner{
+		root:         base64.StdEncoding.EncodeToString(root.Raw),
+		intermediate: base64.StdEncoding.EncodeToString(intermediate.Raw),
+		leaf:         base64.StdEncoding.EncodeToString(leaf.Raw),
+		leafKey:      leafKey,
+	}
+}
+
+// blob returns a signed BLOB with one entry per AAGUID and status, each listing the attestation roots
+func (m *mdsSigner) blob(t *testing.T, statuses map[string]string, roots ...*x509.Certificate) []byte {
+	t.Helper()
+
+	encodedRoots := []string{}
+	for _, root := range roots {
+		encodedRoots = append(encodedRoots, base64.StdEncoding.EncodeToString(root.Raw))
+	}
+
+	var entries []map[string]interface{}
+	for aaguid, status := range statuses {
+		entries = append(entries, map[string]interface{}{
+			"aaguid": aaguid,
+			"metadataStatement": map[string]interface{}{
+				"aaguid":                      aaguid,
+				"description":                 "Test authenticator",
+				"attestationRootCertificates": encodedRoots,
+			},
+			"statusReports":          []map[string]string{{"status": status, "effectiveDate": "2024-01-01"}},
+			"timeOfLastStatusChange": "2024-01-01",
+		})
+	}
+
+	header, err := json.Marshal(map[string]interface{}{"alg": "ES256", "typ": "JWT", "x5c": []string{m.leaf, m.intermediate}})
+	require.NoError(t, err)
+	payload, err := json.Marshal(map[string]interface{}{
+		"legalHeader": "test",
+		"no":          1,
+		"nextUpdate":  time.Now().AddDate(0, 1, 0).Format(time.DateOnly),
+		"entries":     entries,
+	})
+	require.NoError(t, err)
+
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, m.leafKey, digest[:])
+	require.NoError(t, err)
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return []byte(signingInput + "." + b64(signature))
+}
+
+// store writes a BLOB to a temporary file and returns a store reading it
+func (m *mdsSigner) store(t *testing.T, blob []byte) *MetadataStore {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "blob.jwt")
+	require.NoError(t, os.WriteFile(path, blob, 0o600))
+	store, err := NewMetadataStore(path, m.root)
+	require.NoError(t, err)
+	return store
+}
+
+func TestAttestationPolicy_CertificationLevel(t *testing.T) {
+	ca := newAttestationCA(t)
+	signer := newMDSSigner(t)
+	store := signer.store(t, signer.blob(t, map[string]string{
+		testAAGUIDString:  "FIDO_CERTIFIED_L1",
+		otherAAGUIDString: "REVOKED",
+	}, ca.root))
+
+	_, err := registerWithPolicy(t, &AttestationPolicy{Metadata: store, MinCertificationLevel: CertificationL1}, ca.authenticator(t))
+	assert.NoError(t, err)
+
+	_, err = registerWithPolicy(t, &AttestationPolicy{Metadata: store, MinCertificationLevel: CertificationL2}, ca.authenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+
+	// A self-asserted AAGUID doesn't meet a minimum level, even if its entry would
+	_, err = registerWithPolicy(t, &AttestationPolicy{Metadata: store, MinCertificationLevel: CertificationL1}, newSoftAuthenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+
+	// Revoked authenticators are rejected regardless of the minimum level
+	revoked := signer.store(t, signer.blob(t, map[string]string{testAAGUIDString: "REVOKED"}))
+	_, err = registerWithPolicy(t, &AttestationPolicy{Metadata: revoked}, newSoftAuthenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+
+	// Without an entry, only a minimum level causes a rejection
+	empty := signer.store(t, signer.blob(t, map[string]string{otherAAGUIDString: "FIDO_CERTIFIED_L3"}))
+	_, err = registerWithPolicy(t, &AttestationPolicy{Metadata: empty}, newSoftAuthenticator(t))
+	assert.NoError(t, err)
+	_, err = registerWithPolicy(t, &AttestationPolicy{Metadata: empty, MinCertificationLevel: CertificationL1}, ca.authenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+}
+
+func TestMetadataStore_RejectsBadSignature(t *testing.T) {
+	signer := newMDSSigner(t)
+	blob := signer.blob(t, map[string]string{testAAGUIDString: "FIDO_CERTIFIED_L1"})
+
+	// A BLOB signed under a different root doesn't verify
+	other := newMDSSigner(t)
+	store, err := NewMetadataStore(signer.store(t, blob).source, other.root)
+	require.NoError(t, err)
+
+	_, err = registerWithPolicy(t, &AttestationPolicy{Metadata: store}, newSoftAuthenticator(t))
+	assert.ErrorIs(t, err, ErrAuthenticatorNotAllowed)
+	assert.Contains(t, err.Error(), "failed to load metadata")
+}
+
+func TestMetadataStore_LoadsFromURLAndCaches(t *testing.T) {
+	ca := newAttestationCA(t)
+	signer := newMDSSigner(t)
+	blob := signer.blob(t, map[string]string{testAAGUIDString: "FIDO_CERTIFIED_L2"}, ca.root)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(blob)
+	}))
+	defer server.Close()
+
+	store, err := NewMetadataStore(server.URL, signer.root)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = registerWithPolicy(t, &AttestationPolicy{Metadata: store, MinCertificationLevel: CertificationL2}, ca.authenticator(t))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, requests)
+}
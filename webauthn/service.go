@@ -3,6 +3,7 @@ package webauthn
 import (
 	"errors"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
@@ -12,8 +13,17 @@ import (
 type Service struct {
 	webAuthn  *webauthn.WebAuthn
 	userStore *UserStore
+	events    *eventDispatcher
+	policy    PolicyFunc
 }
 
+// ErrCredentialNotFound is returned when a user has no credential with the given ID
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// ErrCloneDetected is returned by FinishLogin when the authenticator's sign count did not
+// increase since the last login, which suggests the credential's private key was copied.
+var ErrCloneDetected = errors.New("authenticator sign count did not increase, it may be cloned")
+
 // NewService creates a new WebAuthn service
 func NewService(rpID, rpOrigin, rpDisplayName string) (*Service, error) {
 	// Initialize WebAuthn
@@ -30,11 +40,43 @@ func NewService(rpID, rpOrigin, rpDisplayName string) (*Service, error) {
 	return &Service{
 		webAuthn:  webAuthn,
 		userStore: NewUserStore(),
+		events:    newEventDispatcher(),
 	}, nil
 }
 
+// OnEvent registers a callback for registration, login and credential events.
+// Callbacks run asynchronously on a bounded queue; events are dropped if it fills up.
+func (s *Service) OnEvent(callback func(e Event)) {
+	s.events.subscribe(callback)
+}
+
+// SetAttestationPolicy sets the callback choosing the attestation policy applied to each
+// registration. Users with a policy are asked for direct attestation so their AAGUID can be checked.
+func (s *Service) SetAttestationPolicy(policy PolicyFunc) {
+	s.policy = policy
+}
+
+// policyFor returns the attestation policy for a user, or nil if there is none
+func (s *Service) policyFor(user *User) *AttestationPolicy {
+	if s.policy == nil {
+		return nil
+	}
+	return s.policy(user)
+}
+
+// DroppedEvents returns the number of events dropped because the queue was full
+func (s *Service) DroppedEvents() int64 {
+	return atomic.LoadInt64(&s.events.dropped)
+}
+
+// Close delivers any queued events and stops event delivery
+func (s *Service) Close() error {
+	s.events.close()
+	return nil
+}
+
 // BeginRegistration starts the registration process
-func (s *Service) BeginRegistration(username, displayName string) (*protocol.CredentialCreation, *User, error) {
+func (s *Service) BeginRegistration(username, displayName string, client ClientInfo) (*protocol.CredentialCreation, *User, error) {
 	// Get user or create a new one
 	user, err := s.userStore.GetUser(username)
 	if err != nil {
@@ -43,8 +85,14 @@ func (s *Service) BeginRegistration(username, displayName string) (*protocol.Cre
 		s.userStore.PutUser(user)
 	}
 
+	// Ask for attestation when a policy needs to check the authenticator
+	var opts []webauthn.RegistrationOption
+	if s.policyFor(user) != nil {
+		opts = append(opts, webauthn.WithConveyancePreference(protocol.PreferDirectAttestation))
+	}
+
 	// Begin registration
-	options, sessionData, err := s.webAuthn.BeginRegistration(user)
+	options, sessionData, err := s.webAuthn.BeginRegistration(user, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -52,6 +100,8 @@ func (s *Service) BeginRegistration(username, displayName string) (*protocol.Cre
 	// Store session data in the user
 	user.RegistrationSessionData = sessionData
 
+	s.events.emit(newEvent(EventRegistrationStarted, username, client))
+
 	return options, user, nil
 }
 
@@ -75,6 +125,14 @@ func (s *Service) FinishRegistration(username string, response *http.Request) er
 		return err
 	}
 
+	// Enforce the attestation policy before keeping the credential
+	if policy := s.policyFor(user); policy != nil {
+		if err := policy.Check(credential); err != nil {
+			user.RegistrationSessionData = nil
+			return err
+		}
+	}
+
 	// Add credential to user
 	user.AddCredential(*credential)
 
@@ -84,11 +142,16 @@ func (s *Service) FinishRegistration(username string, response *http.Request) er
 	// Update user in store
 	s.userStore.PutUser(user)
 
+	event := newEvent(EventRegistrationCompleted, username, ClientInfoFromRequest(response))
+	event.CredentialID = encodeCredentialID(credential.ID)
+	event.AAGUID = formatAAGUID(credential.Authenticator.AAGUID)
+	s.events.emit(event)
+
 	return nil
 }
 
 // BeginLogin starts the login process
-func (s *Service) BeginLogin(username string) (*protocol.CredentialAssertion, error) {
+func (s *Service) BeginLogin(username string, client ClientInfo) (*protocol.CredentialAssertion, error) {
 	// Get user
 	user, err := s.userStore.GetUser(username)
 	if err != nil {
@@ -107,34 +170,89 @@ func (s *Service) BeginLogin(username string) (*protocol.CredentialAssertion, er
 	// Update user in store
 	s.userStore.PutUser(user)
 
+	s.events.emit(newEvent(EventLoginStarted, username, client))
+
 	return options, nil
 }
 
 // FinishLogin completes the login process
 func (s *Service) FinishLogin(username string, response *http.Request) error {
+	credential, err := s.finishLogin(username, response)
+
+	event := newEvent(EventLoginCompleted, username, ClientInfoFromRequest(response))
+	if err != nil {
+		event.Type = EventLoginFailed
+		event.Error = err.Error()
+	}
+	if credential != nil {
+		event.CredentialID = encodeCredentialID(credential.ID)
+		event.AAGUID = formatAAGUID(credential.Authenticator.AAGUID)
+	}
+	s.events.emit(event)
+
+	return err
+}
+
+// finishLogin verifies the login assertion and returns the credential that was used.
+// A credential is also returned with ErrCloneDetected.
+func (s *Service) finishLogin(username string, response *http.Request) (*webauthn.Credential, error) {
 	// Get user
 	user, err := s.userStore.GetUser(username)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Get session data
 	sessionData := user.AuthenticationSessionData
 	if sessionData == nil {
-		return errors.New("no authentication session data found")
+		return nil, errors.New("no authentication session data found")
 	}
 
 	// Parse response
-	_, err = s.webAuthn.FinishLogin(user, *sessionData, response)
+	credential, err := s.webAuthn.FinishLogin(user, *sessionData, response)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Clear session data
 	user.AuthenticationSessionData = nil
 
+	// Persist the new sign count, or the clone warning if the count went backwards
+	user.UpdateCredential(*credential)
+
 	// Update user in store
 	s.userStore.PutUser(user)
 
+	if credential.Authenticator.CloneWarning {
+		return credential, ErrCloneDetected
+	}
+	return credential, nil
+}
+
+// RenameUser changes a user's username. The user handle stays the same, so credentials
+// registered under the old name keep working.
+func (s *Service) RenameUser(oldName, newName string) error {
+	return s.userStore.RenameUser(oldName, newName)
+}
+
+// DeleteCredential removes a credential from a user
+func (s *Service) DeleteCredential(username string, credentialID []byte, client ClientInfo) error {
+	user, err := s.userStore.GetUser(username)
+	if err != nil {
+		return err
+	}
+
+	credential, ok := user.RemoveCredential(credentialID)
+	if !ok {
+		return ErrCredentialNotFound
+	}
+
+	s.userStore.PutUser(user)
+
+	event := newEvent(EventCredentialDeleted, username, client)
+	event.CredentialID = encodeCredentialID(credential.ID)
+	event.AAGUID = formatAAGUID(credential.Authenticator.AAGUID)
+	s.events.emit(event)
+
 	return nil
 }
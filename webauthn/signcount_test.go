@@ -0,0 +1,70 @@
+package webauthn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_SignCountIncrementAccepted(t *testing.T) {
+	service := newTestService(t)
+	auth := newSoftAuthenticator(t)
+	user := register(t, service, auth, "alice")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, login(t, service, auth, user))
+	}
+
+	// The stored credential tracks the authenticator's counter
+	require.Len(t, user.Credentials, 1)
+	assert.Equal(t, auth.signCount, user.Credentials[0].Authenticator.SignCount)
+	assert.False(t, user.Credentials[0].Authenticator.CloneWarning)
+}
+
+func TestService_SignCountRegressionFlagged(t *testing.T) {
+	service := newTestService(t)
+	auth := newSoftAuthenticator(t)
+	user := register(t, service, auth, "alice")
+
+	require.NoError(t, login(t, service, auth, user))
+	require.NoError(t, login(t, service, auth, user))
+	stored := user.Credentials[0].Authenticator.SignCount
+
+	var events []Event
+	service.OnEvent(func(e Event) { events = append(events, e) })
+
+	// A clone replays from an older counter value
+	auth.signCount = 0
+	err := login(t, service, auth, user)
+	assert.ErrorIs(t, err, ErrCloneDetected)
+
+	// The warning is persisted and the stored counter is not rolled back
+	assert.True(t, user.Credentials[0].Authenticator.CloneWarning)
+	assert.Equal(t, stored, user.Credentials[0].Authenticator.SignCount)
+
+	service.Close()
+	require.Len(t, events, 2)
+	assert.Equal(t, EventLoginFailed, events[1].Type)
+	assert.Equal(t, encodeCredentialID(auth.credentialID), events[1].CredentialID)
+}
+
+func TestFinishLoginHandler_CloneDetected(t *testing.T) {
+	service := newTestService(t)
+	auth := newSoftAuthenticator(t)
+	user := register(t, service, auth, "alice")
+	require.NoError(t, login(t, service, auth, user))
+
+	auth.signCount = 0
+	options, err := service.BeginLogin("alice", ClientInfo{})
+	require.NoError(t, err)
+	req := finishRequest(auth.loginResponse(t, options, user.ID))
+	req.URL.RawQuery = "username=alice"
+	req.Method = http.MethodPost
+
+	rec := httptest.NewRecorder()
+	NewHandlers(service).FinishLoginHandler(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -5,16 +5,24 @@ import (
 	"sync"
 )
 
+// ErrUsernameTaken is returned when renaming a user to a username that is already in use
+var ErrUsernameTaken = errors.New("username already taken")
+
+// errUserNotFound is returned when no user matches a username or user handle
+var errUserNotFound = errors.New("user not found")
+
 // UserStore is a simple in-memory store for users
 type UserStore struct {
-	users map[string]*User
-	mu    sync.RWMutex
+	users   map[string]*User
+	handles map[string]*User // Keyed by user handle
+	mu      sync.RWMutex
 }
 
 // NewUserStore creates a new UserStore
 func NewUserStore() *UserStore {
 	return &UserStore{
-		users: make(map[string]*User),
+		users:   make(map[string]*User),
+		handles: make(map[string]*User),
 	}
 }
 
@@ -25,7 +33,20 @@ func (s *UserStore) GetUser(username string) (*User, error) {
 
 	user, ok := s.users[username]
 	if !ok {
-		return nil, errors.New("user not found")
+		return nil, errUserNotFound
+	}
+
+	return user, nil
+}
+
+// GetUserByHandle returns a user by user handle
+func (s *UserStore) GetUserByHandle(handle []byte) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.handles[string(handle)]
+	if !ok {
+		return nil, errUserNotFound
 	}
 
 	return user, nil
@@ -37,6 +58,26 @@ func (s *UserStore) PutUser(user *User) {
 	defer s.mu.Unlock()
 
 	s.users[user.Name] = user
+	s.handles[string(user.ID)] = user
+}
+
+// RenameUser changes a user's username, keeping the user handle and credentials
+func (s *UserStore) RenameUser(oldName, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[oldName]
+	if !ok {
+		return errUserNotFound
+	}
+	if _, taken := s.users[newName]; taken {
+		return ErrUsernameTaken
+	}
+
+	delete(s.users, oldName)
+	user.Name = newName
+	s.users[newName] = user
+	return nil
 }
 
 // DeleteUser removes a user
@@ -44,5 +85,8 @@ func (s *UserStore) DeleteUser(username string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.users, username)
+	if user, ok := s.users[username]; ok {
+		delete(s.handles, string(user.ID))
+		delete(s.users, username)
+	}
 }
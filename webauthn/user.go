@@ -1,15 +1,18 @@
 package webauthn
 
 import (
-	"encoding/binary"
+	"bytes"
+	"crypto/rand"
 
 	"github.com/go-webauthn/webauthn/webauthn"
-	"github.com/google/uuid"
 )
 
+// userHandleSize is the length of generated user handles. The spec allows up to 64 bytes.
+const userHandleSize = 32
+
 // User represents the user model for WebAuthn
 type User struct {
-	ID                        []byte
+	ID                        []byte // Opaque user handle, stable across renames
 	Name                      string
 	DisplayName               string
 	Credentials               []webauthn.Credential
@@ -17,25 +20,22 @@ type User struct {
 	AuthenticationSessionData *webauthn.SessionData
 }
 
-// NewUser creates a new User
+// NewUser creates a new User with a random user handle
 func NewUser(name string, displayName string) *User {
-	user := &User{
-		ID:          make([]byte, 8),
+	return &User{
+		ID:          newUserHandle(),
 		Name:        name,
 		DisplayName: displayName,
 		Credentials: []webauthn.Credential{},
 	}
+}
 
-	// Generate a random ID
-	uid, err := uuid.NewRandom()
-	if err != nil {
-		// If we can't generate a random UUID, use a simple counter
-		binary.BigEndian.PutUint64(user.ID, uint64(1))
-	} else {
-		copy(user.ID, uid[:])
-	}
-
-	return user
+// newUserHandle returns a random user handle. Handles carry no user information,
+// so authenticators never learn the username from them.
+func newUserHandle() []byte {
+	handle := make([]byte, userHandleSize)
+	rand.Read(handle) // Never fails; it crashes the program instead
+	return handle
 }
 
 // WebAuthnID returns the user's ID
@@ -67,3 +67,26 @@ func (u *User) WebAuthnCredentials() []webauthn.Credential {
 func (u *User) AddCredential(cred webauthn.Credential) {
 	u.Credentials = append(u.Credentials, cred)
 }
+
+// UpdateCredential replaces the stored credential with the same ID, e.g. after a login
+// changed its sign count. It returns false if the user has no such credential.
+func (u *User) UpdateCredential(cred webauthn.Credential) bool {
+	for i := range u.Credentials {
+		if bytes.Equal(u.Credentials[i].ID, cred.ID) {
+			u.Credentials[i] = cred
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveCredential removes the credential with the given ID and returns it
+func (u *User) RemoveCredential(id []byte) (webauthn.Credential, bool) {
+	for i, cred := range u.Credentials {
+		if bytes.Equal(cred.ID, id) {
+			u.Credentials = append(u.Credentials[:i:i], u.Credentials[i+1:]...)
+			return cred, true
+		}
+	}
+	return webauthn.Credential{}, false
+}
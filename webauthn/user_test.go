@@ -0,0 +1,71 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUser_RandomHandle(t *testing.T) {
+	a := NewUser("alice", "Alice")
+	b := NewUser("alice", "Alice")
+
+	for _, user := range []*User{a, b} {
+		assert.GreaterOrEqual(t, len(user.ID), 1)
+		assert.LessOrEqual(t, len(user.ID), 64)
+		assert.False(t, bytes.Contains(user.ID, []byte("alice")))
+	}
+	assert.NotEqual(t, a.ID, b.ID)
+}
+
+func TestService_UserHandleStableAcrossCeremonies(t *testing.T) {
+	service := newTestService(t)
+	auth := newSoftAuthenticator(t)
+
+	options, user, err := service.BeginRegistration("alice", "Alice", ClientInfo{})
+	require.NoError(t, err)
+	handle := append([]byte{}, user.ID...)
+	assert.Equal(t, handle, []byte(options.Response.User.ID.(protocol.URLEncodedBase64)))
+	require.NoError(t, service.FinishRegistration("alice", finishRequest(auth.registrationResponse(t, options))))
+
+	// Starting another registration for the same user keeps the handle
+	options, user, err = service.BeginRegistration("alice", "Alice", ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, handle, user.ID)
+	assert.Equal(t, handle, []byte(options.Response.User.ID.(protocol.URLEncodedBase64)))
+
+	require.NoError(t, login(t, service, auth, user))
+
+	// Assertions carrying another user's handle are rejected
+	assertion, err := service.BeginLogin("alice", ClientInfo{})
+	require.NoError(t, err)
+	err = service.FinishLogin("alice", finishRequest(auth.loginResponse(t, assertion, newUserHandle())))
+	assert.Error(t, err)
+
+	found, err := service.userStore.GetUserByHandle(handle)
+	require.NoError(t, err)
+	assert.Same(t, user, found)
+}
+
+func TestService_RenameUserKeepsCredentials(t *testing.T) {
+	service := newTestService(t)
+	auth := newSoftAuthenticator(t)
+	user := register(t, service, auth, "alice")
+	handle := append([]byte{}, user.ID...)
+	register(t, service, newSoftAuthenticator(t), "bob")
+
+	assert.ErrorIs(t, service.RenameUser("alice", "bob"), ErrUsernameTaken)
+	assert.Error(t, service.RenameUser("nobody", "carol"))
+
+	require.NoError(t, service.RenameUser("alice", "alice.smith"))
+	_, err := service.BeginLogin("alice", ClientInfo{})
+	assert.Error(t, err)
+
+	renamed, err := service.userStore.GetUser("alice.smith")
+	require.NoError(t, err)
+	assert.Equal(t, handle, renamed.ID)
+	require.NoError(t, login(t, service, auth, renamed))
+}
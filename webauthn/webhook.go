@@ -0,0 +1,77 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"huba/cryptoutils/hmac"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the webhook body
+const SignatureHeader = "X-Webhook-Signature"
+
+// defaultWebhookTimeout bounds how long a single webhook delivery may take
+const defaultWebhookTimeout = 5 * time.Second
+
+// WebhookSender posts events as signed JSON to an HTTP endpoint
+type WebhookSender struct {
+	url    string
+	signer hmac.HMACer
+	client *http.Client
+}
+
+// NewWebhookSender creates a sender that signs payloads with the given secret
+func NewWebhookSender(url string, secret []byte) (*WebhookSender, error) {
+	signer, err := hmac.NewHMAC(secret, hmac.SHA256, hmac.HEX)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookSender{
+		url:    url,
+		signer: signer,
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}, nil
+}
+
+// Send posts the event and returns an error if delivery fails
+func (w *WebhookSender) Send(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	signature, err := w.signer.Sign(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Handle sends the event and logs delivery failures. Register it with Service.OnEvent.
+func (w *WebhookSender) Handle(e Event) {
+	if err := w.Send(e); err != nil {
+		log.Printf("WebAuthn webhook delivery failed: type=%s error=%v", e.Type, err)
+	}
+}
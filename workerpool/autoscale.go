@@ -0,0 +1,90 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultScaleInterval is how often the autoscaler re-evaluates the worker count.
+	defaultScaleInterval = 5 * time.Second
+
+	// defaultIdleThreshold is the utilization below which idle workers are retired.
+	defaultIdleThreshold = 0.25
+)
+
+// scalingState holds autoscaler thresholds and the load observed since the last adjustment.
+type scalingState struct {
+	interval         time.Duration
+	latencyThreshold time.Duration
+	idleThreshold    float64
+
+	tasks      int64 // Tasks completed since the last adjustment
+	busy       int64 // Nanoseconds spent executing tasks since the last adjustment
+	lastAdjust time.Time
+	retire     chan struct{}
+}
+
+// WithAutoScaleInterval sets how often the autoscaler adjusts the worker count.
+func WithAutoScaleInterval(interval time.Duration) Option {
+	return func(wp *WorkerPool) {
+		if interval > 0 {
+			wp.scaling.interval = interval
+		}
+	}
+}
+
+// WithLatencyThreshold makes the autoscaler add workers when the average task duration over
+// the last interval exceeds threshold while tasks are waiting in the queue.
+func WithLatencyThreshold(threshold time.Duration) Option {
+	return func(wp *WorkerPool) {
+		wp.scaling.latencyThreshold = threshold
+	}
+}
+
+// WithIdleThreshold sets the worker utilization, between 0 and 1, below which the autoscaler
+// retires idle workers while the queue is empty. Utilization is the time spent executing tasks
+// divided by the time available to all workers over the last interval.
+func WithIdleThreshold(threshold float64) Option {
+	return func(wp *WorkerPool) {
+		wp.scaling.idleThreshold = threshold
+	}
+}
+
+// recordExecution adds a finished task to the load observed in the current interval.
+func (wp *WorkerPool) recordExecution(duration time.Duration) {
+	atomic.AddInt64(&wp.scaling.tasks, 1)
+	atomic.AddInt64(&wp.scaling.busy, int64(duration))
+}
+
+// observedLoad returns the average task duration and worker utilization since the last call.
+func (wp *WorkerPool) observedLoad(workers int) (time.Duration, float64) {
+	now := time.Now()
+	elapsed := now.Sub(wp.scaling.lastAdjust)
+	wp.scaling.lastAdjust = now
+
+	tasks := atomic.SwapInt64(&wp.scaling.tasks, 0)
+	busy := atomic.SwapInt64(&wp.scaling.busy, 0)
+
+	var avgLatency time.Duration
+	if tasks > 0 {
+		avgLatency = time.Duration(busy / tasks)
+	}
+
+	var utilization float64
+	if workers > 0 && elapsed > 0 {
+		utilization = float64(busy) / (float64(workers) * float64(elapsed))
+	}
+	return avgLatency, utilization
+}
+
+// retireWorkers asks up to n idle workers to exit. Busy workers are never interrupted.
+func (wp *WorkerPool) retireWorkers(n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case wp.scaling.retire <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
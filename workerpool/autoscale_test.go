@@ -0,0 +1,99 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_ScalesUpOnLatency(t *testing.T) {
+	const interval = 100 * time.Millisecond
+
+	wp := NewWorkerPool(1, 4,
+		WithAutoScaling(),
+		WithAutoScaleInterval(interval),
+		WithLatencyThreshold(20*time.Millisecond),
+	)
+	wp.Start()
+	defer wp.Stop()
+
+	go func() {
+		for range wp.Results() {
+		}
+	}()
+
+	// Keep a single task waiting so the queue never outgrows the worker count,
+	// leaving latency as the only reason to scale
+	slow := func(ctx context.Context) (interface{}, error) {
+		time.Sleep(40 * time.Millisecond)
+		return nil, nil
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if len(wp.taskQueue) == 0 {
+				_ = wp.Submit(Task{Execute: slow})
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&wp.activeWorkers) > 1
+	}, 3*interval, 5*time.Millisecond)
+}
+
+func TestWorkerPool_NoLatencyScalingBelowThreshold(t *testing.T) {
+	wp := NewWorkerPool(1, 4, WithLatencyThreshold(time.Second))
+	wp.mu.Lock()
+	wp.isRunning = true
+	wp.mu.Unlock()
+	wp.scaling.lastAdjust = time.Now()
+
+	// One queued task and fast completions: neither backlog nor latency calls for more workers
+	atomic.StoreInt32(&wp.activeWorkers, 1)
+	wp.taskQueue <- Task{ID: "queued", Execute: func(ctx context.Context) (interface{}, error) { return nil, nil }}
+	wp.recordExecution(time.Millisecond)
+
+	wp.adjustWorkers()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&wp.activeWorkers))
+}
+
+func TestWorkerPool_RetiresIdleWorkers(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	wp := NewWorkerPool(1, 4, WithAutoScaling(), WithAutoScaleInterval(interval), WithQueueCapacity(100))
+	wp.Start()
+	defer wp.Stop()
+
+	go func() {
+		for range wp.Results() {
+		}
+	}()
+
+	// A burst of work scales the pool up
+	for i := 0; i < 40; i++ {
+		require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, nil
+		}}))
+	}
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&wp.activeWorkers) == 4
+	}, time.Second, 5*time.Millisecond)
+
+	// Once the queue drains, idle workers are retired down to the minimum
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&wp.activeWorkers) == 1
+	}, 3*time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,106 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTaskCanceled is the Result error of a task canceled with Cancel or CancelAll.
+var ErrTaskCanceled = errors.New("task canceled")
+
+// taskState tracks a submitted task until it finishes.
+type taskState struct {
+	canceled bool
+	cancel   context.CancelFunc // Set while the task is running
+}
+
+// taskRegistry indexes queued and running tasks by ID so they can be canceled.
+// Queued tasks stay in the channel when canceled and are skipped when dequeued.
+type taskRegistry struct {
+	mu    sync.Mutex
+	tasks map[string]*taskState
+}
+
+// add registers a task that is about to be queued.
+func (r *taskRegistry) add(id string) *taskState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tasks == nil {
+		r.tasks = make(map[string]*taskState)
+	}
+	state := &taskState{}
+	r.tasks[id] = state
+	return state
+}
+
+// start marks a dequeued task as running. It returns false if the task was canceled while queued.
+func (r *taskRegistry) start(state *taskState, cancel context.CancelFunc) bool {
+	if state == nil {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if state.canceled {
+		return false
+	}
+	state.cancel = cancel
+	return true
+}
+
+// finish unregisters a task and reports whether it was canceled.
+func (r *taskRegistry) finish(id string, state *taskState) bool {
+	if state == nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A later task may have reused the ID
+	if r.tasks[id] == state {
+		delete(r.tasks, id)
+	}
+	return state.canceled
+}
+
+// cancelLocked cancels a registered task. The caller must hold r.mu.
+func (r *taskRegistry) cancelLocked(state *taskState) {
+	state.canceled = true
+	if state.cancel != nil {
+		state.cancel()
+	}
+}
+
+// Cancel cancels a queued or running task. A queued task is skipped and its Result carries
+// ErrTaskCanceled; a running task has its context canceled so a cooperative Execute can
+// return early. It returns false if no queued or running task has the ID.
+func (wp *WorkerPool) Cancel(taskID string) bool {
+	wp.tasks.mu.Lock()
+	defer wp.tasks.mu.Unlock()
+
+	state, ok := wp.tasks.tasks[taskID]
+	if !ok || state.canceled {
+		return false
+	}
+	wp.tasks.cancelLocked(state)
+	return true
+}
+
+// CancelAll cancels every queued and running task and returns how many were canceled.
+func (wp *WorkerPool) CancelAll() int {
+	wp.tasks.mu.Lock()
+	defer wp.tasks.mu.Unlock()
+
+	count := 0
+	for _, state := range wp.tasks.tasks {
+		if !state.canceled {
+			wp.tasks.cancelLocked(state)
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,219 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTask returns a task that waits for release and reports when it starts
+func blockingTask(started chan<- struct{}, release <-chan struct{}) TaskFunc {
+	return func(ctx context.Context) (interface{}, error) {
+		if started != nil {
+			close(started)
+		}
+		<-release
+		return "done", nil
+	}
+}
+
+func TestWorkerPool_CancelQueued(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "running", Execute: blockingTask(started, release)}))
+	<-started
+
+	ran := false
+	require.NoError(t, wp.Submit(Task{ID: "queued", Execute: func(ctx context.Context) (interface{}, error) {
+		ran = true
+		return nil, nil
+	}}))
+
+	assert.True(t, wp.Cancel("queued"))
+	assert.False(t, wp.Cancel("queued"), "already canceled")
+	close(release)
+
+	results := make(map[string]Result)
+	for i := 0; i < 2; i++ {
+		r := <-wp.Results()
+		results[r.TaskID] = r
+	}
+	assert.False(t, ran)
+	assert.ErrorIs(t, results["queued"].Error, ErrTaskCanceled)
+	assert.NoError(t, results["running"].Error)
+}
+
+func TestWorkerPool_CancelRunning(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "slow", Execute: func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "finished", nil
+		}
+	}}))
+	<-started
+
+	assert.True(t, wp.Cancel("slow"))
+
+	select {
+	case r := <-wp.Results():
+		assert.ErrorIs(t, r.Error, ErrTaskCanceled)
+		assert.Less(t, r.Duration, time.Second)
+	case <-time.After(2 * time.Second):
+		t.Fatal("task was not canceled")
+	}
+}
+
+func TestWorkerPool_CancelFinishedOrUnknown(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+	defer wp.Stop()
+
+	require.NoError(t, wp.Submit(Task{ID: "quick", Execute: func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}}))
+	<-wp.Results()
+
+	assert.False(t, wp.Cancel("quick"))
+	assert.False(t, wp.Cancel("unknown"))
+}
+
+func TestWorkerPool_CancelAll(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "running", Execute: func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}))
+	<-started
+	for i := 0; i < 3; i++ {
+		require.NoError(t, wp.Submit(Task{ID: fmt.Sprintf("queued-%d", i), Execute: blockingTask(nil, nil)}))
+	}
+
+	assert.Equal(t, 4, wp.CancelAll())
+	for i := 0; i < 4; i++ {
+		assert.ErrorIs(t, (<-wp.Results()).Error, ErrTaskCanceled)
+	}
+	assert.Equal(t, 0, wp.CancelAll())
+}
+
+func TestWorkerPool_CancelRacesCompletion(t *testing.T) {
+	wp := NewWorkerPool(4, 4, WithQueueCapacity(200))
+	wp.Start()
+	defer wp.Stop()
+
+	const tasks = 200
+	for i := 0; i < tasks; i++ {
+		require.NoError(t, wp.Submit(Task{ID: fmt.Sprintf("task-%d", i), Execute: func(ctx context.Context) (interface{}, error) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return "ok", nil
+		}}))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < tasks; i += 2 {
+			wp.Cancel(fmt.Sprintf("task-%d", i))
+		}
+	}()
+
+	// Every task produces exactly one result, either completed or canceled
+	seen := make(map[string]bool)
+	for i := 0; i < tasks; i++ {
+		select {
+		case r := <-wp.Results():
+			assert.False(t, seen[r.TaskID])
+			seen[r.TaskID] = true
+			if r.Error != nil {
+				assert.ErrorIs(t, r.Error, ErrTaskCanceled)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("missing results")
+		}
+	}
+	wg.Wait()
+
+	// Nothing is left registered once every result has been delivered
+	for i := 0; i < tasks; i++ {
+		assert.False(t, wp.Cancel(fmt.Sprintf("task-%d", i)))
+	}
+}
+
+func TestWorkerPool_DrainCancelsRunning(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "running", Execute: func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}))
+	<-started
+	require.NoError(t, wp.Submit(Task{ID: "queued", Execute: blockingTask(nil, nil)}))
+
+	assert.Equal(t, 1, wp.Drain())
+
+	select {
+	case r := <-wp.Results():
+		assert.Equal(t, "running", r.TaskID)
+		assert.ErrorIs(t, r.Error, ErrTaskCanceled)
+	case <-time.After(2 * time.Second):
+		t.Fatal("running task was not canceled")
+	}
+}
+
+func TestWorkerPool_CancelQueuedEmitsEvent(t *testing.T) {
+	var mu sync.Mutex
+	events := make(map[string]TaskEvent)
+	wp := NewWorkerPool(1, 1, WithTaskEvents(func(e TaskEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events[e.TaskID] = e
+	}))
+	wp.Start()
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "running", Execute: blockingTask(started, release)}))
+	<-started
+	require.NoError(t, wp.Submit(Task{ID: "queued", Execute: blockingTask(nil, nil)}))
+
+	require.True(t, wp.Cancel("queued"))
+	close(release)
+	for i := 0; i < 2; i++ {
+		<-wp.Results()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, TaskSucceeded, events["running"].Status)
+	assert.Equal(t, TaskCanceled, events["queued"].Status)
+	assert.ErrorIs(t, events["queued"].Error, ErrTaskCanceled)
+}
@@ -0,0 +1,55 @@
+package workerpool
+
+import (
+	"errors"
+	"time"
+)
+
+// TaskStatus describes how a task finished.
+type TaskStatus string
+
+const (
+	// TaskSucceeded means Execute returned a nil error.
+	TaskSucceeded TaskStatus = "succeeded"
+	// TaskFailed means Execute returned an error.
+	TaskFailed TaskStatus = "failed"
+	// TaskCanceled means the task was canceled with Cancel or CancelAll.
+	TaskCanceled TaskStatus = "canceled"
+)
+
+// TaskEvent is a compact completion notification that omits the task's result value.
+type TaskEvent struct {
+	TaskID   string
+	Status   TaskStatus
+	Duration time.Duration
+	Error    error
+}
+
+// WithTaskEvents sets a handler called with a TaskEvent whenever a task completes.
+// It runs on the worker goroutine before the Result is sent, so it should return quickly.
+func WithTaskEvents(handler func(TaskEvent)) Option {
+	return func(wp *WorkerPool) {
+		wp.onTaskEvent = handler
+	}
+}
+
+// emitTaskEvent reports a finished task to the event handler, if one is set.
+func (wp *WorkerPool) emitTaskEvent(result Result) {
+	if wp.onTaskEvent == nil {
+		return
+	}
+
+	status := TaskSucceeded
+	if errors.Is(result.Error, ErrTaskCanceled) {
+		status = TaskCanceled
+	} else if result.Error != nil {
+		status = TaskFailed
+	}
+
+	wp.onTaskEvent(TaskEvent{
+		TaskID:   result.TaskID,
+		Status:   status,
+		Duration: result.Duration,
+		Error:    result.Error,
+	})
+}
@@ -0,0 +1,57 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_TaskEvents(t *testing.T) {
+	var mu sync.Mutex
+	events := make(map[string]TaskEvent)
+
+	wp := NewWorkerPool(2, 2, WithTaskEvents(func(e TaskEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events[e.TaskID] = e
+	}))
+	wp.Start()
+	defer wp.Stop()
+
+	errBoom := errors.New("boom")
+	require.NoError(t, wp.Submit(Task{ID: "ok", Execute: func(ctx context.Context) (interface{}, error) {
+		time.Sleep(30 * time.Millisecond)
+		return make([]byte, 1<<20), nil
+	}}))
+	require.NoError(t, wp.Submit(Task{ID: "fail", Execute: func(ctx context.Context) (interface{}, error) {
+		time.Sleep(10 * time.Millisecond)
+		return nil, errBoom
+	}}))
+
+	results := make(map[string]Result)
+	for i := 0; i < 2; i++ {
+		r := <-wp.Results()
+		results[r.TaskID] = r
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, events, 2)
+
+	ok := events["ok"]
+	assert.Equal(t, TaskSucceeded, ok.Status)
+	assert.NoError(t, ok.Error)
+	assert.GreaterOrEqual(t, ok.Duration, 30*time.Millisecond)
+	assert.Equal(t, results["ok"].Duration, ok.Duration)
+
+	fail := events["fail"]
+	assert.Equal(t, TaskFailed, fail.Status)
+	assert.ErrorIs(t, fail.Error, errBoom)
+	assert.GreaterOrEqual(t, fail.Duration, 10*time.Millisecond)
+	assert.Equal(t, results["fail"].Duration, fail.Duration)
+}
@@ -0,0 +1,35 @@
+package workerpool
+
+import "runtime"
+
+// WithLockedOSThreads pins every worker to its own OS thread for the worker's lifetime,
+// for tasks that call into C libraries with thread-local state. The worker init hook runs
+// after the thread is locked, so per-thread setup it performs is seen by every task on that
+// worker. The thread exits with its worker rather than returning to the Go scheduler, so
+// thread-local state never leaks to other goroutines.
+//
+// At most maxThreads workers run at once, regardless of the pool's min and max workers,
+// since each one holds an OS thread. With autoscaling, locked pools add one worker per
+// adjustment instead of doubling.
+func WithLockedOSThreads(maxThreads int) Option {
+	return func(wp *WorkerPool) {
+		wp.lockedThreads = max(maxThreads, 1)
+	}
+}
+
+// lockWorkerThread locks the calling worker goroutine to its OS thread if the pool pins workers.
+// The thread is never unlocked, so it is terminated when the worker goroutine exits.
+func (wp *WorkerPool) lockWorkerThread() {
+	if wp.lockedThreads > 0 {
+		runtime.LockOSThread()
+	}
+}
+
+// clampWorkers limits the worker bounds to the locked thread limit, if any.
+func (wp *WorkerPool) clampWorkers(minWorkers, maxWorkers int) (int, int) {
+	if wp.lockedThreads > 0 {
+		minWorkers = min(minWorkers, wp.lockedThreads)
+		maxWorkers = min(maxWorkers, wp.lockedThreads)
+	}
+	return minWorkers, maxWorkers
+}
@@ -0,0 +1,105 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_LockedOSThreads(t *testing.T) {
+	if _, ok := threadID(); !ok {
+		t.Skip("thread IDs are only available on Linux")
+	}
+
+	// The init hook records the thread each worker is pinned to
+	wp := NewWorkerPool(3, 3,
+		WithLockedOSThreads(3),
+		WithWorkerInit(func(ctx context.Context, workerID int) (interface{}, error) {
+			tid, _ := threadID()
+			return tid, nil
+		}),
+	)
+	wp.Start()
+	defer wp.Stop()
+
+	const tasks = 30
+	for i := 0; i < tasks; i++ {
+		require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+			res, _ := WorkerResourceFromContext(ctx)
+			initTID := res.(int)
+
+			// Parking the goroutine would let an unpinned worker resume on another thread
+			for j := 0; j < 5; j++ {
+				time.Sleep(time.Millisecond)
+				if tid, _ := threadID(); tid != initTID {
+					return nil, fmt.Errorf("task moved from thread %d to %d", initTID, tid)
+				}
+			}
+			return initTID, nil
+		}}))
+	}
+
+	threads := make(map[int]bool)
+	for i := 0; i < tasks; i++ {
+		result := <-wp.Results()
+		require.NoError(t, result.Error)
+		threads[result.Value.(int)] = true
+	}
+	assert.Len(t, threads, 3)
+}
+
+func TestWorkerPool_LockedOSThreadsLimit(t *testing.T) {
+	wp := NewWorkerPool(4, 8, WithLockedOSThreads(2))
+	s := wp.Snapshot()
+	assert.Equal(t, 2, s.MinWorkers)
+	assert.Equal(t, 2, s.MaxWorkers)
+
+	wp.Start()
+	defer wp.Stop()
+	assert.Equal(t, 2, wp.Size())
+
+	// Resizing can't exceed the thread limit
+	wp.Resize(5, 10)
+	assert.Equal(t, 2, wp.Snapshot().MaxWorkers)
+	assert.Equal(t, 2, wp.Size())
+
+	// Unlocked pools are not limited
+	unlocked := NewWorkerPool(4, 8)
+	assert.Equal(t, 8, unlocked.Snapshot().MaxWorkers)
+}
+
+func TestWorkerPool_LockedOSThreadsScaleOneAtATime(t *testing.T) {
+	release := make(chan struct{})
+	wp := NewWorkerPool(1, 4,
+		WithLockedOSThreads(4),
+		WithAutoScaling(),
+		WithAutoScaleInterval(time.Hour),
+	)
+	wp.Start()
+	defer wp.Stop()
+	defer close(release)
+
+	var started sync.WaitGroup
+	started.Add(1)
+	for i := 0; i < 10; i++ {
+		first := i == 0
+		require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+			if first {
+				started.Done()
+			}
+			<-release
+			return nil, nil
+		}}))
+	}
+	started.Wait()
+
+	wp.adjustWorkers()
+	assert.Equal(t, 2, wp.Size())
+	wp.adjustWorkers()
+	assert.Equal(t, 3, wp.Size())
+}
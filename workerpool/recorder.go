@@ -0,0 +1,45 @@
+package workerpool
+
+import "time"
+
+// WorkerState is a worker activity transition reported to a Recorder.
+type WorkerState int
+
+const (
+	// WorkerIdle means the worker started, or finished a task, and is waiting for the next one.
+	WorkerIdle WorkerState = iota
+	// WorkerBusy means the worker picked up a task.
+	WorkerBusy
+	// WorkerExited means the worker stopped and no longer counts toward capacity.
+	WorkerExited
+)
+
+// Recorder receives every task result and worker state transition, for example to build
+// execution reports. Its methods are called concurrently from worker goroutines before the
+// Result is sent, so they should return quickly.
+type Recorder interface {
+	RecordResult(result Result)
+	RecordWorkerState(workerID int, state WorkerState, at time.Time)
+}
+
+// WithRecorder sets a Recorder that observes every task result and worker state transition.
+// Tasks run by DrainAndProcess report results but no worker transitions.
+func WithRecorder(recorder Recorder) Option {
+	return func(wp *WorkerPool) {
+		wp.recorder = recorder
+	}
+}
+
+// recordResult reports a finished task to the recorder, if one is set.
+func (wp *WorkerPool) recordResult(result Result) {
+	if wp.recorder != nil {
+		wp.recorder.RecordResult(result)
+	}
+}
+
+// recordWorkerState reports a worker transition to the recorder, if one is set.
+func (wp *WorkerPool) recordWorkerState(workerID int, state WorkerState) {
+	if wp.recorder != nil {
+		wp.recorder.RecordWorkerState(workerID, state, time.Now())
+	}
+}
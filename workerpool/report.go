@@ -0,0 +1,481 @@
+package workerpool
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// defaultReportSlice is the period each ReportRecorder slice aggregates.
+	defaultReportSlice = time.Hour
+
+	// defaultReportRetention is how long a ReportRecorder keeps slices.
+	defaultReportRetention = 7 * 24 * time.Hour
+
+	// defaultReportTopK is how many tags and slow tasks a report lists.
+	defaultReportTopK = 10
+
+	// reportSampleSize is the number of latency samples kept per slice.
+	reportSampleSize = 512
+
+	// maxReportTags and maxReportErrors bound the distinct tags and error buckets per slice.
+	// Further ones are grouped under otherBucket.
+	maxReportTags   = 256
+	maxReportErrors = 64
+
+	// maxErrorLength truncates error strings before bucketing.
+	maxErrorLength = 200
+
+	otherBucket    = "(other)"
+	untaggedBucket = "(untagged)"
+)
+
+// errorDigits matches the variable parts of error strings, such as IDs and counts.
+var errorDigits = regexp.MustCompile(`[0-9]+`)
+
+// TagUsage is the work done for one task tag.
+type TagUsage struct {
+	Tag           string        `json:"tag"`
+	Tasks         int64         `json:"tasks"`
+	Failures      int64         `json:"failures"`
+	TotalDuration time.Duration `json:"total_duration"`
+}
+
+// SlowTask is a single long-running task execution.
+type SlowTask struct {
+	TaskID    string        `json:"task_id"`
+	Tag       string        `json:"tag,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	StartTime time.Time     `json:"start_time"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ErrorCount is the number of failures with the same error, after digits are replaced with '#'.
+type ErrorCount struct {
+	Error string `json:"error"`
+	Count int64  `json:"count"`
+}
+
+// LatencyPercentiles are task execution time percentiles estimated from sampled durations.
+type LatencyPercentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+}
+
+// ExecutionReport summarizes what a pool spent its time on over a period.
+// Durations are encoded in JSON as nanoseconds.
+type ExecutionReport struct {
+	Since         time.Time          `json:"since"`
+	Until         time.Time          `json:"until"`
+	Tasks         int64              `json:"tasks"`
+	Failures      int64              `json:"failures"`
+	BusyTime      time.Duration      `json:"busy_time"`      // Worker time spent executing tasks
+	AvailableTime time.Duration      `json:"available_time"` // Worker time available, busy or idle
+	Utilization   float64            `json:"utilization"`    // BusyTime / AvailableTime
+	Latency       LatencyPercentiles `json:"latency"`
+	TopTags       []TagUsage         `json:"top_tags"`      // By total execution time
+	SlowestTasks  []SlowTask         `json:"slowest_tasks"` // Slowest first
+	Errors        []ErrorCount       `json:"errors"`        // Most frequent first
+}
+
+// ReportRecorder is a Recorder that aggregates results into fixed time slices so that
+// ExecutionReports can be produced for any recent period with bounded memory.
+// Reports are accurate to the slice size.
+type ReportRecorder struct {
+	sliceSize time.Duration
+	retention time.Duration
+	topK      int
+
+	mu      sync.Mutex
+	slices  []*reportSlice // Oldest first
+	workers map[int]workerActivity
+	now     func() time.Time
+}
+
+// workerActivity is a worker's current state and when it was entered.
+type workerActivity struct {
+	state WorkerState
+	since time.Time
+}
+
+// reportSlice aggregates the results and worker time of one period.
+type reportSlice struct {
+	start     time.Time
+	tasks     int64
+	failures  int64
+	busy      time.Duration
+	available time.Duration
+	tags      map[string]*TagUsage
+	errors    map[string]int64
+	slowest   slowHeap
+	samples   []time.Duration // Reservoir sample of task durations
+	seen      int64           // Durations offered to the reservoir
+}
+
+// NewReportRecorder creates a recorder that aggregates into slices of sliceSize, keeps them
+// for retention, and lists the topK tags and slowest tasks in reports. Zero values select
+// hourly slices, a week of retention and a top 10.
+func NewReportRecorder(sliceSize, retention time.Duration, topK int) *ReportRecorder {
+	if sliceSize <= 0 {
+		sliceSize = defaultReportSlice
+	}
+	if retention <= 0 {
+		retention = defaultReportRetention
+	}
+	if topK <= 0 {
+		topK = defaultReportTopK
+	}
+
+	return &ReportRecorder{
+		sliceSize: sliceSize,
+		retention: retention,
+		topK:      topK,
+		workers:   make(map[int]workerActivity),
+		now:       time.Now,
+	}
+}
+
+// RecordResult implements Recorder.
+func (r *ReportRecorder) RecordResult(result Result) {
+	at := result.EndTime
+	if at.IsZero() {
+		at = r.now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.slice(at)
+	if s == nil {
+		return
+	}
+
+	tag := result.Tag
+	if tag == "" {
+		tag = untaggedBucket
+	}
+	usage, ok := s.tags[tag]
+	if !ok {
+		if len(s.tags) >= maxReportTags {
+			tag = otherBucket
+		}
+		if usage, ok = s.tags[tag]; !ok {
+			usage = &TagUsage{Tag: tag}
+			s.tags[tag] = usage
+		}
+	}
+
+	s.tasks++
+	usage.Tasks++
+	usage.TotalDuration += result.Duration
+
+	var errText string
+	if result.Error != nil {
+		errText = result.Error.Error()
+		s.failures++
+		usage.Failures++
+		s.addError(errText)
+	}
+
+	s.addSample(result.Duration)
+	s.slowest.offer(SlowTask{
+		TaskID:    result.TaskID,
+		Tag:       result.Tag,
+		Duration:  result.Duration,
+		StartTime: result.StartTime,
+		Error:     errText,
+	}, r.topK)
+}
+
+// RecordWorkerState implements Recorder.
+func (r *ReportRecorder) RecordWorkerState(workerID int, state WorkerState, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if prev, ok := r.workers[workerID]; ok {
+		r.addWorkerTime(prev.since, at, prev.state == WorkerBusy)
+	}
+
+	if state == WorkerExited {
+		delete(r.workers, workerID)
+		return
+	}
+	r.workers[workerID] = workerActivity{state: state, since: at}
+}
+
+// Report summarizes the slices overlapping the period from since until now.
+func (r *ReportRecorder) Report(since time.Time) ExecutionReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Account for time spent in the current worker states
+	now := r.now()
+	for id, activity := range r.workers {
+		r.addWorkerTime(activity.since, now, activity.state == WorkerBusy)
+		activity.since = now
+		r.workers[id] = activity
+	}
+
+	report := ExecutionReport{Since: since, Until: now}
+	tags := make(map[string]*TagUsage)
+	errs := make(map[string]int64)
+	var slowest []SlowTask
+	var samples []weightedSample
+
+	for _, s := range r.slices {
+		if !s.start.Add(r.sliceSize).After(since) {
+			continue
+		}
+
+		report.Tasks += s.tasks
+		report.Failures += s.failures
+		report.BusyTime += s.busy
+		report.AvailableTime += s.available
+
+		for tag, usage := range s.tags {
+			total, ok := tags[tag]
+			if !ok {
+				total = &TagUsage{Tag: tag}
+				tags[tag] = total
+			}
+			total.Tasks += usage.Tasks
+			total.Failures += usage.Failures
+			total.TotalDuration += usage.TotalDuration
+		}
+		for text, count := range s.errors {
+			errs[text] += count
+		}
+		slowest = append(slowest, s.slowest...)
+
+		// Each sample stands for the durations the reservoir skipped
+		weight := float64(s.seen) / float64(len(s.samples))
+		for _, d := range s.samples {
+			samples = append(samples, weightedSample{duration: d, weight: weight})
+		}
+	}
+
+	if report.AvailableTime > 0 {
+		report.Utilization = float64(report.BusyTime) / float64(report.AvailableTime)
+	}
+	report.Latency = percentiles(samples)
+
+	for _, usage := range tags {
+		report.TopTags = append(report.TopTags, *usage)
+	}
+	sort.Slice(report.TopTags, func(i, j int) bool {
+		a, b := report.TopTags[i], report.TopTags[j]
+		if a.TotalDuration != b.TotalDuration {
+			return a.TotalDuration > b.TotalDuration
+		}
+		return a.Tag < b.Tag
+	})
+	if len(report.TopTags) > r.topK {
+		report.TopTags = report.TopTags[:r.topK]
+	}
+
+	sort.Slice(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	if len(slowest) > r.topK {
+		slowest = slowest[:r.topK]
+	}
+	report.SlowestTasks = slowest
+
+	for text, count := range errs {
+		report.Errors = append(report.Errors, ErrorCount{Error: text, Count: count})
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		a, b := report.Errors[i], report.Errors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Error < b.Error
+	})
+
+	return report
+}
+
+// slice returns the slice containing t, creating it if needed. It returns nil for times
+// older than the retention period.
+func (r *ReportRecorder) slice(t time.Time) *reportSlice {
+	start := t.Truncate(r.sliceSize)
+
+	i := sort.Search(len(r.slices), func(i int) bool { return !r.slices[i].start.Before(start) })
+	if i < len(r.slices) && r.slices[i].start.Equal(start) {
+		return r.slices[i]
+	}
+
+	newest := start
+	if n := len(r.slices); n > 0 && r.slices[n-1].start.After(newest) {
+		newest = r.slices[n-1].start
+	}
+	cutoff := newest.Add(-r.retention)
+	if !start.After(cutoff) {
+		return nil
+	}
+
+	s := &reportSlice{
+		start:  start,
+		tags:   make(map[string]*TagUsage),
+		errors: make(map[string]int64),
+	}
+	r.slices = append(r.slices, nil)
+	copy(r.slices[i+1:], r.slices[i:])
+	r.slices[i] = s
+
+	// Drop slices that fell out of the retention period
+	expired := 0
+	for expired < len(r.slices) && !r.slices[expired].start.After(cutoff) {
+		expired++
+	}
+	r.slices = r.slices[expired:]
+
+	return s
+}
+
+// addWorkerTime splits a worker interval across the slices it spans.
+func (r *ReportRecorder) addWorkerTime(from, to time.Time, busy bool) {
+	if earliest := to.Add(-r.retention); from.Before(earliest) {
+		from = earliest
+	}
+
+	for from.Before(to) {
+		end := from.Truncate(r.sliceSize).Add(r.sliceSize)
+		if end.After(to) {
+			end = to
+		}
+
+		if s := r.slice(from); s != nil {
+			s.available += end.Sub(from)
+			if busy {
+				s.busy += end.Sub(from)
+			}
+		}
+		from = end
+	}
+}
+
+// addError counts a failure under its error bucket.
+func (s *reportSlice) addError(text string) {
+	if len(text) > maxErrorLength {
+		text = text[:maxErrorLength]
+	}
+	text = errorDigits.ReplaceAllString(text, "#")
+
+	if _, ok := s.errors[text]; !ok && len(s.errors) >= maxReportErrors {
+		text = otherBucket
+	}
+	s.errors[text]++
+}
+
+// addSample offers a duration to the slice's reservoir.
+func (s *reportSlice) addSample(d time.Duration) {
+	s.seen++
+	if len(s.samples) < reportSampleSize {
+		s.samples = append(s.samples, d)
+		return
+	}
+	if i := rand.Int64N(s.seen); i < reportSampleSize {
+		s.samples[i] = d
+	}
+}
+
+// slowHeap is a min-heap of the slowest tasks, so the fastest of them is replaced first.
+type slowHeap []SlowTask
+
+func (h slowHeap) Len() int           { return len(h) }
+func (h slowHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h slowHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *slowHeap) Push(x any)        { *h = append(*h, x.(SlowTask)) }
+func (h *slowHeap) Pop() any {
+	old := *h
+	task := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return task
+}
+
+// offer keeps task if it is among the k slowest seen.
+func (h *slowHeap) offer(task SlowTask, k int) {
+	if h.Len() < k {
+		heap.Push(h, task)
+		return
+	}
+	if task.Duration > (*h)[0].Duration {
+		(*h)[0] = task
+		heap.Fix(h, 0)
+	}
+}
+
+// weightedSample is a sampled duration standing for weight executions.
+type weightedSample struct {
+	duration time.Duration
+	weight   float64
+}
+
+// percentiles estimates latency percentiles from weighted samples.
+func percentiles(samples []weightedSample) LatencyPercentiles {
+	if len(samples) == 0 {
+		return LatencyPercentiles{}
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i].duration < samples[j].duration })
+	var total float64
+	for _, s := range samples {
+		total += s.weight
+	}
+
+	at := func(p float64) time.Duration {
+		target := p * total
+		var cumulative float64
+		for _, s := range samples {
+			cumulative += s.weight
+			if cumulative >= target {
+				return s.duration
+			}
+		}
+		return samples[len(samples)-1].duration
+	}
+
+	return LatencyPercentiles{P50: at(0.50), P90: at(0.90), P99: at(0.99)}
+}
+
+// WriteJSON writes the report as indented JSON.
+func (rep ExecutionReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// WriteTable writes the report as human-readable tables.
+func (rep ExecutionReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Period\t%s - %s\n", rep.Since.Format(time.RFC3339), rep.Until.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Tasks\t%d (%d failed)\n", rep.Tasks, rep.Failures)
+	fmt.Fprintf(tw, "Utilization\t%.1f%% (busy %s of %s)\n", rep.Utilization*100, rep.BusyTime, rep.AvailableTime)
+	fmt.Fprintf(tw, "Latency\tp50 %s  p90 %s  p99 %s\n", rep.Latency.P50, rep.Latency.P90, rep.Latency.P99)
+
+	fmt.Fprintf(tw, "\nTAG\tTASKS\tFAILED\tTOTAL TIME\n")
+	for _, usage := range rep.TopTags {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", usage.Tag, usage.Tasks, usage.Failures, usage.TotalDuration)
+	}
+
+	fmt.Fprintf(tw, "\nSLOWEST TASK\tTAG\tDURATION\tSTARTED\tERROR\n")
+	for _, task := range rep.SlowestTasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", task.TaskID, task.Tag, task.Duration, task.StartTime.Format(time.RFC3339), task.Error)
+	}
+
+	fmt.Fprintf(tw, "\nERROR\tCOUNT\n")
+	for _, e := range rep.Errors {
+		fmt.Fprintf(tw, "%s\t%d\n", e.Error, e.Count)
+	}
+
+	return tw.Flush()
+}
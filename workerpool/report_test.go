@@ -0,0 +1,175 @@
+package workerpool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedResult builds a result for a task that ran for d and ended at end.
+func scriptedResult(id, tag string, end time.Time, d time.Duration, err error) Result {
+	return Result{TaskID: id, Tag: tag, Error: err, StartTime: end.Add(-d), EndTime: end, Duration: d}
+}
+
+func TestReportRecorder_ScriptedWorkload(t *testing.T) {
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+
+	rec := NewReportRecorder(time.Hour, 24*time.Hour, 2)
+	rec.now = func() time.Time { return at(2 * time.Hour) }
+
+	// Worker 1 runs for two hours and is busy for 36 minutes in the first hour
+	rec.RecordWorkerState(1, WorkerIdle, at(0))
+	rec.RecordWorkerState(1, WorkerBusy, at(10*time.Minute))
+	rec.RecordWorkerState(1, WorkerIdle, at(46*time.Minute))
+	rec.RecordWorkerState(1, WorkerExited, at(2*time.Hour))
+	// Worker 2 joins after an hour, is busy for 32 minutes and is still idle when the report is taken
+	rec.RecordWorkerState(2, WorkerIdle, at(time.Hour))
+	rec.RecordWorkerState(2, WorkerBusy, at(70*time.Minute))
+	rec.RecordWorkerState(2, WorkerIdle, at(102*time.Minute))
+
+	// First hour
+	rec.RecordResult(scriptedResult("resize-1", "resize", at(22*time.Minute), 12*time.Minute, nil))
+	rec.RecordResult(scriptedResult("resize-2", "resize", at(34*time.Minute), 12*time.Minute, nil))
+	rec.RecordResult(scriptedResult("resize-3", "resize", at(46*time.Minute), 12*time.Minute, nil))
+	// Second hour
+	rec.RecordResult(scriptedResult("export-1", "export", at(100*time.Minute), 30*time.Minute, nil))
+	rec.RecordResult(scriptedResult("email-1", "email", at(101*time.Minute), time.Minute, errors.New("smtp timeout after 30s")))
+	rec.RecordResult(scriptedResult("email-2", "email", at(102*time.Minute), time.Minute, errors.New("smtp timeout after 45s")))
+
+	report := rec.Report(base)
+	assert.Equal(t, int64(6), report.Tasks)
+	assert.Equal(t, int64(2), report.Failures)
+	assert.Equal(t, 68*time.Minute, report.BusyTime)
+	assert.Equal(t, 3*time.Hour, report.AvailableTime)
+	assert.InDelta(t, 68.0/180.0, report.Utilization, 0.001)
+
+	// Top two tags by total execution time
+	require.Len(t, report.TopTags, 2)
+	assert.Equal(t, TagUsage{Tag: "resize", Tasks: 3, TotalDuration: 36 * time.Minute}, report.TopTags[0])
+	assert.Equal(t, TagUsage{Tag: "export", Tasks: 1, TotalDuration: 30 * time.Minute}, report.TopTags[1])
+
+	require.Len(t, report.SlowestTasks, 2)
+	assert.Equal(t, "export-1", report.SlowestTasks[0].TaskID)
+	assert.Equal(t, at(70*time.Minute), report.SlowestTasks[0].StartTime)
+	assert.Equal(t, 12*time.Minute, report.SlowestTasks[1].Duration)
+
+	// Error strings that differ only in numbers share a bucket
+	assert.Equal(t, []ErrorCount{{Error: "smtp timeout after #s", Count: 2}}, report.Errors)
+
+	// Reports cover only the slices overlapping the period
+	lastHour := rec.Report(at(time.Hour))
+	assert.Equal(t, int64(3), lastHour.Tasks)
+	assert.Equal(t, 32*time.Minute, lastHour.BusyTime)
+	assert.Equal(t, 2*time.Hour, lastHour.AvailableTime)
+	assert.Equal(t, "export", lastHour.TopTags[0].Tag)
+	assert.Equal(t, TagUsage{Tag: "email", Tasks: 2, Failures: 2, TotalDuration: 2 * time.Minute}, lastHour.TopTags[1])
+}
+
+func TestReportRecorder_Retention(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	rec := NewReportRecorder(time.Hour, 3*time.Hour, 0)
+	rec.now = func() time.Time { return base.Add(10 * time.Hour) }
+
+	for h := 0; h < 10; h++ {
+		rec.RecordResult(scriptedResult(fmt.Sprint(h), "", base.Add(time.Duration(h)*time.Hour), time.Second, nil))
+	}
+
+	// Only the slices within the retention period are kept
+	assert.Len(t, rec.slices, 3)
+	assert.Equal(t, int64(3), rec.Report(time.Time{}).Tasks)
+	assert.Equal(t, untaggedBucket, rec.Report(time.Time{}).TopTags[0].Tag)
+
+	// Results older than the retention period are dropped
+	rec.RecordResult(scriptedResult("late", "", base, time.Second, nil))
+	assert.Equal(t, int64(3), rec.Report(time.Time{}).Tasks)
+}
+
+func TestReportRecorder_LatencyPercentiles(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	rec := NewReportRecorder(time.Hour, 0, 0)
+	rec.now = func() time.Time { return base.Add(2 * time.Hour) }
+
+	// 1ms..10000ms spread over two slices, more than the reservoir holds
+	for i := 1; i <= 10000; i++ {
+		end := base.Add(time.Duration(i%2) * time.Hour)
+		rec.RecordResult(scriptedResult(fmt.Sprint(i), "", end, time.Duration(i)*time.Millisecond, nil))
+	}
+
+	latency := rec.Report(time.Time{}).Latency
+	assert.InDelta(t, 5000, latency.P50.Milliseconds(), 600)
+	assert.InDelta(t, 9000, latency.P90.Milliseconds(), 600)
+	assert.InDelta(t, 9900, latency.P99.Milliseconds(), 300)
+}
+
+func TestReportRecorder_WithPool(t *testing.T) {
+	rec := NewReportRecorder(0, 0, 0)
+	wp := NewWorkerPool(2, 2, WithRecorder(rec))
+	wp.Start()
+
+	for i := 0; i < 4; i++ {
+		tag := "even"
+		if i%2 == 1 {
+			tag = "odd"
+		}
+		require.NoError(t, wp.Submit(Task{Tag: tag, Execute: func(ctx context.Context) (interface{}, error) {
+			time.Sleep(40 * time.Millisecond)
+			return nil, nil
+		}}))
+	}
+	for i := 0; i < 4; i++ {
+		r := <-wp.Results()
+		assert.NotEmpty(t, r.Tag)
+	}
+	wp.Stop()
+
+	report := rec.Report(time.Now().Add(-time.Minute))
+	assert.Equal(t, int64(4), report.Tasks)
+	require.Len(t, report.TopTags, 2)
+	for _, usage := range report.TopTags {
+		assert.Equal(t, int64(2), usage.Tasks)
+		assert.GreaterOrEqual(t, usage.TotalDuration, 80*time.Millisecond)
+	}
+
+	// Both workers were busy for most of their lifetime
+	assert.GreaterOrEqual(t, report.BusyTime, 160*time.Millisecond)
+	assert.InDelta(t, 0.9, report.Utilization, 0.25)
+	assert.LessOrEqual(t, report.Utilization, 1.0)
+}
+
+func TestExecutionReport_Render(t *testing.T) {
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	report := ExecutionReport{
+		Since:         base,
+		Until:         base.Add(time.Hour),
+		Tasks:         3,
+		Failures:      1,
+		BusyTime:      30 * time.Minute,
+		AvailableTime: time.Hour,
+		Utilization:   0.5,
+		TopTags:       []TagUsage{{Tag: "resize", Tasks: 3, Failures: 1, TotalDuration: 30 * time.Minute}},
+		SlowestTasks:  []SlowTask{{TaskID: "resize-1", Tag: "resize", Duration: 20 * time.Minute, StartTime: base}},
+		Errors:        []ErrorCount{{Error: "disk full", Count: 1}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJSON(&buf))
+	var decoded ExecutionReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, report, decoded)
+
+	buf.Reset()
+	require.NoError(t, report.WriteTable(&buf))
+	table := buf.String()
+	assert.Contains(t, table, "50.0% (busy 30m0s of 1h0m0s)")
+	assert.Regexp(t, `resize\s+3\s+1\s+30m0s`, table)
+	assert.Regexp(t, `resize-1\s+resize\s+20m0s`, table)
+	assert.Regexp(t, `disk full\s+1`, table)
+}
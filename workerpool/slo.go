@@ -0,0 +1,182 @@
+package workerpool
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// sloMaxSamples bounds the queue waits kept for the p95 calculation.
+	sloMaxSamples = 2048
+
+	// sloComfortFraction is the fraction of the target below which the p95 wait is
+	// comfortable enough to remove workers.
+	sloComfortFraction = 0.5
+
+	// sloDecreaseFactor is the multiplicative decrease applied to the worker count.
+	sloDecreaseFactor = 0.75
+)
+
+// ScaleDirection is the direction of an autoscaling decision.
+type ScaleDirection string
+
+const (
+	ScaleUp   ScaleDirection = "up"
+	ScaleDown ScaleDirection = "down"
+)
+
+// ScaleDecision describes a worker count change made by the SLO autoscaler.
+type ScaleDecision struct {
+	Direction ScaleDirection
+	Reason    string
+	P95Wait   time.Duration // Measured p95 queue wait that triggered the change
+	Target    time.Duration
+	Samples   int // Queue waits the p95 was computed from
+	From      int // Workers before the change
+	To        int // Requested workers; retiring only stops idle workers, so it may take effect later
+	At        time.Time
+}
+
+// sloState holds the SLO autoscaler's configuration and measurements.
+type sloState struct {
+	target     time.Duration
+	window     time.Duration
+	cooldown   time.Duration
+	onDecision func(ScaleDecision)
+	waits      waitSamples
+
+	mu         sync.Mutex // Guards the fields below, which Snapshot reads
+	lastAdjust time.Time
+	lastP95    time.Duration
+	last       ScaleDecision
+}
+
+// WithSLOAutoscaling scales workers to keep the p95 queue wait (submit to start) within
+// targetQueueWait, measured over evaluationWindow. Workers are added one at a time while the
+// p95 exceeds the target, and reduced by a quarter while it is below half the target and the
+// queue is empty. Changes stay within the pool's min and max workers and happen at most once
+// per cooldown (evaluationWindow by default).
+//
+// On its own it replaces the queue length heuristic; combined with WithAutoScaling both run,
+// and the SLO policy takes precedence when it changes the worker count.
+func WithSLOAutoscaling(targetQueueWait, evaluationWindow time.Duration) Option {
+	return func(wp *WorkerPool) {
+		wp.slo.target = targetQueueWait
+		wp.slo.window = evaluationWindow
+		if wp.slo.cooldown == 0 {
+			wp.slo.cooldown = evaluationWindow
+		}
+	}
+}
+
+// WithSLOCooldown sets the minimum time between SLO autoscaler adjustments.
+func WithSLOCooldown(cooldown time.Duration) Option {
+	return func(wp *WorkerPool) {
+		wp.slo.cooldown = cooldown
+	}
+}
+
+// WithScaleDecisionHandler sets a callback for every change made by the SLO autoscaler.
+func WithScaleDecisionHandler(handler func(ScaleDecision)) Option {
+	return func(wp *WorkerPool) {
+		wp.slo.onDecision = handler
+	}
+}
+
+// waitSample is a queue wait observed when a task started.
+type waitSample struct {
+	at   time.Time
+	wait time.Duration
+}
+
+// waitSamples is a ring of the most recent queue waits.
+type waitSamples struct {
+	mu      sync.Mutex
+	samples []waitSample
+	next    int
+}
+
+// add records a queue wait, replacing the oldest sample once the ring is full.
+func (w *waitSamples) add(at time.Time, wait time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.samples) < sloMaxSamples {
+		w.samples = append(w.samples, waitSample{at: at, wait: wait})
+		return
+	}
+	w.samples[w.next] = waitSample{at: at, wait: wait}
+	w.next = (w.next + 1) % sloMaxSamples
+}
+
+// p95 returns the 95th percentile of waits recorded after since, and how many there were.
+func (w *waitSamples) p95(since time.Time) (time.Duration, int) {
+	w.mu.Lock()
+	waits := make([]time.Duration, 0, len(w.samples))
+	for _, s := range w.samples {
+		if s.at.After(since) {
+			waits = append(waits, s.wait)
+		}
+	}
+	w.mu.Unlock()
+
+	if len(waits) == 0 {
+		return 0, 0
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	index := (len(waits)*95 + 99) / 100 // ceil(0.95 * n)
+	return waits[index-1], len(waits)
+}
+
+// adjustForSLO adds or retires workers when the p95 queue wait is outside its bounds.
+// It reports the decision if the worker count was changed.
+func (wp *WorkerPool) adjustForSLO(now time.Time) (ScaleDecision, bool) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if !wp.isRunning {
+		return ScaleDecision{}, false
+	}
+
+	s := &wp.slo
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Only waits since the last adjustment reflect the current worker count
+	since := now.Add(-s.window)
+	if s.lastAdjust.After(since) {
+		since = s.lastAdjust
+	}
+	p95, samples := s.waits.p95(since)
+	s.lastP95 = p95
+
+	if now.Sub(s.lastAdjust) < s.cooldown {
+		return ScaleDecision{}, false
+	}
+
+	current := int(atomic.LoadInt32(&wp.activeWorkers))
+	decision := ScaleDecision{P95Wait: p95, Target: s.target, Samples: samples, From: current, At: now}
+
+	switch {
+	case p95 > s.target && current < wp.maxWorkers:
+		// Additive increase
+		decision.Direction = ScaleUp
+		decision.Reason = "p95 queue wait above target"
+		decision.To = current + 1
+		wp.startWorker()
+	case p95 < time.Duration(float64(s.target)*sloComfortFraction) && current > wp.minWorkers && wp.queued() == 0:
+		// Multiplicative decrease
+		decision.Direction = ScaleDown
+		decision.Reason = "p95 queue wait well below target"
+		decision.To = max(wp.minWorkers, min(current-1, int(float64(current)*sloDecreaseFactor)))
+		wp.retireWorkers(current - decision.To)
+	default:
+		return ScaleDecision{}, false
+	}
+
+	s.lastAdjust = now
+	s.last = decision
+	return decision, true
+}
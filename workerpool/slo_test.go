@@ -0,0 +1,203 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSLOTestPool returns a running pool whose SLO autoscaler only runs when adjustForSLO is called.
+func newSLOTestPool(t *testing.T, min, max int, options ...Option) *WorkerPool {
+	t.Helper()
+	options = append(options, WithAutoScaleInterval(time.Hour))
+	wp := NewWorkerPool(min, max, options...)
+	wp.Start()
+	t.Cleanup(wp.Stop)
+	return wp
+}
+
+// recordWaits adds queue waits observed at the given time.
+func recordWaits(wp *WorkerPool, at time.Time, waits ...time.Duration) {
+	for _, wait := range waits {
+		wp.slo.waits.add(at, wait)
+	}
+}
+
+func TestWaitSamples_P95(t *testing.T) {
+	var w waitSamples
+	start := time.Now()
+	for i := 1; i <= 100; i++ {
+		w.add(start.Add(time.Duration(i)*time.Millisecond), time.Duration(i)*time.Millisecond)
+	}
+
+	p95, n := w.p95(start)
+	assert.Equal(t, 95*time.Millisecond, p95)
+	assert.Equal(t, 100, n)
+
+	// Only samples after since count
+	p95, n = w.p95(start.Add(90 * time.Millisecond))
+	assert.Equal(t, 100*time.Millisecond, p95)
+	assert.Equal(t, 10, n)
+
+	p95, n = w.p95(start.Add(time.Second))
+	assert.Zero(t, p95)
+	assert.Zero(t, n)
+}
+
+func TestWorkerPool_SLOScalesUpOnBreachAndDownAfterRecovery(t *testing.T) {
+	wp := newSLOTestPool(t, 2, 6,
+		WithSLOAutoscaling(100*time.Millisecond, time.Minute),
+		WithSLOCooldown(10*time.Second),
+	)
+	now := time.Now()
+
+	// Waits under the target leave the pool alone
+	recordWaits(wp, now.Add(time.Second), 60*time.Millisecond, 80*time.Millisecond)
+	_, changed := wp.adjustForSLO(now.Add(11 * time.Second))
+	assert.False(t, changed)
+	assert.Equal(t, 2, wp.Size())
+
+	// A p95 over the target adds one worker
+	waits := make([]time.Duration, 20)
+	for i := range waits {
+		waits[i] = 300 * time.Millisecond
+	}
+	recordWaits(wp, now.Add(12*time.Second), waits...)
+	decision, changed := wp.adjustForSLO(now.Add(13 * time.Second))
+	require.True(t, changed)
+	assert.Equal(t, ScaleUp, decision.Direction)
+	assert.Equal(t, 300*time.Millisecond, decision.P95Wait)
+	assert.Equal(t, 2, decision.From)
+	assert.Equal(t, 3, decision.To)
+	assert.Equal(t, 3, wp.Size())
+
+	// Keep breaching until max workers
+	at := now.Add(13 * time.Second)
+	for i := 0; i < 3; i++ {
+		recordWaits(wp, at.Add(time.Second), waits...)
+		at = at.Add(10 * time.Second)
+		wp.adjustForSLO(at)
+	}
+	assert.Equal(t, 6, wp.Size())
+
+	// At max workers a breach changes nothing
+	recordWaits(wp, at.Add(time.Second), waits...)
+	_, changed = wp.adjustForSLO(at.Add(10 * time.Second))
+	assert.False(t, changed)
+
+	// Fast waits after recovery retire a quarter of the workers per adjustment.
+	// Only idle workers can be retired, so let the new ones reach their loop first.
+	time.Sleep(50 * time.Millisecond)
+	at = at.Add(time.Minute)
+	recordWaits(wp, at.Add(time.Second), 5*time.Millisecond, 10*time.Millisecond)
+	decision, changed = wp.adjustForSLO(at.Add(10 * time.Second))
+	require.True(t, changed)
+	assert.Equal(t, ScaleDown, decision.Direction)
+	assert.Equal(t, 6, decision.From)
+	assert.Equal(t, 4, decision.To)
+	require.Eventually(t, func() bool { return wp.Size() == 4 }, time.Second, 5*time.Millisecond)
+
+	snapshot := wp.Snapshot()
+	assert.Equal(t, decision, snapshot.LastScaleDecision)
+	assert.Equal(t, 10*time.Millisecond, snapshot.QueueWaitP95)
+}
+
+func TestWorkerPool_SLORespectsCooldown(t *testing.T) {
+	wp := newSLOTestPool(t, 1, 10,
+		WithSLOAutoscaling(50*time.Millisecond, time.Minute),
+		WithSLOCooldown(5*time.Second),
+	)
+	now := time.Now()
+
+	recordWaits(wp, now.Add(time.Millisecond), time.Second)
+	_, changed := wp.adjustForSLO(now.Add(time.Second))
+	assert.False(t, changed, "within the cooldown since Start")
+
+	_, changed = wp.adjustForSLO(now.Add(5 * time.Second))
+	require.True(t, changed)
+
+	// Still breaching, but the last adjustment was too recent
+	recordWaits(wp, now.Add(6*time.Second), time.Second)
+	_, changed = wp.adjustForSLO(now.Add(9 * time.Second))
+	assert.False(t, changed)
+	assert.Equal(t, 2, wp.Size())
+
+	_, changed = wp.adjustForSLO(now.Add(10 * time.Second))
+	assert.True(t, changed)
+	assert.Equal(t, 3, wp.Size())
+}
+
+func TestWorkerPool_SLONoScaleDownWithQueuedTasks(t *testing.T) {
+	wp := NewWorkerPool(1, 4, WithSLOAutoscaling(100*time.Millisecond, time.Minute), WithSLOCooldown(time.Nanosecond))
+	wp.mu.Lock()
+	wp.isRunning = true
+	wp.mu.Unlock()
+
+	// No recent samples, but work is still waiting for busy workers
+	atomic.StoreInt32(&wp.activeWorkers, 3)
+	wp.taskQueue <- Task{ID: "queued", Execute: func(ctx context.Context) (interface{}, error) { return nil, nil }}
+
+	_, changed := wp.adjustForSLO(time.Now())
+	assert.False(t, changed)
+}
+
+func TestWorkerPool_SLOSyntheticWorkload(t *testing.T) {
+	const target = 30 * time.Millisecond
+
+	var mu sync.Mutex
+	var decisions []ScaleDecision
+	wp := NewWorkerPool(1, 8,
+		WithSLOAutoscaling(target, 200*time.Millisecond),
+		WithSLOCooldown(50*time.Millisecond),
+		WithAutoScaleInterval(10*time.Millisecond),
+		WithQueueCapacity(1000),
+		WithScaleDecisionHandler(func(d ScaleDecision) {
+			mu.Lock()
+			decisions = append(decisions, d)
+			mu.Unlock()
+		}),
+	)
+	wp.Start()
+	defer wp.Stop()
+
+	go func() {
+		for range wp.Results() {
+		}
+	}()
+
+	submitFor := func(d, every, taskDuration time.Duration) {
+		deadline := time.Now().Add(d)
+		for time.Now().Before(deadline) {
+			_ = wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+				time.Sleep(taskDuration)
+				return nil, nil
+			}})
+			time.Sleep(every)
+		}
+	}
+
+	// Fast tasks keep the queue wait low on a single worker
+	submitFor(200*time.Millisecond, 10*time.Millisecond, time.Millisecond)
+	assert.Equal(t, 1, wp.Size())
+
+	// Slow tasks arriving faster than one worker can handle breach the SLO
+	go submitFor(600*time.Millisecond, 5*time.Millisecond, 20*time.Millisecond)
+	require.Eventually(t, func() bool { return wp.Size() >= 3 }, 2*time.Second, 5*time.Millisecond)
+
+	// Once the load stops the pool shrinks back to the minimum
+	require.Eventually(t, func() bool { return wp.Size() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, decisions)
+	assert.Equal(t, ScaleUp, decisions[0].Direction)
+	assert.Greater(t, decisions[0].P95Wait, target)
+	for i := 1; i < len(decisions); i++ {
+		assert.GreaterOrEqual(t, decisions[i].At.Sub(decisions[i-1].At), 50*time.Millisecond, "cooldown between decisions")
+	}
+}
@@ -0,0 +1,278 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultWaitWindow is the default window for queue wait statistics.
+	defaultWaitWindow = time.Minute
+
+	// waitWindowBuckets is the number of buckets the wait window is divided into.
+	waitWindowBuckets = 10
+)
+
+// StatsSnapshot is a point-in-time view of the worker pool's state and metrics.
+type StatsSnapshot struct {
+	Name          string
+	IsRunning     bool
+	MinWorkers    int
+	MaxWorkers    int
+	ActiveWorkers int
+	QueueCapacity int
+	QueueSize     int
+	InitFailures  int64
+
+	// TotalTasks, CompletedTasks and FailedTasks count since the last ResetStats.
+	TotalTasks     int64
+	CompletedTasks int64
+	FailedTasks    int64
+	StatsResetAt   time.Time
+
+	// Lifetime counters are never reset.
+	LifetimeTotalTasks     int64
+	LifetimeCompletedTasks int64
+	LifetimeFailedTasks    int64
+
+	// QueueHighWater is the largest queue size seen since the pool was created.
+	QueueHighWater int
+	// QueueHighWaterSinceReset is the largest queue size seen since the last ResetWatermarks.
+	QueueHighWaterSinceReset int
+	// AvgQueueWait and MaxQueueWait cover tasks dequeued within the wait window.
+	AvgQueueWait time.Duration
+	MaxQueueWait time.Duration
+	// RejectedTasks counts submits that failed with ErrQueueFull since the last reset.
+	RejectedTasks int64
+	// SaturatedDuration is the time spent with the queue at capacity since the last reset.
+	SaturatedDuration time.Duration
+
+	// QueueWaitP95 is the p95 queue wait last measured by the SLO autoscaler.
+	QueueWaitP95 time.Duration
+	// LastScaleDecision is the SLO autoscaler's most recent change, zero if it made none.
+	LastScaleDecision ScaleDecision
+
+	// SubPools breaks usage down by open sub-pool, in creation order.
+	SubPools []SubPoolSnapshot
+}
+
+// WithQueueWaitWindow sets the window over which queue wait times are averaged.
+func WithQueueWaitWindow(window time.Duration) Option {
+	return func(wp *WorkerPool) {
+		if window >= waitWindowBuckets {
+			wp.marks.waits.bucketSize = int64(window) / waitWindowBuckets
+		}
+	}
+}
+
+// statsBaseline holds the lifetime counter values at the last ResetStats.
+// Window counters are the lifetime counters minus the baseline, so resets never lose increments.
+type statsBaseline struct {
+	mu        sync.Mutex
+	total     int64
+	completed int64
+	failed    int64
+	resetAt   time.Time
+}
+
+// ResetStats starts a new window for the total, completed and failed task counters.
+// Lifetime counters are unaffected.
+func (wp *WorkerPool) ResetStats() {
+	wp.statsBase.mu.Lock()
+	defer wp.statsBase.mu.Unlock()
+
+	wp.statsBase.total = atomic.LoadInt64(&wp.totalTasks)
+	wp.statsBase.completed = atomic.LoadInt64(&wp.completedTasks)
+	wp.statsBase.failed = atomic.LoadInt64(&wp.failedTasks)
+	wp.statsBase.resetAt = time.Now()
+}
+
+// watermarks tracks queue pressure with atomic updates only.
+type watermarks struct {
+	highWater      int64
+	highWaterReset int64
+	rejected       int64
+	saturatedSince int64 // unix nanos, zero when the queue isn't full
+	saturatedTotal int64 // nanos
+	waits          waitWindow
+}
+
+// waitBucket aggregates queue waits for one slice of the wait window.
+type waitBucket struct {
+	epoch int64
+	count int64
+	total int64
+	max   int64
+}
+
+// waitWindow is a ring of time buckets covering the wait window.
+type waitWindow struct {
+	bucketSize int64
+	buckets    [waitWindowBuckets]waitBucket
+}
+
+// record adds a queue wait observed at now.
+func (w *waitWindow) record(now time.Time, wait time.Duration) {
+	epoch := now.UnixNano() / w.bucketSize
+	b := &w.buckets[epoch%waitWindowBuckets]
+
+	// Recycle a bucket left over from an earlier pass around the ring
+	if old := atomic.LoadInt64(&b.epoch); old != epoch && atomic.CompareAndSwapInt64(&b.epoch, old, epoch) {
+		atomic.StoreInt64(&b.count, 0)
+		atomic.StoreInt64(&b.total, 0)
+		atomic.StoreInt64(&b.max, 0)
+	}
+
+	atomic.AddInt64(&b.count, 1)
+	atomic.AddInt64(&b.total, int64(wait))
+	storeMax(&b.max, int64(wait))
+}
+
+// stats returns the average and maximum wait over buckets still inside the window.
+func (w *waitWindow) stats(now time.Time) (time.Duration, time.Duration) {
+	current := now.UnixNano() / w.bucketSize
+
+	var count, total, maxWait int64
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if current-atomic.LoadInt64(&b.epoch) >= waitWindowBuckets {
+			continue
+		}
+		count += atomic.LoadInt64(&b.count)
+		total += atomic.LoadInt64(&b.total)
+		if m := atomic.LoadInt64(&b.max); m > maxWait {
+			maxWait = m
+		}
+	}
+
+	if count == 0 {
+		return 0, 0
+	}
+	return time.Duration(total / count), time.Duration(maxWait)
+}
+
+// reset discards all recorded waits.
+func (w *waitWindow) reset() {
+	for i := range w.buckets {
+		atomic.StoreInt64(&w.buckets[i].epoch, 0)
+		atomic.StoreInt64(&w.buckets[i].count, 0)
+		atomic.StoreInt64(&w.buckets[i].total, 0)
+		atomic.StoreInt64(&w.buckets[i].max, 0)
+	}
+}
+
+// storeMax raises *addr to value if value is larger.
+func storeMax(addr *int64, value int64) {
+	for {
+		current := atomic.LoadInt64(addr)
+		if value <= current || atomic.CompareAndSwapInt64(addr, current, value) {
+			return
+		}
+	}
+}
+
+// recordEnqueue updates watermarks after a task is queued.
+func (wp *WorkerPool) recordEnqueue() {
+	size := int64(len(wp.taskQueue))
+	storeMax(&wp.marks.highWater, size)
+	storeMax(&wp.marks.highWaterReset, size)
+
+	if size >= int64(wp.queueCapacity) {
+		wp.markSaturated()
+	}
+}
+
+// recordRejection updates watermarks after a submit fails with ErrQueueFull.
+func (wp *WorkerPool) recordRejection() {
+	atomic.AddInt64(&wp.marks.rejected, 1)
+	wp.markSaturated()
+}
+
+// markSaturated starts the saturation clock if it isn't already running.
+func (wp *WorkerPool) markSaturated() {
+	atomic.CompareAndSwapInt64(&wp.marks.saturatedSince, 0, time.Now().UnixNano())
+}
+
+// recordDequeue updates watermarks after a worker takes a task off the queue.
+func (wp *WorkerPool) recordDequeue(task Task) {
+	now := time.Now()
+	if !task.enqueuedAt.IsZero() {
+		wp.marks.waits.record(now, now.Sub(task.enqueuedAt))
+		if wp.slo.target > 0 {
+			wp.slo.waits.add(now, now.Sub(task.enqueuedAt))
+		}
+	}
+
+	// Stop the saturation clock once there's room in the queue again
+	if since := atomic.LoadInt64(&wp.marks.saturatedSince); since != 0 && len(wp.taskQueue) < wp.queueCapacity {
+		if atomic.CompareAndSwapInt64(&wp.marks.saturatedSince, since, 0) {
+			atomic.AddInt64(&wp.marks.saturatedTotal, now.UnixNano()-since)
+		}
+	}
+}
+
+// ResetWatermarks clears the since-reset high-water mark, queue wait window,
+// rejection count and saturation time. The lifetime high-water mark is kept.
+func (wp *WorkerPool) ResetWatermarks() {
+	atomic.StoreInt64(&wp.marks.highWaterReset, int64(len(wp.taskQueue)))
+	atomic.StoreInt64(&wp.marks.rejected, 0)
+	atomic.StoreInt64(&wp.marks.saturatedTotal, 0)
+	if since := atomic.LoadInt64(&wp.marks.saturatedSince); since != 0 {
+		atomic.CompareAndSwapInt64(&wp.marks.saturatedSince, since, time.Now().UnixNano())
+	}
+	wp.marks.waits.reset()
+}
+
+// Snapshot returns the current statistics as a typed snapshot.
+func (wp *WorkerPool) Snapshot() StatsSnapshot {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+
+	now := time.Now()
+	saturated := atomic.LoadInt64(&wp.marks.saturatedTotal)
+	if since := atomic.LoadInt64(&wp.marks.saturatedSince); since != 0 {
+		saturated += now.UnixNano() - since
+	}
+	avgWait, maxWait := wp.marks.waits.stats(now)
+
+	// Read the counters under the baseline lock so a concurrent reset can't make the window negative
+	wp.statsBase.mu.Lock()
+	total := atomic.LoadInt64(&wp.totalTasks)
+	completed := atomic.LoadInt64(&wp.completedTasks)
+	failed := atomic.LoadInt64(&wp.failedTasks)
+	baseTotal, baseCompleted, baseFailed := wp.statsBase.total, wp.statsBase.completed, wp.statsBase.failed
+	resetAt := wp.statsBase.resetAt
+	wp.statsBase.mu.Unlock()
+
+	wp.slo.mu.Lock()
+	p95, decision := wp.slo.lastP95, wp.slo.last
+	wp.slo.mu.Unlock()
+
+	return StatsSnapshot{
+		Name:                     wp.name,
+		IsRunning:                wp.isRunning,
+		MinWorkers:               wp.minWorkers,
+		MaxWorkers:               wp.maxWorkers,
+		ActiveWorkers:            int(atomic.LoadInt32(&wp.activeWorkers)),
+		QueueCapacity:            wp.queueCapacity,
+		QueueSize:                len(wp.taskQueue),
+		InitFailures:             atomic.LoadInt64(&wp.initFailures),
+		TotalTasks:               total - baseTotal,
+		CompletedTasks:           completed - baseCompleted,
+		FailedTasks:              failed - baseFailed,
+		StatsResetAt:             resetAt,
+		LifetimeTotalTasks:       total,
+		LifetimeCompletedTasks:   completed,
+		LifetimeFailedTasks:      failed,
+		QueueHighWater:           int(atomic.LoadInt64(&wp.marks.highWater)),
+		QueueHighWaterSinceReset: int(atomic.LoadInt64(&wp.marks.highWaterReset)),
+		AvgQueueWait:             avgWait,
+		MaxQueueWait:             maxWait,
+		RejectedTasks:            atomic.LoadInt64(&wp.marks.rejected),
+		SaturatedDuration:        time.Duration(saturated),
+		QueueWaitP95:             p95,
+		LastScaleDecision:        decision,
+		SubPools:                 wp.subs.snapshots(),
+	}
+}
@@ -0,0 +1,213 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_Watermarks(t *testing.T) {
+	wp := NewWorkerPool(1, 1, WithQueueCapacity(5))
+	wp.Start()
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	blocking := func(ctx context.Context) (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+
+	// Occupy the only worker, then fill the queue
+	started := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "first", Execute: func(ctx context.Context) (interface{}, error) {
+		close(started)
+		return blocking(ctx)
+	}}))
+	<-started
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, wp.Submit(Task{ID: fmt.Sprintf("queued-%d", i), Execute: blocking}))
+	}
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, wp.Submit(Task{Execute: blocking}), ErrQueueFull)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	s := wp.Snapshot()
+	assert.Equal(t, 5, s.QueueSize)
+	assert.Equal(t, 5, s.QueueHighWater)
+	assert.Equal(t, 5, s.QueueHighWaterSinceReset)
+	assert.Equal(t, int64(3), s.RejectedTasks)
+	assert.GreaterOrEqual(t, s.SaturatedDuration, 50*time.Millisecond)
+	assert.Less(t, s.SaturatedDuration, 5*time.Second)
+
+	// Let everything run; queued tasks waited at least as long as the sleep
+	close(release)
+	for i := 0; i < 6; i++ {
+		<-wp.Results()
+	}
+
+	s = wp.Snapshot()
+	assert.Equal(t, 0, s.QueueSize)
+	assert.GreaterOrEqual(t, s.MaxQueueWait, 50*time.Millisecond)
+	assert.Positive(t, s.AvgQueueWait)
+	assert.LessOrEqual(t, s.AvgQueueWait, s.MaxQueueWait)
+
+	// Saturation stops accruing once the queue has room
+	saturated := s.SaturatedDuration
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, saturated, wp.Snapshot().SaturatedDuration)
+
+	wp.ResetWatermarks()
+	s = wp.Snapshot()
+	assert.Equal(t, 5, s.QueueHighWater)
+	assert.Equal(t, 0, s.QueueHighWaterSinceReset)
+	assert.Zero(t, s.RejectedTasks)
+	assert.Zero(t, s.SaturatedDuration)
+	assert.Zero(t, s.AvgQueueWait)
+	assert.Zero(t, s.MaxQueueWait)
+}
+
+func TestWorkerPool_WatermarksConcurrent(t *testing.T) {
+	wp := NewWorkerPool(4, 4, WithQueueCapacity(8))
+	wp.Start()
+	defer wp.Stop()
+
+	go func() {
+		for range wp.Results() {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted, rejected := 0, 0
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				err := wp.Submit(Task{ID: fmt.Sprintf("%d-%d", g, i), Execute: func(ctx context.Context) (interface{}, error) {
+					time.Sleep(100 * time.Microsecond)
+					return nil, nil
+				}})
+				mu.Lock()
+				if err == nil {
+					accepted++
+				} else {
+					rejected++
+				}
+				mu.Unlock()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	s := wp.Snapshot()
+	assert.Equal(t, int64(rejected), s.RejectedTasks)
+	assert.Equal(t, 1600, accepted+rejected)
+	assert.LessOrEqual(t, s.QueueHighWater, 8)
+	assert.Positive(t, s.QueueHighWater)
+}
+
+func TestWaitWindow_ExpiresOldBuckets(t *testing.T) {
+	w := waitWindow{bucketSize: int64(time.Second)}
+	start := time.Unix(1000, 0)
+
+	w.record(start, 10*time.Millisecond)
+	w.record(start.Add(time.Second), 30*time.Millisecond)
+
+	avg, maxWait := w.stats(start.Add(time.Second))
+	assert.Equal(t, 20*time.Millisecond, avg)
+	assert.Equal(t, 30*time.Millisecond, maxWait)
+
+	// The first bucket falls out of the window, the second is still inside
+	avg, maxWait = w.stats(start.Add(10 * time.Second))
+	assert.Equal(t, 30*time.Millisecond, avg)
+	assert.Equal(t, 30*time.Millisecond, maxWait)
+
+	// Recording over a stale bucket replaces its contents
+	w.record(start.Add(10*time.Second), 5*time.Millisecond)
+	avg, _ = w.stats(start.Add(10 * time.Second))
+	assert.Equal(t, 17500*time.Microsecond, avg)
+}
+
+func TestWorkerPool_ResetStats(t *testing.T) {
+	wp := NewWorkerPool(2, 2)
+	wp.Start()
+	defer wp.Stop()
+
+	run := func(n int, fail bool) {
+		for i := 0; i < n; i++ {
+			require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+				if fail {
+					return nil, fmt.Errorf("boom")
+				}
+				return nil, nil
+			}}))
+			<-wp.Results()
+		}
+	}
+
+	run(3, false)
+	run(2, true)
+
+	wp.ResetStats()
+	s := wp.Snapshot()
+	assert.Zero(t, s.TotalTasks)
+	assert.Zero(t, s.CompletedTasks)
+	assert.Zero(t, s.FailedTasks)
+	assert.False(t, s.StatsResetAt.IsZero())
+	assert.Equal(t, int64(5), s.LifetimeTotalTasks)
+	// Completed counts every finished task, failed or not
+	assert.Equal(t, int64(5), s.LifetimeCompletedTasks)
+	assert.Equal(t, int64(2), s.LifetimeFailedTasks)
+
+	run(1, false)
+	s = wp.Snapshot()
+	assert.Equal(t, int64(1), s.TotalTasks)
+	assert.Equal(t, int64(1), s.CompletedTasks)
+	assert.Equal(t, int64(6), s.LifetimeTotalTasks)
+	assert.Equal(t, int64(6), s.LifetimeCompletedTasks)
+	assert.Equal(t, int64(2), s.LifetimeFailedTasks)
+}
+
+func TestWorkerPool_ResetStatsConcurrent(t *testing.T) {
+	wp := NewWorkerPool(4, 4, WithQueueCapacity(1000))
+	wp.Start()
+	defer wp.Stop()
+
+	const tasks = 500
+	go func() {
+		for i := 0; i < tasks; i++ {
+			<-wp.Results()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < tasks; i++ {
+			assert.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) { return nil, nil }}))
+		}
+	}()
+
+	// Reset repeatedly while tasks complete; window counters must never go negative
+	for i := 0; i < 50; i++ {
+		s := wp.Snapshot()
+		assert.GreaterOrEqual(t, s.CompletedTasks, int64(0))
+		assert.GreaterOrEqual(t, s.TotalTasks, int64(0))
+		wp.ResetStats()
+	}
+	wg.Wait()
+
+	require.Eventually(t, func() bool {
+		return wp.Snapshot().LifetimeCompletedTasks == tasks
+	}, 5*time.Second, 5*time.Millisecond)
+}
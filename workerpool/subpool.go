@@ -0,0 +1,632 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSubPoolClosed is returned when submitting to a sub-pool that has been closed.
+var ErrSubPoolClosed = errors.New("sub-pool is closed")
+
+// ErrTaskPanicked is the result of a sub-pool task that panicked. The panic still reaches
+// the pool's panic handler and ends the worker that ran the task, which is replaced.
+var ErrTaskPanicked = errors.New("task panicked")
+
+// SubPool is a named share of a WorkerPool's workers. It can always have its reserved number
+// of tasks in flight, and bursts up to its maximum into workers that are neither busy nor
+// held for other sub-pools' unmet reservations. Tasks submitted to the pool itself use the
+// same unreserved workers, so reserving every worker leaves none for them.
+// Running tasks are never preempted: when a worker frees up, sub-pools below their
+// reservation are served first and queued tasks of sub-pools above it wait.
+type SubPool struct {
+	pool     *WorkerPool
+	name     string
+	reserved int
+	maxBurst int
+	capacity int
+	results  chan Result
+	drained  chan struct{} // Closed once the sub-pool is closing and has no tasks left
+	closeRes sync.Once
+
+	// Guarded by pool.subs.mu
+	queue   []Task
+	running int // Tasks holding a worker, including ones waiting to deliver their result
+	closing bool
+	waiters map[string]chan Result
+
+	submitted int64
+	completed int64
+	failed    int64
+	rejected  int64
+}
+
+// SubPoolSnapshot is a point-in-time view of a sub-pool's usage of its parent's workers.
+type SubPoolSnapshot struct {
+	Name          string
+	Reserved      int
+	MaxBurst      int
+	Running       int
+	QueueSize     int
+	QueueCapacity int
+	IsClosed      bool
+
+	TotalTasks     int64
+	CompletedTasks int64
+	FailedTasks    int64
+	// RejectedTasks counts submits that failed with ErrQueueFull.
+	RejectedTasks int64
+}
+
+// subPoolScheduler hands queued sub-pool tasks to idle workers of the parent pool.
+type subPoolScheduler struct {
+	mu       sync.Mutex
+	pools    []*SubPool
+	reserved int           // Sum of the open sub-pools' reservations
+	cursor   int           // Round-robin position so equally eligible sub-pools take turns
+	count    int32         // len(pools), read without the lock on the worker fast path
+	ready    chan struct{} // Wakes an idle worker when a sub-pool task may be runnable
+	draining bool          // Set by StopAndWait so reservations no longer hold back queued tasks
+
+	parentRunning int32         // Tasks submitted to the pool itself that hold a worker
+	holding       int32         // Parent tasks dequeued by workers waiting for an unreserved worker
+	parentReady   chan struct{} // Wakes a worker holding a parent task when a worker frees up
+}
+
+// NewSubPool creates a sub-pool that shares the pool's workers. Reservations are capped so
+// their sum never exceeds maxWorkers, and minWorkers is raised to that sum so reserved
+// capacity is running without waiting for the autoscaler. maxBurst is raised to reserved
+// if smaller and capped at maxWorkers.
+func (wp *WorkerPool) NewSubPool(name string, reserved, maxBurst int) *SubPool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	s := &wp.subs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reserved = min(max(reserved, 0), wp.maxWorkers-s.reserved)
+	maxBurst = min(max(max(maxBurst, reserved), 1), wp.maxWorkers)
+
+	sp := &SubPool{
+		pool:     wp,
+		name:     name,
+		reserved: reserved,
+		maxBurst: maxBurst,
+		capacity: maxBurst * 10,
+		results:  make(chan Result, maxBurst*10),
+		drained:  make(chan struct{}),
+		waiters:  make(map[string]chan Result),
+	}
+	s.pools = append(s.pools, sp)
+	s.reserved += reserved
+	atomic.StoreInt32(&s.count, int32(len(s.pools)))
+
+	// Keep enough workers running to honor every reservation
+	if s.reserved > wp.minWorkers {
+		wp.minWorkers = s.reserved
+		if wp.started && wp.ctx.Err() == nil {
+			for i := int(atomic.LoadInt32(&wp.activeWorkers)); i < wp.minWorkers; i++ {
+				wp.startWorker()
+			}
+		}
+	}
+
+	return sp
+}
+
+// Name returns the sub-pool's name.
+func (sp *SubPool) Name() string {
+	return sp.name
+}
+
+// Submit adds a task to the sub-pool's queue.
+// Returns ErrPoolStopped if the parent pool is not running, ErrSubPoolClosed after Close,
+// and ErrQueueFull if the sub-pool's queue is full.
+func (sp *SubPool) Submit(task Task) error {
+	return sp.submit(task, nil)
+}
+
+// SubmitWait adds a task to the sub-pool and waits for its completion.
+// The result is returned directly and is not sent on Results.
+func (sp *SubPool) SubmitWait(task Task) (interface{}, error) {
+	resultCh := make(chan Result, 1)
+	if err := sp.submit(task, resultCh); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-sp.pool.ctx.Done():
+		return nil, errors.New("worker pool shutdown while waiting for task completion")
+	case result := <-resultCh:
+		return result.Value, result.Error
+	}
+}
+
+// submit queues a task, registering waiter to receive its result instead of Results.
+func (sp *SubPool) submit(task Task, waiter chan Result) error {
+	wp := sp.pool
+	if task.Execute == nil {
+		return errors.New("task function cannot be nil")
+	}
+
+	// Generate an ID if not provided
+	if task.ID == "" {
+		task.ID = fmt.Sprintf("task-%d", atomic.AddInt64(&wp.nextTaskID, 1))
+	}
+
+	wp.mu.RLock()
+	isRunning := wp.isRunning
+	wp.mu.RUnlock()
+
+	if !isRunning {
+		return ErrPoolStopped
+	}
+
+	s := &wp.subs
+	s.mu.Lock()
+	if sp.closing {
+		s.mu.Unlock()
+		return ErrSubPoolClosed
+	}
+	if len(sp.queue) >= sp.capacity {
+		s.mu.Unlock()
+		atomic.AddInt64(&sp.rejected, 1)
+		return ErrQueueFull
+	}
+	task.enqueuedAt = time.Now()
+	task.state = wp.tasks.add(task.ID)
+	sp.queue = append(sp.queue, task)
+	if waiter != nil {
+		sp.waiters[task.ID] = waiter
+	}
+	s.mu.Unlock()
+
+	atomic.AddInt64(&sp.submitted, 1)
+	atomic.AddInt64(&wp.totalTasks, 1)
+	s.notify()
+	return nil
+}
+
+// Results returns a channel for receiving the sub-pool's task results.
+// It is closed by Close and when the parent pool stops.
+func (sp *SubPool) Results() <-chan Result {
+	return sp.results
+}
+
+// Close stops the sub-pool accepting tasks, waits until its queued and running tasks have
+// finished, and closes Results. Results must be read meanwhile if they can fill its buffer.
+// Sibling sub-pools are unaffected. If the parent stops first, queued tasks are discarded.
+func (sp *SubPool) Close() {
+	wp := sp.pool
+	s := &wp.subs
+
+	s.mu.Lock()
+	sp.closing = true
+	sp.checkDrainedLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-sp.drained:
+	case <-wp.ctx.Done():
+		// Workers exit without running the rest of the queue
+		wp.wg.Wait()
+	}
+
+	s.remove(sp)
+	sp.closeResults()
+}
+
+// closeResults closes the Results channel once.
+func (sp *SubPool) closeResults() {
+	sp.closeRes.Do(func() {
+		close(sp.results)
+	})
+}
+
+// checkDrainedLocked closes drained if the sub-pool is closing and idle. The caller must hold pool.subs.mu.
+func (sp *SubPool) checkDrainedLocked() {
+	if !sp.closing || len(sp.queue) > 0 || sp.running > 0 {
+		return
+	}
+	select {
+	case <-sp.drained:
+	default:
+		close(sp.drained)
+	}
+}
+
+// Snapshot returns the sub-pool's current usage.
+func (sp *SubPool) Snapshot() SubPoolSnapshot {
+	s := &sp.pool.subs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sp.snapshotLocked()
+}
+
+// snapshotLocked builds a snapshot. The caller must hold pool.subs.mu.
+func (sp *SubPool) snapshotLocked() SubPoolSnapshot {
+	return SubPoolSnapshot{
+		Name:           sp.name,
+		Reserved:       sp.reserved,
+		MaxBurst:       sp.maxBurst,
+		Running:        sp.running,
+		QueueSize:      len(sp.queue),
+		QueueCapacity:  sp.capacity,
+		IsClosed:       sp.closing,
+		TotalTasks:     atomic.LoadInt64(&sp.submitted),
+		CompletedTasks: atomic.LoadInt64(&sp.completed),
+		FailedTasks:    atomic.LoadInt64(&sp.failed),
+		RejectedTasks:  atomic.LoadInt64(&sp.rejected),
+	}
+}
+
+// Stats returns current statistics about the sub-pool.
+func (sp *SubPool) Stats() map[string]interface{} {
+	return sp.Snapshot().stats()
+}
+
+// stats converts a snapshot to the map form used by Stats.
+func (s SubPoolSnapshot) stats() map[string]interface{} {
+	return map[string]interface{}{
+		"name":            s.Name,
+		"reserved":        s.Reserved,
+		"max_burst":       s.MaxBurst,
+		"running":         s.Running,
+		"queue_size":      s.QueueSize,
+		"queue_capacity":  s.QueueCapacity,
+		"is_closed":       s.IsClosed,
+		"total_tasks":     s.TotalTasks,
+		"completed_tasks": s.CompletedTasks,
+		"failed_tasks":    s.FailedTasks,
+		"rejected_tasks":  s.RejectedTasks,
+	}
+}
+
+// deliver hands a finished task's result to its waiter or to Results, then frees its slot.
+// It returns false if the pool stopped before the result could be sent.
+func (sp *SubPool) deliver(result Result) bool {
+	s := &sp.pool.subs
+	s.mu.Lock()
+	waiter := sp.waiters[result.TaskID]
+	delete(sp.waiters, result.TaskID)
+	s.mu.Unlock()
+
+	if result.Error != nil {
+		atomic.AddInt64(&sp.failed, 1)
+	}
+	atomic.AddInt64(&sp.completed, 1)
+
+	delivered := true
+	if waiter != nil {
+		waiter <- result
+	} else {
+		select {
+		case <-sp.pool.ctx.Done():
+			delivered = false
+		case sp.results <- result:
+		}
+	}
+
+	s.mu.Lock()
+	sp.running--
+	sp.checkDrainedLocked()
+	wake := s.runnableLocked(sp.pool.Size())
+	s.mu.Unlock()
+
+	if wake {
+		s.notify()
+	}
+	s.notifyHolders()
+	return delivered
+}
+
+// notify wakes an idle worker without blocking. One pending wakeup is enough, since each
+// worker that takes a task wakes another while runnable tasks remain.
+func (s *subPoolScheduler) notify() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// notifyHolders wakes a worker holding a parent task, if any, without blocking.
+func (s *subPoolScheduler) notifyHolders() {
+	if atomic.LoadInt32(&s.holding) == 0 {
+		return
+	}
+	select {
+	case s.parentReady <- struct{}{}:
+	default:
+	}
+}
+
+// next dequeues the task an idle worker should run. Sub-pools below their reservation go
+// first; unless reservedOnly is set, the others may then burst up to their maximum if one of
+// the pool's workers is spare.
+func (s *subPoolScheduler) next(reservedOnly bool, workers int) (*SubPool, Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sp := s.pickLocked(func(sp *SubPool) bool { return sp.running < sp.reserved })
+	if sp == nil && !reservedOnly && s.spareLocked(workers) {
+		sp = s.pickLocked(func(sp *SubPool) bool { return sp.running < sp.maxBurst })
+	}
+	if sp == nil {
+		return nil, Task{}, false
+	}
+
+	task := sp.queue[0]
+	sp.queue[0] = Task{}
+	sp.queue = sp.queue[1:]
+	sp.running++
+
+	if s.runnableLocked(workers) {
+		s.notify()
+	}
+	return sp, task, true
+}
+
+// pickLocked returns the first sub-pool after the cursor that has queued tasks and passes eligible.
+func (s *subPoolScheduler) pickLocked(eligible func(*SubPool) bool) *SubPool {
+	for i := range s.pools {
+		idx := (s.cursor + i) % len(s.pools)
+		if sp := s.pools[idx]; len(sp.queue) > 0 && eligible(sp) {
+			s.cursor = idx + 1
+			return sp
+		}
+	}
+	return nil
+}
+
+// runnableLocked reports whether any sub-pool has a queued task it has room to run.
+func (s *subPoolScheduler) runnableLocked(workers int) bool {
+	spare := s.spareLocked(workers)
+	for _, sp := range s.pools {
+		if len(sp.queue) > 0 && (sp.running < sp.reserved || spare && sp.running < sp.maxBurst) {
+			return true
+		}
+	}
+	return false
+}
+
+// spareLocked reports whether one of workers is neither busy nor held for a sub-pool's unmet
+// reservation, so a sub-pool may burst or a parent task may start. The caller must hold mu.
+func (s *subPoolScheduler) spareLocked(workers int) bool {
+	if s.draining {
+		return true
+	}
+	used := int(atomic.LoadInt32(&s.parentRunning))
+	for _, sp := range s.pools {
+		// A sub-pool below its reservation keeps the rest of it free
+		used += max(sp.running, sp.reserved)
+	}
+	return used < workers
+}
+
+// admitParent reports whether a task submitted to the pool itself may start on one of
+// workers, counting it as running if so.
+func (s *subPoolScheduler) admitParent(workers int) bool {
+	if atomic.LoadInt32(&s.count) == 0 {
+		atomic.AddInt32(&s.parentRunning, 1)
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.spareLocked(workers) {
+		return false
+	}
+	atomic.AddInt32(&s.parentRunning, 1)
+	if s.spareLocked(workers) {
+		// Another held task may fit in what is left
+		s.notifyHolders()
+	}
+	return true
+}
+
+// parentDone records a parent task freeing its worker and wakes a worker that may use it.
+func (s *subPoolScheduler) parentDone(workers int) {
+	atomic.AddInt32(&s.parentRunning, -1)
+	s.notifyHolders()
+	if atomic.LoadInt32(&s.count) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	wake := s.runnableLocked(workers)
+	s.mu.Unlock()
+
+	if wake {
+		s.notify()
+	}
+}
+
+// drain stops reservations holding back queued tasks, so StopAndWait can run all of them.
+func (s *subPoolScheduler) drain() {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	s.notify()
+	s.notifyHolders()
+}
+
+// queued returns the number of tasks waiting in sub-pool queues, and parent tasks held by
+// workers until an unreserved worker is free.
+func (s *subPoolScheduler) queued() int {
+	n := int(atomic.LoadInt32(&s.holding))
+	if atomic.LoadInt32(&s.count) == 0 {
+		return n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sp := range s.pools {
+		n += len(sp.queue)
+	}
+	return n
+}
+
+// remove detaches a closed sub-pool and releases its reservation.
+func (s *subPoolScheduler) remove(sp *SubPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.pools {
+		if p == sp {
+			s.pools = append(s.pools[:i], s.pools[i+1:]...)
+			s.reserved -= sp.reserved
+			atomic.StoreInt32(&s.count, int32(len(s.pools)))
+
+			// Its unused reservation is free for the others
+			s.notify()
+			s.notifyHolders()
+			return
+		}
+	}
+}
+
+// drainQueues drops every queued sub-pool task for Drain, answering waiters with
+// ErrTaskCanceled. It returns the number of tasks dropped.
+func (s *subPoolScheduler) drainQueues(tasks *taskRegistry) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, sp := range s.pools {
+		for _, task := range sp.queue {
+			tasks.finish(task.ID, task.state)
+			if waiter := sp.waiters[task.ID]; waiter != nil {
+				delete(sp.waiters, task.ID)
+				waiter <- Result{TaskID: task.ID, Tag: task.Tag, Error: ErrTaskCanceled}
+			}
+		}
+		count += len(sp.queue)
+		sp.queue = nil
+		sp.checkDrainedLocked()
+	}
+	return count
+}
+
+// discard drops every queued sub-pool task when the parent pool stops.
+func (s *subPoolScheduler) discard(tasks *taskRegistry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sp := range s.pools {
+		for _, task := range sp.queue {
+			tasks.finish(task.ID, task.state)
+		}
+		sp.queue = nil
+	}
+}
+
+// closeResults closes every sub-pool's Results channel once the parent's workers have exited.
+func (s *subPoolScheduler) closeResults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sp := range s.pools {
+		sp.closeResults()
+	}
+}
+
+// snapshots returns each open sub-pool's usage in creation order.
+func (s *subPoolScheduler) snapshots() []SubPoolSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pools) == 0 {
+		return nil
+	}
+	snapshots := make([]SubPoolSnapshot, len(s.pools))
+	for i, sp := range s.pools {
+		snapshots[i] = sp.snapshotLocked()
+	}
+	return snapshots
+}
+
+// runSubPoolTask runs the next sub-pool task picked by the scheduler, considering only
+// sub-pools below their reservation if reservedOnly is set. It reports whether a task ran,
+// and ok is false if the pool stopped before the result was delivered.
+func (wp *WorkerPool) runSubPoolTask(workerCtx context.Context, workerID int, reservedOnly bool) (ran, ok bool) {
+	if atomic.LoadInt32(&wp.subs.count) == 0 {
+		return false, true
+	}
+	if wp.ctx.Err() != nil {
+		return false, false
+	}
+
+	sp, task, found := wp.subs.next(reservedOnly, wp.Size())
+	if !found {
+		return false, true
+	}
+
+	// A panicking task still frees its slot and answers its waiter before the panic ends the worker
+	finished := false
+	defer func() {
+		if !finished {
+			sp.deliver(Result{TaskID: task.ID, Tag: task.Tag, Error: ErrTaskPanicked})
+		}
+	}()
+
+	wp.recordWorkerState(workerID, WorkerBusy)
+	taskResult := wp.runTask(workerCtx, task)
+	wp.recordWorkerState(workerID, WorkerIdle)
+	finished = true
+
+	return true, sp.deliver(taskResult)
+}
+
+// runParentTask runs a task submitted to the pool itself once awaitParentSlot admitted it.
+// Its slot is freed even if the task panics.
+func (wp *WorkerPool) runParentTask(workerCtx context.Context, workerID int, task Task) Result {
+	defer wp.subs.parentDone(wp.Size())
+
+	wp.recordWorkerState(workerID, WorkerBusy)
+	taskResult := wp.runTask(workerCtx, task)
+	wp.recordWorkerState(workerID, WorkerIdle)
+	return taskResult
+}
+
+// awaitParentSlot waits until a parent task dequeued by the worker may start, running
+// sub-pool tasks meanwhile. It returns false if the pool stopped first, in which case the
+// task is dropped like Stop drops queued ones.
+func (wp *WorkerPool) awaitParentSlot(workerCtx context.Context, workerID int, task Task) bool {
+	s := &wp.subs
+	if s.admitParent(wp.Size()) {
+		return true
+	}
+
+	atomic.AddInt32(&s.holding, 1)
+	defer atomic.AddInt32(&s.holding, -1)
+
+	for {
+		ran, ok := wp.runSubPoolTask(workerCtx, workerID, false)
+		if !ok {
+			wp.tasks.finish(task.ID, task.state)
+			return false
+		}
+		if !ran {
+			select {
+			case <-wp.ctx.Done():
+				wp.tasks.finish(task.ID, task.state)
+				return false
+			case <-s.parentReady:
+			case <-s.ready:
+			}
+		}
+
+		// The held task goes before further sub-pool bursts
+		if s.admitParent(wp.Size()) {
+			return true
+		}
+	}
+}
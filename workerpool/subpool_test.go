@@ -0,0 +1,413 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gate counts the tasks it holds and releases them one per send on release
+type gate struct {
+	release chan struct{}
+	running int32
+	peak    int32
+}
+
+func newGate() *gate {
+	return &gate{release: make(chan struct{})}
+}
+
+func (g *gate) task(ctx context.Context) (interface{}, error) {
+	storeMax32(&g.peak, atomic.AddInt32(&g.running, 1))
+	defer atomic.AddInt32(&g.running, -1)
+
+	select {
+	case <-g.release:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func storeMax32(addr *int32, value int32) {
+	for {
+		current := atomic.LoadInt32(addr)
+		if value <= current || atomic.CompareAndSwapInt32(addr, current, value) {
+			return
+		}
+	}
+}
+
+// waitRunning waits until the sub-pools have the given numbers of tasks in flight
+func waitRunning(t *testing.T, pools []*SubPool, want ...int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		for i, sp := range pools {
+			if sp.Snapshot().Running != want[i] {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+func TestSubPool_ReservationsUnderFullLoad(t *testing.T) {
+	wp := NewWorkerPool(6, 6)
+	wp.Start()
+	defer wp.Stop()
+
+	batch := wp.NewSubPool("batch", 1, 6)
+	api := wp.NewSubPool("api", 3, 3)
+	parentGate, batchGate, apiGate := newGate(), newGate(), newGate()
+
+	// The parent queue and a bursting batch share the two unreserved workers while api is idle
+	for i := 0; i < 30; i++ {
+		require.NoError(t, wp.Submit(Task{Execute: parentGate.task}))
+		require.NoError(t, batch.Submit(Task{Execute: batchGate.task}))
+	}
+	require.Eventually(t, func() bool {
+		running := batch.Snapshot().Running
+		return running >= 1 && running+int(atomic.LoadInt32(&parentGate.running)) == 3
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 3, batch.Snapshot().Running+int(atomic.LoadInt32(&parentGate.running)),
+		"api's reservation stays free")
+
+	// api starts on its reserved workers without waiting for anything to finish
+	for i := 0; i < 30; i++ {
+		require.NoError(t, api.Submit(Task{Execute: apiGate.task}))
+	}
+	require.Eventually(t, func() bool {
+		return api.Snapshot().Running == 3
+	}, time.Second, time.Millisecond)
+
+	// With every queue full, a finished api task is replaced by another api task and the
+	// others keep to the unreserved workers
+	for round := 0; round < 5; round++ {
+		apiGate.release <- struct{}{}
+		select {
+		case batchGate.release <- struct{}{}:
+		case parentGate.release <- struct{}{}:
+		}
+		require.Eventually(t, func() bool {
+			running := batch.Snapshot().Running
+			return api.Snapshot().Running == 3 && running >= 1 &&
+				running+int(atomic.LoadInt32(&parentGate.running)) == 3
+		}, time.Second, time.Millisecond)
+	}
+
+	assert.Equal(t, int64(5), api.Snapshot().CompletedTasks)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&apiGate.peak))
+	assert.LessOrEqual(t, atomic.LoadInt32(&batchGate.peak), int32(3))
+	assert.LessOrEqual(t, atomic.LoadInt32(&parentGate.peak), int32(2))
+
+	// Once api's queue is empty its workers go idle rather than to batch or the parent
+	close(apiGate.release)
+	require.Eventually(t, func() bool {
+		snapshot := api.Snapshot()
+		return snapshot.QueueSize == 0 && snapshot.Running == 0
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 3, batch.Snapshot().Running+int(atomic.LoadInt32(&parentGate.running)))
+
+	close(batchGate.release)
+	close(parentGate.release)
+}
+
+func TestSubPool_LoneSubPoolBurstsToMax(t *testing.T) {
+	wp := NewWorkerPool(4, 4)
+	wp.Start()
+	defer wp.Stop()
+
+	sp := wp.NewSubPool("reports", 1, 3)
+	g := newGate()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, sp.Submit(Task{Execute: g.task}))
+	}
+
+	waitRunning(t, []*SubPool{sp}, 3)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&g.peak), "the fourth worker stays idle")
+	assert.Equal(t, 7, sp.Snapshot().QueueSize)
+
+	close(g.release)
+	for i := 0; i < 10; i++ {
+		r := <-sp.Results()
+		assert.NoError(t, r.Error)
+	}
+}
+
+func TestSubPool_ParentTasksWaitForUnreservedWorkers(t *testing.T) {
+	wp := NewWorkerPool(2, 2)
+	wp.Start()
+
+	wp.NewSubPool("everything", 2, 2)
+	var ran int32
+	require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&ran, 1)
+		return nil, nil
+	}}))
+
+	// Every worker is reserved, so the task waits even though the sub-pool is idle
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&ran))
+	assert.Equal(t, 1, wp.queued(), "a held task still counts as queued")
+
+	// StopAndWait runs it anyway
+	wp.StopAndWait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ran))
+}
+
+func TestSubPool_PanickingTaskReleasesSlot(t *testing.T) {
+	wp := NewWorkerPool(2, 2, WithPanicHandler(func(interface{}) {}))
+	wp.Start()
+	defer wp.Stop()
+
+	sp := wp.NewSubPool("panics", 1, 1)
+	_, err := sp.SubmitWait(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	}})
+	assert.ErrorIs(t, err, ErrTaskPanicked)
+	assert.Equal(t, 0, sp.Snapshot().Running)
+
+	closed := make(chan struct{})
+	go func() {
+		sp.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked after a panicking task")
+	}
+}
+
+func TestSubPool_ParentAdmittedAfterPanic(t *testing.T) {
+	wp := NewWorkerPool(2, 2, WithPanicHandler(func(interface{}) {}))
+	wp.Start()
+	defer wp.Stop()
+
+	wp.NewSubPool("reserved", 1, 1)
+	require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	}}))
+
+	// The panicking task freed its unreserved worker and the worker was replaced
+	require.NoError(t, wp.Submit(Task{ID: "after", Execute: func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}}))
+	select {
+	case r := <-wp.Results():
+		assert.Equal(t, "after", r.TaskID)
+	case <-time.After(time.Second):
+		t.Fatal("parent task starved after a panicking task")
+	}
+	assert.Equal(t, 2, wp.Size())
+}
+
+func TestSubPool_PanicReplacesLockedWorker(t *testing.T) {
+	var started int32
+	wp := NewWorkerPool(2, 2,
+		WithLockedOSThreads(2),
+		WithPanicHandler(func(interface{}) {}),
+		WithWorkerInit(func(ctx context.Context, workerID int) (interface{}, error) {
+			atomic.AddInt32(&started, 1)
+			return nil, nil
+		}),
+	)
+	wp.Start()
+	defer wp.Stop()
+
+	sp := wp.NewSubPool("reserved", 1, 2)
+	_, err := sp.SubmitWait(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	}})
+	require.ErrorIs(t, err, ErrTaskPanicked)
+
+	// The replacement runs its init and the pool stays within its thread limit
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&started) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, wp.Size())
+	value, err := sp.SubmitWait(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		return "ok", nil
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", value)
+}
+
+func TestSubPool_PanickingInitIsNotReplaced(t *testing.T) {
+	var inits int32
+	wp := NewWorkerPool(2, 2,
+		WithPanicHandler(func(interface{}) {}),
+		WithWorkerInit(func(ctx context.Context, workerID int) (interface{}, error) {
+			atomic.AddInt32(&inits, 1)
+			panic("init failed")
+		}),
+	)
+	wp.NewSubPool("reserved", 1, 1)
+	wp.Start()
+	defer wp.Stop()
+
+	// Replacing the workers would panic again in a loop
+	require.Eventually(t, func() bool { return wp.Size() == 0 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&inits))
+}
+
+func TestSubPool_DrainIncludesSubPoolQueues(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+	defer wp.Stop()
+
+	sp := wp.NewSubPool("drained", 1, 1)
+	g := newGate()
+	require.NoError(t, sp.Submit(Task{ID: "running", Execute: g.task}))
+	waitRunning(t, []*SubPool{sp}, 1)
+	require.NoError(t, sp.Submit(Task{ID: "queued", Execute: g.task}))
+
+	waited := make(chan error, 1)
+	go func() {
+		_, err := sp.SubmitWait(Task{ID: "waited", Execute: g.task})
+		waited <- err
+	}()
+	require.Eventually(t, func() bool { return sp.Snapshot().QueueSize == 2 }, time.Second, time.Millisecond)
+
+	assert.Equal(t, 2, wp.Drain())
+	assert.ErrorIs(t, <-waited, ErrTaskCanceled)
+
+	// The running task was canceled too
+	r := <-sp.Results()
+	assert.Equal(t, "running", r.TaskID)
+	assert.ErrorIs(t, r.Error, ErrTaskCanceled)
+	assert.False(t, wp.Cancel("queued"))
+}
+
+func TestSubPool_StopFinishesHeldParentTask(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+
+	wp.NewSubPool("everything", 1, 1)
+	require.NoError(t, wp.Submit(Task{ID: "held", Execute: func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}}))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&wp.subs.holding) == 1 }, time.Second, time.Millisecond)
+
+	wp.Stop()
+	assert.False(t, wp.Cancel("held"), "a dropped held task is no longer registered")
+}
+
+func TestSubPool_CapsReservationsAndRaisesMinWorkers(t *testing.T) {
+	wp := NewWorkerPool(1, 4)
+
+	a := wp.NewSubPool("a", 3, 2)
+	b := wp.NewSubPool("b", 3, 10)
+
+	assert.Equal(t, 3, a.Snapshot().Reserved)
+	assert.Equal(t, 3, a.Snapshot().MaxBurst, "burst is at least the reservation")
+	assert.Equal(t, 1, b.Snapshot().Reserved, "only one worker is left unreserved")
+	assert.Equal(t, 4, b.Snapshot().MaxBurst)
+	assert.Equal(t, 4, wp.Snapshot().MinWorkers)
+
+	wp.Start()
+	defer wp.Stop()
+	assert.Equal(t, 4, wp.Size())
+}
+
+func TestSubPool_CloseDrainsWithoutAffectingSiblings(t *testing.T) {
+	wp := NewWorkerPool(2, 2)
+	wp.Start()
+	defer wp.Stop()
+
+	closing := wp.NewSubPool("closing", 1, 1)
+	sibling := wp.NewSubPool("sibling", 1, 1)
+
+	var ran int32
+	for i := 0; i < 5; i++ {
+		require.NoError(t, closing.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&ran, 1)
+			return nil, nil
+		}}))
+	}
+	g := newGate()
+	require.NoError(t, sibling.Submit(Task{Execute: g.task}))
+
+	closing.Close()
+	assert.Equal(t, int32(5), atomic.LoadInt32(&ran), "queued tasks run before Close returns")
+	assert.ErrorIs(t, closing.Submit(Task{Execute: g.task}), ErrSubPoolClosed)
+
+	count := 0
+	for range closing.Results() {
+		count++
+	}
+	assert.Equal(t, 5, count)
+
+	// The sibling keeps its running task and accepts new ones
+	assert.Equal(t, 1, sibling.Snapshot().Running)
+	close(g.release)
+	value, err := sibling.SubmitWait(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		return "ok", nil
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", value)
+
+	subPools := wp.Snapshot().SubPools
+	require.Len(t, subPools, 1)
+	assert.Equal(t, "sibling", subPools[0].Name)
+}
+
+func TestSubPool_ParentStatsBreakdown(t *testing.T) {
+	wp := NewWorkerPool(2, 2)
+	wp.Start()
+	defer wp.Stop()
+
+	ok := wp.NewSubPool("ok", 1, 2)
+	failing := wp.NewSubPool("failing", 1, 2)
+
+	_, err := ok.SubmitWait(Task{Execute: func(ctx context.Context) (interface{}, error) { return nil, nil }})
+	require.NoError(t, err)
+	_, err = failing.SubmitWait(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		return nil, assert.AnError
+	}})
+	require.ErrorIs(t, err, assert.AnError)
+
+	stats := wp.Stats()
+	assert.Equal(t, int64(2), stats["completed_tasks"])
+	subPools := stats["sub_pools"].(map[string]interface{})
+	require.Len(t, subPools, 2)
+
+	okStats := subPools["ok"].(map[string]interface{})
+	assert.Equal(t, int64(1), okStats["completed_tasks"])
+	assert.Equal(t, int64(0), okStats["failed_tasks"])
+	assert.Equal(t, 1, okStats["reserved"])
+
+	failingStats := subPools["failing"].(map[string]interface{})
+	assert.Equal(t, int64(1), failingStats["failed_tasks"])
+	assert.Equal(t, failing.Stats(), failingStats)
+}
+
+func TestSubPool_StopDiscardsQueueAndClosesResults(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+
+	sp := wp.NewSubPool("jobs", 1, 1)
+	g := newGate()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sp.Submit(Task{Execute: g.task}))
+	}
+	waitRunning(t, []*SubPool{sp}, 1)
+
+	wp.Stop()
+	sp.Close()
+
+	// Only the running task can have reported; Results is closed after it
+	count := 0
+	for range sp.Results() {
+		count++
+	}
+	assert.LessOrEqual(t, count, 1)
+	assert.ErrorIs(t, sp.Submit(Task{Execute: g.task}), ErrPoolStopped)
+}
@@ -0,0 +1,8 @@
+package workerpool
+
+import "syscall"
+
+// threadID returns the ID of the calling OS thread.
+func threadID() (int, bool) {
+	return syscall.Gettid(), true
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package workerpool
+
+// threadID is unsupported outside Linux.
+func threadID() (int, bool) {
+	return 0, false
+}
@@ -22,12 +22,16 @@ type Task struct {
 	ID      string
 	Execute TaskFunc
 	Timeout time.Duration // Optional per-task timeout
+	Tag     string        // Optional category used to group tasks in execution reports
 
+	enqueuedAt time.Time  // Set by Submit for queue wait tracking
+	state      *taskState // Set by Submit for cancellation
 }
 
 // Result represents the outcome of a task execution.
 type Result struct {
 	TaskID    string
+	Tag       string
 	Value     interface{}
 	Error     error
 	StartTime time.Time
@@ -35,6 +39,32 @@ type Result struct {
 	Duration  time.Duration
 }
 
+// Errors returned by Submit.
+var (
+	// ErrPoolStopped is returned when submitting to a pool that isn't running.
+	ErrPoolStopped = errors.New("worker pool is not running")
+
+	// ErrQueueFull is returned when the task queue is at capacity.
+	ErrQueueFull = errors.New("task queue is full")
+)
+
+// WorkerInitFunc creates a per-worker resource when a worker starts.
+type WorkerInitFunc func(ctx context.Context, workerID int) (interface{}, error)
+
+// WorkerCleanupFunc releases a per-worker resource when a worker exits.
+type WorkerCleanupFunc func(workerID int, resource interface{})
+
+// workerResourceKey is the context key for the per-worker resource.
+type workerResourceKey struct{}
+
+const (
+	// workerInitAttempts is the number of times a failing worker init is attempted.
+	workerInitAttempts = 3
+
+	// workerInitBackoff is the initial delay between worker init attempts.
+	workerInitBackoff = 50 * time.Millisecond
+)
+
 // WorkerPool manages a pool of workers that execute tasks concurrently.
 type WorkerPool struct {
 	// Configuration
@@ -52,6 +82,13 @@ type WorkerPool struct {
 	totalTasks     int64
 	completedTasks int64
 	failedTasks    int64
+	nextWorkerID   int32
+	nextTaskID     int64
+	initFailures   int64
+	marks          watermarks
+	statsBase      statsBaseline
+	tasks          taskRegistry
+	subs           subPoolScheduler
 
 	// Control
 	ctx          context.Context
@@ -59,12 +96,20 @@ type WorkerPool struct {
 	wg           sync.WaitGroup
 	mu           sync.RWMutex
 	isRunning    bool
+	started      bool // Set by Start; Stop shuts down a started pool even if it is paused
 	shutdownOnce sync.Once
 
 	// Options
-	autoScale    bool
-	panicHandler func(interface{})
-	taskTimeout  time.Duration
+	autoScale     bool
+	scaling       scalingState
+	slo           sloState
+	panicHandler  func(interface{})
+	taskTimeout   time.Duration
+	workerInit    WorkerInitFunc
+	workerClean   WorkerCleanupFunc
+	onTaskEvent   func(TaskEvent)
+	recorder      Recorder
+	lockedThreads int // Maximum workers pinned to OS threads (0 leaves workers unpinned)
 }
 
 // Option defines a functional option for configuring the WorkerPool.
@@ -91,7 +136,9 @@ func WithAutoScaling() Option {
 	}
 }
 
-// WithPanicHandler sets a custom panic handler function.
+// WithPanicHandler sets a custom panic handler function. It receives the value of a panicking
+// task, and the worker that ran the task exits. Pools with sub-pools start a replacement
+// worker; other pools run with one fewer worker.
 func WithPanicHandler(handler func(interface{})) Option {
 	return func(wp *WorkerPool) {
 		wp.panicHandler = handler
@@ -105,6 +152,38 @@ func WithDefaultTaskTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithWorkerInit sets a hook that creates a resource for each worker when it starts.
+// The resource is available to tasks via WorkerResourceFromContext. Failing inits are
+// retried with backoff before the worker gives up.
+func WithWorkerInit(init WorkerInitFunc) Option {
+	return func(wp *WorkerPool) {
+		wp.workerInit = init
+	}
+}
+
+// WithWorkerCleanup sets a hook that releases a worker's resource when the worker exits,
+// including exits caused by a panic or by stopping the pool.
+func WithWorkerCleanup(cleanup WorkerCleanupFunc) Option {
+	return func(wp *WorkerPool) {
+		wp.workerClean = cleanup
+	}
+}
+
+// WorkerResourceFromContext returns the resource created by the worker init hook
+// for the worker executing the task.
+func WorkerResourceFromContext(ctx context.Context) (interface{}, bool) {
+	resource, ok := ctx.Value(workerResourceKey{}).(workerResource)
+	if !ok {
+		return nil, false
+	}
+	return resource.value, true
+}
+
+// workerResource wraps a per-worker resource stored in the task context.
+type workerResource struct {
+	value interface{}
+}
+
 // NewWorkerPool creates a new worker pool with the specified configuration.
 func NewWorkerPool(minWorkers, maxWorkers int, options ...Option) *WorkerPool {
 	if minWorkers <= 0 {
@@ -125,12 +204,21 @@ func NewWorkerPool(minWorkers, maxWorkers int, options ...Option) *WorkerPool {
 		cancel:        cancel,
 		panicHandler:  defaultPanicHandler,
 		taskTimeout:   30 * time.Second,
+		scaling: scalingState{
+			interval:      defaultScaleInterval,
+			idleThreshold: defaultIdleThreshold,
+			retire:        make(chan struct{}),
+		},
 	}
+	wp.marks.waits.bucketSize = int64(defaultWaitWindow) / waitWindowBuckets
+	wp.subs.ready = make(chan struct{}, 1)
+	wp.subs.parentReady = make(chan struct{}, 1)
 
 	// Apply options
 	for _, option := range options {
 		option(wp)
 	}
+	wp.minWorkers, wp.maxWorkers = wp.clampWorkers(wp.minWorkers, wp.maxWorkers)
 
 	// Initialize channels
 	wp.taskQueue = make(chan Task, wp.queueCapacity)
@@ -154,6 +242,7 @@ func (wp *WorkerPool) Start() {
 	}
 
 	wp.isRunning = true
+	wp.started = true
 
 	// Launch initial set of workers
 	for i := 0; i < wp.minWorkers; i++ {
@@ -161,7 +250,12 @@ func (wp *WorkerPool) Start() {
 	}
 
 	// Start autoscaler if enabled
-	if wp.autoScale {
+	if wp.autoScale || wp.slo.target > 0 {
+		now := time.Now()
+		wp.scaling.lastAdjust = now
+		wp.slo.mu.Lock()
+		wp.slo.lastAdjust = now
+		wp.slo.mu.Unlock()
 		go wp.autoScaler()
 	}
 }
@@ -171,71 +265,116 @@ func (wp *WorkerPool) startWorker() {
 	wp.wg.Add(1)
 	atomic.AddInt32(&wp.activeWorkers, 1)
 
+	workerID := int(atomic.AddInt32(&wp.nextWorkerID, 1))
+
 	go func() {
+		wp.lockWorkerThread()
+		initialized, replace := false, false
 		defer wp.wg.Done()
+		defer func() {
+			// Replace only once this worker no longer counts as active, so locked pools stay within their thread limit
+			if replace {
+				wp.startWorker()
+			}
+		}()
 		defer atomic.AddInt32(&wp.activeWorkers, -1)
 		defer func() {
 			if r := recover(); r != nil {
 				if wp.panicHandler != nil {
 					wp.panicHandler(r)
 				}
+				// A panicking init would panic again in the replacement, so only task panics are replaced
+				replace = initialized && wp.replacesPanickedWorkers()
 			}
 		}()
 
-		wp.worker()
+		workerCtx := wp.ctx
+		if wp.workerInit != nil {
+			resource, err := wp.initWorker(workerID)
+			if err != nil {
+				atomic.AddInt64(&wp.initFailures, 1)
+				log.Printf("Worker %d init failed: %v", workerID, err)
+				return
+			}
+
+			if wp.workerClean != nil {
+				defer wp.workerClean(workerID, resource)
+			}
+			workerCtx = context.WithValue(wp.ctx, workerResourceKey{}, workerResource{value: resource})
+		}
+		initialized = true
+
+		wp.recordWorkerState(workerID, WorkerIdle)
+		defer wp.recordWorkerState(workerID, WorkerExited)
+
+		wp.worker(workerCtx, workerID)
 	}()
+
+	// The new worker may leave room for a sub-pool to burst or a held parent task to start
+	if atomic.LoadInt32(&wp.subs.count) > 0 {
+		wp.subs.notify()
+		wp.subs.notifyHolders()
+	}
+}
+
+// replacesPanickedWorkers reports whether a worker whose task panicked is replaced. Pools with
+// sub-pools keep their size so reservations stay backed by workers; other pools shrink.
+func (wp *WorkerPool) replacesPanickedWorkers() bool {
+	return wp.ctx.Err() == nil && atomic.LoadInt32(&wp.subs.count) > 0
+}
+
+// initWorker runs the worker init hook, retrying with exponential backoff.
+func (wp *WorkerPool) initWorker(workerID int) (interface{}, error) {
+	var err error
+	for attempt := 0; attempt < workerInitAttempts; attempt++ {
+		var resource interface{}
+		resource, err = wp.workerInit(wp.ctx, workerID)
+		if err == nil {
+			return resource, nil
+		}
+
+		if attempt == workerInitAttempts-1 {
+			break
+		}
+
+		select {
+		case <-wp.ctx.Done():
+			return nil, wp.ctx.Err()
+		case <-time.After(workerInitBackoff * time.Duration(1<<attempt)):
+		}
+	}
+
+	return nil, fmt.Errorf("worker init failed after %d attempts: %w", workerInitAttempts, err)
 }
 
 // worker processes tasks from the queue.
-func (wp *WorkerPool) worker() {
+// Task contexts derive from workerCtx, which carries the worker's resource.
+func (wp *WorkerPool) worker(workerCtx context.Context, workerID int) {
 	for {
+		// Sub-pools below their reservation go before tasks submitted to the pool itself
+		if ran, ok := wp.runSubPoolTask(workerCtx, workerID, true); !ok {
+			return
+		} else if ran {
+			continue
+		}
+
 		select {
 		case <-wp.ctx.Done():
 			// Worker pool has been stopped
 			return
+		case <-wp.scaling.retire:
+			// Retired by the autoscaler
+			return
 		case task, ok := <-wp.taskQueue:
 			if !ok {
 				// Task queue has been closed
 				return
 			}
-
-			// Create task context with timeout if specified
-			var taskCtx context.Context
-			var cancel context.CancelFunc
-
-			if task.Timeout > 0 {
-				taskCtx, cancel = context.WithTimeout(wp.ctx, task.Timeout)
-			} else if wp.taskTimeout > 0 {
-				taskCtx, cancel = context.WithTimeout(wp.ctx, wp.taskTimeout)
-			} else {
-				taskCtx, cancel = context.WithCancel(wp.ctx)
-			}
-
-			// Execute the task and capture metrics
-			startTime := time.Now()
-			result, err := task.Execute(taskCtx)
-			endTime := time.Now()
-			duration := endTime.Sub(startTime)
-
-			// Clean up the context
-			cancel()
-
-			// Create and send the result
-			taskResult := Result{
-				TaskID:    task.ID,
-				Value:     result,
-				Error:     err,
-				StartTime: startTime,
-				EndTime:   endTime,
-				Duration:  duration,
-			}
-
-			// Update metrics
-			if err != nil {
-				atomic.AddInt64(&wp.failedTasks, 1)
+			// Leave workers reserved by sub-pools to them
+			if !wp.awaitParentSlot(workerCtx, workerID, task) {
+				return
 			}
-
-			atomic.AddInt64(&wp.completedTasks, 1)
+			taskResult := wp.runParentTask(workerCtx, workerID, task)
 
 			// Send result if the pool is still running
 			select {
@@ -245,13 +384,82 @@ func (wp *WorkerPool) worker() {
 			case wp.resultChan <- taskResult:
 				// Result sent successfully
 			}
+		case <-wp.subs.ready:
+			if _, ok := wp.runSubPoolTask(workerCtx, workerID, false); !ok {
+				return
+			}
 		}
 	}
 }
 
+// runTask executes a dequeued task with a context derived from parent and records its metrics.
+// Tasks canceled while queued are skipped and reported with ErrTaskCanceled.
+func (wp *WorkerPool) runTask(parent context.Context, task Task) Result {
+	wp.recordDequeue(task)
+
+	// Create task context with timeout if specified
+	var taskCtx context.Context
+	var cancel context.CancelFunc
+
+	if task.Timeout > 0 {
+		taskCtx, cancel = context.WithTimeout(parent, task.Timeout)
+	} else if wp.taskTimeout > 0 {
+		taskCtx, cancel = context.WithTimeout(parent, wp.taskTimeout)
+	} else {
+		taskCtx, cancel = context.WithCancel(parent)
+	}
+
+	// Skip tasks canceled while queued
+	if !wp.tasks.start(task.state, cancel) {
+		cancel()
+		wp.tasks.finish(task.ID, task.state)
+		taskResult := Result{TaskID: task.ID, Tag: task.Tag, Error: ErrTaskCanceled}
+		wp.emitTaskEvent(taskResult)
+		wp.recordResult(taskResult)
+		return taskResult
+	}
+	// Release the context and registry entry even if the task panics
+	defer cancel()
+	defer wp.tasks.finish(task.ID, task.state)
+
+	// Execute the task and capture metrics
+	startTime := time.Now()
+	result, err := task.Execute(taskCtx)
+	endTime := time.Now()
+	duration := endTime.Sub(startTime)
+
+	// Clean up the context
+	cancel()
+	if wp.tasks.finish(task.ID, task.state) && err != nil {
+		err = ErrTaskCanceled
+	}
+
+	taskResult := Result{
+		TaskID:    task.ID,
+		Tag:       task.Tag,
+		Value:     result,
+		Error:     err,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Duration:  duration,
+	}
+
+	// Update metrics
+	if err != nil {
+		atomic.AddInt64(&wp.failedTasks, 1)
+	}
+
+	atomic.AddInt64(&wp.completedTasks, 1)
+	wp.recordExecution(duration)
+	wp.emitTaskEvent(taskResult)
+	wp.recordResult(taskResult)
+
+	return taskResult
+}
+
 // autoScaler periodically adjusts the number of workers based on load.
 func (wp *WorkerPool) autoScaler() {
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(wp.scaling.interval)
 	defer ticker.Stop()
 
 	for {
@@ -264,8 +472,24 @@ func (wp *WorkerPool) autoScaler() {
 	}
 }
 
-// adjustWorkers scales the worker count based on queue size and processing rate.
+// adjustWorkers runs the enabled autoscaling policies. The SLO policy goes first, and the
+// queue heuristic is skipped when it has changed the worker count.
 func (wp *WorkerPool) adjustWorkers() {
+	if wp.slo.target > 0 {
+		if decision, ok := wp.adjustForSLO(time.Now()); ok {
+			if wp.slo.onDecision != nil {
+				wp.slo.onDecision(decision)
+			}
+			return
+		}
+	}
+	if wp.autoScale {
+		wp.adjustForQueue()
+	}
+}
+
+// adjustForQueue scales the worker count based on queue size, task latency and utilization.
+func (wp *WorkerPool) adjustForQueue() {
 	wp.mu.Lock()
 	defer wp.mu.Unlock()
 
@@ -273,25 +497,29 @@ func (wp *WorkerPool) adjustWorkers() {
 		return
 	}
 
-	queueSize := len(wp.taskQueue)
+	queueSize := wp.queued()
 	currentWorkers := int(atomic.LoadInt32(&wp.activeWorkers))
-
-	// Scale up if queue is backing up
-	if queueSize > currentWorkers && currentWorkers < wp.maxWorkers {
-		// Calculate how many workers to add (at most doubling, up to max)
-		toAdd := min(currentWorkers, wp.maxWorkers-currentWorkers)
-		if toAdd > 0 {
-			for i := 0; i < toAdd; i++ {
-				wp.startWorker()
-			}
+	avgLatency, utilization := wp.observedLoad(currentWorkers)
+
+	// Scale up if the queue is backing up, or if tasks are slowing down while others wait
+	backlog := queueSize > currentWorkers
+	slow := wp.scaling.latencyThreshold > 0 && avgLatency > wp.scaling.latencyThreshold && queueSize > 0
+	if (backlog || slow) && currentWorkers < wp.maxWorkers {
+		// Calculate how many workers to add (at most doubling, up to max).
+		// Locked workers each hold an OS thread, so they are added one at a time.
+		toAdd := min(max(currentWorkers, 1), wp.maxWorkers-currentWorkers)
+		if wp.lockedThreads > 0 {
+			toAdd = 1
+		}
+		for i := 0; i < toAdd; i++ {
+			wp.startWorker()
 		}
+		return
 	}
 
-	// Scale down if queue is empty and we have more than minimum workers
-	if queueSize == 0 && currentWorkers > wp.minWorkers {
-		// We'll scale down gradually by 25%
-		max(1, (currentWorkers-wp.minWorkers)/4)
-		// No immediate action - workers will exit naturally when the queue is empty
+	// Scale down gradually by 25% if the queue is empty and workers are mostly idle
+	if queueSize == 0 && currentWorkers > wp.minWorkers && utilization < wp.scaling.idleThreshold {
+		wp.retireWorkers(max(1, (currentWorkers-wp.minWorkers)/4))
 	}
 }
 
@@ -311,6 +539,11 @@ func max(a, b int) int {
 	return b
 }
 
+// queued returns the number of tasks waiting in the pool's queue and its sub-pools' queues.
+func (wp *WorkerPool) queued() int {
+	return len(wp.taskQueue) + wp.subs.queued()
+}
+
 // Submit adds a task to the queue for execution.
 // Returns ErrPoolStopped if the pool is not running or shutting down.
 // Returns ErrQueueFull if the task queue is full and the task cannot be queued.
@@ -321,7 +554,7 @@ func (wp *WorkerPool) Submit(task Task) error {
 
 	// Generate an ID if not provided
 	if task.ID == "" {
-		task.ID = fmt.Sprintf("task-%d", atomic.AddInt64(&wp.totalTasks, 1))
+		task.ID = fmt.Sprintf("task-%d", atomic.AddInt64(&wp.nextTaskID, 1))
 	}
 
 	// Check if pool is running
@@ -330,18 +563,25 @@ func (wp *WorkerPool) Submit(task Task) error {
 	wp.mu.RUnlock()
 
 	if !isRunning {
-		return errors.New("worker pool is not running")
+		return ErrPoolStopped
 	}
 
 	// Try to submit the task
+	task.enqueuedAt = time.Now()
+	task.state = wp.tasks.add(task.ID)
 	select {
 	case <-wp.ctx.Done():
-		return errors.New("worker pool is shutting down")
+		wp.tasks.finish(task.ID, task.state)
+		return ErrPoolStopped
 	case wp.taskQueue <- task:
+		atomic.AddInt64(&wp.totalTasks, 1)
+		wp.recordEnqueue()
 		return nil
 	default:
 		// Queue is full
-		return errors.New("task queue is full")
+		wp.tasks.finish(task.ID, task.state)
+		wp.recordRejection()
+		return ErrQueueFull
 	}
 }
 
@@ -393,7 +633,7 @@ func (wp *WorkerPool) Results() <-chan Result {
 func (wp *WorkerPool) Stop() {
 	wp.shutdownOnce.Do(func() {
 		wp.mu.Lock()
-		if !wp.isRunning {
+		if !wp.started {
 			wp.mu.Unlock()
 			return
 		}
@@ -407,6 +647,7 @@ func (wp *WorkerPool) Stop() {
 		for len(wp.taskQueue) > 0 {
 			<-wp.taskQueue
 		}
+		wp.subs.discard(&wp.tasks)
 
 		// Wait for all workers to finish
 		wp.wg.Wait()
@@ -414,6 +655,7 @@ func (wp *WorkerPool) Stop() {
 		// Close channels
 		close(wp.taskQueue)
 		close(wp.resultChan)
+		wp.subs.closeResults()
 	})
 }
 
@@ -427,9 +669,10 @@ func (wp *WorkerPool) StopAndWait() {
 	}
 	wp.isRunning = false
 	wp.mu.Unlock()
+	wp.subs.drain()
 
 	// Wait for queue to drain
-	for len(wp.taskQueue) > 0 {
+	for wp.queued() > 0 {
 		time.Sleep(100 * time.Millisecond)
 	}
 
@@ -461,36 +704,81 @@ func (wp *WorkerPool) Resume() {
 	wp.isRunning = true
 }
 
-// Drain removes all pending tasks from the queue without executing them.
+// Drain removes all pending tasks from the queue and sub-pool queues without executing them
+// and cancels running tasks with CancelAll. It returns the number of tasks removed.
+// SubmitWait callers of removed sub-pool tasks get ErrTaskCanceled.
 func (wp *WorkerPool) Drain() int {
-	count := 0
+	count := wp.subs.drainQueues(&wp.tasks)
 
 	for {
 		select {
-		case <-wp.taskQueue:
+		case task := <-wp.taskQueue:
+			wp.tasks.finish(task.ID, task.state)
 			count++
 		default:
+			// Stop the tasks that are already running too
+			wp.CancelAll()
 			return count
 		}
 	}
 }
 
+// DrainAndProcess stops accepting new tasks and runs the tasks still in the queue on the
+// calling goroutine, returning their results in queue order. Task contexts derive from ctx;
+// if ctx is done, the remaining tasks stay queued and the results so far are returned with
+// ctx's error. Tasks picked up by workers in the meantime report through Results as usual.
+func (wp *WorkerPool) DrainAndProcess(ctx context.Context) ([]Result, error) {
+	wp.mu.Lock()
+	wp.isRunning = false
+	wp.mu.Unlock()
+
+	var results []Result
+	for {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		select {
+		case task, ok := <-wp.taskQueue:
+			if !ok {
+				return results, nil
+			}
+			results = append(results, wp.runTask(ctx, task))
+		default:
+			return results, nil
+		}
+	}
+}
+
 // Stats returns current statistics about the worker pool.
+// Use Snapshot for typed access including queue watermarks.
 func (wp *WorkerPool) Stats() map[string]interface{} {
-	wp.mu.RLock()
-	defer wp.mu.RUnlock()
+	s := wp.Snapshot()
+
+	subPools := make(map[string]interface{}, len(s.SubPools))
+	for _, sub := range s.SubPools {
+		subPools[sub.Name] = sub.stats()
+	}
 
 	return map[string]interface{}{
-		"name":            wp.name,
-		"is_running":      wp.isRunning,
-		"min_workers":     wp.minWorkers,
-		"max_workers":     wp.maxWorkers,
-		"active_workers":  atomic.LoadInt32(&wp.activeWorkers),
-		"queue_capacity":  wp.queueCapacity,
-		"queue_size":      len(wp.taskQueue),
-		"total_tasks":     atomic.LoadInt64(&wp.totalTasks),
-		"completed_tasks": atomic.LoadInt64(&wp.completedTasks),
-		"failed_tasks":    atomic.LoadInt64(&wp.failedTasks),
+		"name":               s.Name,
+		"is_running":         s.IsRunning,
+		"min_workers":        s.MinWorkers,
+		"max_workers":        s.MaxWorkers,
+		"active_workers":     int32(s.ActiveWorkers),
+		"queue_capacity":     s.QueueCapacity,
+		"queue_size":         s.QueueSize,
+		"total_tasks":        s.TotalTasks,
+		"completed_tasks":    s.CompletedTasks,
+		"failed_tasks":       s.FailedTasks,
+		"lifetime_total":     s.LifetimeTotalTasks,
+		"lifetime_completed": s.LifetimeCompletedTasks,
+		"lifetime_failed":    s.LifetimeFailedTasks,
+		"init_failures":      s.InitFailures,
+		"queue_high_water":   s.QueueHighWater,
+		"rejected_tasks":     s.RejectedTasks,
+		"queue_wait_p95":     s.QueueWaitP95,
+		"sub_pools":          subPools,
 	}
 }
 
@@ -516,8 +804,19 @@ func (wp *WorkerPool) Resize(min, max int) {
 		max = min
 	}
 
-	wp.minWorkers = min
-	wp.maxWorkers = max
+	// Keep enough workers for the sub-pools' reservations
+	wp.subs.mu.Lock()
+	reserved := wp.subs.reserved
+	wp.subs.mu.Unlock()
+	if min < reserved {
+		min = reserved
+	}
+	if max < min {
+		max = min
+	}
+
+	wp.minWorkers, wp.maxWorkers = wp.clampWorkers(min, max)
+	min = wp.minWorkers
 
 	// Adjust current number of workers if needed
 	currentWorkers := int(atomic.LoadInt32(&wp.activeWorkers))
@@ -1 +1,233 @@
-package workerpool
\ No newline at end of file
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResource is a per-worker resource that counts open and close calls
+type fakeResource struct {
+	workerID int
+	closed   int32
+}
+
+// resourceTracker creates fakeResources and records their lifecycle
+type resourceTracker struct {
+	mu        sync.Mutex
+	opened    map[int]*fakeResource
+	openCount int32
+	closeCnt  int32
+}
+
+func newResourceTracker() *resourceTracker {
+	return &resourceTracker{opened: make(map[int]*fakeResource)}
+}
+
+func (rt *resourceTracker) init(ctx context.Context, workerID int) (interface{}, error) {
+	res := &fakeResource{workerID: workerID}
+	rt.mu.Lock()
+	rt.opened[workerID] = res
+	rt.mu.Unlock()
+	atomic.AddInt32(&rt.openCount, 1)
+	return res, nil
+}
+
+func (rt *resourceTracker) cleanup(workerID int, resource interface{}) {
+	atomic.AddInt32(&resource.(*fakeResource).closed, 1)
+	atomic.AddInt32(&rt.closeCnt, 1)
+}
+
+func TestWorkerPool_WorkerLifecycleHooks(t *testing.T) {
+	tracker := newResourceTracker()
+	wp := NewWorkerPool(3, 3,
+		WithWorkerInit(tracker.init),
+		WithWorkerCleanup(tracker.cleanup),
+	)
+	wp.Start()
+
+	var seen sync.Map
+	for i := 0; i < 30; i++ {
+		require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+			res, ok := WorkerResourceFromContext(ctx)
+			if !ok {
+				return nil, errors.New("missing worker resource")
+			}
+			seen.Store(res.(*fakeResource).workerID, true)
+			return nil, nil
+		}}))
+	}
+
+	for i := 0; i < 30; i++ {
+		result := <-wp.Results()
+		require.NoError(t, result.Error)
+	}
+
+	wp.Stop()
+
+	// Exactly one resource per worker, all closed exactly once
+	assert.Equal(t, int32(3), atomic.LoadInt32(&tracker.openCount))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&tracker.closeCnt))
+	for _, res := range tracker.opened {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&res.closed))
+	}
+	seen.Range(func(key, value interface{}) bool {
+		assert.Contains(t, tracker.opened, key)
+		return true
+	})
+}
+
+func TestWorkerPool_WorkerCleanupOnPanic(t *testing.T) {
+	tracker := newResourceTracker()
+	wp := NewWorkerPool(1, 1,
+		WithWorkerInit(tracker.init),
+		WithWorkerCleanup(tracker.cleanup),
+		WithPanicHandler(func(interface{}) {}),
+	)
+	wp.Start()
+
+	require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	}}))
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&tracker.closeCnt) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	wp.Stop()
+	assert.Equal(t, atomic.LoadInt32(&tracker.openCount), atomic.LoadInt32(&tracker.closeCnt))
+}
+
+func TestWorkerPool_PanicShrinksPoolWithoutSubPools(t *testing.T) {
+	var panics int32
+	wp := NewWorkerPool(2, 2, WithPanicHandler(func(interface{}) {
+		atomic.AddInt32(&panics, 1)
+	}))
+	wp.Start()
+	defer wp.Stop()
+
+	require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	}}))
+
+	// The worker that ran the task exits and is not replaced
+	require.Eventually(t, func() bool { return wp.Size() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&panics))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, wp.Size())
+}
+
+func TestWorkerPool_WorkerInitRetry(t *testing.T) {
+	var attempts int32
+	wp := NewWorkerPool(1, 1,
+		WithWorkerInit(func(ctx context.Context, workerID int) (interface{}, error) {
+			if atomic.AddInt32(&attempts, 1) < 2 {
+				return nil, errors.New("connection refused")
+			}
+			return "conn", nil
+		}),
+	)
+	wp.Start()
+	defer wp.Stop()
+
+	value, err := wp.SubmitWait(Task{ID: "with-resource", Execute: func(ctx context.Context) (interface{}, error) {
+		res, _ := WorkerResourceFromContext(ctx)
+		return res, nil
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "conn", value)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	assert.Equal(t, int64(0), wp.Stats()["init_failures"])
+}
+
+func TestWorkerPool_WorkerInitGivesUp(t *testing.T) {
+	var attempts int32
+	wp := NewWorkerPool(2, 2,
+		WithWorkerInit(func(ctx context.Context, workerID int) (interface{}, error) {
+			atomic.AddInt32(&attempts, 1)
+			return nil, errors.New("connection refused")
+		}),
+	)
+	wp.Start()
+	defer wp.Stop()
+
+	require.Eventually(t, func() bool {
+		return wp.Stats()["init_failures"] == int64(2)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2*workerInitAttempts), atomic.LoadInt32(&attempts))
+	assert.Equal(t, 0, wp.Size())
+}
+
+func TestWorkerPool_DrainAndProcess(t *testing.T) {
+	wp := NewWorkerPool(1, 1, WithQueueCapacity(10))
+	wp.Start()
+	defer wp.Stop()
+
+	// Occupy the only worker so the rest stay queued
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "busy", Execute: func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}}))
+	<-started
+	defer close(release)
+
+	for i := 0; i < 5; i++ {
+		i := i
+		require.NoError(t, wp.Submit(Task{ID: fmt.Sprintf("queued-%d", i), Execute: func(ctx context.Context) (interface{}, error) {
+			return i * 10, nil
+		}}))
+	}
+
+	results, err := wp.DrainAndProcess(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	for i, r := range results {
+		assert.Equal(t, fmt.Sprintf("queued-%d", i), r.TaskID)
+		assert.Equal(t, i*10, r.Value)
+		assert.NoError(t, r.Error)
+	}
+
+	// New tasks are rejected after draining
+	assert.ErrorIs(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) { return nil, nil }}), ErrPoolStopped)
+}
+
+func TestWorkerPool_DrainAndProcessRespectsContext(t *testing.T) {
+	wp := NewWorkerPool(1, 1, WithQueueCapacity(10))
+	wp.Start()
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, wp.Submit(Task{ID: "busy", Execute: func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}}))
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, wp.Submit(Task{Execute: func(ctx context.Context) (interface{}, error) {
+			cancel()
+			return nil, nil
+		}}))
+	}
+
+	// The first task cancels the context, so the others stay queued
+	results, err := wp.DrainAndProcess(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, results, 1)
+	assert.Equal(t, 2, len(wp.taskQueue))
+}